@@ -127,6 +127,7 @@ func main() {
 	userCollection := db.Collection("Users")
 	taskCollection := db.Collection("Tasks")
 	tagsCollection := db.Collection("Tags")
+	calendarEventCollection := db.Collection("CalendarEvents")
 
 	secret := environment.Global.Secret
 	if secret == "" {
@@ -138,7 +139,7 @@ func main() {
 	responseManager := communication.ResponseManager{Logger: logging, Environment: environment.Global.Environment}
 	userRepository := users.UserRepository{DB: userCollection, Logger: logging}
 
-	calendarRepositoryManager, err := tasks.NewCalendarRepositoryManager(10, &userRepository, logging)
+	calendarRepositoryManager, err := tasks.NewCalendarRepositoryManager(10, &userRepository, logging, calendarEventCollection)
 	if err != nil {
 		logging.Fatal(err)
 		return
@@ -149,7 +150,9 @@ func main() {
 	var taskRepository = tasks.MongoDBTaskRepository{DB: taskCollection, Logger: logging}
 	// taskRepository.Subscribe(&notificationController)
 
-	planningService := tasks.NewPlanningController(&userRepository, &taskRepository, logging, locker, calendarRepositoryManager)
+	tagRepository := tasks.TagRepository{Logger: logging, DB: tagsCollection}
+
+	planningService := tasks.NewPlanningController(&userRepository, &taskRepository, logging, locker, calendarRepositoryManager, &tagRepository)
 
 	emailService := email.NewSendInBlueService(environment.Global.Sendinblue)
 
@@ -165,12 +168,21 @@ func main() {
 		UserRepository:  &userRepository,
 		PlanningService: planningService}
 
-	tagRepository := tasks.TagRepository{Logger: logging, DB: tagsCollection}
 	tagHandler := tasks.TagHandler{
 		Logger: logging, TagRepository: tagRepository, ResponseManager: &responseManager, UserRepository: &userRepository,
 		TaskRepository: &taskRepository,
 	}
 
+	absenceHandler := tasks.AbsenceHandler{
+		Logger: logging, ResponseManager: &responseManager, UserRepository: &userRepository, Locker: locker,
+		PlanningService: planningService,
+	}
+
+	feedHandler := tasks.FeedHandler{
+		Logger: logging, ResponseManager: &responseManager, UserRepository: &userRepository, Locker: locker,
+		TaskRepository: &taskRepository, TaskTextRenderer: &tasks.TaskTextRenderer{},
+	}
+
 	r := mux.NewRouter()
 
 	authMiddleWare := auth.AuthenticationMiddleware{ErrorManager: &responseManager, Secret: secret}
@@ -195,11 +207,23 @@ func main() {
 	//unauthenticatedAPI.Path("/auth/login").HandlerFunc(userHandler.UserLogin).Methods(http.MethodPost)
 	unauthenticatedAPI.Path("/auth/login/google").HandlerFunc(userHandler.UserLoginWithGoogle).Methods(http.MethodPost)
 	unauthenticatedAPI.Path("/auth/google").HandlerFunc(calendarHandler.GoogleCalendarAuthCallback).Methods(http.MethodGet)
+	unauthenticatedAPI.Path("/auth/microsoft").HandlerFunc(calendarHandler.MicrosoftCalendarAuthCallback).Methods(http.MethodGet)
 
 	unauthenticatedAPI.Path("/calendar/google/notifications").
 		HandlerFunc(calendarHandler.GoogleCalendarNotification).Methods(http.MethodPost)
 	unauthenticatedAPI.Path("/calendar/google/notifications/renew").
 		HandlerFunc(calendarHandler.GoogleCalendarSyncRenewal).Methods(http.MethodPost)
+	unauthenticatedAPI.Path("/calendar/microsoft/notifications").
+		HandlerFunc(calendarHandler.MicrosoftCalendarNotification).Methods(http.MethodPost)
+
+	unauthenticatedAPI.Path("/tasks/recurring/materialize").
+		HandlerFunc(taskHandler.MaterializeRecurringTasks).Methods(http.MethodPost)
+	unauthenticatedAPI.Path("/tasks/workunits/missed/sweep").
+		HandlerFunc(taskHandler.SweepMissedWorkUnits).Methods(http.MethodPost)
+	unauthenticatedAPI.Path("/tasks/backlog/schedule").
+		HandlerFunc(taskHandler.ScheduleBacklogTasks).Methods(http.MethodPost)
+
+	unauthenticatedAPI.Path("/feed/{token:[0-9a-f-]+}.ics").HandlerFunc(feedHandler.ServeFeed).Methods(http.MethodGet)
 
 	unauthenticatedAPI.Path("/newsletter").
 		HandlerFunc(userHandler.RegisterForNewsletter).Methods(http.MethodPost)
@@ -218,6 +242,8 @@ func main() {
 
 	authenticatedAPI.Path("/tasks").HandlerFunc(taskHandler.TaskAdd).Methods(http.MethodPost)
 	authenticatedAPI.Path("/tasks").HandlerFunc(taskHandler.GetAllTasks).Methods(http.MethodGet)
+	authenticatedAPI.Path("/tasks/preview").HandlerFunc(taskHandler.TaskPreview).Methods(http.MethodPost)
+	authenticatedAPI.Path("/tasks/rebalance").HandlerFunc(taskHandler.TasksRebalance).Methods(http.MethodPost)
 	authenticatedAPI.Path("/tasks/between").HandlerFunc(taskHandler.GetTaskBetween).Methods(http.MethodGet)
 	authenticatedAPI.Path("/tasks/workunits").HandlerFunc(taskHandler.GetAllTasksByWorkUnits).Methods(http.MethodGet)
 	authenticatedAPI.Path("/tasks/workunits/between").HandlerFunc(taskHandler.GetWorkUnitsBetween).Methods(http.MethodGet)
@@ -226,6 +252,7 @@ func main() {
 	authenticatedAPI.Path("/tasks/{taskID}").HandlerFunc(taskHandler.TaskUpdate).Methods(http.MethodPatch)
 	authenticatedAPI.Path("/tasks/{taskID}").HandlerFunc(taskHandler.TaskDelete).Methods(http.MethodDelete)
 	authenticatedAPI.Path("/tasks/{taskID}/calendar").HandlerFunc(taskHandler.GetTaskDueDateCalendarData).Methods(http.MethodGet)
+	authenticatedAPI.Path("/tasks/{taskID}/schedule/explain").HandlerFunc(taskHandler.TaskScheduleExplain).Methods(http.MethodGet)
 	authenticatedAPI.Path("/tasks/{taskID}/workunits/{workUnitID}").HandlerFunc(taskHandler.WorkUnitUpdate).Methods(http.MethodPatch)
 	authenticatedAPI.Path("/tasks/{taskID}/workunits/{workUnitID}/calendar").HandlerFunc(taskHandler.GetWorkUnitCalendarData).Methods(http.MethodGet)
 	authenticatedAPI.Path("/tasks/{taskID}/workunits/{workUnitID}/done").HandlerFunc(taskHandler.MarkWorkUnitAsDone).Methods(http.MethodPatch)
@@ -233,15 +260,34 @@ func main() {
 	authenticatedAPI.Path("/tasks/{taskID}/workunits/{workUnitID}/reschedule").HandlerFunc(taskHandler.RescheduleWorkUnitGet).Methods(http.MethodPatch)
 	authenticatedAPI.Path("/tasks/{taskID}/workunits/{workUnitID}/reschedule").HandlerFunc(taskHandler.RescheduleWorkUnitPost).Methods(http.MethodPost)
 
+	authenticatedAPI.Path("/capacity").HandlerFunc(taskHandler.Capacity).Methods(http.MethodGet)
+
 	authenticatedAPI.Path("/tags").HandlerFunc(tagHandler.TagAdd).Methods(http.MethodPost)
 	authenticatedAPI.Path("/tags").HandlerFunc(tagHandler.GetAllTags).Methods(http.MethodGet)
 	authenticatedAPI.Path("/tags/{tagID}").HandlerFunc(tagHandler.TagUpdate).Methods(http.MethodPatch)
 	authenticatedAPI.Path("/tags/{tagID}").HandlerFunc(tagHandler.TagDelete).Methods(http.MethodDelete)
 
+	authenticatedAPI.Path("/absences").HandlerFunc(absenceHandler.AbsenceAdd).Methods(http.MethodPost)
+	authenticatedAPI.Path("/absences").HandlerFunc(absenceHandler.GetAbsences).Methods(http.MethodGet)
+	authenticatedAPI.Path("/absences/{absenceID}").HandlerFunc(absenceHandler.AbsenceUpdate).Methods(http.MethodPatch)
+	authenticatedAPI.Path("/absences/{absenceID}").HandlerFunc(absenceHandler.AbsenceDelete).Methods(http.MethodDelete)
+
+	authenticatedAPI.Path("/feed").HandlerFunc(feedHandler.GetFeed).Methods(http.MethodGet)
+	authenticatedAPI.Path("/feed/token").HandlerFunc(feedHandler.RotateFeedToken).Methods(http.MethodPost)
+
 	authenticatedAPI.Path("/connections/google").HandlerFunc(calendarHandler.InitiateGoogleCalendarAuth).Methods(http.MethodPost)
 	authenticatedAPI.Path("/connections/{connectionID}/google").HandlerFunc(calendarHandler.InitiateGoogleCalendarAuth).Methods(http.MethodPost)
 	authenticatedAPI.Path("/connections/{connectionID}/google").HandlerFunc(calendarHandler.DeleteGoogleConnection).Methods(http.MethodDelete)
 	authenticatedAPI.Path("/connections/{connectionID}/google/revoke").HandlerFunc(calendarHandler.RevokeGoogleAuth).Methods(http.MethodPost)
+	authenticatedAPI.Path("/connections/microsoft").HandlerFunc(calendarHandler.InitiateMicrosoftCalendarAuth).Methods(http.MethodPost)
+	authenticatedAPI.Path("/connections/{connectionID}/microsoft").HandlerFunc(calendarHandler.InitiateMicrosoftCalendarAuth).Methods(http.MethodPost)
+	authenticatedAPI.Path("/connections/{connectionID}/microsoft").HandlerFunc(calendarHandler.DeleteMicrosoftConnection).Methods(http.MethodDelete)
+	authenticatedAPI.Path("/connections/caldav").HandlerFunc(calendarHandler.SetCalDAVConnection).Methods(http.MethodPost)
+	authenticatedAPI.Path("/connections/{connectionID}/caldav").HandlerFunc(calendarHandler.SetCalDAVConnection).Methods(http.MethodPost)
+	authenticatedAPI.Path("/connections/{connectionID}/caldav").HandlerFunc(calendarHandler.DeleteCalDAVConnection).Methods(http.MethodDelete)
+	authenticatedAPI.Path("/connections/ics").HandlerFunc(calendarHandler.SetICSConnection).Methods(http.MethodPost)
+	authenticatedAPI.Path("/connections/{connectionID}/ics").HandlerFunc(calendarHandler.SetICSConnection).Methods(http.MethodPost)
+	authenticatedAPI.Path("/connections/{connectionID}/ics").HandlerFunc(calendarHandler.DeleteICSConnection).Methods(http.MethodDelete)
 	authenticatedAPI.Path("/connections/{connectionID}/calendars").HandlerFunc(calendarHandler.GetCalendarsFromConnection).Methods(http.MethodGet)
 	authenticatedAPI.Path("/connections/{connectionID}/calendars").HandlerFunc(calendarHandler.PatchCalendars).Methods(http.MethodPut)
 
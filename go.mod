@@ -20,6 +20,7 @@ require (
 	github.com/pkg/errors v0.9.1
 	github.com/sendinblue/APIv3-go-library v2.0.0+incompatible
 	github.com/stripe/stripe-go/v72 v72.99.0
+	github.com/teambition/rrule-go v1.8.2
 	github.com/youmark/pkcs8 v0.0.0-20201027041543-1326539a0a0a // indirect
 	go.mongodb.org/mongo-driver v1.10.1
 	golang.org/x/crypto v0.0.0-20220622213112-05595931fe9d
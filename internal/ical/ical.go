@@ -0,0 +1,472 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of iCalendar objects
+const ContentType = "text/calendar; charset=utf-8"
+
+// DateTimeLayout is the layout of DATE-TIME values in UTC
+const DateTimeLayout = "20060102T150405Z"
+
+// DateLayout is the layout of DATE values
+const DateLayout = "20060102"
+
+// localDateTimeLayout is the layout of floating DATE-TIME values and values with a TZID
+const localDateTimeLayout = "20060102T150405"
+
+// maxLineLength is the maximum line length in octets before a line has to be folded
+const maxLineLength = 75
+
+// Property is a single content line of an iCalendar object, the value is stored as it appears in the object
+type Property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// Component is an iCalendar component like VCALENDAR or VEVENT
+type Component struct {
+	Name       string
+	Properties []Property
+	Components []*Component
+}
+
+// NewCalendar creates a VCALENDAR with the required properties
+func NewCalendar(productID string) *Component {
+	cal := &Component{Name: "VCALENDAR"}
+	cal.Set("VERSION", "2.0")
+	cal.Set("PRODID", productID)
+	cal.Set("CALSCALE", "GREGORIAN")
+
+	return cal
+}
+
+// Get returns the first property with a name or nil
+func (c *Component) Get(name string) *Property {
+	for i := range c.Properties {
+		if c.Properties[i].Name == name {
+			return &c.Properties[i]
+		}
+	}
+
+	return nil
+}
+
+// GetAll returns all properties with a name
+func (c *Component) GetAll(name string) []Property {
+	var properties []Property
+	for _, property := range c.Properties {
+		if property.Name == name {
+			properties = append(properties, property)
+		}
+	}
+
+	return properties
+}
+
+// Value returns the value of the first property with a name or an empty string
+func (c *Component) Value(name string) string {
+	property := c.Get(name)
+	if property == nil {
+		return ""
+	}
+
+	return property.Value
+}
+
+// Text returns the unescaped value of the first TEXT property with a name
+func (c *Component) Text(name string) string {
+	return UnescapeText(c.Value(name))
+}
+
+// Set replaces all properties with a name by a single property
+func (c *Component) Set(name string, value string) {
+	properties := c.Properties[:0]
+	for _, property := range c.Properties {
+		if property.Name != name {
+			properties = append(properties, property)
+		}
+	}
+
+	c.Properties = append(properties, Property{Name: name, Value: value})
+}
+
+// SetText replaces all properties with a name by a single escaped TEXT property
+func (c *Component) SetText(name string, value string) {
+	c.Set(name, EscapeText(value))
+}
+
+// SetDateTime replaces all properties with a name by a single UTC DATE-TIME property
+func (c *Component) SetDateTime(name string, t time.Time) {
+	c.Set(name, t.UTC().Format(DateTimeLayout))
+}
+
+// Add adds a child component
+func (c *Component) Add(component *Component) {
+	c.Components = append(c.Components, component)
+}
+
+// ComponentsByName returns all direct child components with a name
+func (c *Component) ComponentsByName(name string) []*Component {
+	var components []*Component
+	for _, component := range c.Components {
+		if component.Name == name {
+			components = append(components, component)
+		}
+	}
+
+	return components
+}
+
+// Events returns all VEVENT components of a calendar
+func (c *Component) Events() []*Component {
+	return c.ComponentsByName("VEVENT")
+}
+
+// Encode writes the component as an iCalendar object with folded CRLF lines
+func (c *Component) Encode(writer io.Writer) error {
+	w := bufio.NewWriter(writer)
+
+	err := c.encode(w)
+	if err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+func (c *Component) encode(w *bufio.Writer) error {
+	err := writeLine(w, "BEGIN:"+c.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, property := range c.Properties {
+		err = writeLine(w, property.String())
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, component := range c.Components {
+		err = component.encode(w)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeLine(w, "END:"+c.Name)
+}
+
+// String returns the unfolded content line of the property
+func (p *Property) String() string {
+	var builder strings.Builder
+	builder.WriteString(p.Name)
+
+	names := make([]string, 0, len(p.Params))
+	for name := range p.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := p.Params[name]
+		builder.WriteString(";")
+		builder.WriteString(name)
+		builder.WriteString("=")
+		if strings.ContainsAny(value, ":;,") {
+			builder.WriteString(`"` + value + `"`)
+		} else {
+			builder.WriteString(value)
+		}
+	}
+
+	builder.WriteString(":")
+	builder.WriteString(p.Value)
+
+	return builder.String()
+}
+
+// writeLine folds a content line after 75 octets without splitting UTF-8 characters
+func writeLine(w *bufio.Writer, line string) error {
+	length := 0
+	for _, r := range line {
+		size := len(string(r))
+		if length+size > maxLineLength {
+			if _, err := w.WriteString("\r\n "); err != nil {
+				return err
+			}
+			length = 1
+		}
+
+		if _, err := w.WriteRune(r); err != nil {
+			return err
+		}
+		length += size
+	}
+
+	_, err := w.WriteString("\r\n")
+	return err
+}
+
+// Parse reads the first component of an iCalendar object, usually a VCALENDAR
+func Parse(reader io.Reader) (*Component, error) {
+	lines, err := unfold(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var stack []*Component
+	var root *Component
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		property, err := parseLine(line)
+		if err != nil {
+			return nil, err
+		}
+
+		switch property.Name {
+		case "BEGIN":
+			component := &Component{Name: strings.ToUpper(property.Value)}
+			if len(stack) > 0 {
+				stack[len(stack)-1].Add(component)
+			}
+			stack = append(stack, component)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(property.Value) {
+				return nil, fmt.Errorf("unexpected end of component %s", property.Value)
+			}
+
+			root = stack[0]
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return root, nil
+			}
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("property %s outside of a component", property.Name)
+			}
+
+			component := stack[len(stack)-1]
+			component.Properties = append(component.Properties, *property)
+		}
+	}
+
+	return nil, fmt.Errorf("no complete component found")
+}
+
+func unfold(reader io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+func parseLine(line string) (*Property, error) {
+	inQuotes := false
+	valueIndex := -1
+	var segments []string
+	segmentStart := 0
+
+	for i, r := range line {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == ';' && !inQuotes:
+			segments = append(segments, line[segmentStart:i])
+			segmentStart = i + 1
+		case r == ':' && !inQuotes:
+			valueIndex = i
+		}
+
+		if valueIndex != -1 {
+			break
+		}
+	}
+
+	if valueIndex == -1 {
+		return nil, fmt.Errorf("invalid content line %q", line)
+	}
+
+	segments = append(segments, line[segmentStart:valueIndex])
+
+	property := &Property{
+		Name:  strings.ToUpper(segments[0]),
+		Value: line[valueIndex+1:],
+	}
+
+	for _, segment := range segments[1:] {
+		parts := strings.SplitN(segment, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		if property.Params == nil {
+			property.Params = make(map[string]string)
+		}
+		property.Params[strings.ToUpper(parts[0])] = strings.Trim(parts[1], `"`)
+	}
+
+	return property, nil
+}
+
+// EscapeText escapes a value for a TEXT property
+func EscapeText(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
+
+// UnescapeText reverts EscapeText
+func UnescapeText(value string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(value)
+}
+
+// IsDate returns true if the property holds a DATE instead of a DATE-TIME
+func (p *Property) IsDate() bool {
+	return p.Params["VALUE"] == "DATE" || (len(p.Value) == len(DateLayout) && !strings.Contains(p.Value, "T"))
+}
+
+// Time parses a DATE or DATE-TIME property, floating times and dates are interpreted in the location
+func (p *Property) Time(location *time.Location) (time.Time, error) {
+	if location == nil {
+		location = time.UTC
+	}
+
+	if p.IsDate() {
+		return time.ParseInLocation(DateLayout, p.Value, location)
+	}
+
+	if strings.HasSuffix(p.Value, "Z") {
+		return time.Parse(DateTimeLayout, p.Value)
+	}
+
+	if tzID, ok := p.Params["TZID"]; ok {
+		if tzLocation, err := time.LoadLocation(tzID); err == nil {
+			location = tzLocation
+		}
+	}
+
+	return time.ParseInLocation(localDateTimeLayout, p.Value, location)
+}
+
+// ParseDuration parses a DURATION value like PT1H30M or -P1D
+func ParseDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+
+	sign := time.Duration(1)
+	switch value[0] {
+	case '-':
+		sign = -1
+		value = value[1:]
+	case '+':
+		value = value[1:]
+	}
+
+	if !strings.HasPrefix(value, "P") {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	var duration time.Duration
+	number := ""
+	for _, r := range value[1:] {
+		if r >= '0' && r <= '9' {
+			number += string(r)
+			continue
+		}
+
+		if r == 'T' {
+			continue
+		}
+
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		number = ""
+
+		switch r {
+		case 'W':
+			duration += time.Duration(n) * 7 * 24 * time.Hour
+		case 'D':
+			duration += time.Duration(n) * 24 * time.Hour
+		case 'H':
+			duration += time.Duration(n) * time.Hour
+		case 'M':
+			duration += time.Duration(n) * time.Minute
+		case 'S':
+			duration += time.Duration(n) * time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+	}
+
+	return sign * duration, nil
+}
+
+// EventTimespan returns the start and end of a VEVENT, if the end is missing it is derived from DURATION or DTSTART
+func EventTimespan(event *Component, location *time.Location) (time.Time, time.Time, error) {
+	startProperty := event.Get("DTSTART")
+	if startProperty == nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("event %s has no start", event.Value("UID"))
+	}
+
+	start, err := startProperty.Time(location)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if endProperty := event.Get("DTEND"); endProperty != nil {
+		end, err := endProperty.Time(location)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+
+		return start, end, nil
+	}
+
+	if durationValue := event.Value("DURATION"); durationValue != "" {
+		duration, err := ParseDuration(durationValue)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+
+		return start, start.Add(duration), nil
+	}
+
+	if startProperty.IsDate() {
+		return start, start.AddDate(0, 0, 1), nil
+	}
+
+	return start, start, nil
+}
+
+// IsEventBlocking returns true if a VEVENT should be treated as busy time
+func IsEventBlocking(event *Component) bool {
+	return event.Value("TRANSP") != "TRANSPARENT" && event.Value("STATUS") != "CANCELLED"
+}
@@ -0,0 +1,128 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	object := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:1\r\n" +
+		"DTSTART;TZID=\"Europe/Berlin\":20210104T090000\r\n" +
+		"DURATION:PT1H30M\r\n" +
+		"SUMMARY:Lunch\\, with a\r\n" +
+		"  folded line\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:2\r\n" +
+		"DTSTART;VALUE=DATE:20210105\r\n" +
+		"TRANSP:TRANSPARENT\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	cal, err := Parse(strings.NewReader(object))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := cal.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+
+	if summary := events[0].Text("SUMMARY"); summary != "Lunch, with a folded line" {
+		t.Errorf("got summary %q", summary)
+	}
+
+	start, end, err := EventTimespan(events[0], time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantStart := time.Date(2021, 1, 4, 8, 0, 0, 0, time.UTC)
+	if !start.Equal(wantStart) || !end.Equal(wantStart.Add(time.Minute*90)) {
+		t.Errorf("got %s - %s, want %s and 90 minutes", start, end, wantStart)
+	}
+
+	start, end, err = EventTimespan(events[1], time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !start.Equal(time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC)) || end.Sub(start) != time.Hour*24 {
+		t.Errorf("got all day event %s - %s", start, end)
+	}
+
+	if !IsEventBlocking(events[0]) || IsEventBlocking(events[1]) {
+		t.Error("transparency wasn't respected")
+	}
+}
+
+func TestComponent_Encode(t *testing.T) {
+	cal := NewCalendar("-//Test//EN")
+
+	event := &Component{Name: "VEVENT"}
+	event.Set("UID", "1")
+	event.SetDateTime("DTSTART", time.Date(2021, 1, 4, 9, 0, 0, 0, time.FixedZone("", 3600)))
+	event.SetText("DESCRIPTION", strings.Repeat("a; b, c\n", 20))
+	cal.Add(event)
+
+	var buffer bytes.Buffer
+	err := cal.Encode(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(buffer.String(), "\r\n"), "\r\n") {
+		if len(line) > maxLineLength {
+			t.Errorf("line %q is longer than %d octets", line, maxLineLength)
+		}
+	}
+
+	parsed, err := Parse(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsedEvent := parsed.Events()[0]
+	if parsedEvent.Value("DTSTART") != "20210104T080000Z" {
+		t.Errorf("got start %s", parsedEvent.Value("DTSTART"))
+	}
+
+	if parsedEvent.Text("DESCRIPTION") != strings.Repeat("a; b, c\n", 20) {
+		t.Errorf("got description %q", parsedEvent.Text("DESCRIPTION"))
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"PT15M", time.Minute * 15},
+		{"P1DT2H", time.Hour * 26},
+		{"P1W", time.Hour * 24 * 7},
+		{"-PT30S", -time.Second * 30},
+	}
+
+	for _, test := range tests {
+		got, err := ParseDuration(test.value)
+		if err != nil {
+			t.Errorf("got error %v for %s", err, test.value)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("got %s for %s, want %s", got, test.value, test.want)
+		}
+	}
+
+	_, err := ParseDuration("1H")
+	if err == nil {
+		t.Error("invalid duration was parsed")
+	}
+}
@@ -0,0 +1,172 @@
+package ical
+
+import (
+	"fmt"
+	"github.com/teambition/rrule-go"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Occurrence is a single instance of a possibly recurring VEVENT
+type Occurrence struct {
+	Event *Component
+	Start time.Time
+	End   time.Time
+}
+
+// ID identifies the occurrence within its calendar, it is made of the UID and the start of the occurrence
+func (o *Occurrence) ID() string {
+	return fmt.Sprintf("%s/%s", o.Event.Value("UID"), o.Start.UTC().Format(DateTimeLayout))
+}
+
+// ExpandEvents returns all occurrences of the events of a calendar that intersect the timespan from - to sorted by
+// their start.
+// Recurring events are expanded with their RRULE, RDATE and EXDATE properties, instances that were overridden by an
+// event with a RECURRENCE-ID are replaced by the overriding event. Floating times and dates are interpreted in
+// location. Events that can't be read are skipped, a single broken event shouldn't hide the rest of a calendar.
+func ExpandEvents(cal *Component, location *time.Location, from time.Time, to time.Time) []Occurrence {
+	var occurrences []Occurrence
+
+	overridden := make(map[string]map[int64]bool)
+	var masters []*Component
+
+	for _, event := range cal.Events() {
+		recurrenceID := event.Get("RECURRENCE-ID")
+		if recurrenceID == nil {
+			masters = append(masters, event)
+			continue
+		}
+
+		recurrenceStart, err := recurrenceID.Time(location)
+		if err != nil {
+			continue
+		}
+
+		uid := event.Value("UID")
+		if overridden[uid] == nil {
+			overridden[uid] = make(map[int64]bool)
+		}
+		overridden[uid][recurrenceStart.Unix()] = true
+
+		start, end, err := EventTimespan(event, location)
+		if err != nil {
+			continue
+		}
+
+		if intersects(start, end, from, to) {
+			occurrences = append(occurrences, Occurrence{Event: event, Start: start, End: end})
+		}
+	}
+
+	for _, event := range masters {
+		start, end, err := EventTimespan(event, location)
+		if err != nil {
+			continue
+		}
+
+		if event.Get("RRULE") == nil && event.Get("RDATE") == nil {
+			if intersects(start, end, from, to) {
+				occurrences = append(occurrences, Occurrence{Event: event, Start: start, End: end})
+			}
+			continue
+		}
+
+		set, err := recurrenceSet(event, start, location)
+		if err != nil {
+			continue
+		}
+
+		duration := end.Sub(start)
+		overriddenStarts := overridden[event.Value("UID")]
+
+		// Instances starting before from can still reach into the timespan
+		for _, occurrenceStart := range set.Between(from.Add(-duration), to, true) {
+			if overriddenStarts[occurrenceStart.Unix()] {
+				continue
+			}
+
+			occurrenceEnd := occurrenceStart.Add(duration)
+			if intersects(occurrenceStart, occurrenceEnd, from, to) {
+				occurrences = append(occurrences, Occurrence{Event: event, Start: occurrenceStart, End: occurrenceEnd})
+			}
+		}
+	}
+
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		return occurrences[i].Start.Before(occurrences[j].Start)
+	})
+
+	return occurrences
+}
+
+// recurrenceSet builds the recurrence set of an event, the start keeps its location so that the clock of the
+// instances stays the same across DST changes
+func recurrenceSet(event *Component, start time.Time, location *time.Location) (*rrule.Set, error) {
+	set := &rrule.Set{}
+	set.DTStart(start)
+
+	if rule := event.Get("RRULE"); rule != nil {
+		option, err := rrule.StrToROptionInLocation(rule.Value, start.Location())
+		if err != nil {
+			return nil, err
+		}
+		option.Dtstart = start
+
+		recurrenceRule, err := rrule.NewRRule(*option)
+		if err != nil {
+			return nil, err
+		}
+		set.RRule(recurrenceRule)
+	} else {
+		// Without a rule only the start and the RDATEs are instances
+		set.RDate(start)
+	}
+
+	dates, err := propertyTimes(event.GetAll("RDATE"), location)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range dates {
+		set.RDate(t)
+	}
+
+	dates, err = propertyTimes(event.GetAll("EXDATE"), location)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range dates {
+		set.ExDate(t)
+	}
+
+	return set, nil
+}
+
+// propertyTimes parses properties like EXDATE that can hold a comma separated list of dates
+func propertyTimes(properties []Property, location *time.Location) ([]time.Time, error) {
+	var times []time.Time
+
+	for _, property := range properties {
+		// Periods are only allowed in RDATE and have no place in a recurrence set, so they are skipped
+		if property.Params["VALUE"] == "PERIOD" {
+			continue
+		}
+
+		for _, value := range strings.Split(property.Value, ",") {
+			single := Property{Name: property.Name, Params: property.Params, Value: strings.TrimSpace(value)}
+
+			t, err := single.Time(location)
+			if err != nil {
+				return nil, err
+			}
+
+			times = append(times, t)
+		}
+	}
+
+	return times, nil
+}
+
+func intersects(start time.Time, end time.Time, from time.Time, to time.Time) bool {
+	return start.Before(to) && end.After(from)
+}
@@ -0,0 +1,67 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpandEvents(t *testing.T) {
+	object := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:shift\r\n" +
+		"DTSTART;TZID=Europe/Berlin:20210322T080000\r\n" +
+		"DTEND;TZID=Europe/Berlin:20210322T160000\r\n" +
+		"RRULE:FREQ=WEEKLY;BYDAY=MO\r\n" +
+		"EXDATE;TZID=Europe/Berlin:20210405T080000,20210412T080000\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:shift\r\n" +
+		"RECURRENCE-ID;TZID=Europe/Berlin:20210419T080000\r\n" +
+		"DTSTART;TZID=Europe/Berlin:20210420T120000\r\n" +
+		"DTEND;TZID=Europe/Berlin:20210420T200000\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:once\r\n" +
+		"DTSTART:20210323T100000Z\r\n" +
+		"DURATION:PT1H\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:outside\r\n" +
+		"DTSTART:20210601T100000Z\r\n" +
+		"DURATION:PT1H\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	cal, err := Parse(strings.NewReader(object))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	from := time.Date(2021, 3, 22, 12, 0, 0, 0, berlin)
+	to := time.Date(2021, 4, 27, 0, 0, 0, 0, berlin)
+
+	occurrences := ExpandEvents(cal, time.UTC, from, to)
+
+	var got []string
+	for _, occurrence := range occurrences {
+		got = append(got, occurrence.ID()+" "+occurrence.End.UTC().Format(DateTimeLayout))
+	}
+
+	want := []string{
+		// The first instance started before from, but still reaches into the timespan
+		"shift/20210322T070000Z 20210322T150000Z",
+		"once/20210323T100000Z 20210323T110000Z",
+		// After the switch to summer time the clock stays at 8 o'clock
+		"shift/20210329T060000Z 20210329T140000Z",
+		// The excluded instances are missing and the overridden one moved to Tuesday
+		"shift/20210420T100000Z 20210420T180000Z",
+		"shift/20210426T060000Z 20210426T140000Z",
+	}
+
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got occurrences\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
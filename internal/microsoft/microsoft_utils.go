@@ -0,0 +1,145 @@
+package microsoft
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/microsoft"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+)
+
+// AllScopes is a list of all scopes that will be requested
+var AllScopes = []string{"offline_access", "openid", "email", "User.Read", "Calendars.ReadWrite"}
+
+// DefaultGraphBaseURL is the base URL of the Microsoft Graph API
+const DefaultGraphBaseURL = "https://graph.microsoft.com/v1.0"
+
+// UserInfo is the sign in information about the user
+type UserInfo struct {
+	Email     string `json:"email"`
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+	ID        string `json:"id"`
+}
+
+// ReadMicrosoftConfig reads the credentials of the Azure app registration from the environment
+func ReadMicrosoftConfig() (*oauth2.Config, error) {
+	clientID := os.Getenv("MS_AUTH_CLIENT_ID")
+	clientSecret := os.Getenv("MS_AUTH_CLIENT_SECRET")
+
+	if clientID == "" || clientSecret == "" {
+		return nil, fmt.Errorf("microsoft credentials are missing")
+	}
+
+	apiBaseURL := "http://localhost"
+	envBaseURL, ok := os.LookupEnv("BASE_URL")
+	if ok {
+		apiBaseURL = envBaseURL
+	}
+
+	config := &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Endpoint:     microsoft.AzureADEndpoint(os.Getenv("MS_AUTH_TENANT")),
+		RedirectURL:  fmt.Sprintf("%s/v1/auth/microsoft", apiBaseURL),
+		Scopes:       AllScopes,
+	}
+
+	return config, nil
+}
+
+// GraphBaseURL returns the base URL of the Microsoft Graph API, it can be overridden for local testing
+func GraphBaseURL() string {
+	baseURL, ok := os.LookupEnv("MS_GRAPH_BASE_URL")
+	if ok && baseURL != "" {
+		return baseURL
+	}
+
+	return DefaultGraphBaseURL
+}
+
+// GetMicrosoftToken gets a Microsoft OAuth Token with an auth code
+func GetMicrosoftToken(ctx context.Context, authCode string) (*oauth2.Token, error) {
+	config, err := ReadMicrosoftConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	tok, err := config.Exchange(ctx, authCode)
+	if err != nil {
+		return nil, err
+	}
+	return tok, nil
+}
+
+// GetMicrosoftAuthURL returns the URL where the user can allow Timeliness access to the calendar
+func GetMicrosoftAuthURL() (string, string, error) {
+	config, err := ReadMicrosoftConfig()
+	if err != nil {
+		return "", "", err
+	}
+
+	stateToken := uuid.New().String()
+
+	url := config.AuthCodeURL(stateToken, oauth2.SetAuthURLParam("prompt", "select_account"))
+
+	return url, stateToken, nil
+}
+
+// GetUserInfo gets the Microsoft account that belongs to a token from the Graph API
+func GetUserInfo(ctx context.Context, token *oauth2.Token) (*UserInfo, error) {
+	config, err := ReadMicrosoftConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	client := config.Client(ctx, token)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/me", GraphBaseURL()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("microsoft graph: status %d: %s", resp.StatusCode, body)
+	}
+
+	var me struct {
+		ID                string `json:"id"`
+		GivenName         string `json:"givenName"`
+		Surname           string `json:"surname"`
+		Mail              string `json:"mail"`
+		UserPrincipalName string `json:"userPrincipalName"`
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&me)
+	if err != nil {
+		return nil, err
+	}
+
+	email := me.Mail
+	if email == "" {
+		email = me.UserPrincipalName
+	}
+
+	return &UserInfo{
+		Email:     email,
+		Firstname: me.GivenName,
+		Lastname:  me.Surname,
+		ID:        me.ID,
+	}, nil
+}
@@ -10,7 +10,7 @@ import (
 	"io"
 )
 
-// Decrypt decrypt a string
+// Decrypt decrypt a string, data that wasn't encrypted by Encrypt results in an empty string
 func Decrypt(data string) string {
 	key := []byte(createHash(getSecret()))
 	block, err := aes.NewCipher(key)
@@ -22,7 +22,10 @@ func Decrypt(data string) string {
 		panic(err.Error())
 	}
 	nonceSize := gcm.NonceSize()
-	dataDecoded, _ := hex.DecodeString(data)
+	dataDecoded, err := hex.DecodeString(data)
+	if err != nil || len(dataDecoded) < nonceSize {
+		return ""
+	}
 	nonce, ciphertext := dataDecoded[:nonceSize], dataDecoded[nonceSize:]
 	plaintext, err := gcm.Open(nil, []byte(nonce), []byte(ciphertext), nil)
 	if err != nil {
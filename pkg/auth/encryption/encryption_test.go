@@ -17,3 +17,11 @@ func Test_Encrypt(t *testing.T) {
 		t.Fatalf("decryped string does not match data")
 	}
 }
+
+func Test_DecryptInvalid(t *testing.T) {
+	for _, invalid := range []string{"", "abc", "not hex", "a4f8754d"} {
+		if decrypted := Decrypt(invalid); decrypted != "" {
+			t.Errorf("decrypting %q returned %q, want an empty string", invalid, decrypted)
+		}
+	}
+}
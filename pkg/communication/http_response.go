@@ -28,6 +28,9 @@ const (
 
 	// Calendar is an error thrown if calendar auth is invalid
 	Calendar = "calendar"
+
+	// Infeasible is an error thrown if the workload of a task doesn't fit into the free time before its due date
+	Infeasible = "infeasible"
 )
 
 // RespondWithError returns an error to the user
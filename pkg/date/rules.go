@@ -1,74 +1,229 @@
 package date
 
 import (
+	"fmt"
+	"math"
 	"time"
 )
 
+// WeekdayTimespans are allowed timespans that only apply to a single weekday
+type WeekdayTimespans struct {
+	Weekday   time.Weekday `json:"weekday" bson:"weekday"`
+	Timespans []Timespan   `json:"timespans" bson:"timespans"`
+}
+
+// NormalizeAllowedTimespans moves the end of allowed timespans that end before they start to the following day,
+// so that overnight timespans like 22:00 - 01:00 can be merged with others, and merges them
+func NormalizeAllowedTimespans(timespans []Timespan) ([]Timespan, error) {
+	for i, timespan := range timespans {
+		for timespan.End.Before(timespan.Start) {
+			timespan.End = timespan.End.AddDate(0, 0, 1)
+		}
+
+		if timespan.Duration() == 0 {
+			return nil, fmt.Errorf("allowed timespan %s is invalid", timespan)
+		}
+
+		timespans[i] = timespan
+	}
+
+	return MergeTimespans(timespans), nil
+}
+
+// NormalizeAllowedWeekdayTimespans checks that every weekday is valid and configured only once
+// and normalizes its timespans like NormalizeAllowedTimespans
+func NormalizeAllowedWeekdayTimespans(weekdays []WeekdayTimespans) ([]WeekdayTimespans, error) {
+	seenWeekdays := make(map[time.Weekday]bool)
+	for i, weekdayTimespans := range weekdays {
+		if weekdayTimespans.Weekday < time.Sunday || weekdayTimespans.Weekday > time.Saturday {
+			return nil, fmt.Errorf("weekday %d is invalid", weekdayTimespans.Weekday)
+		}
+
+		if seenWeekdays[weekdayTimespans.Weekday] {
+			return nil, fmt.Errorf("weekday %s is configured more than once", weekdayTimespans.Weekday)
+		}
+		seenWeekdays[weekdayTimespans.Weekday] = true
+
+		timespans, err := NormalizeAllowedTimespans(weekdayTimespans.Timespans)
+		if err != nil {
+			return nil, fmt.Errorf("%s on %s", err, weekdayTimespans.Weekday)
+		}
+
+		weekdays[i].Timespans = timespans
+	}
+
+	return weekdays, nil
+}
+
 // FreeConstraint is for constraints that a single timespan has to comply with
-// AllowedTimeSpans can only contain []Timespan with dates 0 and times that don't cross the dateline (0:00)
+// AllowedTimeSpans are only compared by their clock in Location, the date is ignored. A timespan whose end clock
+// is before or equal to its start clock crosses midnight and ends on the following day, e.g. 22:00 - 01:00.
+// AllowedWeekdayTimeSpans override AllowedTimeSpans for their weekday, an entry without timespans blocks the whole day.
+// Overnight timespans belong to the weekday they start on.
+// Free time additionally has to comply with every constraint of Intersect, e.g. the constraints of other users
+// in their own Location.
 type FreeConstraint struct {
-	DistanceToBusy   time.Duration
-	AllowedTimeSpans []Timespan
-	Location         *time.Location
+	DistanceToBusy          time.Duration
+	AllowedTimeSpans        []Timespan
+	AllowedWeekdayTimeSpans []WeekdayTimespans
+	Location                *time.Location
+	Intersect               []*FreeConstraint
+}
+
+// allowedTimeSpansForWeekday returns the allowed timespans of a weekday and false if the whole day is allowed
+func (r *FreeConstraint) allowedTimeSpansForWeekday(weekday time.Weekday) ([]Timespan, bool) {
+	for _, weekdayTimespans := range r.AllowedWeekdayTimeSpans {
+		if weekdayTimespans.Weekday == weekday {
+			return weekdayTimespans.Timespans, true
+		}
+	}
+
+	return r.AllowedTimeSpans, len(r.AllowedTimeSpans) > 0
 }
 
 // Test tests multiple constrains and cuts free timeslots to these constraints
 func (r *FreeConstraint) Test(timespan Timespan) []Timespan {
+	result := r.testOwn(timespan)
+
+	for _, other := range r.Intersect {
+		var intersected []Timespan
+		for _, allowed := range result {
+			intersected = append(intersected, other.Test(allowed)...)
+		}
+
+		result = intersected
+	}
+
+	return result
+}
+
+// testOwn cuts a timespan to the allowed timespans of this constraint only
+func (r *FreeConstraint) testOwn(timespan Timespan) []Timespan {
 	var result []Timespan
 
-	if len(r.AllowedTimeSpans) == 0 {
+	if len(r.AllowedTimeSpans) == 0 && len(r.AllowedWeekdayTimeSpans) == 0 {
 		return append(result, timespan)
 	}
 
 	timespan = timespan.In(r.Location)
-	p := timespan.Start
 
-	for p.Before(timespan.End) {
+	// The days are walked by calendar date and not by adding 24 hours, because days can be 23 or 25 hours long.
+	// We start one day early, because an overnight timespan of that day can reach into the timespan.
+	year, month, day := timespan.Start.Date()
+	currentDay := time.Date(year, month, day-1, 0, 0, 0, 0, time.UTC)
 
-		for _, span := range r.AllowedTimeSpans {
-			localAllowedTimespan := span.In(r.Location)
-			allowedDuration := span.Duration()
+	year, month, day = timespan.End.Date()
+	lastDay := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
 
-			if span.ContainsClock(p) {
-				end := time.Date(p.Year(), p.Month(), p.Day(), localAllowedTimespan.End.Hour(), localAllowedTimespan.End.Minute(), 0, 0, r.Location)
-				if timespan.End.Before(end) {
-					end = timespan.End
-				}
+	for ; !currentDay.After(lastDay); currentDay = currentDay.AddDate(0, 0, 1) {
+		year, month, day = currentDay.Date()
 
-				if p.Equal(end) {
-					continue
-				}
+		allowedTimeSpans, isRestricted := r.allowedTimeSpansForWeekday(currentDay.Weekday())
 
-				result = append(result, Timespan{p, end})
-			} else {
-				start := time.Date(p.Year(), p.Month(), p.Day(), localAllowedTimespan.Start.Hour(), localAllowedTimespan.Start.Minute(), 0, 0, r.Location)
+		// The whole day is allowed
+		if !isRestricted {
+			allowed := Timespan{
+				Start: wallClock(year, month, day, 0, r.Location, false),
+				End:   wallClock(year, month, day, secondsPerDay, r.Location, false),
+			}
 
-				newEnd := start.Add(allowedDuration)
-				if timespan.End.Before(newEnd) {
-					newEnd = timespan.End
-				}
+			if cut := allowed.intersection(timespan); cut != nil {
+				result = append(result, *cut)
+			}
 
-				newTimespan := Timespan{start, newEnd}
+			continue
+		}
 
-				if start.After(newEnd) || start.Before(timespan.Start) || start.Equal(newEnd) || !timespan.Contains(newTimespan) {
-					continue
-				}
+		for _, span := range allowedTimeSpans {
+			localAllowedTimespan := span.In(r.Location)
 
-				result = append(result, newTimespan)
+			startSeconds := calcSecondsFromClock(localAllowedTimespan.Start.Clock())
+			endSeconds := calcSecondsFromClock(localAllowedTimespan.End.Clock())
+
+			// The timespan crosses midnight, so it ends on the next day
+			if endSeconds <= startSeconds {
+				endSeconds += secondsPerDay
+			}
+
+			allowed := Timespan{
+				Start: wallClock(year, month, day, startSeconds, r.Location, false),
+				End:   wallClock(year, month, day, endSeconds, r.Location, true),
+			}
+
+			if cut := allowed.intersection(timespan); cut != nil {
+				result = append(result, *cut)
 			}
 		}
+	}
 
-		nextDay := p.Add(24 * time.Hour)
-		p = time.Date(nextDay.Year(), nextDay.Month(), nextDay.Day(), 0, 0, 0, 0, r.Location)
+	return MergeTimespans(result)
+}
+
+const secondsPerDay = 24 * 60 * 60
+
+// wallClock returns the instant at which the clocks in location show the given seconds after midnight of the given day.
+// A clock time that is skipped by a DST transition resolves to the transition itself.
+// A clock time that occurs twice resolves to the earlier occurrence, or the later one if preferLater is true.
+func wallClock(year int, month time.Month, day int, seconds int, location *time.Location, preferLater bool) time.Time {
+	naive := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Add(time.Duration(seconds) * time.Second)
+
+	// The offsets a day before and after cover both sides of a possible transition
+	_, offsetBefore := naive.Add(-24 * time.Hour).In(location).Zone()
+	_, offsetAfter := naive.Add(24 * time.Hour).In(location).Zone()
+
+	var occurrences []time.Time
+	for _, offset := range []int{offsetBefore, offsetAfter} {
+		candidate := naive.Add(time.Duration(-offset) * time.Second).In(location)
+		if !sameWallClock(candidate, naive) {
+			continue
+		}
+
+		if len(occurrences) > 0 && occurrences[0].Equal(candidate) {
+			continue
+		}
+
+		occurrences = append(occurrences, candidate)
 	}
 
-	return result
+	switch len(occurrences) {
+	case 0:
+		return findOffsetTransition(naive.Add(time.Duration(-offsetAfter)*time.Second), naive.Add(time.Duration(-offsetBefore)*time.Second), offsetAfter, location)
+	case 1:
+		return occurrences[0]
+	}
+
+	if occurrences[1].Before(occurrences[0]) {
+		occurrences[0], occurrences[1] = occurrences[1], occurrences[0]
+	}
+
+	if preferLater {
+		return occurrences[1]
+	}
+
+	return occurrences[0]
+}
+
+// findOffsetTransition finds the first instant between from and to where location has the given offset
+func findOffsetTransition(from time.Time, to time.Time, offset int, location *time.Location) time.Time {
+	for to.Sub(from) > time.Second {
+		middle := from.Add(to.Sub(from) / 2)
+		if _, middleOffset := middle.In(location).Zone(); middleOffset == offset {
+			to = middle
+		} else {
+			from = middle
+		}
+	}
+
+	return to.Truncate(time.Second).In(location)
 }
 
-func newTimeFromDateAndTime(date time.Time, clock time.Time) time.Time {
-	year, month, day := date.Date()
-	hour, minute, second := clock.Clock()
-	return time.Date(year, month, day, hour, minute, second, 0, date.Location())
+func sameWallClock(t time.Time, naive time.Time) bool {
+	year, month, day := t.Date()
+	hour, minute, second := t.Clock()
+	nYear, nMonth, nDay := naive.Date()
+	nHour, nMinute, nSecond := naive.Clock()
+
+	return year == nYear && month == nMonth && day == nDay && hour == nHour && minute == nMinute && second == nSecond
 }
 
 // RuleDuration sets minimum and maximum times
@@ -95,4 +250,133 @@ func (r *RuleDuration) Test(timespan Timespan, cutFromEnd bool) *Timespan {
 	return &timespan
 }
 
-// TODO Rule Weekdays/Weekends
+const unlimitedWorkload = time.Duration(math.MaxInt64)
+
+// WorkloadCap limits the work that can be scheduled per day and per week (Monday to Sunday) in Location.
+// A maximum of zero means there is no limit. Work that is already scheduled has to be added with AddScheduled.
+type WorkloadCap struct {
+	MaxPerDay  time.Duration
+	MaxPerWeek time.Duration
+	Location   *time.Location
+	scheduled  []Timespan
+}
+
+// AddScheduled counts a timespan as scheduled work
+func (c *WorkloadCap) AddScheduled(timespan Timespan) {
+	c.scheduled = append(c.scheduled, timespan)
+}
+
+func (c *WorkloadCap) copy() *WorkloadCap {
+	workloadCap := *c
+	workloadCap.scheduled = append([]Timespan{}, c.scheduled...)
+	return &workloadCap
+}
+
+func (c *WorkloadCap) scheduledBetween(start time.Time, end time.Time) time.Duration {
+	span := Timespan{Start: start, End: end}
+
+	var duration time.Duration
+	for _, scheduled := range c.scheduled {
+		if cut := span.intersection(scheduled); cut != nil {
+			duration += cut.Duration()
+		}
+	}
+
+	return duration
+}
+
+func (c *WorkloadCap) startOfDay(t time.Time) time.Time {
+	year, month, day := t.In(c.Location).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, c.Location)
+}
+
+func (c *WorkloadCap) startOfWeek(t time.Time) time.Time {
+	year, month, day := t.In(c.Location).Date()
+	daysSinceMonday := (int(t.In(c.Location).Weekday()) + 6) % 7
+	return time.Date(year, month, day-daysSinceMonday, 0, 0, 0, 0, c.Location)
+}
+
+// allowed returns how much more work fits into the day and week of t,
+// usedInWeek is work of the current slot that is not yet added as scheduled
+func (c *WorkloadCap) allowed(t time.Time, usedInWeek time.Duration) time.Duration {
+	allowed := unlimitedWorkload
+
+	if c.MaxPerDay > 0 {
+		dayStart := c.startOfDay(t)
+		allowed = c.MaxPerDay - c.scheduledBetween(dayStart, dayStart.AddDate(0, 0, 1))
+	}
+
+	if c.MaxPerWeek > 0 {
+		weekStart := c.startOfWeek(t)
+		allowedInWeek := c.MaxPerWeek - c.scheduledBetween(weekStart, weekStart.AddDate(0, 0, 7)) - usedInWeek
+		if allowedInWeek < allowed {
+			allowed = allowedInWeek
+		}
+	}
+
+	if allowed < 0 {
+		return 0
+	}
+
+	return allowed
+}
+
+// Limit shortens a timespan so that the scheduled work stays within the cap on every day and week it touches.
+// If cutFromEnd is true the start of the timespan is kept, otherwise the end is kept.
+func (c *WorkloadCap) Limit(timespan Timespan, cutFromEnd bool) Timespan {
+	if c.MaxPerDay == 0 && c.MaxPerWeek == 0 {
+		return timespan
+	}
+
+	var usedInWeek time.Duration
+	var currentWeek time.Time
+
+	if cutFromEnd {
+		for cursor := timespan.Start; cursor.Before(timespan.End); {
+			if weekStart := c.startOfWeek(cursor); !weekStart.Equal(currentWeek) {
+				currentWeek = weekStart
+				usedInWeek = 0
+			}
+
+			segmentEnd := c.startOfDay(cursor).AddDate(0, 0, 1)
+			if timespan.End.Before(segmentEnd) {
+				segmentEnd = timespan.End
+			}
+
+			if allowed := c.allowed(cursor, usedInWeek); segmentEnd.Sub(cursor) > allowed {
+				timespan.End = cursor.Add(allowed)
+				return timespan
+			}
+
+			usedInWeek += segmentEnd.Sub(cursor)
+			cursor = segmentEnd
+		}
+
+		return timespan
+	}
+
+	for cursor := timespan.End; cursor.After(timespan.Start); {
+		// The last instant before the cursor belongs to the day we are looking at
+		last := cursor.Add(-time.Nanosecond)
+
+		if weekStart := c.startOfWeek(last); !weekStart.Equal(currentWeek) {
+			currentWeek = weekStart
+			usedInWeek = 0
+		}
+
+		segmentStart := c.startOfDay(last)
+		if timespan.Start.After(segmentStart) {
+			segmentStart = timespan.Start
+		}
+
+		if allowed := c.allowed(last, usedInWeek); cursor.Sub(segmentStart) > allowed {
+			timespan.Start = cursor.Add(-allowed)
+			return timespan
+		}
+
+		usedInWeek += cursor.Sub(segmentStart)
+		cursor = segmentStart
+	}
+
+	return timespan
+}
@@ -293,3 +293,489 @@ func TestRuleDuration_Test(t *testing.T) {
 		})
 	}
 }
+
+func TestFreeConstraint_TestWeekdays(t *testing.T) {
+	var constraintTests = []struct {
+		in       Timespan
+		allowed  []Timespan
+		weekdays []WeekdayTimespans
+		out      []Timespan
+	}{
+		// Case short fridays, free weekends and late tuesdays
+		{
+			Timespan{
+				Start: timeDate(2021, 1, 1, 0, 0, 0),
+				End:   timeDate(2021, 1, 5, 23, 0, 0),
+			},
+			[]Timespan{
+				{
+					Start: timeDate(0, 0, 0, 8, 0, 0),
+					End:   timeDate(0, 0, 0, 16, 30, 0),
+				},
+			},
+			[]WeekdayTimespans{
+				{
+					Weekday: time.Friday,
+					Timespans: []Timespan{
+						{
+							Start: timeDate(0, 0, 0, 8, 0, 0),
+							End:   timeDate(0, 0, 0, 12, 0, 0),
+						},
+					},
+				},
+				{Weekday: time.Saturday},
+				{Weekday: time.Sunday},
+				{
+					Weekday: time.Tuesday,
+					Timespans: []Timespan{
+						{
+							Start: timeDate(0, 0, 0, 10, 0, 0),
+							End:   timeDate(0, 0, 0, 20, 0, 0),
+						},
+					},
+				},
+			},
+			[]Timespan{
+				{
+					Start: timeDate(2021, 1, 1, 8, 0, 0),
+					End:   timeDate(2021, 1, 1, 12, 0, 0),
+				},
+				{
+					Start: timeDate(2021, 1, 4, 8, 0, 0),
+					End:   timeDate(2021, 1, 4, 16, 30, 0),
+				},
+				{
+					Start: timeDate(2021, 1, 5, 10, 0, 0),
+					End:   timeDate(2021, 1, 5, 20, 0, 0),
+				},
+			},
+		},
+		// Case no general restriction, only saturday is blocked
+		{
+			Timespan{
+				Start: timeDate(2021, 1, 1, 20, 0, 0),
+				End:   timeDate(2021, 1, 3, 10, 0, 0),
+			},
+			nil,
+			[]WeekdayTimespans{
+				{Weekday: time.Saturday},
+			},
+			[]Timespan{
+				{
+					Start: timeDate(2021, 1, 1, 20, 0, 0),
+					End:   timeDate(2021, 1, 2, 0, 0, 0),
+				},
+				{
+					Start: timeDate(2021, 1, 3, 0, 0, 0),
+					End:   timeDate(2021, 1, 3, 10, 0, 0),
+				},
+			},
+		},
+		// Case whole days without restriction are joined
+		{
+			Timespan{
+				Start: timeDate(2021, 1, 4, 12, 0, 0),
+				End:   timeDate(2021, 1, 6, 12, 0, 0),
+			},
+			nil,
+			[]WeekdayTimespans{
+				{Weekday: time.Saturday},
+			},
+			[]Timespan{
+				{
+					Start: timeDate(2021, 1, 4, 12, 0, 0),
+					End:   timeDate(2021, 1, 6, 12, 0, 0),
+				},
+			},
+		},
+	}
+
+	for index, tt := range constraintTests {
+		t.Run("Case "+string(rune(index)), func(t *testing.T) {
+			constraint := FreeConstraint{AllowedTimeSpans: tt.allowed, AllowedWeekdayTimeSpans: tt.weekdays, Location: getLocation()}
+			result := constraint.Test(tt.in)
+			if !reflect.DeepEqual(result, tt.out) {
+				t.Errorf("got %v, want %v", result, tt.out)
+			}
+		})
+	}
+}
+
+func TestFreeConstraint_TestOvernightAndDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	berlinDate := func(year int, month time.Month, day int, hour int, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, berlin)
+	}
+
+	var constraintTests = []struct {
+		name     string
+		in       Timespan
+		allowed  []Timespan
+		location *time.Location
+		out      []Timespan
+	}{
+		{
+			name: "Overnight allowed timespan",
+			in: Timespan{
+				Start: timeDate(2021, 1, 1, 0, 0, 0),
+				End:   timeDate(2021, 1, 3, 0, 0, 0),
+			},
+			allowed: []Timespan{
+				{
+					Start: timeDate(0, 0, 0, 22, 0, 0),
+					End:   timeDate(0, 0, 0, 1, 0, 0),
+				},
+			},
+			location: getLocation(),
+			out: []Timespan{
+				{
+					Start: timeDate(2021, 1, 1, 0, 0, 0),
+					End:   timeDate(2021, 1, 1, 1, 0, 0),
+				},
+				{
+					Start: timeDate(2021, 1, 1, 22, 0, 0),
+					End:   timeDate(2021, 1, 2, 1, 0, 0),
+				},
+				{
+					Start: timeDate(2021, 1, 2, 22, 0, 0),
+					End:   timeDate(2021, 1, 3, 0, 0, 0),
+				},
+			},
+		},
+		{
+			name: "Allowed timespan until midnight",
+			in: Timespan{
+				Start: timeDate(2021, 1, 1, 12, 0, 0),
+				End:   timeDate(2021, 1, 2, 12, 0, 0),
+			},
+			allowed: []Timespan{
+				{
+					Start: timeDate(0, 0, 0, 18, 0, 0),
+					End:   timeDate(0, 0, 0, 0, 0, 0),
+				},
+			},
+			location: getLocation(),
+			out: []Timespan{
+				{
+					Start: timeDate(2021, 1, 1, 18, 0, 0),
+					End:   timeDate(2021, 1, 2, 0, 0, 0),
+				},
+			},
+		},
+		{
+			name: "Allowed timespan with the same start and end lasts the whole day",
+			in: Timespan{
+				Start: timeDate(2021, 1, 1, 12, 0, 0),
+				End:   timeDate(2021, 1, 2, 12, 0, 0),
+			},
+			allowed: []Timespan{
+				{
+					Start: timeDate(0, 0, 0, 9, 0, 0),
+					End:   timeDate(0, 0, 0, 9, 0, 0),
+				},
+			},
+			location: getLocation(),
+			out: []Timespan{
+				{
+					Start: timeDate(2021, 1, 1, 12, 0, 0),
+					End:   timeDate(2021, 1, 2, 12, 0, 0),
+				},
+			},
+		},
+		{
+			name: "Overnight and day timespan touching at midnight are merged",
+			in: Timespan{
+				Start: timeDate(2021, 1, 1, 20, 0, 0),
+				End:   timeDate(2021, 1, 2, 3, 0, 0),
+			},
+			allowed: []Timespan{
+				{
+					Start: timeDate(0, 0, 0, 22, 0, 0),
+					End:   timeDate(0, 0, 0, 0, 0, 0),
+				},
+				{
+					Start: timeDate(0, 0, 0, 0, 0, 0),
+					End:   timeDate(0, 0, 0, 2, 0, 0),
+				},
+			},
+			location: getLocation(),
+			out: []Timespan{
+				{
+					Start: timeDate(2021, 1, 1, 22, 0, 0),
+					End:   timeDate(2021, 1, 2, 2, 0, 0),
+				},
+			},
+		},
+		{
+			name: "Spring forward shortens the allowed timespan",
+			in: Timespan{
+				Start: berlinDate(2021, 3, 28, 0, 0),
+				End:   berlinDate(2021, 3, 28, 23, 0),
+			},
+			allowed: []Timespan{
+				{
+					Start: berlinDate(0, 0, 0, 1, 0),
+					End:   berlinDate(0, 0, 0, 4, 0),
+				},
+			},
+			location: berlin,
+			out: []Timespan{
+				{
+					Start: time.Date(2021, 3, 28, 0, 0, 0, 0, time.UTC).In(berlin),
+					End:   time.Date(2021, 3, 28, 2, 0, 0, 0, time.UTC).In(berlin),
+				},
+			},
+		},
+		{
+			name: "Spring forward skips the start of the allowed timespan",
+			in: Timespan{
+				Start: berlinDate(2021, 3, 28, 0, 0),
+				End:   berlinDate(2021, 3, 28, 23, 0),
+			},
+			allowed: []Timespan{
+				{
+					Start: berlinDate(0, 0, 0, 2, 30),
+					End:   berlinDate(0, 0, 0, 5, 0),
+				},
+			},
+			location: berlin,
+			out: []Timespan{
+				{
+					Start: time.Date(2021, 3, 28, 1, 0, 0, 0, time.UTC).In(berlin),
+					End:   time.Date(2021, 3, 28, 3, 0, 0, 0, time.UTC).In(berlin),
+				},
+			},
+		},
+		{
+			name: "Fall back repeats the end of the allowed timespan",
+			in: Timespan{
+				Start: berlinDate(2021, 10, 30, 12, 0),
+				End:   berlinDate(2021, 10, 31, 12, 0),
+			},
+			allowed: []Timespan{
+				{
+					Start: berlinDate(0, 0, 0, 0, 0),
+					End:   berlinDate(0, 0, 0, 2, 30),
+				},
+			},
+			location: berlin,
+			out: []Timespan{
+				{
+					Start: time.Date(2021, 10, 30, 22, 0, 0, 0, time.UTC).In(berlin),
+					End:   time.Date(2021, 10, 31, 1, 30, 0, 0, time.UTC).In(berlin),
+				},
+			},
+		},
+		{
+			name: "Overnight timespan across spring forward",
+			in: Timespan{
+				Start: berlinDate(2021, 3, 27, 12, 0),
+				End:   berlinDate(2021, 3, 28, 12, 0),
+			},
+			allowed: []Timespan{
+				{
+					Start: berlinDate(0, 0, 0, 22, 0),
+					End:   berlinDate(0, 0, 0, 3, 30),
+				},
+			},
+			location: berlin,
+			out: []Timespan{
+				{
+					Start: time.Date(2021, 3, 27, 21, 0, 0, 0, time.UTC).In(berlin),
+					End:   time.Date(2021, 3, 28, 1, 30, 0, 0, time.UTC).In(berlin),
+				},
+			},
+		},
+		{
+			name: "Overnight timespan across fall back",
+			in: Timespan{
+				Start: berlinDate(2021, 10, 30, 12, 0),
+				End:   berlinDate(2021, 10, 31, 12, 0),
+			},
+			allowed: []Timespan{
+				{
+					Start: berlinDate(0, 0, 0, 22, 0),
+					End:   berlinDate(0, 0, 0, 4, 0),
+				},
+			},
+			location: berlin,
+			out: []Timespan{
+				{
+					Start: time.Date(2021, 10, 30, 20, 0, 0, 0, time.UTC).In(berlin),
+					End:   time.Date(2021, 10, 31, 3, 0, 0, 0, time.UTC).In(berlin),
+				},
+			},
+		},
+		{
+			name: "Whole days stay whole across spring forward",
+			in: Timespan{
+				Start: berlinDate(2021, 3, 27, 12, 0),
+				End:   berlinDate(2021, 3, 29, 12, 0),
+			},
+			allowed:  nil,
+			location: berlin,
+			out: []Timespan{
+				{
+					Start: berlinDate(2021, 3, 27, 12, 0),
+					End:   berlinDate(2021, 3, 29, 12, 0),
+				},
+			},
+		},
+	}
+
+	for _, tt := range constraintTests {
+		t.Run(tt.name, func(t *testing.T) {
+			constraint := FreeConstraint{AllowedTimeSpans: tt.allowed, Location: tt.location}
+			if tt.allowed == nil {
+				// Restrict a different weekday, so the whole day logic is used
+				constraint.AllowedWeekdayTimeSpans = []WeekdayTimespans{{Weekday: time.Wednesday}}
+			}
+
+			result := constraint.Test(tt.in)
+			if !reflect.DeepEqual(result, tt.out) {
+				t.Errorf("got %v, want %v", result, tt.out)
+			}
+		})
+	}
+}
+
+func TestWorkloadCap_Limit(t *testing.T) {
+	// 2021-01-04 is a Monday
+	var capTests = []struct {
+		name       string
+		workload   WorkloadCap
+		scheduled  []Timespan
+		in         Timespan
+		cutFromEnd bool
+		out        Timespan
+	}{
+		{
+			name:     "No limit",
+			workload: WorkloadCap{Location: getLocation()},
+			in:       Timespan{Start: timeDate(2021, 1, 4, 9, 0, 0), End: timeDate(2021, 1, 4, 12, 0, 0)},
+			out:      Timespan{Start: timeDate(2021, 1, 4, 9, 0, 0), End: timeDate(2021, 1, 4, 12, 0, 0)},
+		},
+		{
+			name:       "Daily limit cuts the end",
+			workload:   WorkloadCap{MaxPerDay: time.Hour * 4, Location: getLocation()},
+			scheduled:  []Timespan{{Start: timeDate(2021, 1, 4, 13, 0, 0), End: timeDate(2021, 1, 4, 16, 0, 0)}},
+			in:         Timespan{Start: timeDate(2021, 1, 4, 9, 0, 0), End: timeDate(2021, 1, 4, 12, 0, 0)},
+			cutFromEnd: true,
+			out:        Timespan{Start: timeDate(2021, 1, 4, 9, 0, 0), End: timeDate(2021, 1, 4, 10, 0, 0)},
+		},
+		{
+			name:      "Daily limit cuts the start",
+			workload:  WorkloadCap{MaxPerDay: time.Hour * 4, Location: getLocation()},
+			scheduled: []Timespan{{Start: timeDate(2021, 1, 4, 13, 0, 0), End: timeDate(2021, 1, 4, 16, 0, 0)}},
+			in:        Timespan{Start: timeDate(2021, 1, 4, 9, 0, 0), End: timeDate(2021, 1, 4, 12, 0, 0)},
+			out:       Timespan{Start: timeDate(2021, 1, 4, 11, 0, 0), End: timeDate(2021, 1, 4, 12, 0, 0)},
+		},
+		{
+			name:       "Work of other days doesn't count for the daily limit",
+			workload:   WorkloadCap{MaxPerDay: time.Hour * 4, Location: getLocation()},
+			scheduled:  []Timespan{{Start: timeDate(2021, 1, 3, 9, 0, 0), End: timeDate(2021, 1, 3, 13, 0, 0)}},
+			in:         Timespan{Start: timeDate(2021, 1, 4, 9, 0, 0), End: timeDate(2021, 1, 4, 12, 0, 0)},
+			cutFromEnd: true,
+			out:        Timespan{Start: timeDate(2021, 1, 4, 9, 0, 0), End: timeDate(2021, 1, 4, 12, 0, 0)},
+		},
+		{
+			name:       "Weekly limit",
+			workload:   WorkloadCap{MaxPerWeek: time.Hour * 10, Location: getLocation()},
+			scheduled:  []Timespan{{Start: timeDate(2021, 1, 4, 8, 0, 0), End: timeDate(2021, 1, 4, 17, 0, 0)}},
+			in:         Timespan{Start: timeDate(2021, 1, 6, 9, 0, 0), End: timeDate(2021, 1, 6, 12, 0, 0)},
+			cutFromEnd: true,
+			out:        Timespan{Start: timeDate(2021, 1, 6, 9, 0, 0), End: timeDate(2021, 1, 6, 10, 0, 0)},
+		},
+		{
+			name:       "Work of the previous week doesn't count for the weekly limit",
+			workload:   WorkloadCap{MaxPerWeek: time.Hour * 10, Location: getLocation()},
+			scheduled:  []Timespan{{Start: timeDate(2021, 1, 3, 8, 0, 0), End: timeDate(2021, 1, 3, 20, 0, 0)}},
+			in:         Timespan{Start: timeDate(2021, 1, 4, 9, 0, 0), End: timeDate(2021, 1, 4, 12, 0, 0)},
+			cutFromEnd: true,
+			out:        Timespan{Start: timeDate(2021, 1, 4, 9, 0, 0), End: timeDate(2021, 1, 4, 12, 0, 0)},
+		},
+		{
+			name:       "Timespan across midnight counts for both days",
+			workload:   WorkloadCap{MaxPerDay: time.Hour * 2, Location: getLocation()},
+			in:         Timespan{Start: timeDate(2021, 1, 4, 23, 0, 0), End: timeDate(2021, 1, 5, 3, 0, 0)},
+			cutFromEnd: true,
+			out:        Timespan{Start: timeDate(2021, 1, 4, 23, 0, 0), End: timeDate(2021, 1, 5, 2, 0, 0)},
+		},
+		{
+			name:       "Daily limit is used up",
+			workload:   WorkloadCap{MaxPerDay: time.Hour * 3, Location: getLocation()},
+			scheduled:  []Timespan{{Start: timeDate(2021, 1, 4, 13, 0, 0), End: timeDate(2021, 1, 4, 16, 0, 0)}},
+			in:         Timespan{Start: timeDate(2021, 1, 4, 9, 0, 0), End: timeDate(2021, 1, 4, 12, 0, 0)},
+			cutFromEnd: true,
+			out:        Timespan{Start: timeDate(2021, 1, 4, 9, 0, 0), End: timeDate(2021, 1, 4, 9, 0, 0)},
+		},
+	}
+
+	for _, tt := range capTests {
+		t.Run(tt.name, func(t *testing.T) {
+			workloadCap := tt.workload
+			for _, scheduled := range tt.scheduled {
+				workloadCap.AddScheduled(scheduled)
+			}
+
+			result := workloadCap.Limit(tt.in, tt.cutFromEnd)
+			if !result.Start.Equal(tt.out.Start) || !result.End.Equal(tt.out.End) {
+				t.Errorf("got %s, want %s", result.String(), tt.out.String())
+			}
+		})
+	}
+}
+
+func TestNormalizeAllowedWeekdayTimespans(t *testing.T) {
+	var normalizeTests = []struct {
+		name    string
+		in      []WeekdayTimespans
+		out     []WeekdayTimespans
+		wantErr bool
+	}{
+		{
+			name: "Overnight timespan ends on the following day and is merged",
+			in: []WeekdayTimespans{{Weekday: time.Monday, Timespans: []Timespan{
+				{Start: timeDate(0, 1, 1, 22, 0, 0), End: timeDate(0, 1, 1, 1, 0, 0)},
+				{Start: timeDate(0, 1, 1, 20, 0, 0), End: timeDate(0, 1, 1, 23, 0, 0)},
+			}}},
+			out: []WeekdayTimespans{{Weekday: time.Monday, Timespans: []Timespan{
+				{Start: timeDate(0, 1, 1, 20, 0, 0), End: timeDate(0, 1, 2, 1, 0, 0)},
+			}}},
+		},
+		{
+			name: "Empty timespan",
+			in: []WeekdayTimespans{{Weekday: time.Monday, Timespans: []Timespan{
+				{Start: timeDate(0, 1, 1, 9, 0, 0), End: timeDate(0, 1, 1, 9, 0, 0)},
+			}}},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid weekday",
+			in:      []WeekdayTimespans{{Weekday: time.Weekday(7)}},
+			wantErr: true,
+		},
+		{
+			name:    "Weekday configured twice",
+			in:      []WeekdayTimespans{{Weekday: time.Monday}, {Weekday: time.Monday}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range normalizeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := NormalizeAllowedWeekdayTimespans(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error is %v, want error %t", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(out, tt.out) {
+				t.Errorf("normalized timespans are %v, want %v", out, tt.out)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package date
+
+import (
+	"time"
+)
+
+// Scorer rates free timespans of a TimeWindow, free timespans with a lower score are used first
+type Scorer interface {
+	Score(window *TimeWindow, target time.Time, timespan Timespan) float64
+}
+
+// DefaultScorer prefers free time close to the target. Free time next to a preferred neighbor is always preferred,
+// as long as it is within three days of the target.
+type DefaultScorer struct{}
+
+// Score returns the distance to the target or zero for a neighbor
+func (s DefaultScorer) Score(window *TimeWindow, target time.Time, timespan Timespan) float64 {
+	value := absoluteOfDuration(timespan.Start.Sub(target))
+
+	if value <= time.Hour*24*3 && window.isPreferredNeighbor(timespan) {
+		value = 0
+	}
+
+	return float64(value)
+}
+
+// DistanceFactor is the distance in hours between the start of the free time and the target
+type DistanceFactor struct{}
+
+// Score returns the distance to the target in hours
+func (f DistanceFactor) Score(window *TimeWindow, target time.Time, timespan Timespan) float64 {
+	return absoluteOfDuration(timespan.Start.Sub(target)).Hours()
+}
+
+// TimeOfDayFactor is the distance in hours between the clock of the start of the free time and a preferred clock,
+// both clocks are compared in Location
+type TimeOfDayFactor struct {
+	Preferred time.Time
+	Location  *time.Location
+}
+
+// Score returns the distance to the preferred clock in hours
+func (f TimeOfDayFactor) Score(window *TimeWindow, target time.Time, timespan Timespan) float64 {
+	start := calcSecondsFromClock(timespan.Start.In(f.Location).Clock())
+	preferred := calcSecondsFromClock(f.Preferred.In(f.Location).Clock())
+
+	distance := start - preferred
+	if distance < 0 {
+		distance = -distance
+	}
+
+	// The distance across midnight can be shorter
+	if secondsPerDay-distance < distance {
+		distance = secondsPerDay - distance
+	}
+
+	return (time.Duration(distance) * time.Second).Hours()
+}
+
+// NeighborFactor is zero for free time next to a preferred neighbor and one otherwise
+type NeighborFactor struct{}
+
+// Score returns zero for neighbors and one otherwise
+func (f NeighborFactor) Score(window *TimeWindow, target time.Time, timespan Timespan) float64 {
+	if window.isPreferredNeighbor(timespan) {
+		return 0
+	}
+
+	return 1
+}
+
+// FragmentationFactor is the free time in hours that is left when a work unit of maximum length is taken from the
+// free time. Preferring small free timespans keeps larger blocks of free time intact.
+type FragmentationFactor struct{}
+
+// Score returns the remaining free time in hours
+func (f FragmentationFactor) Score(window *TimeWindow, target time.Time, timespan Timespan) float64 {
+	remaining := timespan.Duration() - window.MaxWorkUnitLength
+	if remaining < 0 {
+		return 0
+	}
+
+	return remaining.Hours()
+}
+
+// WeightedFactor is a Scorer together with its weight
+type WeightedFactor struct {
+	Scorer Scorer
+	Weight float64
+}
+
+// WeightedScorer sums up the weighted scores of multiple factors
+type WeightedScorer struct {
+	Factors []WeightedFactor
+}
+
+// Score returns the weighted sum of all factors
+func (s WeightedScorer) Score(window *TimeWindow, target time.Time, timespan Timespan) float64 {
+	var score float64
+	for _, factor := range s.Factors {
+		if factor.Weight == 0 {
+			continue
+		}
+
+		score += factor.Weight * factor.Scorer.Score(window, target, timespan)
+	}
+
+	return score
+}
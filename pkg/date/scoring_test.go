@@ -0,0 +1,121 @@
+package date
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScorers_Score(t *testing.T) {
+	window := &TimeWindow{
+		MaxWorkUnitLength: time.Hour * 4,
+		PreferredNeighbors: []Timespan{
+			{Start: timeDate(2021, 1, 4, 8, 0, 0), End: timeDate(2021, 1, 4, 9, 0, 0)},
+		},
+	}
+	target := timeDate(2021, 1, 5, 9, 0, 0)
+
+	var scorerTests = []struct {
+		name     string
+		scorer   Scorer
+		timespan Timespan
+		out      float64
+	}{
+		{
+			name:     "Default scorer uses the distance",
+			scorer:   DefaultScorer{},
+			timespan: Timespan{Start: timeDate(2021, 1, 5, 13, 0, 0), End: timeDate(2021, 1, 5, 15, 0, 0)},
+			out:      float64(time.Hour * 4),
+		},
+		{
+			name:     "Default scorer prefers neighbors",
+			scorer:   DefaultScorer{},
+			timespan: Timespan{Start: timeDate(2021, 1, 4, 9, 0, 0), End: timeDate(2021, 1, 4, 12, 0, 0)},
+			out:      0,
+		},
+		{
+			name:     "Distance in hours",
+			scorer:   DistanceFactor{},
+			timespan: Timespan{Start: timeDate(2021, 1, 4, 21, 0, 0), End: timeDate(2021, 1, 4, 22, 0, 0)},
+			out:      12,
+		},
+		{
+			name:     "Time of day",
+			scorer:   TimeOfDayFactor{Preferred: timeDate(0, 0, 0, 14, 0, 0), Location: getLocation()},
+			timespan: Timespan{Start: timeDate(2021, 1, 6, 9, 30, 0), End: timeDate(2021, 1, 6, 12, 0, 0)},
+			out:      4.5,
+		},
+		{
+			name:     "Time of day across midnight",
+			scorer:   TimeOfDayFactor{Preferred: timeDate(0, 0, 0, 23, 0, 0), Location: getLocation()},
+			timespan: Timespan{Start: timeDate(2021, 1, 6, 1, 0, 0), End: timeDate(2021, 1, 6, 3, 0, 0)},
+			out:      2,
+		},
+		{
+			name:     "Neighbor",
+			scorer:   NeighborFactor{},
+			timespan: Timespan{Start: timeDate(2021, 1, 4, 9, 0, 0), End: timeDate(2021, 1, 4, 12, 0, 0)},
+			out:      0,
+		},
+		{
+			name:     "No neighbor",
+			scorer:   NeighborFactor{},
+			timespan: Timespan{Start: timeDate(2021, 1, 4, 13, 0, 0), End: timeDate(2021, 1, 4, 15, 0, 0)},
+			out:      1,
+		},
+		{
+			name:     "Fragmentation of a large free timespan",
+			scorer:   FragmentationFactor{},
+			timespan: Timespan{Start: timeDate(2021, 1, 4, 9, 0, 0), End: timeDate(2021, 1, 4, 19, 0, 0)},
+			out:      6,
+		},
+		{
+			name:     "Fragmentation of a free timespan that is used up",
+			scorer:   FragmentationFactor{},
+			timespan: Timespan{Start: timeDate(2021, 1, 4, 9, 0, 0), End: timeDate(2021, 1, 4, 11, 0, 0)},
+			out:      0,
+		},
+		{
+			name: "Weighted",
+			scorer: WeightedScorer{Factors: []WeightedFactor{
+				{Scorer: DistanceFactor{}, Weight: 1},
+				{Scorer: NeighborFactor{}, Weight: 24},
+				{Scorer: FragmentationFactor{}, Weight: 0.5},
+			}},
+			timespan: Timespan{Start: timeDate(2021, 1, 5, 13, 0, 0), End: timeDate(2021, 1, 5, 19, 0, 0)},
+			out:      4 + 24 + 1,
+		},
+	}
+
+	for _, tt := range scorerTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if score := tt.scorer.Score(window, target, tt.timespan); score != tt.out {
+				t.Errorf("got score %f, want %f", score, tt.out)
+			}
+		})
+	}
+}
+
+func TestTimeWindow_ComputeFreeWithScorer(t *testing.T) {
+	window := TimeWindow{
+		Start:             timeDate(2021, 1, 4, 0, 0, 0),
+		End:               timeDate(2021, 1, 5, 0, 0, 0),
+		MaxWorkUnitLength: time.Hour * 2,
+		Scorer: WeightedScorer{Factors: []WeightedFactor{
+			{Scorer: TimeOfDayFactor{Preferred: timeDate(0, 0, 0, 16, 0, 0), Location: getLocation()}, Weight: 1},
+		}},
+	}
+
+	constraint := &FreeConstraint{
+		Location: getLocation(),
+		AllowedTimeSpans: []Timespan{
+			{Start: timeDate(0, 0, 0, 9, 0, 0), End: timeDate(0, 0, 0, 12, 0, 0)},
+			{Start: timeDate(0, 0, 0, 15, 0, 0), End: timeDate(0, 0, 0, 18, 0, 0)},
+		},
+	}
+
+	// The default scorer would prefer the morning, because the target is at the start of the window
+	free := window.ComputeFree(constraint, window.Start, Timespan{Start: window.Start, End: window.End})
+	if len(free) != 2 || !free[0].Start.Equal(timeDate(2021, 1, 4, 15, 0, 0)) {
+		t.Errorf("got free timespans %v, want the afternoon first", free)
+	}
+}
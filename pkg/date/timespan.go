@@ -56,15 +56,14 @@ func (t *Timespan) ContainsByClock(timespan Timespan) bool {
 	return false
 }
 
-// ContainsClock checks if a time is contained in a Timespan
+// ContainsClock checks if a time is contained in a Timespan, a Timespan whose end clock isn't after its start clock crosses midnight
 func (t *Timespan) ContainsClock(clock time.Time) bool {
-	cStart := calcSecondsFromClock(t.Start.Clock())
-	cEnd := calcSecondsFromClock(t.End.Clock())
-
 	clockSeconds := calcSecondsFromClock(clock.Clock())
 
-	if cStart <= clockSeconds && clockSeconds <= cEnd {
-		return true
+	for _, interval := range t.clockIntervals() {
+		if interval[0] <= clockSeconds && clockSeconds <= interval[1] {
+			return true
+		}
 	}
 	return false
 }
@@ -77,18 +76,49 @@ func (t *Timespan) In(location *time.Location) Timespan {
 	return *t
 }
 
-// IntersectsWithClock checks if a timespan even intersects
+// IntersectsWithClock checks if a timespan even intersects, timespans whose end clock isn't after their start clock cross midnight
 func (t *Timespan) IntersectsWithClock(timespan Timespan) bool {
-	cStart := calcSecondsFromClock(t.Start.Clock())
-	cEnd := calcSecondsFromClock(t.End.Clock())
+	for _, c := range t.clockIntervals() {
+		for _, interval := range timespan.clockIntervals() {
+			if interval[0] <= c[1] && interval[1] >= c[0] {
+				return true
+			}
+		}
+	}
+	return false
+}
 
-	tStart := calcSecondsFromClock(timespan.Start.Clock())
-	tEnd := calcSecondsFromClock(timespan.End.Clock())
+// clockIntervals returns the clock of a Timespan as intervals of seconds within a day,
+// a Timespan crossing midnight is split into one interval before and one after midnight.
+// A Timespan whose end clock equals its start clock lasts a whole day, just like in FreeConstraint.Test.
+func (t *Timespan) clockIntervals() [][2]int {
+	start := calcSecondsFromClock(t.Start.Clock())
+	end := calcSecondsFromClock(t.End.Clock())
+
+	if end <= start {
+		return [][2]int{{start, secondsPerDay}, {0, end}}
+	}
+
+	return [][2]int{{start, end}}
+}
+
+// intersection returns the part of t that lies within timespan or nil if they don't intersect
+func (t *Timespan) intersection(timespan Timespan) *Timespan {
+	start := t.Start
+	if timespan.Start.After(start) {
+		start = timespan.Start
+	}
+
+	end := t.End
+	if timespan.End.Before(end) {
+		end = timespan.End
+	}
 
-	if tStart > cEnd || tEnd < cStart {
-		return false
+	if !start.Before(end) {
+		return nil
 	}
-	return true
+
+	return &Timespan{Start: start, End: end}
 }
 
 // IntersectsWith checks if one timespan intersects with another
@@ -183,8 +213,14 @@ type TimeWindow struct {
 	Start              time.Time
 	End                time.Time
 	BusyPadding        time.Duration
+	MinWorkUnitLength  time.Duration
 	MaxWorkUnitLength  time.Duration
+	ChunkLength        time.Duration
+	BreakLength        time.Duration
 	PreferredNeighbors []Timespan
+	WorkloadCaps       []*WorkloadCap
+	Scorer             Scorer
+	Trace              *Trace
 	busy               []Timespan
 	free               []Timespan
 	freeDuration       time.Duration
@@ -197,6 +233,55 @@ func (w *TimeWindow) FreeDuration() time.Duration {
 	return w.freeDuration
 }
 
+// FreeDurationWithinCaps returns the duration of the free timeslots combined that can be used without exceeding the
+// workload caps
+func (w *TimeWindow) FreeDurationWithinCaps() time.Duration {
+	if len(w.WorkloadCaps) == 0 {
+		return w.freeDuration
+	}
+
+	var duration time.Duration
+	for _, timespan := range w.FreeWithinCaps() {
+		duration += timespan.Duration()
+	}
+
+	return duration
+}
+
+// FreeWithinCaps returns the free timeslots sorted by their start and cut down, so that using them in this order
+// doesn't exceed the workload caps
+func (w *TimeWindow) FreeWithinCaps() []Timespan {
+	w.freeMutex.Lock()
+	defer w.freeMutex.Unlock()
+
+	workloadCaps := make([]*WorkloadCap, len(w.WorkloadCaps))
+	for i, workloadCap := range w.WorkloadCaps {
+		workloadCaps[i] = workloadCap.copy()
+	}
+
+	free := append([]Timespan{}, w.free...)
+	sort.Slice(free, func(i, j int) bool {
+		return free[i].Start.Before(free[j].Start)
+	})
+
+	var limited []Timespan
+	for _, timespan := range free {
+		for _, workloadCap := range workloadCaps {
+			timespan = workloadCap.Limit(timespan, true)
+		}
+
+		for _, workloadCap := range workloadCaps {
+			workloadCap.AddScheduled(timespan)
+		}
+
+		if timespan.Duration() > 0 {
+			limited = append(limited, timespan)
+		}
+	}
+
+	return limited
+}
+
 // Duration simply get the duration of a Timespan
 func (w *TimeWindow) Duration() time.Duration {
 	w.busyMutex.Lock()
@@ -205,6 +290,22 @@ func (w *TimeWindow) Duration() time.Duration {
 	return w.End.Sub(w.Start)
 }
 
+// Busy returns a copy of the busy timespans of the window
+func (w *TimeWindow) Busy() []Timespan {
+	w.busyMutex.Lock()
+	defer w.busyMutex.Unlock()
+
+	return append([]Timespan{}, w.busy...)
+}
+
+// Free returns a copy of the free timespans of the window
+func (w *TimeWindow) Free() []Timespan {
+	w.freeMutex.Lock()
+	defer w.freeMutex.Unlock()
+
+	return append([]Timespan{}, w.free...)
+}
+
 // AddToBusy adds a single Timespan to the sorted busy timespan array in a TimeWindow
 func (w *TimeWindow) AddToBusy(timespan Timespan) {
 	isPreferred := false
@@ -285,6 +386,15 @@ func (w *TimeWindow) ComputeFree(constraint *FreeConstraint, target time.Time, t
 	w.busyMutex.Lock()
 	defer w.busyMutex.Unlock()
 
+	w.Trace.setConstraints(w, constraint)
+	w.Trace.addExplored(timeInterval)
+
+	testConstraint := func(timespan Timespan) []Timespan {
+		constrained := constraint.Test(timespan)
+		w.Trace.addNotAllowed(timespan, constrained)
+		return constrained
+	}
+
 	var relevantBusyEntries []Timespan
 
 	for _, busy := range w.busy {
@@ -307,7 +417,7 @@ func (w *TimeWindow) ComputeFree(constraint *FreeConstraint, target time.Time, t
 	}
 
 	if len(relevantBusyEntries) == 0 {
-		w.free = append(w.free, constraint.Test(Timespan{Start: timeInterval.Start, End: timeInterval.End})...)
+		w.free = append(w.free, testConstraint(Timespan{Start: timeInterval.Start, End: timeInterval.End})...)
 		for _, timespan := range w.free {
 			w.freeDuration += timespan.Duration()
 		}
@@ -316,7 +426,7 @@ func (w *TimeWindow) ComputeFree(constraint *FreeConstraint, target time.Time, t
 	for index, busy := range relevantBusyEntries {
 		if index == 0 {
 			if timeInterval.Start.Before(busy.Start) {
-				constrained := constraint.Test(Timespan{Start: timeInterval.Start, End: busy.Start})
+				constrained := testConstraint(Timespan{Start: timeInterval.Start, End: busy.Start})
 				for _, timespan := range constrained {
 					w.freeDuration += timespan.Duration()
 				}
@@ -325,7 +435,7 @@ func (w *TimeWindow) ComputeFree(constraint *FreeConstraint, target time.Time, t
 		}
 
 		if index == len(relevantBusyEntries)-1 {
-			constrained := constraint.Test(Timespan{Start: busy.End, End: timeInterval.End})
+			constrained := testConstraint(Timespan{Start: busy.End, End: timeInterval.End})
 			for _, timespan := range constrained {
 				w.freeDuration += timespan.Duration()
 			}
@@ -333,7 +443,7 @@ func (w *TimeWindow) ComputeFree(constraint *FreeConstraint, target time.Time, t
 			continue
 		}
 
-		constrained := constraint.Test(Timespan{Start: busy.End, End: relevantBusyEntries[index+1].Start})
+		constrained := testConstraint(Timespan{Start: busy.End, End: relevantBusyEntries[index+1].Start})
 		for _, timespan := range constrained {
 			w.freeDuration += timespan.Duration()
 		}
@@ -346,26 +456,29 @@ func (w *TimeWindow) ComputeFree(constraint *FreeConstraint, target time.Time, t
 		return w.calculateHeuristic(target, w.free[i]) < w.calculateHeuristic(target, w.free[j])
 	})
 
+	w.Trace.setCandidates(w, target, w.free)
+
 	return w.free
 }
 
-func (w *TimeWindow) calculateHeuristic(target time.Time, timespan Timespan) time.Duration {
-	value := absoluteOfDuration(timespan.Start.Sub(target))
+// calculateHeuristic scores a free timespan with the Scorer of the window, the DefaultScorer is used if there is none
+func (w *TimeWindow) calculateHeuristic(target time.Time, timespan Timespan) float64 {
+	if w.Scorer == nil {
+		return DefaultScorer{}.Score(w, target, timespan)
+	}
 
-	isNeighbor := false
+	return w.Scorer.Score(w, target, timespan)
+}
+
+// isPreferredNeighbor checks if a timespan is next to a preferred neighbor that can still grow
+func (w *TimeWindow) isPreferredNeighbor(timespan Timespan) bool {
 	for _, neighbor := range w.PreferredNeighbors {
 		if neighbor.Duration() < w.MaxWorkUnitLength && timespan.Neighbors(neighbor) {
-			isNeighbor = true
-			break
+			return true
 		}
 	}
 
-	// If it's a neighbor we want to prioritize it, it also has to be within 3 days distance to the target date
-	if isNeighbor && value <= time.Hour*24*3 {
-		value = 0
-	}
-
-	return value
+	return false
 }
 
 func absoluteOfDuration(duration time.Duration) time.Duration {
@@ -375,7 +488,23 @@ func absoluteOfDuration(duration time.Duration) time.Duration {
 	return duration
 }
 
-// FindTimeSlot finds one or multiple time slots that comply with the specified rules
+// limitToWorkloadCaps shortens a timespan to the workload caps of the window
+func (w *TimeWindow) limitToWorkloadCaps(timespan Timespan, cutFromEnd bool) Timespan {
+	for _, workloadCap := range w.WorkloadCaps {
+		timespan = workloadCap.Limit(timespan, cutFromEnd)
+	}
+
+	return timespan
+}
+
+// addToWorkloadCaps counts a found time slot as scheduled work in all workload caps
+func (w *TimeWindow) addToWorkloadCaps(timespan Timespan) {
+	for _, workloadCap := range w.WorkloadCaps {
+		workloadCap.AddScheduled(timespan)
+	}
+}
+
+// FindTimeSlot finds one or multiple time slots that comply with the specified rules and the workload caps
 func (w *TimeWindow) FindTimeSlot(ruleDuration *RuleDuration) *Timespan {
 	w.freeMutex.Lock()
 	defer w.freeMutex.Unlock()
@@ -424,13 +553,21 @@ func (w *TimeWindow) FindTimeSlot(ruleDuration *RuleDuration) *Timespan {
 		}
 
 		if timespan.Duration() == 0 {
+			w.Trace.addRejection(w.free[index], TraceRejectionPadding)
 			continue
 		}
 
 		foundFlag = false
 		// Base case: If there are no rules, we can return the timespan
 		if ruleDuration == nil {
-			tmp := timespan
+			tmp := w.limitToWorkloadCaps(timespan, !neighborEnd)
+			if tmp.Duration() == 0 {
+				w.Trace.addRejection(timespan, TraceRejectionWorkloadCap)
+				continue
+			}
+
+			w.addToWorkloadCaps(tmp)
+			w.Trace.addSlot(tmp)
 			w.free = RemoveFromTimespanSlice(w.free, index)
 			return &tmp
 		}
@@ -439,13 +576,24 @@ func (w *TimeWindow) FindTimeSlot(ruleDuration *RuleDuration) *Timespan {
 		// !neighborEnd means we want to cut the end if it's not a neighbor at the end
 		result := ruleDuration.Test(timespan, !neighborEnd)
 		if result == nil {
+			w.Trace.addRejection(timespan, TraceRejectionTooShort)
 			continue
 		}
+
+		// The workload caps can shorten the time slot below the minimum
+		capped := w.limitToWorkloadCaps(*result, !neighborEnd)
+		if capped.Duration() == 0 || capped.Duration() < ruleDuration.Minimum {
+			w.Trace.addRejection(*result, TraceRejectionWorkloadCap)
+			continue
+		}
+
 		foundFlag = true
-		timespan = *result
+		timespan = capped
 
 		if foundFlag {
 			tmp := timespan
+			w.addToWorkloadCaps(tmp)
+			w.Trace.addSlot(tmp)
 
 			if w.free[index].Duration() != tmp.Duration() {
 				// If neighborEnd is true it means we have cut something from the start
@@ -720,6 +720,82 @@ func TestTimespan_ContainsInClock_Test(t *testing.T) {
 	}
 }
 
+func TestTimespan_ContainsClock(t *testing.T) {
+	var timespanContainsClockTests = []struct {
+		container Timespan
+		clock     time.Time
+		out       bool
+	}{
+		{
+			// Case is contained
+			Timespan{
+				Start: timeDate(0, 0, 0, 8, 0, 0),
+				End:   timeDate(0, 0, 0, 18, 0, 0)},
+			timeDate(2021, 1, 1, 12, 0, 0),
+			true,
+		},
+		{
+			// Case is not contained
+			Timespan{
+				Start: timeDate(0, 0, 0, 8, 0, 0),
+				End:   timeDate(0, 0, 0, 18, 0, 0)},
+			timeDate(2021, 1, 1, 19, 0, 0),
+			false,
+		},
+		{
+			// Overnight contained before midnight
+			Timespan{
+				Start: timeDate(0, 0, 0, 22, 0, 0),
+				End:   timeDate(0, 0, 0, 1, 0, 0)},
+			timeDate(2021, 1, 1, 23, 0, 0),
+			true,
+		},
+		{
+			// Overnight contained after midnight
+			Timespan{
+				Start: timeDate(0, 0, 0, 22, 0, 0),
+				End:   timeDate(0, 0, 0, 1, 0, 0)},
+			timeDate(2021, 1, 1, 0, 30, 0),
+			true,
+		},
+		{
+			// Overnight not contained
+			Timespan{
+				Start: timeDate(0, 0, 0, 22, 0, 0),
+				End:   timeDate(0, 0, 0, 1, 0, 0)},
+			timeDate(2021, 1, 1, 12, 0, 0),
+			false,
+		},
+		{
+			// Until midnight contains midnight
+			Timespan{
+				Start: timeDate(0, 0, 0, 18, 0, 0),
+				End:   timeDate(0, 0, 0, 0, 0, 0)},
+			timeDate(2021, 1, 1, 0, 0, 0),
+			true,
+		},
+		{
+			// Same start and end clock lasts the whole day
+			Timespan{
+				Start: timeDate(0, 0, 0, 9, 0, 0),
+				End:   timeDate(0, 0, 0, 9, 0, 0)},
+			timeDate(2021, 1, 1, 3, 0, 0),
+			true,
+		},
+	}
+
+	for index, tt := range timespanContainsClockTests {
+		t.Run(fmt.Sprintf("Case %d", index), func(t *testing.T) {
+			tt := tt
+			t.Parallel()
+			result := tt.container.ContainsClock(tt.clock)
+			if result != tt.out {
+				t.Errorf("got %v, want %v", result, tt.out)
+			}
+		})
+	}
+}
+
 func TestTimespan_OverflowsStart(t *testing.T) {
 	var timespanOverflowStartTests = []struct {
 		container Timespan
@@ -966,6 +1042,46 @@ func TestTimespan_IntersectsWithClock(t *testing.T) {
 				End:   timeDate(0, 0, 0, 22, 0, 0)},
 			false,
 		},
+		{
+			// Overnight container intersects after midnight
+			Timespan{
+				Start: timeDate(0, 0, 0, 22, 0, 0),
+				End:   timeDate(0, 0, 0, 2, 0, 0)},
+			Timespan{
+				Start: timeDate(0, 0, 0, 1, 0, 0),
+				End:   timeDate(0, 0, 0, 3, 0, 0)},
+			true,
+		},
+		{
+			// Overnight container intersects before midnight
+			Timespan{
+				Start: timeDate(0, 0, 0, 22, 0, 0),
+				End:   timeDate(0, 0, 0, 2, 0, 0)},
+			Timespan{
+				Start: timeDate(0, 0, 0, 20, 0, 0),
+				End:   timeDate(0, 0, 0, 23, 0, 0)},
+			true,
+		},
+		{
+			// Overnight container outside
+			Timespan{
+				Start: timeDate(0, 0, 0, 22, 0, 0),
+				End:   timeDate(0, 0, 0, 2, 0, 0)},
+			Timespan{
+				Start: timeDate(0, 0, 0, 8, 0, 0),
+				End:   timeDate(0, 0, 0, 18, 0, 0)},
+			false,
+		},
+		{
+			// Both overnight
+			Timespan{
+				Start: timeDate(0, 0, 0, 23, 0, 0),
+				End:   timeDate(0, 0, 0, 1, 0, 0)},
+			Timespan{
+				Start: timeDate(0, 0, 0, 22, 0, 0),
+				End:   timeDate(0, 0, 0, 0, 30, 0)},
+			true,
+		},
 	}
 
 	for index, tt := range timespanIntersectTests {
@@ -1111,3 +1227,35 @@ func TestTimeWindow_FindTimeSlot(t *testing.T) {
 		})
 	}
 }
+
+func TestTimeWindow_FindTimeSlotWithWorkloadCaps(t *testing.T) {
+	window := TimeWindow{
+		Start:             timeDate(2021, 1, 4, 9, 0, 0),
+		End:               timeDate(2021, 1, 5, 17, 0, 0),
+		MaxWorkUnitLength: time.Hour * 6,
+		WorkloadCaps:      []*WorkloadCap{{MaxPerDay: time.Hour * 3, Location: getLocation()}},
+	}
+
+	window.AddToBusy(Timespan{Start: timeDate(2021, 1, 4, 17, 0, 0), End: timeDate(2021, 1, 5, 9, 0, 0)})
+	window.ComputeFree(&FreeConstraint{Location: getLocation()}, window.Start, Timespan{Start: window.Start, End: window.End})
+
+	if window.FreeDurationWithinCaps() != time.Hour*6 {
+		t.Errorf("free duration within caps is %s, want 6h", window.FreeDurationWithinCaps())
+	}
+
+	want := []Timespan{
+		{Start: timeDate(2021, 1, 4, 9, 0, 0), End: timeDate(2021, 1, 4, 12, 0, 0)},
+		{Start: timeDate(2021, 1, 5, 9, 0, 0), End: timeDate(2021, 1, 5, 12, 0, 0)},
+	}
+
+	for _, timespan := range want {
+		slot := window.FindTimeSlot(&RuleDuration{Minimum: time.Minute * 30, Maximum: time.Hour * 6})
+		if slot == nil || !slot.Start.Equal(timespan.Start) || !slot.End.Equal(timespan.End) {
+			t.Fatalf("got time slot %v, want %s", slot, timespan.String())
+		}
+	}
+
+	if slot := window.FindTimeSlot(&RuleDuration{Minimum: time.Minute * 30, Maximum: time.Hour * 6}); slot != nil {
+		t.Errorf("got time slot %s although the daily limit is used up", slot.String())
+	}
+}
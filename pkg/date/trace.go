@@ -0,0 +1,221 @@
+package date
+
+import (
+	"sync"
+	"time"
+)
+
+// Trace records the decisions made while searching free time in a TimeWindow.
+// Nothing is recorded unless the window has a trace, all methods can be called on a nil trace.
+type Trace struct {
+	Window             Timespan         `json:"window"`
+	Target             time.Time        `json:"target"`
+	WorkloadToSchedule time.Duration    `json:"workloadToSchedule"`
+	Constraints        TraceConstraints `json:"constraints"`
+	Explored           []Timespan       `json:"explored"`
+	Busy               []TraceBusy      `json:"busy"`
+	Candidates         []TraceCandidate `json:"candidates"`
+	Rejections         []TraceRejection `json:"rejections"`
+	Slots              []Timespan       `json:"slots"`
+	mutex              sync.Mutex
+}
+
+// TraceConstraints are the constraints free time had to comply with
+type TraceConstraints struct {
+	Location                string             `json:"location"`
+	AllowedTimespans        []Timespan         `json:"allowedTimespans"`
+	AllowedWeekdayTimespans []WeekdayTimespans `json:"allowedWeekdayTimespans"`
+	BusyPadding             time.Duration      `json:"busyPadding"`
+	MinWorkUnitLength       time.Duration      `json:"minWorkUnitLength"`
+	MaxWorkUnitLength       time.Duration      `json:"maxWorkUnitLength"`
+	WorkloadCaps            []TraceWorkloadCap `json:"workloadCaps"`
+	Intersected             []TraceConstraints `json:"intersected"`
+}
+
+// TraceWorkloadCap is a workload cap that was applied
+type TraceWorkloadCap struct {
+	MaxPerDay  time.Duration `json:"maxPerDay"`
+	MaxPerWeek time.Duration `json:"maxPerWeek"`
+}
+
+// TraceBusy are the busy timespans a single source added
+type TraceBusy struct {
+	Source    string     `json:"source"`
+	Timespans []Timespan `json:"timespans"`
+}
+
+// TraceCandidate is a free timespan together with its score, lower scores are used first
+type TraceCandidate struct {
+	Timespan Timespan `json:"timespan"`
+	Score    float64  `json:"score"`
+}
+
+// TraceRejection is time that couldn't be used together with the reason
+type TraceRejection struct {
+	Timespan Timespan `json:"timespan"`
+	Reason   string   `json:"reason"`
+}
+
+// TraceRejectionNotAllowed is the reason for time outside of the allowed timespans
+const TraceRejectionNotAllowed = "outside of the allowed timespans"
+
+// TraceRejectionTooShort is the reason for free time that is shorter than the minimum work unit duration
+const TraceRejectionTooShort = "shorter than the minimum work unit duration"
+
+// TraceRejectionPadding is the reason for free time that is used up by the padding to neighboring work units
+const TraceRejectionPadding = "used up by the padding to neighboring work units"
+
+// TraceRejectionWorkloadCap is the reason for free time on days or weeks that reached their workload cap
+const TraceRejectionWorkloadCap = "workload cap reached"
+
+// SetWindow records the window that is searched and the target the free time should be close to
+func (t *Trace) SetWindow(window Timespan, target time.Time, workloadToSchedule time.Duration) {
+	if t == nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.Window = window
+	t.Target = target
+	t.WorkloadToSchedule = workloadToSchedule
+}
+
+// AddBusy records the busy timespans of a source
+func (t *Trace) AddBusy(source string, timespans []Timespan) {
+	if t == nil || len(timespans) == 0 {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for i, busy := range t.Busy {
+		if busy.Source == source {
+			t.Busy[i].Timespans = MergeTimespans(append(busy.Timespans, timespans...))
+			return
+		}
+	}
+
+	t.Busy = append(t.Busy, TraceBusy{Source: source, Timespans: MergeTimespans(append([]Timespan{}, timespans...))})
+}
+
+func (t *Trace) setConstraints(window *TimeWindow, constraint *FreeConstraint) {
+	if t == nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.Constraints = TraceConstraints{
+		AllowedTimespans:        constraint.AllowedTimeSpans,
+		AllowedWeekdayTimespans: constraint.AllowedWeekdayTimeSpans,
+		BusyPadding:             window.BusyPadding,
+		MinWorkUnitLength:       window.MinWorkUnitLength,
+		MaxWorkUnitLength:       window.MaxWorkUnitLength,
+	}
+
+	if constraint.Location != nil {
+		t.Constraints.Location = constraint.Location.String()
+	}
+
+	for _, other := range constraint.Intersect {
+		intersected := TraceConstraints{
+			AllowedTimespans:        other.AllowedTimeSpans,
+			AllowedWeekdayTimespans: other.AllowedWeekdayTimeSpans,
+		}
+
+		if other.Location != nil {
+			intersected.Location = other.Location.String()
+		}
+
+		t.Constraints.Intersected = append(t.Constraints.Intersected, intersected)
+	}
+
+	for _, workloadCap := range window.WorkloadCaps {
+		t.Constraints.WorkloadCaps = append(t.Constraints.WorkloadCaps, TraceWorkloadCap{
+			MaxPerDay:  workloadCap.MaxPerDay,
+			MaxPerWeek: workloadCap.MaxPerWeek,
+		})
+	}
+}
+
+func (t *Trace) addExplored(timespan Timespan) {
+	if t == nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.Explored = append(t.Explored, timespan)
+}
+
+func (t *Trace) setCandidates(window *TimeWindow, target time.Time, free []Timespan) {
+	if t == nil {
+		return
+	}
+
+	candidates := make([]TraceCandidate, 0, len(free))
+	for _, timespan := range free {
+		candidates = append(candidates, TraceCandidate{Timespan: timespan, Score: window.calculateHeuristic(target, timespan)})
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.Candidates = candidates
+}
+
+func (t *Trace) addRejection(timespan Timespan, reason string) {
+	if t == nil || timespan.Duration() <= 0 {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	// The same free time can be looked at multiple times
+	for _, rejection := range t.Rejections {
+		if rejection.Reason == reason && rejection.Timespan.Start.Equal(timespan.Start) && rejection.Timespan.End.Equal(timespan.End) {
+			return
+		}
+	}
+
+	t.Rejections = append(t.Rejections, TraceRejection{Timespan: timespan, Reason: reason})
+}
+
+// addNotAllowed records the parts of a timespan that are not part of the allowed timespans
+func (t *Trace) addNotAllowed(timespan Timespan, allowed []Timespan) {
+	if t == nil {
+		return
+	}
+
+	start := timespan.Start
+	for _, allowedTimespan := range MergeTimespans(append([]Timespan{}, allowed...)) {
+		if allowedTimespan.Start.After(start) {
+			t.addRejection(Timespan{Start: start, End: allowedTimespan.Start}, TraceRejectionNotAllowed)
+		}
+
+		if allowedTimespan.End.After(start) {
+			start = allowedTimespan.End
+		}
+	}
+
+	if timespan.End.After(start) {
+		t.addRejection(Timespan{Start: start, End: timespan.End}, TraceRejectionNotAllowed)
+	}
+}
+
+func (t *Trace) addSlot(timespan Timespan) {
+	if t == nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.Slots = append(t.Slots, timespan)
+}
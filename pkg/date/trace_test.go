@@ -0,0 +1,49 @@
+package date
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeWindow_Trace(t *testing.T) {
+	window := TimeWindow{
+		Start:             timeDate(2021, 1, 4, 8, 0, 0),
+		End:               timeDate(2021, 1, 4, 18, 0, 0),
+		MaxWorkUnitLength: time.Hour * 6,
+		Trace:             &Trace{},
+	}
+
+	constraint := &FreeConstraint{
+		Location:         getLocation(),
+		AllowedTimeSpans: []Timespan{{Start: timeDate(0, 0, 0, 9, 0, 0), End: timeDate(0, 0, 0, 17, 0, 0)}},
+	}
+
+	window.AddToBusy(Timespan{Start: timeDate(2021, 1, 4, 9, 20, 0), End: timeDate(2021, 1, 4, 16, 0, 0)})
+	window.ComputeFree(constraint, window.Start, Timespan{Start: window.Start, End: window.End})
+
+	slot := window.FindTimeSlot(&RuleDuration{Minimum: time.Minute * 30, Maximum: time.Hour * 6})
+	if slot == nil || !slot.Start.Equal(timeDate(2021, 1, 4, 16, 0, 0)) {
+		t.Fatalf("got time slot %v, want 16:00 - 17:00", slot)
+	}
+
+	wantRejections := []TraceRejection{
+		{Timespan: Timespan{Start: timeDate(2021, 1, 4, 8, 0, 0), End: timeDate(2021, 1, 4, 9, 0, 0)}, Reason: TraceRejectionNotAllowed},
+		{Timespan: Timespan{Start: timeDate(2021, 1, 4, 17, 0, 0), End: timeDate(2021, 1, 4, 18, 0, 0)}, Reason: TraceRejectionNotAllowed},
+		{Timespan: Timespan{Start: timeDate(2021, 1, 4, 9, 0, 0), End: timeDate(2021, 1, 4, 9, 20, 0)}, Reason: TraceRejectionTooShort},
+	}
+
+	if len(window.Trace.Rejections) != len(wantRejections) {
+		t.Fatalf("got rejections %+v, want %+v", window.Trace.Rejections, wantRejections)
+	}
+
+	for i, rejection := range window.Trace.Rejections {
+		want := wantRejections[i]
+		if rejection.Reason != want.Reason || !rejection.Timespan.Start.Equal(want.Timespan.Start) || !rejection.Timespan.End.Equal(want.Timespan.End) {
+			t.Errorf("got rejection %+v, want %+v", rejection, want)
+		}
+	}
+
+	if len(window.Trace.Candidates) != 2 || len(window.Trace.Slots) != 1 || len(window.Trace.Explored) != 1 {
+		t.Errorf("got %d candidates, %d slots and %d explored timespans, want 2, 1 and 1", len(window.Trace.Candidates), len(window.Trace.Slots), len(window.Trace.Explored))
+	}
+}
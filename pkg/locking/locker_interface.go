@@ -2,9 +2,13 @@ package locking
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrNotObtained is returned when a lock is held by someone else and couldn't be acquired in time
+var ErrNotObtained = errors.New("lock not obtained")
+
 // LockerInterface represents a Locker
 type LockerInterface interface {
 	Acquire(ctx context.Context, key string, ttl time.Duration, tryOnlyOnce bool, waitMax time.Duration) (LockInterface, error)
@@ -32,6 +32,9 @@ func (l *LockerRedis) Acquire(ctx context.Context, key string, ttl time.Duration
 	obtain, err := l.locker.Obtain(ctx, key, ttl, &redislock.Options{
 		RetryStrategy: retryStrategy,
 	})
+	if err == redislock.ErrNotObtained {
+		return nil, ErrNotObtained
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,222 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/timeliness-app/timeliness-backend/pkg/auth"
+	"github.com/timeliness-app/timeliness-backend/pkg/communication"
+	"github.com/timeliness-app/timeliness-backend/pkg/locking"
+	"github.com/timeliness-app/timeliness-backend/pkg/logger"
+	"github.com/timeliness-app/timeliness-backend/pkg/users"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"time"
+)
+
+// AbsenceHandler handles all absence related API calls
+type AbsenceHandler struct {
+	UserRepository  users.UserRepositoryInterface
+	Logger          logger.Interface
+	ResponseManager *communication.ResponseManager
+	Locker          locking.LockerInterface
+	PlanningService *PlanningService
+}
+
+// GetAbsences is the route for getting all absences of a user
+func (handler *AbsenceHandler) GetAbsences(writer http.ResponseWriter, request *http.Request) {
+	userID := request.Context().Value(auth.KeyUserID).(string)
+
+	user, err := handler.UserRepository.FindByID(request.Context(), userID)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusNotFound, fmt.Sprintf("Could not find user %s", userID), err, request, nil)
+		return
+	}
+
+	absences := user.Absences
+	if absences == nil {
+		absences = users.Absences{}
+	}
+
+	handler.ResponseManager.Respond(writer, absences)
+}
+
+// AbsenceAdd is the route for adding an absence, it moves all work units out of the absence, pinned ones included
+func (handler *AbsenceHandler) AbsenceAdd(writer http.ResponseWriter, request *http.Request) {
+	userID := request.Context().Value(auth.KeyUserID).(string)
+	absence := users.Absence{}
+
+	err := json.NewDecoder(request.Body).Decode(&absence)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Wrong format", err, request, absence)
+		return
+	}
+
+	err = validateAbsence(&absence)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, err.Error(), err, request, absence)
+		return
+	}
+
+	lock, err := handler.Locker.Acquire(request.Context(), fmt.Sprintf("user-%s", userID), time.Minute*3, false, time.Minute*5)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, fmt.Sprintf("Error acquiring lock for user %s", userID), err, request, absence)
+		return
+	}
+
+	defer func() {
+		if err := lock.Release(request.Context()); err != nil {
+			handler.Logger.Error(fmt.Sprintf("error while releasing lock for user %s", userID), err)
+		}
+	}()
+
+	user, err := handler.UserRepository.FindByID(request.Context(), userID)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusNotFound, fmt.Sprintf("Could not find user %s", userID), err, request, absence)
+		return
+	}
+
+	absence.ID = primitive.NewObjectID()
+	absence.CreatedAt = time.Now()
+	absence.LastModifiedAt = time.Now()
+
+	user.Absences = append(user.Absences, absence)
+
+	err = handler.UserRepository.UpdateAbsences(request.Context(), user)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, "Persisting absence in database did not work", err, request, absence)
+		return
+	}
+
+	err = handler.PlanningService.ApplyAbsence(request.Context(), user, &absence)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, "Could not move work units out of the absence", err, request, absence)
+		return
+	}
+
+	handler.ResponseManager.Respond(writer, absence)
+}
+
+// AbsenceUpdate is the route for updating an absence, it moves all work units out of the updated absence, pinned ones
+// included
+func (handler *AbsenceHandler) AbsenceUpdate(writer http.ResponseWriter, request *http.Request) {
+	userID := request.Context().Value(auth.KeyUserID).(string)
+	absenceID := mux.Vars(request)["absenceID"]
+
+	lock, err := handler.Locker.Acquire(request.Context(), fmt.Sprintf("user-%s", userID), time.Minute*3, false, time.Minute*5)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, fmt.Sprintf("Error acquiring lock for user %s", userID), err, request, nil)
+		return
+	}
+
+	defer func() {
+		if err := lock.Release(request.Context()); err != nil {
+			handler.Logger.Error(fmt.Sprintf("error while releasing lock for user %s", userID), err)
+		}
+	}()
+
+	user, err := handler.UserRepository.FindByID(request.Context(), userID)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusNotFound, fmt.Sprintf("Could not find user %s", userID), err, request, nil)
+		return
+	}
+
+	absence, index, err := user.Absences.FindByID(absenceID)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusNotFound, "Couldn't find absence", err, request, nil)
+		return
+	}
+
+	err = json.NewDecoder(request.Body).Decode(absence)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Wrong format", err, request, absence)
+		return
+	}
+
+	err = validateAbsence(absence)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, err.Error(), err, request, absence)
+		return
+	}
+
+	absence.ID = user.Absences[index].ID
+	absence.CreatedAt = user.Absences[index].CreatedAt
+	absence.LastModifiedAt = time.Now()
+
+	user.Absences[index] = *absence
+
+	err = handler.UserRepository.UpdateAbsences(request.Context(), user)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, "Couldn't update absence", err, request, absence)
+		return
+	}
+
+	err = handler.PlanningService.ApplyAbsence(request.Context(), user, absence)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, "Could not move work units out of the absence", err, request, absence)
+		return
+	}
+
+	handler.ResponseManager.Respond(writer, absence)
+}
+
+// AbsenceDelete deletes an absence, work units are not moved back into it
+func (handler *AbsenceHandler) AbsenceDelete(writer http.ResponseWriter, request *http.Request) {
+	userID := request.Context().Value(auth.KeyUserID).(string)
+	absenceID := mux.Vars(request)["absenceID"]
+
+	lock, err := handler.Locker.Acquire(request.Context(), fmt.Sprintf("user-%s", userID), time.Minute*3, false, time.Minute*5)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, fmt.Sprintf("Error acquiring lock for user %s", userID), err, request, nil)
+		return
+	}
+
+	defer func() {
+		if err := lock.Release(request.Context()); err != nil {
+			handler.Logger.Error(fmt.Sprintf("error while releasing lock for user %s", userID), err)
+		}
+	}()
+
+	user, err := handler.UserRepository.FindByID(request.Context(), userID)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusNotFound, fmt.Sprintf("Could not find user %s", userID), err, request, nil)
+		return
+	}
+
+	_, _, err = user.Absences.FindByID(absenceID)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusNotFound, "Couldn't find absence", err, request, nil)
+		return
+	}
+
+	user.Absences = user.Absences.RemoveByID(absenceID)
+
+	err = handler.UserRepository.UpdateAbsences(request.Context(), user)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, "Could not delete absence", err, request, nil)
+		return
+	}
+
+	err = handler.PlanningService.FlagTasksDueDuringAbsences(request.Context(), user)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, "Could not update the tasks due during the absence", err, request, nil)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func validateAbsence(absence *users.Absence) error {
+	v := validator.New()
+	err := v.Struct(absence)
+	if err != nil {
+		return err
+	}
+
+	if !absence.Date.Start.Before(absence.Date.End) {
+		return fmt.Errorf("the start of the absence has to be before its end")
+	}
+
+	return nil
+}
@@ -0,0 +1,922 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/timeliness-app/timeliness-backend/internal/ical"
+	"github.com/timeliness-app/timeliness-backend/pkg/auth/encryption"
+	"github.com/timeliness-app/timeliness-backend/pkg/communication"
+	"github.com/timeliness-app/timeliness-backend/pkg/date"
+	"github.com/timeliness-app/timeliness-backend/pkg/logger"
+	"github.com/timeliness-app/timeliness-backend/pkg/users"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ICalProductID is the PRODID of all iCalendar objects created by Timeliness
+const ICalProductID = "-//Timeliness//Timeliness Backend//EN"
+
+// caldavTaskIDProperty marks events that were created by Timeliness and holds the id of the task
+const caldavTaskIDProperty = "X-TIMELINESS-TASK-ID"
+
+// caldavMultigetBatchSize limits how many events are requested in a single calendar-multiget report
+const caldavMultigetBatchSize = 50
+
+// caldavMaxRedirects limits how many redirects are followed for a single request
+const caldavMaxRedirects = 5
+
+// caldavMaxResponseSize is the maximum size of a response in bytes, larger responses are rejected
+const caldavMaxResponseSize = 10 * 1024 * 1024
+
+// CalDAVCalendarRepository provides functions for editing calendars on a CalDAV server like Nextcloud, Fastmail or iCloud
+type CalDAVCalendarRepository struct {
+	Logger                   logger.Interface
+	Client                   *http.Client
+	ctx                      context.Context
+	baseURL                  *url.URL
+	username                 string
+	password                 string
+	connection               *users.CalendarConnection
+	userID                   primitive.ObjectID
+	updateConnectionFunction UpdateConnection
+}
+
+// CalDAVError is an unexpected status of a CalDAV server
+type CalDAVError struct {
+	StatusCode int
+	Method     string
+	URL        string
+}
+
+func (e *CalDAVError) Error() string {
+	return fmt.Sprintf("caldav: %s %s: status %d", e.Method, e.URL, e.StatusCode)
+}
+
+type davMultistatus struct {
+	XMLName   xml.Name      `xml:"DAV: multistatus"`
+	Responses []davResponse `xml:"DAV: response"`
+	SyncToken string        `xml:"DAV: sync-token"`
+}
+
+type davResponse struct {
+	Href     string        `xml:"DAV: href"`
+	Status   string        `xml:"DAV: status"`
+	Propstat []davPropstat `xml:"DAV: propstat"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"DAV: prop"`
+	Status string  `xml:"DAV: status"`
+}
+
+type davHref struct {
+	Href string `xml:"DAV: href"`
+}
+
+type davResourceType struct {
+	Collection *struct{} `xml:"DAV: collection"`
+	Calendar   *struct{} `xml:"urn:ietf:params:xml:ns:caldav calendar"`
+}
+
+type davComponent struct {
+	Name string `xml:"name,attr"`
+}
+
+type davComponentSet struct {
+	Components []davComponent `xml:"urn:ietf:params:xml:ns:caldav comp"`
+}
+
+type davProp struct {
+	CurrentUserPrincipal *davHref         `xml:"DAV: current-user-principal"`
+	CalendarHomeSet      *davHref         `xml:"urn:ietf:params:xml:ns:caldav calendar-home-set"`
+	DisplayName          string           `xml:"DAV: displayname"`
+	ResourceType         *davResourceType `xml:"DAV: resourcetype"`
+	ComponentSet         *davComponentSet `xml:"urn:ietf:params:xml:ns:caldav supported-calendar-component-set"`
+	ETag                 string           `xml:"DAV: getetag"`
+	CalendarData         string           `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+}
+
+// okProp returns the properties the server found for a response
+func (r *davResponse) okProp() *davProp {
+	for _, propstat := range r.Propstat {
+		if davStatusCode(propstat.Status) == http.StatusOK {
+			return &propstat.Prop
+		}
+	}
+
+	return nil
+}
+
+// davStatusCode reads the code of a status line like "HTTP/1.1 404 Not Found"
+func davStatusCode(status string) int {
+	fields := strings.Fields(status)
+	if len(fields) < 2 {
+		return 0
+	}
+
+	code, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0
+	}
+
+	return code
+}
+
+const caldavPrincipalRequest = `<?xml version="1.0" encoding="utf-8"?>
+<D:propfind xmlns:D="DAV:">
+  <D:prop><D:current-user-principal/></D:prop>
+</D:propfind>`
+
+const caldavHomeSetRequest = `<?xml version="1.0" encoding="utf-8"?>
+<D:propfind xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><C:calendar-home-set/></D:prop>
+</D:propfind>`
+
+const caldavCalendarsRequest = `<?xml version="1.0" encoding="utf-8"?>
+<D:propfind xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><D:resourcetype/><D:displayname/><C:supported-calendar-component-set/></D:prop>
+</D:propfind>`
+
+const caldavMakeCalendarRequest = `<?xml version="1.0" encoding="utf-8"?>
+<C:mkcalendar xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:set>
+    <D:prop>
+      <D:displayname>Timeliness Tasks</D:displayname>
+      <C:supported-calendar-component-set><C:comp name="VEVENT"/></C:supported-calendar-component-set>
+    </D:prop>
+  </D:set>
+</C:mkcalendar>`
+
+const caldavCalendarQueryRequest = `<?xml version="1.0" encoding="utf-8"?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop>
+    <C:calendar-data><C:expand start="%[1]s" end="%[2]s"/></C:calendar-data>
+  </D:prop>
+  <C:filter>
+    <C:comp-filter name="VCALENDAR">
+      <C:comp-filter name="VEVENT"><C:time-range start="%[1]s" end="%[2]s"/></C:comp-filter>
+    </C:comp-filter>
+  </C:filter>
+</C:calendar-query>`
+
+const caldavSyncCollectionRequest = `<?xml version="1.0" encoding="utf-8"?>
+<D:sync-collection xmlns:D="DAV:">
+  <D:sync-token>%s</D:sync-token>
+  <D:sync-level>1</D:sync-level>
+  <D:prop><D:getetag/></D:prop>
+</D:sync-collection>`
+
+const caldavMultigetRequest = `<?xml version="1.0" encoding="utf-8"?>
+<C:calendar-multiget xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><D:getetag/><C:calendar-data/></D:prop>
+  %s
+</C:calendar-multiget>`
+
+// NewCalDAVCalendarRepository constructs a CalDAVCalendarRepository, only use CalendarRepositoryManager for this
+func NewCalDAVCalendarRepository(ctx context.Context, userID primitive.ObjectID, connection *users.CalendarConnection, logger logger.Interface, updateConnectionFunction UpdateConnection) (*CalDAVCalendarRepository, error) {
+	if connection.ServerURL == "" || connection.Password == "" {
+		return nil, communication.ErrCalendarAuthInvalid
+	}
+
+	newRepo, err := newCalDAVClient(ctx, connection.ServerURL, connection.Username, encryption.Decrypt(connection.Password), logger)
+	if err != nil {
+		// The server url can't be used anymore, e.g. because it was connected before https was required
+		return nil, errors.Wrap(communication.ErrCalendarAuthInvalid, err.Error())
+	}
+
+	newRepo.connection = connection
+	newRepo.userID = userID
+	newRepo.updateConnectionFunction = updateConnectionFunction
+
+	return newRepo, nil
+}
+
+// newCalDAVClient creates a repository that is only able to send requests, it is used for discovery
+func newCalDAVClient(ctx context.Context, serverURL string, username string, password string, logger logger.Interface) (*CalDAVCalendarRepository, error) {
+	baseURL, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// The credentials are sent with every request, so they must never travel unencrypted
+	if baseURL.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported url scheme %s, caldav servers have to use https", baseURL.Scheme)
+	}
+
+	if baseURL.Host == "" {
+		return nil, errors.New("url has no host")
+	}
+
+	return &CalDAVCalendarRepository{
+		Logger: logger,
+		Client: &http.Client{
+			Transport: publicTransport,
+			Timeout:   time.Second * 30,
+			// Redirects are followed by do, because the default client turns every method into a GET
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		ctx:      ctx,
+		baseURL:  baseURL,
+		username: username,
+		password: password,
+	}, nil
+}
+
+// DiscoverCalDAV finds the calendar home set of a user on a CalDAV server, the server url can either be the
+// root of the server, a principal url or the calendar home set itself
+func DiscoverCalDAV(ctx context.Context, serverURL string, username string, password string, logger logger.Interface) (string, error) {
+	c, err := newCalDAVClient(ctx, serverURL, username, password, logger)
+	if err != nil {
+		return "", err
+	}
+
+	candidates := []string{c.baseURL.String()}
+	if c.baseURL.Path == "" || c.baseURL.Path == "/" {
+		candidates = append([]string{c.resolve("/.well-known/caldav")}, candidates...)
+	}
+
+	principal := ""
+	for _, candidate := range candidates {
+		var response davMultistatus
+
+		_, err = c.do("PROPFIND", candidate, "0", caldavPrincipalRequest, &response)
+		if err != nil {
+			if checkForCalDAVStatus(err, http.StatusUnauthorized) {
+				return "", errors.WithStack(communication.ErrCalendarAuthInvalid)
+			}
+
+			continue
+		}
+
+		for _, r := range response.Responses {
+			prop := r.okProp()
+			if prop != nil && prop.CurrentUserPrincipal != nil && prop.CurrentUserPrincipal.Href != "" {
+				principal = prop.CurrentUserPrincipal.Href
+				break
+			}
+		}
+
+		if principal != "" {
+			break
+		}
+	}
+
+	if principal == "" {
+		return "", errors.New("could not find a caldav principal on the server")
+	}
+
+	var response davMultistatus
+
+	_, err = c.do("PROPFIND", c.resolve(principal), "0", caldavHomeSetRequest, &response)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	for _, r := range response.Responses {
+		prop := r.okProp()
+		if prop != nil && prop.CalendarHomeSet != nil && prop.CalendarHomeSet.Href != "" {
+			return c.resolve(prop.CalendarHomeSet.Href), nil
+		}
+	}
+
+	return "", errors.New("could not find a caldav calendar home set on the server")
+}
+
+// resolve resolves an href of the server to an absolute url
+func (c *CalDAVCalendarRepository) resolve(href string) string {
+	reference, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+
+	return c.baseURL.ResolveReference(reference).String()
+}
+
+// isOnServer tells if a url points to the server of the repository, credentials are never sent anywhere else
+func (c *CalDAVCalendarRepository) isOnServer(requestURL *url.URL) bool {
+	return requestURL.Scheme == c.baseURL.Scheme && strings.EqualFold(requestURL.Host, c.baseURL.Host)
+}
+
+// hrefPath returns the path of an href, which is used as id of calendars and events
+func (c *CalDAVCalendarRepository) hrefPath(href string) string {
+	reference, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+
+	return reference.Path
+}
+
+// do sends a request to the CalDAV server and decodes a multistatus response into result, redirects are followed
+// with the same method as long as they stay on the server. Additional headers are passed as pairs of name and value.
+func (c *CalDAVCalendarRepository) do(method string, requestURL string, depth string, body string, result interface{}, headers ...string) (http.Header, error) {
+	for i := 0; i <= caldavMaxRedirects; i++ {
+		parsedURL, err := url.Parse(requestURL)
+		if err != nil {
+			return nil, err
+		}
+
+		if !c.isOnServer(parsedURL) {
+			return nil, fmt.Errorf("caldav: %s is not on the server %s", requestURL, c.baseURL.Host)
+		}
+
+		var requestBody io.Reader
+		if body != "" {
+			requestBody = strings.NewReader(body)
+		}
+
+		req, err := http.NewRequestWithContext(c.ctx, method, requestURL, requestBody)
+		if err != nil {
+			return nil, err
+		}
+
+		req.SetBasicAuth(c.username, c.password)
+		if depth != "" {
+			req.Header.Set("Depth", depth)
+		}
+		if body != "" {
+			req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+		}
+		for j := 0; j+1 < len(headers); j += 2 {
+			req.Header.Set(headers[j], headers[j+1])
+		}
+
+		resp, err := c.Client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		switch resp.StatusCode {
+		case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+			location, err := resp.Location()
+			_ = resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+
+			requestURL = location.String()
+			continue
+		}
+
+		binary, err := ioutil.ReadAll(io.LimitReader(resp.Body, caldavMaxResponseSize+1))
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(binary) > caldavMaxResponseSize {
+			return nil, fmt.Errorf("caldav: response of %s %s is larger than %d bytes", method, requestURL, caldavMaxResponseSize)
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return resp.Header, &CalDAVError{StatusCode: resp.StatusCode, Method: method, URL: requestURL}
+		}
+
+		if result != nil && len(binary) > 0 {
+			err = xml.Unmarshal(binary, result)
+			if err != nil {
+				return nil, errors.Wrap(err, "could not decode caldav response")
+			}
+		}
+
+		return resp.Header, nil
+	}
+
+	return nil, fmt.Errorf("caldav: too many redirects for %s", requestURL)
+}
+
+func checkForCalDAVStatus(err error, statusCodes ...int) bool {
+	caldavError, ok := errors.Cause(err).(*CalDAVError)
+	if !ok {
+		return false
+	}
+
+	for _, statusCode := range statusCodes {
+		if caldavError.StatusCode == statusCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *CalDAVCalendarRepository) checkForInvalidCredentialsError(err error) error {
+	if err == nil {
+		return err
+	}
+
+	c.Logger.Debug(err.Error())
+
+	if checkForCalDAVStatus(err, http.StatusUnauthorized) {
+		if c.updateConnectionFunction != nil {
+			c.connection.Status = users.CalendarConnectionStatusExpired
+			c.updateConnectionFunction(c.connection)
+		}
+
+		return errors.WithStack(communication.ErrCalendarAuthInvalid)
+	}
+
+	return errors.WithStack(err)
+}
+
+// updateUserConnection writes the connection of the repository back into the user
+func (c *CalDAVCalendarRepository) updateUserConnection(user *users.User) {
+	for i, connection := range user.CalDAVCalendarConnections {
+		if connection.ID == c.connection.ID {
+			user.CalDAVCalendarConnections[i] = *c.connection
+		}
+	}
+}
+
+func (c *CalDAVCalendarRepository) createCalendar() (string, error) {
+	calendarPath := path.Join(c.baseURL.Path, fmt.Sprintf("timeliness-%s", primitive.NewObjectID().Hex())) + "/"
+
+	_, err := c.do("MKCALENDAR", c.resolve(calendarPath), "", caldavMakeCalendarRequest, nil)
+	if err != nil {
+		return "", c.checkForInvalidCredentialsError(err)
+	}
+
+	return calendarPath, nil
+}
+
+// TestTaskCalendarExistence checks if the task calendar still exists and creates a new one if it doesn't
+func (c *CalDAVCalendarRepository) TestTaskCalendarExistence(u *users.User) (*users.User, error) {
+	if !c.connection.IsTaskCalendarConnection {
+		return u, nil
+	}
+
+	createCalendar := false
+
+	if c.connection.TaskCalendarID == "" {
+		createCalendar = true
+	} else {
+		_, err := c.do("PROPFIND", c.resolve(c.connection.TaskCalendarID), "0", caldavCalendarsRequest, nil)
+		if err != nil {
+			if errors.Cause(c.checkForInvalidCredentialsError(err)) == communication.ErrCalendarAuthInvalid {
+				return nil, communication.ErrCalendarAuthInvalid
+			}
+
+			createCalendar = true
+		}
+	}
+
+	if createCalendar {
+		calendarID, err := c.createCalendar()
+		if err != nil {
+			return nil, err
+		}
+
+		if c.connection.TaskCalendarID != "" {
+			c.connection.CalendarsOfInterest = c.connection.CalendarsOfInterest.RemoveCalendar(c.connection.TaskCalendarID)
+		}
+
+		c.connection.TaskCalendarID = calendarID
+
+		c.connection.CalendarsOfInterest = append(c.connection.CalendarsOfInterest,
+			users.CalendarSync{CalendarID: calendarID})
+
+		c.updateUserConnection(u)
+	} else if !c.connection.CalendarsOfInterest.HasCalendarWithID(c.connection.TaskCalendarID) {
+		c.connection.CalendarsOfInterest = append(c.connection.CalendarsOfInterest,
+			users.CalendarSync{CalendarID: c.connection.TaskCalendarID})
+
+		c.updateUserConnection(u)
+	}
+
+	return u, nil
+}
+
+// GetAllCalendarsOfInterest retrieves all event calendars from the calendar home set
+func (c *CalDAVCalendarRepository) GetAllCalendarsOfInterest() (map[string]*Calendar, error) {
+	var calendars = make(map[string]*Calendar)
+	var response davMultistatus
+
+	_, err := c.do("PROPFIND", c.baseURL.String(), "1", caldavCalendarsRequest, &response)
+	if err != nil {
+		return calendars, c.checkForInvalidCredentialsError(err)
+	}
+
+	for _, r := range response.Responses {
+		prop := r.okProp()
+		if prop == nil || prop.ResourceType == nil || prop.ResourceType.Calendar == nil {
+			continue
+		}
+
+		if !supportsEvents(prop.ComponentSet) {
+			continue
+		}
+
+		calendarID := c.hrefPath(r.Href)
+		if calendarID == c.connection.TaskCalendarID {
+			continue
+		}
+
+		name := prop.DisplayName
+		if name == "" {
+			name = path.Base(calendarID)
+		}
+
+		calendars[calendarID] = &Calendar{CalendarID: calendarID, Name: name}
+	}
+
+	return calendars, nil
+}
+
+// supportsEvents returns true if a calendar can hold events, calendars without a component set support everything
+func supportsEvents(componentSet *davComponentSet) bool {
+	if componentSet == nil || len(componentSet.Components) == 0 {
+		return true
+	}
+
+	for _, component := range componentSet.Components {
+		if strings.EqualFold(component.Name, "VEVENT") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewEvent creates a new event in the task calendar
+func (c *CalDAVCalendarRepository) NewEvent(event *Event, taskID string, title string, description string, withReminder bool) (*Event, error) {
+	uid := primitive.NewObjectID().Hex()
+	eventPath := path.Join(c.connection.TaskCalendarID, uid+".ics")
+
+	body, err := eventToICal(event, uid, taskID, title, description, withReminder)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.putEvent(eventPath, body, "If-None-Match", "*")
+	if err != nil {
+		return nil, c.checkForInvalidCredentialsError(err)
+	}
+
+	calEvent := PersistedEvent{
+		CalendarEventID: eventPath,
+		CalendarType:    PersistedCalendarTypeCalDAVCalendar,
+		UserID:          c.userID,
+	}
+
+	event.CalendarEvents = append(event.CalendarEvents, calEvent)
+
+	return event, nil
+}
+
+func (c *CalDAVCalendarRepository) putEvent(eventPath string, body string, headers ...string) error {
+	headers = append(headers, "Content-Type", ical.ContentType)
+
+	_, err := c.do(http.MethodPut, c.resolve(eventPath), "", body, nil, headers...)
+
+	return err
+}
+
+// UpdateEvent replaces an existing event
+func (c *CalDAVCalendarRepository) UpdateEvent(event *Event, taskID string, title string, description string, withReminder bool) error {
+	calendarEvent := event.CalendarEvents.FindByUserID(c.userID.Hex())
+	if calendarEvent == nil {
+		return errors.Errorf("no calendar event found for user %s", c.userID.Hex())
+	}
+
+	uid := strings.TrimSuffix(path.Base(calendarEvent.CalendarEventID), ".ics")
+
+	body, err := eventToICal(event, uid, taskID, title, description, withReminder)
+	if err != nil {
+		return err
+	}
+
+	err = c.putEvent(calendarEvent.CalendarEventID, body)
+	if err != nil {
+		return c.checkForInvalidCredentialsError(err)
+	}
+
+	return nil
+}
+
+// DeleteEvent deletes a single Event
+func (c *CalDAVCalendarRepository) DeleteEvent(event *Event) error {
+	calendarEvent := event.CalendarEvents.FindByUserID(c.userID.Hex())
+	if calendarEvent == nil {
+		return fmt.Errorf("persisted calendar event for user %s could not be found while deleting event", c.userID.Hex())
+	}
+
+	_, err := c.do(http.MethodDelete, c.resolve(calendarEvent.CalendarEventID), "", "", nil)
+	if err != nil {
+		if checkForCalDAVStatus(err, http.StatusNotFound, http.StatusGone) {
+			return nil
+		}
+
+		return c.checkForInvalidCredentialsError(err)
+	}
+
+	return nil
+}
+
+// AddBusyToWindow reads times from a window and fills it with busy timeslots, it takes all set availability calendars apart from the task calendar into account.
+// Recurring events are expanded by the server.
+func (c *CalDAVCalendarRepository) AddBusyToWindow(window *date.TimeWindow, start time.Time, end time.Time) error {
+	calList := c.connection.CalendarsOfInterest
+
+	if c.connection.IsTaskCalendarConnection {
+		calList = calList.RemoveCalendar(c.connection.TaskCalendarID)
+	}
+
+	body := fmt.Sprintf(caldavCalendarQueryRequest, start.UTC().Format(ical.DateTimeLayout), end.UTC().Format(ical.DateTimeLayout))
+
+	for _, cal := range calList {
+		var response davMultistatus
+
+		_, err := c.do("REPORT", c.resolve(cal.CalendarID), "1", body, &response)
+		if err != nil {
+			return c.checkForInvalidCredentialsError(err)
+		}
+
+		for _, r := range response.Responses {
+			prop := r.okProp()
+			if prop == nil || prop.CalendarData == "" {
+				continue
+			}
+
+			object, err := ical.Parse(strings.NewReader(prop.CalendarData))
+			if err != nil {
+				c.Logger.Warning(fmt.Sprintf("Could not parse caldav event %s", r.Href), err)
+				continue
+			}
+
+			for _, vevent := range object.Events() {
+				if !ical.IsEventBlocking(vevent) {
+					continue
+				}
+
+				eventStart, eventEnd, err := ical.EventTimespan(vevent, time.UTC)
+				if err != nil {
+					return err
+				}
+
+				window.AddToBusy(date.Timespan{Start: eventStart.UTC(), End: eventEnd.UTC()})
+			}
+		}
+	}
+
+	return nil
+}
+
+// WatchCalendar marks a calendar for polling, CalDAV has no push notifications, so calendars are synced whenever
+// the sync renewal runs
+func (c *CalDAVCalendarRepository) WatchCalendar(calendarID string, user *users.User) (*users.User, error) {
+	index := findSyncByID(c.connection, calendarID)
+	if index == -1 {
+		return nil, errors.New("calendar id could not be found in calendars of interest")
+	}
+
+	if c.connection.CalendarsOfInterest[index].IsNotSyncable || !c.connection.CalendarsOfInterest[index].Expiration.IsZero() {
+		return user, nil
+	}
+
+	c.connection.CalendarsOfInterest[index].Expiration = time.Now().UTC()
+	c.updateUserConnection(user)
+
+	return user, nil
+}
+
+// StopWatchingCalendar stops polling a calendar
+func (c *CalDAVCalendarRepository) StopWatchingCalendar(calendarID string, user *users.User) (*users.User, error) {
+	index := findSyncByID(c.connection, calendarID)
+	if index == -1 {
+		return nil, errors.New("calendar id could not be found in calendars of interest")
+	}
+
+	c.connection.CalendarsOfInterest[index].SyncToken = ""
+	c.connection.CalendarsOfInterest[index].Expiration = time.Time{}
+
+	c.updateUserConnection(user)
+
+	return user, nil
+}
+
+// SyncEvents syncs the calendar events of a single calendar with a sync-collection report
+func (c *CalDAVCalendarRepository) SyncEvents(calendarID string, user *users.User,
+	eventChannel *chan *Event,
+	errorChannel *chan error,
+	userChannel *chan *users.User) {
+
+	defer close(*eventChannel)
+	defer close(*errorChannel)
+	defer close(*userChannel)
+
+	now := time.Now()
+	syncIndex := findSyncByID(c.connection, calendarID)
+	if syncIndex == -1 {
+		*errorChannel <- errors.New("calendar id could not be found in calendars of interest")
+		return
+	}
+
+	syncToken := c.connection.CalendarsOfInterest[syncIndex].SyncToken
+	initialSync := syncToken == ""
+
+	for {
+		var response davMultistatus
+
+		var buffer bytes.Buffer
+		_ = xml.EscapeText(&buffer, []byte(syncToken))
+
+		_, err := c.do("REPORT", c.resolve(calendarID), "0", fmt.Sprintf(caldavSyncCollectionRequest, buffer.String()), &response)
+		if err != nil {
+			// Servers answer with one of these if the sync token isn't valid anymore
+			if !initialSync && checkForCalDAVStatus(err, http.StatusForbidden, http.StatusConflict, http.StatusGone) {
+				c.connection.CalendarsOfInterest[syncIndex].SyncToken = ""
+				c.updateUserConnection(user)
+
+				*userChannel <- user
+				return
+			}
+
+			*errorChannel <- c.checkForInvalidCredentialsError(err)
+			return
+		}
+
+		var changed []string
+		truncated := false
+
+		for _, r := range response.Responses {
+			href := c.hrefPath(r.Href)
+			if strings.TrimSuffix(href, "/") == strings.TrimSuffix(calendarID, "/") {
+				truncated = truncated || davStatusCode(r.Status) == http.StatusInsufficientStorage
+				continue
+			}
+
+			if davStatusCode(r.Status) == http.StatusNotFound {
+				*eventChannel <- &Event{
+					CalendarEvents: []PersistedEvent{
+						{
+							CalendarEventID: href,
+							CalendarType:    PersistedCalendarTypeCalDAVCalendar,
+							UserID:          c.userID,
+						},
+					},
+					Deleted: true,
+				}
+				continue
+			}
+
+			changed = append(changed, href)
+		}
+
+		events, err := c.getEvents(calendarID, changed)
+		if err != nil {
+			*errorChannel <- err
+			return
+		}
+
+		for _, event := range events {
+			// The initial sync contains the whole history of the calendar, only the future is relevant
+			if initialSync && event.Date.End.Before(now) {
+				continue
+			}
+
+			*eventChannel <- event
+		}
+
+		if response.SyncToken == "" {
+			*errorChannel <- errors.New("sync token is missing in caldav response")
+			return
+		}
+
+		syncToken = response.SyncToken
+		if !truncated {
+			break
+		}
+	}
+
+	c.connection.CalendarsOfInterest[syncIndex].SyncToken = syncToken
+	c.updateUserConnection(user)
+
+	*userChannel <- user
+}
+
+// getEvents loads events with a calendar-multiget report
+func (c *CalDAVCalendarRepository) getEvents(calendarID string, hrefs []string) ([]*Event, error) {
+	var events []*Event
+
+	for start := 0; start < len(hrefs); start += caldavMultigetBatchSize {
+		end := start + caldavMultigetBatchSize
+		if end > len(hrefs) {
+			end = len(hrefs)
+		}
+
+		var hrefElements strings.Builder
+		for _, href := range hrefs[start:end] {
+			hrefElements.WriteString("<D:href>")
+			_ = xml.EscapeText(&hrefElements, []byte(href))
+			hrefElements.WriteString("</D:href>")
+		}
+
+		var response davMultistatus
+
+		_, err := c.do("REPORT", c.resolve(calendarID), "1", fmt.Sprintf(caldavMultigetRequest, hrefElements.String()), &response)
+		if err != nil {
+			return nil, c.checkForInvalidCredentialsError(err)
+		}
+
+		for _, r := range response.Responses {
+			prop := r.okProp()
+			if prop == nil || prop.CalendarData == "" {
+				continue
+			}
+
+			event, err := c.icalToEvent(c.hrefPath(r.Href), prop.CalendarData)
+			if err != nil {
+				c.Logger.Warning(fmt.Sprintf("Could not parse caldav event %s", r.Href), err)
+				continue
+			}
+
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func (c *CalDAVCalendarRepository) icalToEvent(href string, calendarData string) (*Event, error) {
+	object, err := ical.Parse(strings.NewReader(calendarData))
+	if err != nil {
+		return nil, err
+	}
+
+	// Overridden occurrences of recurring events follow the master event, which is the one that matters here
+	vevents := object.Events()
+	if len(vevents) == 0 {
+		return nil, errors.New("calendar object contains no event")
+	}
+	vevent := vevents[0]
+
+	newEvent := &Event{
+		Blocking:   ical.IsEventBlocking(vevent),
+		IsOriginal: vevent.Get(caldavTaskIDProperty) != nil,
+		Deleted:    vevent.Value("STATUS") == "CANCELLED",
+		CalendarEvents: []PersistedEvent{
+			{
+				CalendarEventID: href,
+				CalendarType:    PersistedCalendarTypeCalDAVCalendar,
+				UserID:          c.userID,
+			},
+		},
+	}
+
+	start, end, err := ical.EventTimespan(vevent, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+
+	newEvent.Date = date.Timespan{Start: start.UTC(), End: end.UTC()}
+
+	return newEvent, nil
+}
+
+func eventToICal(event *Event, uid string, taskID string, title string, description string, withReminder bool) (string, error) {
+	vevent := &ical.Component{Name: "VEVENT"}
+	vevent.Set("UID", uid)
+	vevent.SetDateTime("DTSTAMP", time.Now())
+	vevent.SetDateTime("DTSTART", event.Date.Start)
+	vevent.SetDateTime("DTEND", event.Date.End)
+	vevent.SetText("SUMMARY", title)
+	vevent.SetText("DESCRIPTION", description)
+	vevent.Set(caldavTaskIDProperty, taskID)
+
+	if event.Blocking {
+		vevent.Set("TRANSP", "OPAQUE")
+	} else {
+		vevent.Set("TRANSP", "TRANSPARENT")
+	}
+
+	if withReminder {
+		alarm := &ical.Component{Name: "VALARM"}
+		alarm.Set("ACTION", "DISPLAY")
+		alarm.Set("TRIGGER", "PT0M")
+		alarm.SetText("DESCRIPTION", title)
+		vevent.Add(alarm)
+	}
+
+	cal := ical.NewCalendar(ICalProductID)
+	cal.Add(vevent)
+
+	var buffer bytes.Buffer
+	err := cal.Encode(&buffer)
+	if err != nil {
+		return "", err
+	}
+
+	return buffer.String(), nil
+}
@@ -0,0 +1,638 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/timeliness-app/timeliness-backend/internal/ical"
+	"github.com/timeliness-app/timeliness-backend/pkg/auth/encryption"
+	"github.com/timeliness-app/timeliness-backend/pkg/communication"
+	"github.com/timeliness-app/timeliness-backend/pkg/date"
+	"github.com/timeliness-app/timeliness-backend/pkg/logger"
+	"github.com/timeliness-app/timeliness-backend/pkg/users"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const fakeCalDAVHomeSet = "/dav/calendars/alice/"
+
+type fakeCalDAVCalendar struct {
+	name       string
+	components []string
+	// changes is a log of changed event paths, a sync token is an index into it
+	changes []string
+}
+
+// fakeCalDAV is an in-memory CalDAV server that supports the requests the repository makes
+type fakeCalDAV struct {
+	server    *httptest.Server
+	password  string
+	mutex     sync.Mutex
+	calendars map[string]*fakeCalDAVCalendar
+	events    map[string]string
+}
+
+func newFakeCalDAV() *fakeCalDAV {
+	f := &fakeCalDAV{
+		password:  "secret",
+		calendars: make(map[string]*fakeCalDAVCalendar),
+		events:    make(map[string]string),
+	}
+
+	f.server = httptest.NewTLSServer(http.HandlerFunc(f.serve))
+	// The server runs on the loopback interface, which the public transport refuses to connect to
+	publicTransport = f.server.Client().Transport
+
+	return f
+}
+
+func (f *fakeCalDAV) addCalendar(name string, components ...string) string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	calendarPath := path.Join(fakeCalDAVHomeSet, strings.ToLower(name)) + "/"
+	f.calendars[calendarPath] = &fakeCalDAVCalendar{name: name, components: components}
+
+	return calendarPath
+}
+
+func (f *fakeCalDAV) addEvent(calendarPath string, uid string, start time.Time, end time.Time, properties ...string) string {
+	body := fmt.Sprintf("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//EN\r\nBEGIN:VEVENT\r\nUID:%s\r\nDTSTART:%s\r\nDTEND:%s\r\n%sEND:VEVENT\r\nEND:VCALENDAR\r\n",
+		uid, start.UTC().Format(ical.DateTimeLayout), end.UTC().Format(ical.DateTimeLayout), strings.Join(append(properties, ""), "\r\n"))
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	eventPath := calendarPath + uid + ".ics"
+	f.events[eventPath] = body
+	f.calendars[calendarPath].changes = append(f.calendars[calendarPath].changes, eventPath)
+
+	return eventPath
+}
+
+func (f *fakeCalDAV) removeEvent(eventPath string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	delete(f.events, eventPath)
+	calendarPath := path.Dir(eventPath) + "/"
+	f.calendars[calendarPath].changes = append(f.calendars[calendarPath].changes, eventPath)
+}
+
+func (f *fakeCalDAV) multistatus(writer http.ResponseWriter, responses string, extra string) {
+	writer.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	writer.WriteHeader(http.StatusMultiStatus)
+	_, _ = fmt.Fprintf(writer, `<?xml version="1.0" encoding="utf-8"?><d:multistatus xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav">%s%s</d:multistatus>`, responses, extra)
+}
+
+func propResponse(href string, prop string) string {
+	return fmt.Sprintf(`<d:response><d:href>%s</d:href><d:propstat><d:prop>%s</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, href, prop)
+}
+
+func calendarDataResponse(href string, data string) string {
+	var escaped bytes.Buffer
+	_ = xml.EscapeText(&escaped, []byte(data))
+
+	return propResponse(href, fmt.Sprintf(`<d:getetag>"1"</d:getetag><cal:calendar-data>%s</cal:calendar-data>`, escaped.String()))
+}
+
+func (f *fakeCalDAV) serve(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path == "/.well-known/caldav" {
+		http.Redirect(writer, request, "/dav/", http.StatusMovedPermanently)
+		return
+	}
+
+	username, password, ok := request.BasicAuth()
+	if !ok || username != "alice" || password != f.password {
+		writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	body, _ := ioutil.ReadAll(request.Body)
+	requestPath := request.URL.Path
+
+	switch request.Method {
+	case "PROPFIND":
+		f.propfind(writer, request, requestPath)
+	case "MKCALENDAR":
+		f.calendars[requestPath] = &fakeCalDAVCalendar{name: "Timeliness Tasks", components: []string{"VEVENT"}}
+		writer.WriteHeader(http.StatusCreated)
+	case http.MethodPut:
+		calendar := f.calendars[path.Dir(requestPath)+"/"]
+		if calendar == nil {
+			writer.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		if request.Header.Get("If-None-Match") == "*" && f.events[requestPath] != "" {
+			writer.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+
+		f.events[requestPath] = string(body)
+		calendar.changes = append(calendar.changes, requestPath)
+		writer.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		if f.events[requestPath] == "" {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		delete(f.events, requestPath)
+		calendar := f.calendars[path.Dir(requestPath)+"/"]
+		calendar.changes = append(calendar.changes, requestPath)
+		writer.WriteHeader(http.StatusNoContent)
+	case "REPORT":
+		f.report(writer, requestPath, body)
+	default:
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeCalDAV) propfind(writer http.ResponseWriter, request *http.Request, requestPath string) {
+	switch {
+	case requestPath == "/dav/":
+		f.multistatus(writer, propResponse(requestPath, `<d:current-user-principal><d:href>/dav/principals/alice/</d:href></d:current-user-principal>`), "")
+	case requestPath == "/dav/principals/alice/":
+		f.multistatus(writer, propResponse(requestPath, `<cal:calendar-home-set><d:href>`+fakeCalDAVHomeSet+`</d:href></cal:calendar-home-set>`), "")
+	case requestPath == fakeCalDAVHomeSet && request.Header.Get("Depth") == "1":
+		responses := propResponse(requestPath, `<d:resourcetype><d:collection/></d:resourcetype>`)
+
+		paths := make([]string, 0, len(f.calendars))
+		for calendarPath := range f.calendars {
+			paths = append(paths, calendarPath)
+		}
+		sort.Strings(paths)
+
+		for _, calendarPath := range paths {
+			calendar := f.calendars[calendarPath]
+
+			components := ""
+			for _, component := range calendar.components {
+				components += fmt.Sprintf(`<cal:comp name="%s"/>`, component)
+			}
+
+			responses += propResponse(calendarPath, fmt.Sprintf(`<d:resourcetype><d:collection/><cal:calendar/></d:resourcetype><d:displayname>%s</d:displayname><cal:supported-calendar-component-set>%s</cal:supported-calendar-component-set>`,
+				calendar.name, components))
+		}
+
+		f.multistatus(writer, responses, "")
+	case f.calendars[requestPath] != nil:
+		f.multistatus(writer, propResponse(requestPath, `<d:resourcetype><d:collection/><cal:calendar/></d:resourcetype>`), "")
+	default:
+		writer.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeCalDAV) report(writer http.ResponseWriter, calendarPath string, body []byte) {
+	calendar := f.calendars[calendarPath]
+	if calendar == nil {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var report struct {
+		XMLName   xml.Name
+		SyncToken string   `xml:"sync-token"`
+		Hrefs     []string `xml:"href"`
+		TimeRange struct {
+			Start string `xml:"start,attr"`
+			End   string `xml:"end,attr"`
+		} `xml:"filter>comp-filter>comp-filter>time-range"`
+	}
+
+	err := xml.Unmarshal(body, &report)
+	if err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch report.XMLName.Local {
+	case "calendar-query":
+		start, _ := time.Parse(ical.DateTimeLayout, report.TimeRange.Start)
+		end, _ := time.Parse(ical.DateTimeLayout, report.TimeRange.End)
+
+		responses := ""
+		for eventPath, data := range f.events {
+			if !strings.HasPrefix(eventPath, calendarPath) {
+				continue
+			}
+
+			object, _ := ical.Parse(strings.NewReader(data))
+			eventStart, eventEnd, _ := ical.EventTimespan(object.Events()[0], time.UTC)
+			if eventStart.Before(end) && eventEnd.After(start) {
+				responses += calendarDataResponse(eventPath, data)
+			}
+		}
+
+		f.multistatus(writer, responses, "")
+	case "sync-collection":
+		index := 0
+		if report.SyncToken != "" {
+			index, err = strconv.Atoi(strings.TrimPrefix(report.SyncToken, "token-"))
+			if err != nil || index > len(calendar.changes) {
+				writer.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+
+		seen := make(map[string]bool)
+		responses := ""
+		for _, eventPath := range calendar.changes[index:] {
+			if seen[eventPath] {
+				continue
+			}
+			seen[eventPath] = true
+
+			if f.events[eventPath] == "" {
+				if report.SyncToken != "" {
+					responses += fmt.Sprintf(`<d:response><d:href>%s</d:href><d:status>HTTP/1.1 404 Not Found</d:status></d:response>`, eventPath)
+				}
+				continue
+			}
+
+			responses += propResponse(eventPath, `<d:getetag>"1"</d:getetag>`)
+		}
+
+		f.multistatus(writer, responses, fmt.Sprintf("<d:sync-token>token-%d</d:sync-token>", len(calendar.changes)))
+	case "calendar-multiget":
+		responses := ""
+		for _, href := range report.Hrefs {
+			if f.events[href] != "" {
+				responses += calendarDataResponse(href, f.events[href])
+			}
+		}
+
+		f.multistatus(writer, responses, "")
+	default:
+		writer.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func setupCalDAVTest(t *testing.T) (*fakeCalDAV, *users.User, *CalDAVCalendarRepository) {
+	server := newFakeCalDAV()
+	t.Cleanup(server.server.Close)
+
+	user := &users.User{
+		ID: primitive.NewObjectID(),
+		CalDAVCalendarConnections: users.CalendarConnections{
+			{
+				ID:                       "caldav-account",
+				Status:                   users.CalendarConnectionStatusActive,
+				IsTaskCalendarConnection: true,
+				ServerURL:                server.server.URL + fakeCalDAVHomeSet,
+				Username:                 "alice",
+				Password:                 encryption.Encrypt("secret"),
+			},
+		},
+	}
+
+	connection := user.CalDAVCalendarConnections[0]
+	repository, err := NewCalDAVCalendarRepository(context.Background(), user.ID, &connection, logger.Logger{},
+		func(connection *users.CalendarConnection) {
+			user.CalDAVCalendarConnections[0] = *connection
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return server, user, repository
+}
+
+func TestDiscoverCalDAV(t *testing.T) {
+	server := newFakeCalDAV()
+	defer server.server.Close()
+
+	// The root of the server redirects to the principal through the well-known url
+	homeSet, err := DiscoverCalDAV(context.Background(), server.server.URL, "alice", "secret", logger.Logger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if homeSet != server.server.URL+fakeCalDAVHomeSet {
+		t.Errorf("got home set %s, want %s", homeSet, server.server.URL+fakeCalDAVHomeSet)
+	}
+
+	_, err = DiscoverCalDAV(context.Background(), server.server.URL, "alice", "wrong", logger.Logger{})
+	if errors.Cause(err) != communication.ErrCalendarAuthInvalid {
+		t.Errorf("got error %v with a wrong password, want %v", err, communication.ErrCalendarAuthInvalid)
+	}
+}
+
+func TestDiscoverCalDAV_CredentialsStayOnServer(t *testing.T) {
+	server := newFakeCalDAV()
+	defer server.server.Close()
+
+	var leaked []string
+	var leakedMutex sync.Mutex
+	other := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if _, password, ok := request.BasicAuth(); ok {
+			leakedMutex.Lock()
+			leaked = append(leaked, password)
+			leakedMutex.Unlock()
+		}
+
+		writer.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer other.Close()
+
+	insecureURL := strings.Replace(server.server.URL, "https://", "http://", 1)
+	_, err := DiscoverCalDAV(context.Background(), insecureURL, "alice", "secret", logger.Logger{})
+	if err == nil {
+		t.Error("got no error for a server without https")
+	}
+
+	// Neither redirects nor hrefs lead to another server
+	redirecting := httptest.NewTLSServer(http.RedirectHandler(other.URL+fakeCalDAVHomeSet, http.StatusTemporaryRedirect))
+	defer redirecting.Close()
+
+	_, err = DiscoverCalDAV(context.Background(), redirecting.URL+"/dav/", "alice", "secret", logger.Logger{})
+	if err == nil {
+		t.Error("got no error for a redirect to another server")
+	}
+
+	repository, err := newCalDAVClient(context.Background(), server.server.URL, "alice", "secret", logger.Logger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repository.do("PROPFIND", repository.resolve(other.URL+fakeCalDAVHomeSet), "0", caldavHomeSetRequest, nil)
+	if err == nil {
+		t.Error("got no error for an href on another server")
+	}
+
+	if len(leaked) != 0 {
+		t.Errorf("credentials were sent to another server %d times", len(leaked))
+	}
+}
+
+func TestCalDAVCalendarRepository_TaskEvents(t *testing.T) {
+	server, user, repository := setupCalDAVTest(t)
+
+	user, err := repository.TestTaskCalendarExistence(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	taskCalendarID := user.CalDAVCalendarConnections[0].TaskCalendarID
+	if server.calendars[taskCalendarID] == nil {
+		t.Fatalf("task calendar %s wasn't created", taskCalendarID)
+	}
+
+	if !user.CalDAVCalendarConnections[0].CalendarsOfInterest.HasCalendarWithID(taskCalendarID) {
+		t.Error("task calendar isn't part of the calendars of interest")
+	}
+
+	event := &Event{
+		Date: date.Timespan{
+			Start: time.Date(2021, 1, 4, 9, 0, 0, 0, time.UTC),
+			End:   time.Date(2021, 1, 4, 10, 0, 0, 0, time.UTC),
+		},
+		Blocking: true,
+	}
+
+	event, err = repository.NewEvent(event, "task", "Write report, part 1", "Description", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	persisted := event.CalendarEvents.FindByUserID(user.ID.Hex())
+	if persisted == nil || persisted.CalendarType != PersistedCalendarTypeCalDAVCalendar {
+		t.Fatalf("got persisted events %v, want one caldav event", event.CalendarEvents)
+	}
+
+	if !strings.HasPrefix(persisted.CalendarEventID, taskCalendarID) {
+		t.Fatalf("event %s wasn't created in the task calendar", persisted.CalendarEventID)
+	}
+
+	created, err := ical.Parse(strings.NewReader(server.events[persisted.CalendarEventID]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vevent := created.Events()[0]
+	if vevent.Text("SUMMARY") != "Write report, part 1" || vevent.Value("TRANSP") != "OPAQUE" ||
+		vevent.Value(caldavTaskIDProperty) != "task" || len(vevent.ComponentsByName("VALARM")) != 1 {
+		t.Errorf("got created event %+v", vevent)
+	}
+
+	event.Date.Start = time.Date(2021, 1, 4, 11, 0, 0, 0, time.UTC)
+	event.Date.End = time.Date(2021, 1, 4, 12, 0, 0, 0, time.UTC)
+	event.Blocking = false
+
+	err = repository.UpdateEvent(event, "task", "Write report (updated)", "Description", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := ical.Parse(strings.NewReader(server.events[persisted.CalendarEventID]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vevent = updated.Events()[0]
+	start, end, err := ical.EventTimespan(vevent, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !start.Equal(event.Date.Start) || !end.Equal(event.Date.End) {
+		t.Errorf("got updated timespan %s - %s, want %s", start, end, event.Date)
+	}
+
+	if vevent.Text("SUMMARY") != "Write report (updated)" || vevent.Value("TRANSP") != "TRANSPARENT" ||
+		len(vevent.ComponentsByName("VALARM")) != 0 || vevent.Value("UID") != strings.TrimSuffix(path.Base(persisted.CalendarEventID), ".ics") {
+		t.Errorf("got updated event %+v", vevent)
+	}
+
+	err = repository.DeleteEvent(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if server.events[persisted.CalendarEventID] != "" {
+		t.Error("event wasn't deleted")
+	}
+
+	// Deleting an event that is already gone isn't an error
+	err = repository.DeleteEvent(event)
+	if err != nil {
+		t.Errorf("got error %v when deleting a deleted event", err)
+	}
+}
+
+func TestCalDAVCalendarRepository_GetAllCalendarsOfInterest(t *testing.T) {
+	server, _, repository := setupCalDAVTest(t)
+
+	workCalendarID := server.addCalendar("Work", "VEVENT", "VTODO")
+	server.addCalendar("Todos", "VTODO")
+	taskCalendarID := server.addCalendar("Tasks", "VEVENT")
+
+	repository.connection.TaskCalendarID = taskCalendarID
+
+	calendars, err := repository.GetAllCalendarsOfInterest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(calendars) != 1 || calendars[workCalendarID] == nil || calendars[workCalendarID].Name != "Work" {
+		t.Errorf("got calendars %v, want only the work calendar", calendars)
+	}
+}
+
+func TestCalDAVCalendarRepository_AddBusyToWindow(t *testing.T) {
+	server, _, repository := setupCalDAVTest(t)
+
+	workCalendarID := server.addCalendar("Work", "VEVENT")
+	taskCalendarID := server.addCalendar("Tasks", "VEVENT")
+
+	repository.connection.TaskCalendarID = taskCalendarID
+	repository.connection.CalendarsOfInterest = users.CalendarSyncs{{CalendarID: workCalendarID}, {CalendarID: taskCalendarID}}
+
+	day := time.Date(2021, 1, 4, 0, 0, 0, 0, time.UTC)
+
+	server.addEvent(workCalendarID, "meeting", day.Add(time.Hour*9), day.Add(time.Hour*10))
+	server.addEvent(workCalendarID, "free", day.Add(time.Hour*13), day.Add(time.Hour*14), "TRANSP:TRANSPARENT")
+	server.addEvent(workCalendarID, "cancelled", day.Add(time.Hour*15), day.Add(time.Hour*16), "STATUS:CANCELLED")
+	server.addEvent(workCalendarID, "tomorrow", day.Add(time.Hour*33), day.Add(time.Hour*34))
+	server.addEvent(taskCalendarID, "task", day.Add(time.Hour*11), day.Add(time.Hour*12))
+
+	window := date.TimeWindow{Start: day, End: day.Add(time.Hour * 24)}
+
+	err := repository.AddBusyToWindow(&window, window.Start, window.End)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	busy := window.Busy()
+	want := date.Timespan{Start: day.Add(time.Hour * 9), End: day.Add(time.Hour * 10)}
+
+	if len(busy) != 1 || !busy[0].Start.Equal(want.Start) || !busy[0].End.Equal(want.End) {
+		t.Errorf("got busy %v, want %s", busy, want)
+	}
+}
+
+func TestCalDAVCalendarRepository_SyncEvents(t *testing.T) {
+	server, user, repository := setupCalDAVTest(t)
+
+	calendarID := server.addCalendar("Work", "VEVENT")
+	repository.connection.CalendarsOfInterest = users.CalendarSyncs{{CalendarID: calendarID}}
+	user.CalDAVCalendarConnections[0] = *repository.connection
+
+	user, err := repository.WatchCalendar(calendarID, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.CalDAVCalendarConnections[0].CalendarsOfInterest[0].Expiration.IsZero() {
+		t.Fatal("calendar wasn't marked for polling")
+	}
+
+	start := time.Now().Add(time.Hour * 24).UTC().Truncate(time.Minute)
+
+	server.addEvent(calendarID, "past", start.Add(-time.Hour*72), start.Add(-time.Hour*71))
+	meetingPath := server.addEvent(calendarID, "meeting", start, start.Add(time.Hour))
+	taskPath := server.addEvent(calendarID, "task", start.Add(time.Hour*2), start.Add(time.Hour*3), caldavTaskIDProperty+":task")
+
+	syncCalendar := func() ([]*Event, *users.User, error) {
+		eventChannel := make(chan *Event)
+		errorChannel := make(chan error)
+		userChannel := make(chan *users.User)
+
+		go repository.SyncEvents(calendarID, user, &eventChannel, &errorChannel, &userChannel)
+
+		var events []*Event
+		for {
+			select {
+			case event := <-eventChannel:
+				events = append(events, event)
+			case err := <-errorChannel:
+				return nil, nil, err
+			case u := <-userChannel:
+				return events, u, nil
+			}
+		}
+	}
+
+	// The initial sync skips events in the past
+	events, user, err := syncCalendar()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+
+	for _, event := range events {
+		switch event.CalendarEvents[0].CalendarEventID {
+		case meetingPath:
+			if event.IsOriginal || !event.Blocking || !event.Date.Start.Equal(start) {
+				t.Errorf("got meeting %+v", event)
+			}
+		case taskPath:
+			if !event.IsOriginal {
+				t.Error("event created by Timeliness isn't original")
+			}
+		default:
+			t.Errorf("got unknown event %s", event.CalendarEvents[0].CalendarEventID)
+		}
+	}
+
+	if user.CalDAVCalendarConnections[0].CalendarsOfInterest[0].SyncToken == "" {
+		t.Fatal("sync token wasn't stored")
+	}
+
+	// Only the changes since the last sync are returned
+	server.removeEvent(meetingPath)
+
+	events, user, err = syncCalendar()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 1 || !events[0].Deleted || events[0].CalendarEvents[0].CalendarEventID != meetingPath {
+		t.Fatalf("got events %v, want the deleted meeting", events)
+	}
+
+	// An invalid sync token resets the sync
+	repository.connection.CalendarsOfInterest[0].SyncToken = "token-100"
+
+	events, user, err = syncCalendar()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 0 || user.CalDAVCalendarConnections[0].CalendarsOfInterest[0].SyncToken != "" {
+		t.Errorf("got %d events and sync token %s after the sync token became invalid, want none", len(events), user.CalDAVCalendarConnections[0].CalendarsOfInterest[0].SyncToken)
+	}
+}
+
+func TestCalDAVCalendarRepository_InvalidCredentials(t *testing.T) {
+	server, user, repository := setupCalDAVTest(t)
+
+	server.password = "changed"
+
+	_, err := repository.GetAllCalendarsOfInterest()
+	if errors.Cause(err) != communication.ErrCalendarAuthInvalid {
+		t.Fatalf("got error %v, want %v", err, communication.ErrCalendarAuthInvalid)
+	}
+
+	if user.CalDAVCalendarConnections[0].Status != users.CalendarConnectionStatusExpired {
+		t.Errorf("got connection status %s, want %s", user.CalDAVCalendarConnections[0].Status, users.CalendarConnectionStatusExpired)
+	}
+}
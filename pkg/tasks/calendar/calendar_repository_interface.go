@@ -10,6 +10,9 @@ import (
 // ErrNonSyncable is an error that is returned when a calendar doesn't support syncing
 var ErrNonSyncable = errors.New("non_syncable_calendar")
 
+// ErrReadOnlyCalendar is an error that is returned when events are written to a calendar that can only be read
+var ErrReadOnlyCalendar = errors.New("read_only_calendar")
+
 // RepositoryInterface is an interface for every calendar implementation e.g. Google Calendar, Microsoft Calendar,...
 type RepositoryInterface interface {
 	GetAllCalendarsOfInterest() (map[string]*Calendar, error)
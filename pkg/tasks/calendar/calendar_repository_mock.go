@@ -60,7 +60,9 @@ func (r *MockCalendarRepository) NewEvent(event *Event, taskID string, title str
 
 	event.CalendarEvents = append(event.CalendarEvents, calendarEvent)
 
-	r.Events = append(r.Events, event)
+	// The caller can reuse the event, e.g. as a loop variable, so a copy is stored
+	persisted := *event
+	r.Events = append(r.Events, &persisted)
 	return event, nil
 }
 
@@ -11,6 +11,14 @@ type Type string
 const (
 	// PersistedCalendarTypeGoogleCalendar is different calendar implementation enum
 	PersistedCalendarTypeGoogleCalendar Type = "google_calendar"
+	// PersistedCalendarTypeMicrosoftCalendar is different calendar implementation enum
+	PersistedCalendarTypeMicrosoftCalendar Type = "microsoft_calendar"
+	// PersistedCalendarTypeCalDAVCalendar is different calendar implementation enum
+	PersistedCalendarTypeCalDAVCalendar Type = "caldav_calendar"
+	// PersistedCalendarTypeICSCalendar is different calendar implementation enum
+	PersistedCalendarTypeICSCalendar Type = "ics_calendar"
+	// PersistedCalendarTypeNativeCalendar is different calendar implementation enum
+	PersistedCalendarTypeNativeCalendar Type = "native_calendar"
 )
 
 // Event represents a simple calendar event
@@ -31,17 +31,17 @@ type GoogleCalendarRepository struct {
 	Config                   *oauth2.Config
 	Logger                   logger.Interface
 	Service                  *gcalendar.Service
-	connection               *users.GoogleCalendarConnection
+	connection               *users.CalendarConnection
 	apiBaseURL               string
 	userID                   primitive.ObjectID
 	updateConnectionFunction UpdateConnection
 }
 
 // UpdateConnection is triggered by the repository when a user needs to be updated for example if the token is invalid
-type UpdateConnection func(connection *users.GoogleCalendarConnection)
+type UpdateConnection func(connection *users.CalendarConnection)
 
 // NewGoogleCalendarRepository constructs a GoogleCalendarRepository, only use CalendarRepositoryManager for this
-func NewGoogleCalendarRepository(ctx context.Context, userID primitive.ObjectID, connection *users.GoogleCalendarConnection, logger logger.Interface, updateConnectionFunction UpdateConnection) (*GoogleCalendarRepository, error) {
+func NewGoogleCalendarRepository(ctx context.Context, userID primitive.ObjectID, connection *users.CalendarConnection, logger logger.Interface, updateConnectionFunction UpdateConnection) (*GoogleCalendarRepository, error) {
 	newRepo := GoogleCalendarRepository{}
 
 	config, err := google.ReadGoogleConfig(true)
@@ -189,7 +189,7 @@ func (c *GoogleCalendarRepository) TestTaskCalendarExistence(u *users.User) (*us
 		c.connection.TaskCalendarID = calendarID
 
 		c.connection.CalendarsOfInterest = append(c.connection.CalendarsOfInterest,
-			users.GoogleCalendarSync{CalendarID: calendarID})
+			users.CalendarSync{CalendarID: calendarID})
 
 		for i, connection := range u.GoogleCalendarConnections {
 			if connection.ID == c.connection.ID {
@@ -199,7 +199,7 @@ func (c *GoogleCalendarRepository) TestTaskCalendarExistence(u *users.User) (*us
 	} else {
 		if !c.connection.CalendarsOfInterest.HasCalendarWithID(c.connection.TaskCalendarID) {
 			c.connection.CalendarsOfInterest = append(c.connection.CalendarsOfInterest,
-				users.GoogleCalendarSync{CalendarID: c.connection.TaskCalendarID})
+				users.CalendarSync{CalendarID: c.connection.TaskCalendarID})
 
 			for i, connection := range u.GoogleCalendarConnections {
 				if connection.ID == c.connection.ID {
@@ -368,7 +368,7 @@ func (c *GoogleCalendarRepository) StopWatchingCalendar(calendarID string, user
 	return user, nil
 }
 
-func findSyncByID(connection *users.GoogleCalendarConnection, ID string) int {
+func findSyncByID(connection *users.CalendarConnection, ID string) int {
 	for i, sync := range connection.CalendarsOfInterest {
 		if sync.CalendarID == ID {
 			return i
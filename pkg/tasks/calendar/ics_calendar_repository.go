@@ -0,0 +1,325 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/timeliness-app/timeliness-backend/internal/ical"
+	"github.com/timeliness-app/timeliness-backend/pkg/communication"
+	"github.com/timeliness-app/timeliness-backend/pkg/date"
+	"github.com/timeliness-app/timeliness-backend/pkg/logger"
+	"github.com/timeliness-app/timeliness-backend/pkg/users"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// icsMaxFeedSize is the maximum size of a feed in bytes, larger feeds are rejected
+const icsMaxFeedSize = 10 * 1024 * 1024
+
+// icsSyncHorizon is how far into the future the busy times of a changed feed are checked for collisions
+const icsSyncHorizon = time.Hour * 24 * 7 * 12
+
+// ICSError is an unexpected status of the server publishing a feed
+type ICSError struct {
+	StatusCode int
+}
+
+func (e *ICSError) Error() string {
+	return fmt.Sprintf("ics: feed responded with status %d", e.StatusCode)
+}
+
+// ICSCalendarRepository reads busy times from an ICS feed, e.g. a shift plan that is published as a URL.
+// Feeds can't be written to, so they are only a source of availability and never hold the task calendar.
+type ICSCalendarRepository struct {
+	Logger                   logger.Interface
+	Client                   *http.Client
+	ctx                      context.Context
+	connection               *users.CalendarConnection
+	userID                   primitive.ObjectID
+	updateConnectionFunction UpdateConnection
+
+	// feed is fetched once per repository, checksum identifies its content
+	feed     *ical.Component
+	checksum string
+}
+
+// NewICSCalendarRepository creates a repository for the feed of a connection
+func NewICSCalendarRepository(ctx context.Context, userID primitive.ObjectID, connection *users.CalendarConnection, logger logger.Interface, updateConnectionFunction UpdateConnection) (*ICSCalendarRepository, error) {
+	if connection.ServerURL == "" {
+		return nil, communication.ErrCalendarAuthInvalid
+	}
+
+	return &ICSCalendarRepository{
+		Logger:                   logger,
+		Client:                   &http.Client{Transport: publicTransport, Timeout: time.Second * 30},
+		ctx:                      ctx,
+		connection:               connection,
+		userID:                   userID,
+		updateConnectionFunction: updateConnectionFunction,
+	}, nil
+}
+
+// NormalizeICSURL checks the URL of a feed, webcal URLs are turned into their https counterpart
+func NormalizeICSURL(feedURL string) (string, error) {
+	parsedURL, err := url.Parse(feedURL)
+	if err != nil {
+		return "", err
+	}
+
+	switch parsedURL.Scheme {
+	case "webcal", "webcals":
+		parsedURL.Scheme = "https"
+	case "http", "https":
+	default:
+		return "", fmt.Errorf("unsupported url scheme %s", parsedURL.Scheme)
+	}
+
+	if parsedURL.Host == "" {
+		return "", errors.New("url has no host")
+	}
+
+	return parsedURL.String(), nil
+}
+
+// fetch loads and parses the feed, feeds that are gone or not accessible anymore expire the connection
+func (c *ICSCalendarRepository) fetch() (*ical.Component, error) {
+	if c.feed != nil {
+		return c.feed, nil
+	}
+
+	request, err := http.NewRequestWithContext(c.ctx, http.MethodGet, c.connection.ServerURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", ical.ContentType)
+
+	response, err := c.Client.Do(request)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, c.checkForInvalidFeedError(&ICSError{StatusCode: response.StatusCode})
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(response.Body, icsMaxFeedSize+1))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if len(body) > icsMaxFeedSize {
+		return nil, fmt.Errorf("ics feed is larger than %d bytes", icsMaxFeedSize)
+	}
+
+	feed, err := ical.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse ics feed")
+	}
+
+	if feed.Name != "VCALENDAR" {
+		return nil, fmt.Errorf("ics feed contains a %s instead of a VCALENDAR", feed.Name)
+	}
+
+	checksum := sha256.Sum256(body)
+	c.checksum = hex.EncodeToString(checksum[:])
+	c.feed = feed
+
+	return feed, nil
+}
+
+// checkForInvalidFeedError expires the connection if the feed was removed or access to it was revoked
+func (c *ICSCalendarRepository) checkForInvalidFeedError(err *ICSError) error {
+	c.Logger.Debug(err.Error())
+
+	switch err.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusGone:
+		if c.updateConnectionFunction != nil {
+			c.connection.Status = users.CalendarConnectionStatusExpired
+			c.updateConnectionFunction(c.connection)
+		}
+
+		return errors.WithStack(communication.ErrCalendarAuthInvalid)
+	}
+
+	return errors.WithStack(err)
+}
+
+// location is the time zone floating times of the feed are interpreted in
+func (c *ICSCalendarRepository) location() *time.Location {
+	if name := c.feed.Value("X-WR-TIMEZONE"); name != "" {
+		if location, err := time.LoadLocation(name); err == nil {
+			return location
+		}
+	}
+
+	return time.UTC
+}
+
+func (c *ICSCalendarRepository) updateUserConnection(user *users.User) {
+	for i, connection := range user.ICSCalendarConnections {
+		if connection.ID == c.connection.ID {
+			user.ICSCalendarConnections[i] = *c.connection
+		}
+	}
+}
+
+// GetAllCalendarsOfInterest returns the feed as the only calendar of the connection, its id is the connection id
+func (c *ICSCalendarRepository) GetAllCalendarsOfInterest() (map[string]*Calendar, error) {
+	feed, err := c.fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	name := feed.Text("X-WR-CALNAME")
+	if name == "" {
+		parsedURL, err := url.Parse(c.connection.ServerURL)
+		if err == nil {
+			name = parsedURL.Host
+		}
+	}
+
+	return map[string]*Calendar{
+		c.connection.ID: {
+			CalendarID: c.connection.ID,
+			Name:       name,
+			IsActive:   c.connection.CalendarsOfInterest.HasCalendarWithID(c.connection.ID),
+		},
+	}, nil
+}
+
+// TestTaskCalendarExistence fails, a feed can't hold the task calendar
+func (c *ICSCalendarRepository) TestTaskCalendarExistence(user *users.User) (*users.User, error) {
+	return nil, ErrReadOnlyCalendar
+}
+
+// NewEvent fails, a feed can't be written to
+func (c *ICSCalendarRepository) NewEvent(event *Event, taskID string, title string, description string, withReminder bool) (*Event, error) {
+	return nil, ErrReadOnlyCalendar
+}
+
+// UpdateEvent fails, a feed can't be written to
+func (c *ICSCalendarRepository) UpdateEvent(event *Event, taskID string, title string, description string, withReminder bool) error {
+	return ErrReadOnlyCalendar
+}
+
+// DeleteEvent fails, a feed can't be written to
+func (c *ICSCalendarRepository) DeleteEvent(event *Event) error {
+	return ErrReadOnlyCalendar
+}
+
+// AddBusyToWindow fills a window with the busy occurrences of the feed, recurring events are expanded
+func (c *ICSCalendarRepository) AddBusyToWindow(window *date.TimeWindow, start time.Time, end time.Time) error {
+	if !c.connection.CalendarsOfInterest.HasCalendarWithID(c.connection.ID) {
+		return nil
+	}
+
+	feed, err := c.fetch()
+	if err != nil {
+		return err
+	}
+
+	for _, occurrence := range ical.ExpandEvents(feed, c.location(), start, end) {
+		if !ical.IsEventBlocking(occurrence.Event) {
+			continue
+		}
+
+		window.AddToBusy(date.Timespan{Start: occurrence.Start.UTC(), End: occurrence.End.UTC()})
+	}
+
+	return nil
+}
+
+// WatchCalendar marks the feed for polling, feeds can't notify about changes, so they are synced whenever the sync
+// renewal runs
+func (c *ICSCalendarRepository) WatchCalendar(calendarID string, user *users.User) (*users.User, error) {
+	index := findSyncByID(c.connection, calendarID)
+	if index == -1 {
+		return nil, errors.New("calendar id could not be found in calendars of interest")
+	}
+
+	if !c.connection.CalendarsOfInterest[index].Expiration.IsZero() {
+		return user, nil
+	}
+
+	c.connection.CalendarsOfInterest[index].Expiration = time.Now().UTC()
+	c.updateUserConnection(user)
+
+	return user, nil
+}
+
+// StopWatchingCalendar stops polling the feed
+func (c *ICSCalendarRepository) StopWatchingCalendar(calendarID string, user *users.User) (*users.User, error) {
+	index := findSyncByID(c.connection, calendarID)
+	if index == -1 {
+		return nil, errors.New("calendar id could not be found in calendars of interest")
+	}
+
+	c.connection.CalendarsOfInterest[index].SyncToken = ""
+	c.connection.CalendarsOfInterest[index].Expiration = time.Time{}
+
+	c.updateUserConnection(user)
+
+	return user, nil
+}
+
+// SyncEvents polls the feed. The sync token is the checksum of the feed, if it changed all busy occurrences of the
+// near future are sent, so that work units colliding with new busy time are moved. Their ids are the ids of the
+// occurrences, so they never match a task.
+func (c *ICSCalendarRepository) SyncEvents(calendarID string, user *users.User,
+	eventChannel *chan *Event,
+	errorChannel *chan error,
+	userChannel *chan *users.User) {
+
+	defer close(*eventChannel)
+	defer close(*errorChannel)
+	defer close(*userChannel)
+
+	syncIndex := findSyncByID(c.connection, calendarID)
+	if syncIndex == -1 {
+		*errorChannel <- errors.New("calendar id could not be found in calendars of interest")
+		return
+	}
+
+	feed, err := c.fetch()
+	if err != nil {
+		*errorChannel <- err
+		return
+	}
+
+	if c.connection.CalendarsOfInterest[syncIndex].SyncToken == c.checksum {
+		*userChannel <- user
+		return
+	}
+
+	now := time.Now()
+	for _, occurrence := range ical.ExpandEvents(feed, c.location(), now, now.Add(icsSyncHorizon)) {
+		if !ical.IsEventBlocking(occurrence.Event) {
+			continue
+		}
+
+		*eventChannel <- &Event{
+			Date:     date.Timespan{Start: occurrence.Start.UTC(), End: occurrence.End.UTC()},
+			Blocking: true,
+			CalendarEvents: []PersistedEvent{
+				{
+					CalendarEventID: occurrence.ID(),
+					CalendarType:    PersistedCalendarTypeICSCalendar,
+					UserID:          c.userID,
+				},
+			},
+		}
+	}
+
+	c.connection.CalendarsOfInterest[syncIndex].SyncToken = c.checksum
+	c.updateUserConnection(user)
+
+	*userChannel <- user
+}
@@ -0,0 +1,270 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/timeliness-app/timeliness-backend/internal/ical"
+	"github.com/timeliness-app/timeliness-backend/pkg/communication"
+	"github.com/timeliness-app/timeliness-backend/pkg/date"
+	"github.com/timeliness-app/timeliness-backend/pkg/logger"
+	"github.com/timeliness-app/timeliness-backend/pkg/users"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeICSFeed serves a feed that can be changed or removed while a test runs
+type fakeICSFeed struct {
+	server *httptest.Server
+	mutex  sync.Mutex
+	status int
+	body   string
+}
+
+func newFakeICSFeed(t *testing.T) *fakeICSFeed {
+	f := &fakeICSFeed{status: http.StatusOK}
+
+	f.server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+
+		if f.status != http.StatusOK {
+			writer.WriteHeader(f.status)
+			return
+		}
+
+		writer.Header().Set("Content-Type", ical.ContentType)
+		_, _ = writer.Write([]byte(f.body))
+	}))
+	t.Cleanup(f.server.Close)
+
+	// The feed runs on the loopback interface, which the public transport refuses to connect to
+	publicTransport = f.server.Client().Transport
+
+	return f
+}
+
+func (f *fakeICSFeed) setStatus(status int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.status = status
+}
+
+func (f *fakeICSFeed) setEvents(events ...string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.body = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//EN\r\nX-WR-CALNAME:Shifts\r\n"
+	for _, event := range events {
+		f.body += "BEGIN:VEVENT\r\n" + event + "END:VEVENT\r\n"
+	}
+	f.body += "END:VCALENDAR\r\n"
+}
+
+func setupICSTest(t *testing.T) (*fakeICSFeed, *users.User, func() *ICSCalendarRepository) {
+	feed := newFakeICSFeed(t)
+
+	user := &users.User{
+		ID: primitive.NewObjectID(),
+		ICSCalendarConnections: users.CalendarConnections{
+			{
+				ID:                  "shifts",
+				Status:              users.CalendarConnectionStatusActive,
+				ServerURL:           feed.server.URL + "/shifts.ics",
+				CalendarsOfInterest: users.CalendarSyncs{{CalendarID: "shifts"}},
+			},
+		},
+	}
+
+	// Repositories cache the feed, so every use gets a new one like every request does
+	newRepository := func() *ICSCalendarRepository {
+		connection := user.ICSCalendarConnections[0]
+		repository, err := NewICSCalendarRepository(context.Background(), user.ID, &connection, logger.Logger{},
+			func(connection *users.CalendarConnection) {
+				user.ICSCalendarConnections[0] = *connection
+			})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return repository
+	}
+
+	return feed, user, newRepository
+}
+
+func icsEvent(uid string, start time.Time, end time.Time, properties ...string) string {
+	event := fmt.Sprintf("UID:%s\r\nDTSTART:%s\r\nDTEND:%s\r\n", uid, start.UTC().Format(ical.DateTimeLayout), end.UTC().Format(ical.DateTimeLayout))
+	for _, property := range properties {
+		event += property + "\r\n"
+	}
+
+	return event
+}
+
+func TestICSCalendarRepository_AddBusyToWindow(t *testing.T) {
+	feed, _, newRepository := setupICSTest(t)
+
+	day := time.Date(2021, 1, 4, 0, 0, 0, 0, time.UTC)
+
+	feed.setEvents(
+		icsEvent("early-shift", day.Add(time.Hour*6), day.Add(time.Hour*10), "RRULE:FREQ=DAILY;COUNT=5", "EXDATE:20210105T060000Z"),
+		icsEvent("on-call", day.Add(time.Hour*12), day.Add(time.Hour*13), "TRANSP:TRANSPARENT"),
+	)
+
+	repository := newRepository()
+
+	calendars, err := repository.GetAllCalendarsOfInterest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calendars["shifts"] == nil || calendars["shifts"].Name != "Shifts" || !calendars["shifts"].IsActive {
+		t.Errorf("got calendars %v", calendars)
+	}
+
+	window := date.TimeWindow{Start: day, End: day.Add(time.Hour * 48)}
+
+	err = repository.AddBusyToWindow(&window, window.Start, window.End)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	busy := window.Busy()
+	want := date.Timespan{Start: day.Add(time.Hour * 6), End: day.Add(time.Hour * 10)}
+
+	// The second day is excluded and the transparent event doesn't block anything
+	if len(busy) != 1 || !busy[0].Start.Equal(want.Start) || !busy[0].End.Equal(want.End) {
+		t.Errorf("got busy %v, want %s", busy, want)
+	}
+}
+
+func TestICSCalendarRepository_SyncEvents(t *testing.T) {
+	feed, user, newRepository := setupICSTest(t)
+
+	start := time.Now().Add(time.Hour * 24).UTC().Truncate(time.Minute)
+	feed.setEvents(
+		icsEvent("shift", start, start.Add(time.Hour*8), "RRULE:FREQ=WEEKLY;COUNT=2"),
+		icsEvent("past", start.Add(-time.Hour*72), start.Add(-time.Hour*71)),
+	)
+
+	user, err := newRepository().WatchCalendar("shifts", user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.ICSCalendarConnections[0].CalendarsOfInterest[0].Expiration.IsZero() {
+		t.Fatal("feed wasn't marked for polling")
+	}
+
+	syncCalendar := func() ([]*Event, error) {
+		eventChannel := make(chan *Event)
+		errorChannel := make(chan error)
+		userChannel := make(chan *users.User)
+
+		go newRepository().SyncEvents("shifts", user, &eventChannel, &errorChannel, &userChannel)
+
+		var events []*Event
+		for {
+			select {
+			case event := <-eventChannel:
+				events = append(events, event)
+			case err := <-errorChannel:
+				return nil, err
+			case u := <-userChannel:
+				user = u
+				return events, nil
+			}
+		}
+	}
+
+	events, err := syncCalendar()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+
+	for i, event := range events {
+		wantStart := start.Add(time.Hour * 24 * 7 * time.Duration(i))
+		if !event.Blocking || event.IsOriginal || !event.Date.Start.Equal(wantStart) {
+			t.Errorf("got event %+v, want a busy event at %s", event, wantStart)
+		}
+
+		if event.CalendarEvents[0].CalendarType != PersistedCalendarTypeICSCalendar || event.CalendarEvents[0].UserID != user.ID {
+			t.Errorf("got persisted event %+v", event.CalendarEvents[0])
+		}
+	}
+
+	if user.ICSCalendarConnections[0].CalendarsOfInterest[0].SyncToken == "" {
+		t.Fatal("checksum of the feed wasn't stored")
+	}
+
+	// Polling an unchanged feed sends nothing
+	events, err = syncCalendar()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 0 {
+		t.Errorf("got %d events for an unchanged feed", len(events))
+	}
+
+	feed.setEvents(icsEvent("shift", start.Add(time.Hour), start.Add(time.Hour*9)))
+
+	events, err = syncCalendar()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 1 || !events[0].Date.Start.Equal(start.Add(time.Hour)) {
+		t.Errorf("got events %v for the changed feed", events)
+	}
+}
+
+func TestICSCalendarRepository_FeedGone(t *testing.T) {
+	feed, user, newRepository := setupICSTest(t)
+
+	feed.setStatus(http.StatusNotFound)
+
+	_, err := newRepository().GetAllCalendarsOfInterest()
+	if errors.Cause(err) != communication.ErrCalendarAuthInvalid {
+		t.Fatalf("got error %v, want %v", err, communication.ErrCalendarAuthInvalid)
+	}
+
+	if user.ICSCalendarConnections[0].Status != users.CalendarConnectionStatusExpired {
+		t.Errorf("got connection status %s, want %s", user.ICSCalendarConnections[0].Status, users.CalendarConnectionStatusExpired)
+	}
+
+	feed.setStatus(http.StatusServiceUnavailable)
+	user.ICSCalendarConnections[0].Status = users.CalendarConnectionStatusActive
+
+	_, err = newRepository().GetAllCalendarsOfInterest()
+	if err == nil || errors.Cause(err) == communication.ErrCalendarAuthInvalid {
+		t.Fatalf("got error %v for an unavailable feed", err)
+	}
+
+	if user.ICSCalendarConnections[0].Status != users.CalendarConnectionStatusActive {
+		t.Error("connection expired because of a temporary error")
+	}
+}
+
+func TestNormalizeICSURL(t *testing.T) {
+	got, err := NormalizeICSURL("webcal://example.com/rota.ics?token=a")
+	if err != nil || got != "https://example.com/rota.ics?token=a" {
+		t.Errorf("got %s, %v", got, err)
+	}
+
+	for _, feedURL := range []string{"ftp://example.com/rota.ics", "https:///rota.ics", "rota.ics"} {
+		if _, err := NormalizeICSURL(feedURL); err == nil {
+			t.Errorf("%s was accepted", feedURL)
+		}
+	}
+}
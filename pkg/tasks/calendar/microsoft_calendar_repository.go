@@ -0,0 +1,709 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/timeliness-app/timeliness-backend/internal/microsoft"
+	"github.com/timeliness-app/timeliness-backend/pkg/auth/encryption"
+	"github.com/timeliness-app/timeliness-backend/pkg/communication"
+	"github.com/timeliness-app/timeliness-backend/pkg/date"
+	"github.com/timeliness-app/timeliness-backend/pkg/logger"
+	"github.com/timeliness-app/timeliness-backend/pkg/users"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/oauth2"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// MicrosoftSubscriptionDuration is how long a Microsoft Graph subscription lives, Graph allows at most 4230 minutes
+// for events
+const MicrosoftSubscriptionDuration = time.Minute * 4200
+
+// microsoftCategory marks events that were created by Timeliness
+const microsoftCategory = "Timeliness"
+
+// graphDateTimeLayout is the format Microsoft Graph uses for dates without an offset
+const graphDateTimeLayout = "2006-01-02T15:04:05.0000000"
+
+// MicrosoftCalendarRepository provides function for easily editing the users Outlook calendar through Microsoft Graph
+type MicrosoftCalendarRepository struct {
+	Config                   *oauth2.Config
+	Logger                   logger.Interface
+	Client                   *http.Client
+	graphBaseURL             string
+	connection               *users.CalendarConnection
+	apiBaseURL               string
+	userID                   primitive.ObjectID
+	updateConnectionFunction UpdateConnection
+}
+
+// GraphError is an error response of the Microsoft Graph API
+type GraphError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *GraphError) Error() string {
+	return fmt.Sprintf("microsoft graph: status %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+type graphDateTime struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone"`
+}
+
+type graphItemBody struct {
+	ContentType string `json:"contentType"`
+	Content     string `json:"content"`
+}
+
+type graphRemoved struct {
+	Reason string `json:"reason"`
+}
+
+type graphEvent struct {
+	ID                         string         `json:"id,omitempty"`
+	Subject                    string         `json:"subject,omitempty"`
+	Body                       *graphItemBody `json:"body,omitempty"`
+	Start                      *graphDateTime `json:"start,omitempty"`
+	End                        *graphDateTime `json:"end,omitempty"`
+	ShowAs                     string         `json:"showAs,omitempty"`
+	IsAllDay                   bool           `json:"isAllDay,omitempty"`
+	IsCancelled                bool           `json:"isCancelled,omitempty"`
+	IsReminderOn               bool           `json:"isReminderOn"`
+	ReminderMinutesBeforeStart int            `json:"reminderMinutesBeforeStart"`
+	Categories                 []string       `json:"categories,omitempty"`
+	Removed                    *graphRemoved  `json:"@removed,omitempty"`
+}
+
+type graphEventPage struct {
+	Value     []graphEvent `json:"value"`
+	NextLink  string       `json:"@odata.nextLink"`
+	DeltaLink string       `json:"@odata.deltaLink"`
+}
+
+type graphCalendar struct {
+	ID    string `json:"id,omitempty"`
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+}
+
+type graphCalendarPage struct {
+	Value    []graphCalendar `json:"value"`
+	NextLink string          `json:"@odata.nextLink"`
+}
+
+type graphSubscription struct {
+	ID                 string `json:"id,omitempty"`
+	ChangeType         string `json:"changeType,omitempty"`
+	NotificationURL    string `json:"notificationUrl,omitempty"`
+	Resource           string `json:"resource,omitempty"`
+	ExpirationDateTime string `json:"expirationDateTime"`
+	ClientState        string `json:"clientState,omitempty"`
+}
+
+// NewMicrosoftCalendarRepository constructs a MicrosoftCalendarRepository, only use CalendarRepositoryManager for this
+func NewMicrosoftCalendarRepository(ctx context.Context, userID primitive.ObjectID, connection *users.CalendarConnection, logger logger.Interface, updateConnectionFunction UpdateConnection) (*MicrosoftCalendarRepository, error) {
+	config, err := microsoft.ReadMicrosoftConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return newMicrosoftCalendarRepository(ctx, config, microsoft.GraphBaseURL(), userID, connection, logger, updateConnectionFunction)
+}
+
+func newMicrosoftCalendarRepository(ctx context.Context, config *oauth2.Config, graphBaseURL string, userID primitive.ObjectID, connection *users.CalendarConnection, logger logger.Interface, updateConnectionFunction UpdateConnection) (*MicrosoftCalendarRepository, error) {
+	newRepo := MicrosoftCalendarRepository{}
+
+	newRepo.Config = config
+	newRepo.Logger = logger
+	newRepo.graphBaseURL = strings.TrimSuffix(graphBaseURL, "/")
+	newRepo.connection = connection
+	newRepo.userID = userID
+	newRepo.updateConnectionFunction = updateConnectionFunction
+
+	if connection.Token.AccessToken == "" {
+		return nil, communication.ErrCalendarAuthInvalid
+	}
+
+	if connection.Token.Expiry.Before(time.Now()) {
+		source := newRepo.Config.TokenSource(ctx, &connection.Token)
+		newToken, err := source.Token()
+		if err != nil {
+			return nil, newRepo.checkForInvalidTokenError(err)
+		}
+		connection.Token = *newToken
+	}
+
+	newRepo.Client = newRepo.Config.Client(ctx, &connection.Token)
+
+	newRepo.apiBaseURL = "http://localhost"
+	envBaseURL, ok := os.LookupEnv("BASE_URL")
+	if ok {
+		newRepo.apiBaseURL = envBaseURL
+	}
+
+	return &newRepo, nil
+}
+
+func (c *MicrosoftCalendarRepository) checkForInvalidTokenError(err error) error {
+	isInvalid := false
+
+	if err == nil {
+		return err
+	}
+
+	c.Logger.Debug(err.Error())
+
+	graphError, isGraphError := err.(*GraphError)
+
+	if isGraphError && graphError != nil {
+		if graphError.StatusCode == 401 || graphError.StatusCode == 403 {
+			isInvalid = true
+		} else {
+			return errors.Wrap(graphError, "microsoft graph api error")
+		}
+
+	} else if err != nil && strings.Contains(err.Error(), "token") {
+		isInvalid = true
+	}
+
+	if isInvalid {
+		if c.updateConnectionFunction != nil {
+			c.connection.Status = users.CalendarConnectionStatusExpired
+			c.updateConnectionFunction(c.connection)
+		}
+
+		return errors.WithStack(communication.ErrCalendarAuthInvalid)
+	}
+
+	return errors.WithStack(err)
+}
+
+func checkForGraphStatus(err error, statusCodes ...int) bool {
+	graphError, ok := err.(*GraphError)
+	if !ok {
+		return false
+	}
+
+	for _, statusCode := range statusCodes {
+		if graphError.StatusCode == statusCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// do sends a request to Microsoft Graph, path can either be relative to the base url or a full next or delta link
+func (c *MicrosoftCalendarRepository) do(method string, path string, body interface{}, result interface{}) error {
+	requestURL := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		requestURL = c.graphBaseURL + path
+	}
+
+	var requestBody io.Reader
+	if body != nil {
+		binary, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		requestBody = bytes.NewReader(binary)
+	}
+
+	req, err := http.NewRequest(method, requestURL, requestBody)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Prefer", `outlook.timezone="UTC"`)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		graphError := GraphError{StatusCode: resp.StatusCode}
+
+		var errorResponse struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+
+		binary, _ := ioutil.ReadAll(resp.Body)
+		if json.Unmarshal(binary, &errorResponse) == nil {
+			graphError.Code = errorResponse.Error.Code
+			graphError.Message = errorResponse.Error.Message
+		}
+
+		return &graphError
+	}
+
+	if result == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// updateUserConnection writes the connection of the repository back into the user
+func (c *MicrosoftCalendarRepository) updateUserConnection(user *users.User) {
+	for i, connection := range user.MicrosoftCalendarConnections {
+		if connection.ID == c.connection.ID {
+			user.MicrosoftCalendarConnections[i] = *c.connection
+		}
+	}
+}
+
+func (c *MicrosoftCalendarRepository) createCalendar() (string, error) {
+	var cal graphCalendar
+
+	err := c.do(http.MethodPost, "/me/calendars", &graphCalendar{Name: "Timeliness Tasks", Color: "lightBlue"}, &cal)
+	if err != nil {
+		return "", c.checkForInvalidTokenError(err)
+	}
+
+	return cal.ID, nil
+}
+
+// TestTaskCalendarExistence checks if the task calendar still exists and creates a new one if it doesn't
+func (c *MicrosoftCalendarRepository) TestTaskCalendarExistence(u *users.User) (*users.User, error) {
+	if !c.connection.IsTaskCalendarConnection {
+		return u, nil
+	}
+
+	createCalendar := false
+
+	if c.connection.TaskCalendarID == "" {
+		createCalendar = true
+	} else {
+		err := c.do(http.MethodGet, fmt.Sprintf("/me/calendars/%s", url.PathEscape(c.connection.TaskCalendarID)), nil, nil)
+		if err != nil {
+			if errors.Cause(c.checkForInvalidTokenError(err)) == communication.ErrCalendarAuthInvalid {
+				return nil, communication.ErrCalendarAuthInvalid
+			}
+
+			createCalendar = true
+		}
+	}
+
+	if createCalendar {
+		calendarID, err := c.createCalendar()
+		if err != nil {
+			return nil, err
+		}
+
+		if c.connection.TaskCalendarID != "" {
+			c.connection.CalendarsOfInterest = c.connection.CalendarsOfInterest.RemoveCalendar(c.connection.TaskCalendarID)
+		}
+
+		c.connection.TaskCalendarID = calendarID
+
+		c.connection.CalendarsOfInterest = append(c.connection.CalendarsOfInterest,
+			users.CalendarSync{CalendarID: calendarID})
+
+		c.updateUserConnection(u)
+	} else if !c.connection.CalendarsOfInterest.HasCalendarWithID(c.connection.TaskCalendarID) {
+		c.connection.CalendarsOfInterest = append(c.connection.CalendarsOfInterest,
+			users.CalendarSync{CalendarID: c.connection.TaskCalendarID})
+
+		c.updateUserConnection(u)
+	}
+
+	return u, nil
+}
+
+// GetAllCalendarsOfInterest retrieves all calendars from Outlook
+func (c *MicrosoftCalendarRepository) GetAllCalendarsOfInterest() (map[string]*Calendar, error) {
+	var calendars = make(map[string]*Calendar)
+
+	path := "/me/calendars?$select=id,name"
+	for path != "" {
+		var page graphCalendarPage
+
+		err := c.do(http.MethodGet, path, nil, &page)
+		if err != nil {
+			return calendars, c.checkForInvalidTokenError(err)
+		}
+
+		for _, cal := range page.Value {
+			if c.connection.TaskCalendarID == cal.ID {
+				continue
+			}
+			calendars[cal.ID] = &Calendar{CalendarID: cal.ID, Name: cal.Name}
+		}
+
+		path = page.NextLink
+	}
+
+	return calendars, nil
+}
+
+// NewEvent creates a new Event in Outlook
+func (c *MicrosoftCalendarRepository) NewEvent(event *Event, taskID string, title string, description string, withReminder bool) (*Event, error) {
+	var createdEvent graphEvent
+
+	err := c.do(http.MethodPost, fmt.Sprintf("/me/calendars/%s/events", url.PathEscape(c.connection.TaskCalendarID)),
+		eventToGraphEvent(event, title, description, withReminder), &createdEvent)
+	if err != nil {
+		return nil, c.checkForInvalidTokenError(err)
+	}
+
+	calEvent := PersistedEvent{
+		CalendarEventID: createdEvent.ID,
+		CalendarType:    PersistedCalendarTypeMicrosoftCalendar,
+		UserID:          c.userID,
+	}
+
+	event.CalendarEvents = append(event.CalendarEvents, calEvent)
+
+	return event, nil
+}
+
+// UpdateEvent updates an existing Outlook event
+func (c *MicrosoftCalendarRepository) UpdateEvent(event *Event, taskID string, title string, description string, withReminder bool) error {
+	calendarEvent := event.CalendarEvents.FindByUserID(c.userID.Hex())
+	if calendarEvent == nil {
+		return errors.Errorf("no calendar event found for user %s", c.userID.Hex())
+	}
+
+	err := c.do(http.MethodPatch, fmt.Sprintf("/me/events/%s", url.PathEscape(calendarEvent.CalendarEventID)),
+		eventToGraphEvent(event, title, description, withReminder), nil)
+	if err != nil {
+		return c.checkForInvalidTokenError(err)
+	}
+
+	return nil
+}
+
+// DeleteEvent deletes a single Event
+func (c *MicrosoftCalendarRepository) DeleteEvent(event *Event) error {
+	calendarEvent := event.CalendarEvents.FindByUserID(c.userID.Hex())
+	if calendarEvent == nil {
+		return fmt.Errorf("persisted calendar event for user %s could not be found while deleting event", c.userID.Hex())
+	}
+
+	err := c.do(http.MethodDelete, fmt.Sprintf("/me/events/%s", url.PathEscape(calendarEvent.CalendarEventID)), nil, nil)
+	if err != nil {
+		if checkForGraphStatus(err, http.StatusNotFound, http.StatusGone) {
+			return nil
+		}
+
+		return c.checkForInvalidTokenError(err)
+	}
+
+	return nil
+}
+
+// AddBusyToWindow reads times from a window and fills it with busy timeslots, it takes all set availability calendars apart from the task calendar into account
+func (c *MicrosoftCalendarRepository) AddBusyToWindow(window *date.TimeWindow, start time.Time, end time.Time) error {
+	calList := c.connection.CalendarsOfInterest
+
+	if c.connection.IsTaskCalendarConnection {
+		calList = calList.RemoveCalendar(c.connection.TaskCalendarID)
+	}
+
+	for _, cal := range calList {
+		query := url.Values{}
+		query.Set("startDateTime", start.UTC().Format(time.RFC3339))
+		query.Set("endDateTime", end.UTC().Format(time.RFC3339))
+		query.Set("$select", "start,end,showAs,isCancelled,isAllDay")
+
+		path := fmt.Sprintf("/me/calendars/%s/calendarView?%s", url.PathEscape(cal.CalendarID), query.Encode())
+		for path != "" {
+			var page graphEventPage
+
+			err := c.do(http.MethodGet, path, nil, &page)
+			if err != nil {
+				return c.checkForInvalidTokenError(err)
+			}
+
+			for _, item := range page.Value {
+				if item.IsCancelled || !isGraphEventBlocking(&item) {
+					continue
+				}
+
+				timespan, err := graphEventTimespan(&item)
+				if err != nil {
+					return err
+				}
+
+				window.AddToBusy(date.Timespan{Start: timespan.Start.UTC(), End: timespan.End.UTC()})
+			}
+
+			path = page.NextLink
+		}
+	}
+
+	return nil
+}
+
+// WatchCalendar creates or renews a Graph subscription for the events of a calendar
+func (c *MicrosoftCalendarRepository) WatchCalendar(calendarID string, user *users.User) (*users.User, error) {
+	index := findSyncByID(c.connection, calendarID)
+	if index == -1 {
+		return nil, errors.New("calendar id could not be found in calendars of interest")
+	}
+
+	if c.connection.CalendarsOfInterest[index].Expiration.After(time.Now().Add(GoogleNotificationExpirationOffset)) || c.connection.CalendarsOfInterest[index].IsNotSyncable {
+		return user, nil
+	}
+
+	expiration := time.Now().Add(MicrosoftSubscriptionDuration).UTC()
+
+	if c.connection.CalendarsOfInterest[index].SyncResourceID != "" {
+		var renewed graphSubscription
+
+		err := c.do(http.MethodPatch, fmt.Sprintf("/subscriptions/%s", url.PathEscape(c.connection.CalendarsOfInterest[index].SyncResourceID)),
+			&graphSubscription{ExpirationDateTime: expiration.Format(time.RFC3339)}, &renewed)
+		if err == nil {
+			c.connection.CalendarsOfInterest[index].Expiration = expiration
+			c.updateUserConnection(user)
+
+			return user, nil
+		}
+
+		c.Logger.Warning("Bad response on renewing a microsoft subscription, creating a new one", err)
+	}
+
+	subscription := graphSubscription{
+		ChangeType:         "created,updated,deleted",
+		NotificationURL:    fmt.Sprintf("%s/v1/calendar/microsoft/notifications", c.apiBaseURL),
+		Resource:           fmt.Sprintf("me/calendars/%s/events", calendarID),
+		ExpirationDateTime: expiration.Format(time.RFC3339),
+		ClientState:        encryption.Encrypt(c.userID.Hex()),
+	}
+
+	var response graphSubscription
+
+	err := c.do(http.MethodPost, "/subscriptions", &subscription, &response)
+	if err != nil {
+		if checkForGraphStatus(err, http.StatusBadRequest) {
+			return user, errors.WithStack(ErrNonSyncable)
+		}
+
+		return user, c.checkForInvalidTokenError(err)
+	}
+
+	if response.ID == "" {
+		c.Logger.Warning(fmt.Sprintf("Subscription id is empty for user %s", c.userID), errors.New("subscription id is empty"))
+	}
+
+	c.connection.CalendarsOfInterest[index].SyncResourceID = response.ID
+	c.connection.CalendarsOfInterest[index].Expiration = expiration
+
+	c.updateUserConnection(user)
+
+	return user, nil
+}
+
+// StopWatchingCalendar deletes the Graph subscription of a calendar
+func (c *MicrosoftCalendarRepository) StopWatchingCalendar(calendarID string, user *users.User) (*users.User, error) {
+	index := findSyncByID(c.connection, calendarID)
+	if index == -1 {
+		return nil, errors.New("calendar id could not be found in calendars of interest")
+	}
+
+	if c.connection.CalendarsOfInterest[index].SyncResourceID == "" || c.connection.CalendarsOfInterest[index].IsNotSyncable {
+		return user, nil
+	}
+
+	err := c.do(http.MethodDelete, fmt.Sprintf("/subscriptions/%s", url.PathEscape(c.connection.CalendarsOfInterest[index].SyncResourceID)), nil, nil)
+	if err != nil && !checkForGraphStatus(err, http.StatusNotFound) {
+		return nil, err
+	}
+
+	c.connection.CalendarsOfInterest[index].SyncResourceID = ""
+	c.connection.CalendarsOfInterest[index].Expiration = time.Unix(0, 0)
+
+	c.updateUserConnection(user)
+
+	return user, nil
+}
+
+// SyncEvents syncs the calendar events of a single calendar with a delta query
+func (c *MicrosoftCalendarRepository) SyncEvents(calendarID string, user *users.User,
+	eventChannel *chan *Event,
+	errorChannel *chan error,
+	userChannel *chan *users.User) {
+
+	defer close(*eventChannel)
+	defer close(*errorChannel)
+	defer close(*userChannel)
+
+	now := time.Now()
+	syncIndex := findSyncByID(c.connection, calendarID)
+	if syncIndex == -1 {
+		*errorChannel <- errors.New("calendar id could not be found in calendars of interest")
+		return
+	}
+
+	path := c.connection.CalendarsOfInterest[syncIndex].SyncToken
+	if path == "" || !c.connection.CalendarsOfInterest[syncIndex].Expiration.After(now) {
+		query := url.Values{}
+		query.Set("startDateTime", now.UTC().Format(time.RFC3339))
+		query.Set("endDateTime", now.Add(time.Hour*24*31*6).UTC().Format(time.RFC3339)) // 6 months from now
+
+		path = fmt.Sprintf("/me/calendars/%s/calendarView/delta?%s", url.PathEscape(calendarID), query.Encode())
+	}
+
+	for {
+		var response graphEventPage
+
+		err := c.do(http.MethodGet, path, nil, &response)
+		if err != nil {
+			if checkForGraphStatus(err, http.StatusGone) {
+				c.connection.CalendarsOfInterest[syncIndex].SyncToken = ""
+				c.updateUserConnection(user)
+
+				*userChannel <- user
+				return
+			}
+
+			*errorChannel <- errors.WithStack(err)
+			return
+		}
+
+		for _, item := range response.Value {
+			if item.Removed != nil || item.IsCancelled {
+				*eventChannel <- &Event{
+					CalendarEvents: []PersistedEvent{
+						{
+							CalendarEventID: item.ID,
+							CalendarType:    PersistedCalendarTypeMicrosoftCalendar,
+							UserID:          c.userID,
+						},
+					},
+					Deleted: true,
+				}
+				continue
+			}
+
+			event, err := c.graphEventToEvent(&item)
+			if err != nil {
+				*errorChannel <- errors.WithStack(err)
+				return
+			}
+
+			*eventChannel <- event
+		}
+
+		if response.DeltaLink != "" {
+			c.connection.CalendarsOfInterest[syncIndex].SyncToken = response.DeltaLink
+			break
+		}
+
+		if response.NextLink == "" {
+			*errorChannel <- errors.New("neither delta link nor next link found")
+			return
+		}
+
+		path = response.NextLink
+	}
+
+	c.updateUserConnection(user)
+
+	*userChannel <- user
+}
+
+func (c *MicrosoftCalendarRepository) graphEventToEvent(item *graphEvent) (*Event, error) {
+	newEvent := &Event{
+		Blocking: isGraphEventBlocking(item),
+		CalendarEvents: []PersistedEvent{
+			{
+				CalendarEventID: item.ID,
+				CalendarType:    PersistedCalendarTypeMicrosoftCalendar,
+				UserID:          c.userID,
+			},
+		},
+	}
+
+	for _, category := range item.Categories {
+		if category == microsoftCategory {
+			newEvent.IsOriginal = true
+		}
+	}
+
+	timespan, err := graphEventTimespan(item)
+	if err != nil {
+		return nil, err
+	}
+
+	newEvent.Date = *timespan
+
+	return newEvent, nil
+}
+
+func isGraphEventBlocking(item *graphEvent) bool {
+	return item.ShowAs != "free" && item.ShowAs != "workingElsewhere"
+}
+
+func graphEventTimespan(item *graphEvent) (*date.Timespan, error) {
+	if item.Start == nil || item.End == nil {
+		return nil, errors.New("start or end is missing in microsoft event")
+	}
+
+	start, err := parseGraphDateTime(item.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := parseGraphDateTime(item.End)
+	if err != nil {
+		return nil, err
+	}
+
+	return &date.Timespan{Start: start, End: end}, nil
+}
+
+func parseGraphDateTime(dateTime *graphDateTime) (time.Time, error) {
+	location, err := time.LoadLocation(dateTime.TimeZone)
+	if err != nil || dateTime.TimeZone == "" {
+		location = time.UTC
+	}
+
+	return time.ParseInLocation("2006-01-02T15:04:05.9999999", dateTime.DateTime, location)
+}
+
+func eventToGraphEvent(event *Event, title string, description string, withReminder bool) *graphEvent {
+	showAs := "busy"
+	if !event.Blocking {
+		showAs = "free"
+	}
+
+	return &graphEvent{
+		Subject: title,
+		Body:    &graphItemBody{ContentType: "text", Content: description},
+		Start: &graphDateTime{
+			DateTime: event.Date.Start.UTC().Format(graphDateTimeLayout),
+			TimeZone: "UTC",
+		},
+		End: &graphDateTime{
+			DateTime: event.Date.End.UTC().Format(graphDateTimeLayout),
+			TimeZone: "UTC",
+		},
+		ShowAs:                     showAs,
+		IsReminderOn:               withReminder,
+		ReminderMinutesBeforeStart: 0,
+		Categories:                 []string{microsoftCategory},
+	}
+}
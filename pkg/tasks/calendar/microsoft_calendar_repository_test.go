@@ -0,0 +1,684 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/timeliness-app/timeliness-backend/pkg/auth/encryption"
+	"github.com/timeliness-app/timeliness-backend/pkg/date"
+	"github.com/timeliness-app/timeliness-backend/pkg/logger"
+	"github.com/timeliness-app/timeliness-backend/pkg/users"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGraph is an in-memory Microsoft Graph server that supports the calls the repository makes
+type fakeGraph struct {
+	server        *httptest.Server
+	accessToken   string
+	mutex         sync.Mutex
+	nextID        int
+	calendars     map[string]*graphCalendar
+	events        map[string]*graphEvent
+	eventCalendar map[string]string
+	// changes is a log of changed event ids per calendar, a delta token is an index into it
+	changes       map[string][]string
+	subscriptions map[string]*graphSubscription
+	goneTokens    map[string]bool
+}
+
+func newFakeGraph() *fakeGraph {
+	g := &fakeGraph{
+		accessToken:   "valid-token",
+		calendars:     make(map[string]*graphCalendar),
+		events:        make(map[string]*graphEvent),
+		eventCalendar: make(map[string]string),
+		changes:       make(map[string][]string),
+		subscriptions: make(map[string]*graphSubscription),
+		goneTokens:    make(map[string]bool),
+	}
+
+	r := mux.NewRouter()
+	r.Path("/token").HandlerFunc(g.token).Methods(http.MethodPost)
+
+	api := r.PathPrefix("/v1.0").Subrouter()
+	api.Use(g.authenticate)
+	api.Path("/me/calendars").HandlerFunc(g.listCalendars).Methods(http.MethodGet)
+	api.Path("/me/calendars").HandlerFunc(g.createCalendar).Methods(http.MethodPost)
+	api.Path("/me/calendars/{calendarID}").HandlerFunc(g.getCalendar).Methods(http.MethodGet)
+	api.Path("/me/calendars/{calendarID}/events").HandlerFunc(g.createEvent).Methods(http.MethodPost)
+	api.Path("/me/calendars/{calendarID}/calendarView").HandlerFunc(g.calendarView).Methods(http.MethodGet)
+	api.Path("/me/calendars/{calendarID}/calendarView/delta").HandlerFunc(g.delta).Methods(http.MethodGet)
+	api.Path("/me/events/{eventID}").HandlerFunc(g.updateEvent).Methods(http.MethodPatch)
+	api.Path("/me/events/{eventID}").HandlerFunc(g.deleteEvent).Methods(http.MethodDelete)
+	api.Path("/subscriptions").HandlerFunc(g.createSubscription).Methods(http.MethodPost)
+	api.Path("/subscriptions/{subscriptionID}").HandlerFunc(g.renewSubscription).Methods(http.MethodPatch)
+	api.Path("/subscriptions/{subscriptionID}").HandlerFunc(g.deleteSubscription).Methods(http.MethodDelete)
+
+	g.server = httptest.NewServer(r)
+
+	return g
+}
+
+func (g *fakeGraph) newID() string {
+	g.nextID++
+	return fmt.Sprintf("id-%d", g.nextID)
+}
+
+func (g *fakeGraph) respond(writer http.ResponseWriter, status int, body interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	if body != nil {
+		_ = json.NewEncoder(writer).Encode(body)
+	}
+}
+
+func (g *fakeGraph) respondWithError(writer http.ResponseWriter, status int, code string) {
+	g.respond(writer, status, map[string]interface{}{"error": map[string]string{"code": code, "message": code}})
+}
+
+func (g *fakeGraph) token(writer http.ResponseWriter, request *http.Request) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if request.FormValue("refresh_token") != "refresh-token" {
+		g.respond(writer, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	g.accessToken = "refreshed-token"
+	g.respond(writer, http.StatusOK, map[string]interface{}{
+		"access_token":  g.accessToken,
+		"refresh_token": "refresh-token",
+		"token_type":    "Bearer",
+		"expires_in":    3600,
+	})
+}
+
+func (g *fakeGraph) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		g.mutex.Lock()
+		valid := request.Header.Get("Authorization") == "Bearer "+g.accessToken
+		g.mutex.Unlock()
+
+		if !valid {
+			g.respondWithError(writer, http.StatusUnauthorized, "InvalidAuthenticationToken")
+			return
+		}
+
+		if request.Header.Get("Prefer") != `outlook.timezone="UTC"` {
+			g.respondWithError(writer, http.StatusBadRequest, "MissingTimezonePreference")
+			return
+		}
+
+		next.ServeHTTP(writer, request)
+	})
+}
+
+func (g *fakeGraph) addCalendar(name string) string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	id := g.newID()
+	g.calendars[id] = &graphCalendar{ID: id, Name: name}
+
+	return id
+}
+
+func (g *fakeGraph) addEvent(calendarID string, event graphEvent) string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	event.ID = g.newID()
+	g.events[event.ID] = &event
+	g.eventCalendar[event.ID] = calendarID
+	g.changes[calendarID] = append(g.changes[calendarID], event.ID)
+
+	return event.ID
+}
+
+func (g *fakeGraph) removeEvent(eventID string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	calendarID := g.eventCalendar[eventID]
+	delete(g.events, eventID)
+	g.changes[calendarID] = append(g.changes[calendarID], eventID)
+}
+
+func (g *fakeGraph) listCalendars(writer http.ResponseWriter, request *http.Request) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	var calendars []graphCalendar
+	for _, cal := range g.calendars {
+		calendars = append(calendars, *cal)
+	}
+
+	g.respond(writer, http.StatusOK, graphCalendarPage{Value: calendars})
+}
+
+func (g *fakeGraph) createCalendar(writer http.ResponseWriter, request *http.Request) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	var cal graphCalendar
+	_ = json.NewDecoder(request.Body).Decode(&cal)
+	cal.ID = g.newID()
+	g.calendars[cal.ID] = &cal
+
+	g.respond(writer, http.StatusCreated, cal)
+}
+
+func (g *fakeGraph) getCalendar(writer http.ResponseWriter, request *http.Request) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	cal, ok := g.calendars[mux.Vars(request)["calendarID"]]
+	if !ok {
+		g.respondWithError(writer, http.StatusNotFound, "ErrorItemNotFound")
+		return
+	}
+
+	g.respond(writer, http.StatusOK, cal)
+}
+
+func (g *fakeGraph) createEvent(writer http.ResponseWriter, request *http.Request) {
+	calendarID := mux.Vars(request)["calendarID"]
+
+	var event graphEvent
+	_ = json.NewDecoder(request.Body).Decode(&event)
+
+	id := g.addEvent(calendarID, event)
+	event.ID = id
+
+	g.respond(writer, http.StatusCreated, event)
+}
+
+func (g *fakeGraph) updateEvent(writer http.ResponseWriter, request *http.Request) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	eventID := mux.Vars(request)["eventID"]
+	event, ok := g.events[eventID]
+	if !ok {
+		g.respondWithError(writer, http.StatusNotFound, "ErrorItemNotFound")
+		return
+	}
+
+	_ = json.NewDecoder(request.Body).Decode(event)
+	event.ID = eventID
+	g.changes[g.eventCalendar[eventID]] = append(g.changes[g.eventCalendar[eventID]], eventID)
+
+	g.respond(writer, http.StatusOK, event)
+}
+
+func (g *fakeGraph) deleteEvent(writer http.ResponseWriter, request *http.Request) {
+	eventID := mux.Vars(request)["eventID"]
+
+	g.mutex.Lock()
+	_, ok := g.events[eventID]
+	g.mutex.Unlock()
+
+	if !ok {
+		g.respondWithError(writer, http.StatusNotFound, "ErrorItemNotFound")
+		return
+	}
+
+	g.removeEvent(eventID)
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (g *fakeGraph) calendarView(writer http.ResponseWriter, request *http.Request) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	calendarID := mux.Vars(request)["calendarID"]
+	start, _ := time.Parse(time.RFC3339, request.URL.Query().Get("startDateTime"))
+	end, _ := time.Parse(time.RFC3339, request.URL.Query().Get("endDateTime"))
+
+	var events []graphEvent
+	for id, event := range g.events {
+		if g.eventCalendar[id] != calendarID {
+			continue
+		}
+
+		timespan, _ := graphEventTimespan(event)
+		if timespan.Start.Before(end) && timespan.End.After(start) {
+			events = append(events, *event)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID < events[j].ID
+	})
+
+	// Every event gets its own page, so that paging is tested as well
+	page := graphEventPage{}
+	skip, _ := strconv.Atoi(request.URL.Query().Get("$skip"))
+	if skip < len(events) {
+		page.Value = events[skip : skip+1]
+	}
+	if skip+1 < len(events) {
+		query := request.URL.Query()
+		query.Set("$skip", strconv.Itoa(skip+1))
+		page.NextLink = fmt.Sprintf("%s%s?%s", g.server.URL, request.URL.Path, query.Encode())
+	}
+
+	g.respond(writer, http.StatusOK, page)
+}
+
+func (g *fakeGraph) delta(writer http.ResponseWriter, request *http.Request) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	calendarID := mux.Vars(request)["calendarID"]
+	deltaToken := request.URL.Query().Get("$deltatoken")
+
+	if g.goneTokens[deltaToken] {
+		g.respondWithError(writer, http.StatusGone, "SyncStateNotFound")
+		return
+	}
+
+	from, _ := strconv.Atoi(deltaToken)
+	changes := g.changes[calendarID][from:]
+
+	page := graphEventPage{}
+	seen := make(map[string]bool)
+	for _, id := range changes {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		event, ok := g.events[id]
+		if !ok {
+			page.Value = append(page.Value, graphEvent{ID: id, Removed: &graphRemoved{Reason: "deleted"}})
+			continue
+		}
+
+		page.Value = append(page.Value, *event)
+	}
+
+	page.DeltaLink = fmt.Sprintf("%s%s?$deltatoken=%d", g.server.URL, request.URL.Path, len(g.changes[calendarID]))
+
+	g.respond(writer, http.StatusOK, page)
+}
+
+func (g *fakeGraph) createSubscription(writer http.ResponseWriter, request *http.Request) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	var subscription graphSubscription
+	_ = json.NewDecoder(request.Body).Decode(&subscription)
+	subscription.ID = g.newID()
+	g.subscriptions[subscription.ID] = &subscription
+
+	g.respond(writer, http.StatusCreated, subscription)
+}
+
+func (g *fakeGraph) renewSubscription(writer http.ResponseWriter, request *http.Request) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	subscription, ok := g.subscriptions[mux.Vars(request)["subscriptionID"]]
+	if !ok {
+		g.respondWithError(writer, http.StatusNotFound, "ResourceNotFound")
+		return
+	}
+
+	var update graphSubscription
+	_ = json.NewDecoder(request.Body).Decode(&update)
+	subscription.ExpirationDateTime = update.ExpirationDateTime
+
+	g.respond(writer, http.StatusOK, subscription)
+}
+
+func (g *fakeGraph) deleteSubscription(writer http.ResponseWriter, request *http.Request) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	subscriptionID := mux.Vars(request)["subscriptionID"]
+	if _, ok := g.subscriptions[subscriptionID]; !ok {
+		g.respondWithError(writer, http.StatusNotFound, "ResourceNotFound")
+		return
+	}
+
+	delete(g.subscriptions, subscriptionID)
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func graphTime(t time.Time) *graphDateTime {
+	return &graphDateTime{DateTime: t.UTC().Format(graphDateTimeLayout), TimeZone: "UTC"}
+}
+
+func setupMicrosoftTest(t *testing.T, token oauth2.Token) (*fakeGraph, *users.User, *MicrosoftCalendarRepository) {
+	graph := newFakeGraph()
+	t.Cleanup(graph.server.Close)
+
+	user := &users.User{
+		ID: primitive.NewObjectID(),
+		MicrosoftCalendarConnections: users.CalendarConnections{
+			{
+				ID:                       "microsoft-account",
+				Status:                   users.CalendarConnectionStatusActive,
+				IsTaskCalendarConnection: true,
+				Token:                    token,
+			},
+		},
+	}
+
+	config := &oauth2.Config{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Endpoint:     oauth2.Endpoint{TokenURL: graph.server.URL + "/token", AuthStyle: oauth2.AuthStyleInParams},
+	}
+
+	connection := user.MicrosoftCalendarConnections[0]
+	repository, err := newMicrosoftCalendarRepository(context.Background(), config, graph.server.URL+"/v1.0", user.ID,
+		&connection, logger.Logger{}, func(connection *users.CalendarConnection) {
+			user.MicrosoftCalendarConnections[0] = *connection
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return graph, user, repository
+}
+
+func TestMicrosoftCalendarRepository_TaskEvents(t *testing.T) {
+	// The expired token has to be refreshed before the first request
+	graph, user, repository := setupMicrosoftTest(t, oauth2.Token{
+		AccessToken:  "expired-token",
+		RefreshToken: "refresh-token",
+		Expiry:       time.Now().Add(-time.Hour),
+	})
+
+	if repository.connection.Token.AccessToken != "refreshed-token" {
+		t.Fatalf("got access token %s, want refreshed-token", repository.connection.Token.AccessToken)
+	}
+
+	user, err := repository.TestTaskCalendarExistence(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	taskCalendarID := user.MicrosoftCalendarConnections[0].TaskCalendarID
+	if graph.calendars[taskCalendarID] == nil || graph.calendars[taskCalendarID].Name != "Timeliness Tasks" {
+		t.Fatalf("task calendar %s wasn't created", taskCalendarID)
+	}
+
+	if !user.MicrosoftCalendarConnections[0].CalendarsOfInterest.HasCalendarWithID(taskCalendarID) {
+		t.Error("task calendar isn't part of the calendars of interest")
+	}
+
+	event := &Event{
+		Date: date.Timespan{
+			Start: time.Date(2021, 1, 4, 9, 0, 0, 0, time.UTC),
+			End:   time.Date(2021, 1, 4, 10, 0, 0, 0, time.UTC),
+		},
+		Blocking: true,
+	}
+
+	event, err = repository.NewEvent(event, "task", "Write report", "Description", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	persisted := event.CalendarEvents.FindByUserID(user.ID.Hex())
+	if persisted == nil || persisted.CalendarType != PersistedCalendarTypeMicrosoftCalendar {
+		t.Fatalf("got persisted events %v, want one microsoft event", event.CalendarEvents)
+	}
+
+	created := graph.events[persisted.CalendarEventID]
+	if created == nil || graph.eventCalendar[created.ID] != taskCalendarID {
+		t.Fatalf("event %s wasn't created in the task calendar", persisted.CalendarEventID)
+	}
+
+	if created.Subject != "Write report" || created.ShowAs != "busy" || !created.IsReminderOn || created.Categories[0] != microsoftCategory {
+		t.Errorf("got created event %+v", created)
+	}
+
+	event.Date.Start = time.Date(2021, 1, 4, 11, 0, 0, 0, time.UTC)
+	event.Date.End = time.Date(2021, 1, 4, 12, 0, 0, 0, time.UTC)
+	event.Blocking = false
+
+	err = repository.UpdateEvent(event, "task", "Write report (updated)", "Description", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated := graph.events[persisted.CalendarEventID]
+	timespan, err := graphEventTimespan(updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !timespan.Start.Equal(event.Date.Start) || !timespan.End.Equal(event.Date.End) {
+		t.Errorf("got updated timespan %s, want %s", timespan, event.Date)
+	}
+
+	if updated.Subject != "Write report (updated)" || updated.ShowAs != "free" || updated.IsReminderOn {
+		t.Errorf("got updated event %+v", updated)
+	}
+
+	err = repository.DeleteEvent(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if graph.events[persisted.CalendarEventID] != nil {
+		t.Error("event wasn't deleted")
+	}
+
+	// Deleting an event that is already gone isn't an error
+	err = repository.DeleteEvent(event)
+	if err != nil {
+		t.Errorf("got error %v when deleting a deleted event", err)
+	}
+}
+
+func TestMicrosoftCalendarRepository_AddBusyToWindow(t *testing.T) {
+	graph, _, repository := setupMicrosoftTest(t, oauth2.Token{AccessToken: "valid-token", Expiry: time.Now().Add(time.Hour)})
+
+	workCalendarID := graph.addCalendar("Work")
+	taskCalendarID := graph.addCalendar("Timeliness Tasks")
+
+	repository.connection.TaskCalendarID = taskCalendarID
+	repository.connection.CalendarsOfInterest = users.CalendarSyncs{{CalendarID: workCalendarID}, {CalendarID: taskCalendarID}}
+
+	day := time.Date(2021, 1, 4, 0, 0, 0, 0, time.UTC)
+
+	graph.addEvent(workCalendarID, graphEvent{Start: graphTime(day.Add(time.Hour * 9)), End: graphTime(day.Add(time.Hour * 10)), ShowAs: "busy"})
+	graph.addEvent(workCalendarID, graphEvent{Start: graphTime(day.Add(time.Hour * 13)), End: graphTime(day.Add(time.Hour * 14)), ShowAs: "oof"})
+	graph.addEvent(workCalendarID, graphEvent{Start: graphTime(day.Add(time.Hour * 15)), End: graphTime(day.Add(time.Hour * 16)), ShowAs: "free"})
+	graph.addEvent(workCalendarID, graphEvent{Start: graphTime(day.Add(time.Hour * 16)), End: graphTime(day.Add(time.Hour * 17)), ShowAs: "busy", IsCancelled: true})
+	graph.addEvent(taskCalendarID, graphEvent{Start: graphTime(day.Add(time.Hour * 11)), End: graphTime(day.Add(time.Hour * 12)), ShowAs: "busy"})
+
+	window := date.TimeWindow{Start: day, End: day.Add(time.Hour * 24)}
+
+	err := repository.AddBusyToWindow(&window, window.Start, window.End)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []date.Timespan{
+		{Start: day.Add(time.Hour * 9), End: day.Add(time.Hour * 10)},
+		{Start: day.Add(time.Hour * 13), End: day.Add(time.Hour * 14)},
+	}
+
+	busy := window.Busy()
+	if len(busy) != len(want) {
+		t.Fatalf("got busy %v, want %v", busy, want)
+	}
+
+	for i := range want {
+		if !busy[i].Start.Equal(want[i].Start) || !busy[i].End.Equal(want[i].End) {
+			t.Errorf("got busy %s, want %s", busy[i], want[i])
+		}
+	}
+}
+
+func TestMicrosoftCalendarRepository_SyncEvents(t *testing.T) {
+	graph, user, repository := setupMicrosoftTest(t, oauth2.Token{AccessToken: "valid-token", Expiry: time.Now().Add(time.Hour)})
+
+	calendarID := graph.addCalendar("Work")
+	repository.connection.CalendarsOfInterest = users.CalendarSyncs{{CalendarID: calendarID, Expiration: time.Now().Add(time.Hour)}}
+	user.MicrosoftCalendarConnections[0] = *repository.connection
+
+	start := time.Now().Add(time.Hour * 24).UTC().Truncate(time.Minute)
+
+	meetingID := graph.addEvent(calendarID, graphEvent{Start: graphTime(start), End: graphTime(start.Add(time.Hour)), ShowAs: "busy"})
+	taskID := graph.addEvent(calendarID, graphEvent{Start: graphTime(start.Add(time.Hour * 2)), End: graphTime(start.Add(time.Hour * 3)), ShowAs: "busy", Categories: []string{microsoftCategory}})
+
+	syncCalendar := func() ([]*Event, *users.User, error) {
+		eventChannel := make(chan *Event)
+		errorChannel := make(chan error)
+		userChannel := make(chan *users.User)
+
+		go repository.SyncEvents(calendarID, user, &eventChannel, &errorChannel, &userChannel)
+
+		var events []*Event
+		for {
+			select {
+			case event := <-eventChannel:
+				events = append(events, event)
+			case err := <-errorChannel:
+				return nil, nil, err
+			case u := <-userChannel:
+				return events, u, nil
+			}
+		}
+	}
+
+	events, user, err := syncCalendar()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+
+	for _, event := range events {
+		switch event.CalendarEvents[0].CalendarEventID {
+		case meetingID:
+			if event.IsOriginal || !event.Blocking || !event.Date.Start.Equal(start) {
+				t.Errorf("got meeting %+v", event)
+			}
+		case taskID:
+			if !event.IsOriginal {
+				t.Error("event created by Timeliness isn't original")
+			}
+		default:
+			t.Errorf("got unknown event %s", event.CalendarEvents[0].CalendarEventID)
+		}
+	}
+
+	deltaLink := user.MicrosoftCalendarConnections[0].CalendarsOfInterest[0].SyncToken
+	if deltaLink == "" {
+		t.Fatal("delta link wasn't stored")
+	}
+
+	// Only the changes since the last sync are returned
+	graph.removeEvent(meetingID)
+
+	events, user, err = syncCalendar()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 1 || !events[0].Deleted || events[0].CalendarEvents[0].CalendarEventID != meetingID {
+		t.Fatalf("got events %v, want the deleted meeting", events)
+	}
+
+	// An expired delta link resets the sync
+	graph.goneTokens[strconv.Itoa(len(graph.changes[calendarID]))] = true
+
+	events, user, err = syncCalendar()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 0 || user.MicrosoftCalendarConnections[0].CalendarsOfInterest[0].SyncToken != "" {
+		t.Errorf("got %d events and sync token %s after the delta link expired, want none", len(events), user.MicrosoftCalendarConnections[0].CalendarsOfInterest[0].SyncToken)
+	}
+}
+
+func TestMicrosoftCalendarRepository_WatchCalendar(t *testing.T) {
+	graph, user, repository := setupMicrosoftTest(t, oauth2.Token{AccessToken: "valid-token", Expiry: time.Now().Add(time.Hour)})
+
+	calendarID := graph.addCalendar("Work")
+	repository.connection.CalendarsOfInterest = users.CalendarSyncs{{CalendarID: calendarID}}
+
+	user, err := repository.WatchCalendar(calendarID, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calendarSync := user.MicrosoftCalendarConnections[0].CalendarsOfInterest[0]
+	subscription := graph.subscriptions[calendarSync.SyncResourceID]
+	if subscription == nil {
+		t.Fatalf("subscription %s wasn't created", calendarSync.SyncResourceID)
+	}
+
+	if subscription.Resource != fmt.Sprintf("me/calendars/%s/events", calendarID) {
+		t.Errorf("got resource %s", subscription.Resource)
+	}
+
+	if encryption.Decrypt(subscription.ClientState) != user.ID.Hex() {
+		t.Error("client state doesn't contain the user")
+	}
+
+	if !calendarSync.Expiration.After(time.Now().Add(GoogleNotificationExpirationOffset)) {
+		t.Errorf("got expiration %s", calendarSync.Expiration)
+	}
+
+	// A subscription that is about to expire is renewed instead of created again
+	repository.connection.CalendarsOfInterest[0].Expiration = time.Now().Add(time.Hour)
+
+	user, err = repository.WatchCalendar(calendarID, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(graph.subscriptions) != 1 || user.MicrosoftCalendarConnections[0].CalendarsOfInterest[0].SyncResourceID != calendarSync.SyncResourceID {
+		t.Errorf("got %d subscriptions, want the renewed one", len(graph.subscriptions))
+	}
+
+	if !user.MicrosoftCalendarConnections[0].CalendarsOfInterest[0].Expiration.After(time.Now().Add(GoogleNotificationExpirationOffset)) {
+		t.Error("subscription wasn't renewed")
+	}
+
+	user, err = repository.StopWatchingCalendar(calendarID, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(graph.subscriptions) != 0 || user.MicrosoftCalendarConnections[0].CalendarsOfInterest[0].SyncResourceID != "" {
+		t.Error("subscription wasn't deleted")
+	}
+}
+
+func TestMicrosoftCalendarRepository_InvalidToken(t *testing.T) {
+	graph, user, repository := setupMicrosoftTest(t, oauth2.Token{AccessToken: "valid-token", Expiry: time.Now().Add(time.Hour)})
+
+	graph.accessToken = "revoked"
+
+	_, err := repository.GetAllCalendarsOfInterest()
+	if err == nil {
+		t.Fatal("expected an error with a revoked token")
+	}
+
+	if user.MicrosoftCalendarConnections[0].Status != users.CalendarConnectionStatusExpired {
+		t.Errorf("got status %s, want %s", user.MicrosoftCalendarConnections[0].Status, users.CalendarConnectionStatusExpired)
+	}
+}
@@ -0,0 +1,171 @@
+package calendar
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/timeliness-app/timeliness-backend/pkg/date"
+	"github.com/timeliness-app/timeliness-backend/pkg/logger"
+	"github.com/timeliness-app/timeliness-backend/pkg/users"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// nativeEvent is an event of the native task calendar as it is stored in the database
+type nativeEvent struct {
+	ID             primitive.ObjectID `bson:"_id"`
+	UserID         primitive.ObjectID `bson:"userId"`
+	TaskID         string             `bson:"taskId"`
+	Title          string             `bson:"title"`
+	Description    string             `bson:"description"`
+	Date           date.Timespan      `bson:"date"`
+	Blocking       bool               `bson:"blocking"`
+	WithReminder   bool               `bson:"withReminder"`
+	CreatedAt      time.Time          `bson:"createdAt"`
+	LastModifiedAt time.Time          `bson:"lastModifiedAt"`
+}
+
+// NativeCalendarRepository stores the task calendar of users without a task calendar connection in the database, so
+// tasks can be scheduled without connecting an external calendar. Its events are moved to the external task calendar
+// as soon as one is connected.
+type NativeCalendarRepository struct {
+	DB     *mongo.Collection
+	Logger logger.Interface
+	ctx    context.Context
+	userID primitive.ObjectID
+}
+
+// NewNativeCalendarRepository creates the native task calendar of a user
+func NewNativeCalendarRepository(ctx context.Context, userID primitive.ObjectID, db *mongo.Collection, logger logger.Interface) *NativeCalendarRepository {
+	return &NativeCalendarRepository{
+		DB:     db,
+		Logger: logger,
+		ctx:    ctx,
+		userID: userID,
+	}
+}
+
+// GetAllCalendarsOfInterest returns no calendars, the native calendar only holds the events of tasks
+func (c *NativeCalendarRepository) GetAllCalendarsOfInterest() (map[string]*Calendar, error) {
+	return map[string]*Calendar{}, nil
+}
+
+// TestTaskCalendarExistence does nothing, the native calendar always exists
+func (c *NativeCalendarRepository) TestTaskCalendarExistence(user *users.User) (*users.User, error) {
+	return user, nil
+}
+
+// NewEvent stores a new event in the native calendar
+func (c *NativeCalendarRepository) NewEvent(event *Event, taskID string, title string, description string, withReminder bool) (*Event, error) {
+	stored := nativeEvent{
+		ID:             primitive.NewObjectID(),
+		UserID:         c.userID,
+		TaskID:         taskID,
+		Title:          title,
+		Description:    description,
+		Date:           event.Date,
+		Blocking:       event.Blocking,
+		WithReminder:   withReminder,
+		CreatedAt:      time.Now(),
+		LastModifiedAt: time.Now(),
+	}
+
+	_, err := c.DB.InsertOne(c.ctx, stored)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	event.CalendarEvents = append(event.CalendarEvents, PersistedEvent{
+		CalendarEventID: stored.ID.Hex(),
+		CalendarType:    PersistedCalendarTypeNativeCalendar,
+		UserID:          c.userID,
+	})
+
+	return event, nil
+}
+
+// UpdateEvent updates an event of the native calendar
+func (c *NativeCalendarRepository) UpdateEvent(event *Event, taskID string, title string, description string, withReminder bool) error {
+	eventID, err := c.findEventID(event)
+	if err != nil {
+		return err
+	}
+
+	result, err := c.DB.UpdateOne(c.ctx, bson.M{"_id": eventID, "userId": c.userID}, bson.M{"$set": bson.M{
+		"taskId":         taskID,
+		"title":          title,
+		"description":    description,
+		"date":           event.Date,
+		"blocking":       event.Blocking,
+		"withReminder":   withReminder,
+		"lastModifiedAt": time.Now(),
+	}})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if result.MatchedCount != 1 {
+		return errors.New("updated count != 1")
+	}
+
+	return nil
+}
+
+// DeleteEvent deletes an event of the native calendar, events that are already gone count as deleted
+func (c *NativeCalendarRepository) DeleteEvent(event *Event) error {
+	eventID, err := c.findEventID(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.DB.DeleteOne(c.ctx, bson.M{"_id": eventID, "userId": c.userID})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// findEventID returns the id of the stored event that belongs to the user
+func (c *NativeCalendarRepository) findEventID(event *Event) (primitive.ObjectID, error) {
+	calendarEvent := event.CalendarEvents.FindByUserID(c.userID.Hex())
+	if calendarEvent == nil || calendarEvent.CalendarType != PersistedCalendarTypeNativeCalendar {
+		return primitive.NilObjectID, errors.Errorf("no native calendar event found for user %s", c.userID.Hex())
+	}
+
+	eventID, err := primitive.ObjectIDFromHex(calendarEvent.CalendarEventID)
+	if err != nil {
+		return primitive.NilObjectID, errors.WithStack(err)
+	}
+
+	return eventID, nil
+}
+
+// AddBusyToWindow adds nothing, the native calendar only holds the events of tasks, which the planning takes care of
+func (c *NativeCalendarRepository) AddBusyToWindow(window *date.TimeWindow, start time.Time, end time.Time) error {
+	return nil
+}
+
+// WatchCalendar fails, nobody but Timeliness changes the native calendar
+func (c *NativeCalendarRepository) WatchCalendar(calendarID string, user *users.User) (*users.User, error) {
+	return nil, ErrNonSyncable
+}
+
+// StopWatchingCalendar does nothing, the native calendar is never watched
+func (c *NativeCalendarRepository) StopWatchingCalendar(calendarID string, user *users.User) (*users.User, error) {
+	return user, nil
+}
+
+// SyncEvents sends no events, nobody but Timeliness changes the native calendar
+func (c *NativeCalendarRepository) SyncEvents(calendarID string, user *users.User,
+	eventChannel *chan *Event,
+	errorChannel *chan error,
+	userChannel *chan *users.User) {
+
+	defer close(*eventChannel)
+	defer close(*errorChannel)
+	defer close(*userChannel)
+
+	*userChannel <- user
+}
@@ -0,0 +1,118 @@
+package calendar
+
+import (
+	"context"
+	"github.com/timeliness-app/timeliness-backend/pkg/date"
+	"github.com/timeliness-app/timeliness-backend/pkg/logger"
+	"github.com/timeliness-app/timeliness-backend/pkg/users"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"testing"
+	"time"
+)
+
+func TestNativeCalendarRepository(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	user := &users.User{ID: primitive.NewObjectID()}
+	start := time.Date(2021, 1, 4, 9, 0, 0, 0, time.UTC)
+
+	newEvent := func() *Event {
+		return &Event{Date: date.Timespan{Start: start, End: start.Add(time.Hour)}, Blocking: true}
+	}
+
+	mt.Run("NewEvent", func(mt *mtest.T) {
+		repository := NewNativeCalendarRepository(context.Background(), user.ID, mt.Coll, logger.Logger{})
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		event, err := repository.NewEvent(newEvent(), primitive.NewObjectID().Hex(), "Work", "", true)
+		if err != nil {
+			mt.Fatal(err)
+		}
+
+		persisted := event.CalendarEvents.FindByUserID(user.ID.Hex())
+		if persisted == nil || persisted.CalendarType != PersistedCalendarTypeNativeCalendar {
+			mt.Fatalf("got persisted event %+v, want a native calendar event of the user", persisted)
+		}
+
+		inserted := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		if inserted.Lookup("_id").ObjectID().Hex() != persisted.CalendarEventID {
+			mt.Errorf("inserted event %s, want %s", inserted.Lookup("_id").ObjectID().Hex(), persisted.CalendarEventID)
+		}
+
+		if inserted.Lookup("userId").ObjectID() != user.ID {
+			mt.Errorf("inserted event of user %s, want %s", inserted.Lookup("userId").ObjectID().Hex(), user.ID.Hex())
+		}
+	})
+
+	mt.Run("UpdateEvent", func(mt *mtest.T) {
+		repository := NewNativeCalendarRepository(context.Background(), user.ID, mt.Coll, logger.Logger{})
+		event := newEvent()
+		event.CalendarEvents = PersistedEvents{{CalendarEventID: primitive.NewObjectID().Hex(), CalendarType: PersistedCalendarTypeNativeCalendar, UserID: user.ID}}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+		err := repository.UpdateEvent(event, primitive.NewObjectID().Hex(), "Work", "", true)
+		if err != nil {
+			mt.Fatal(err)
+		}
+
+		// The event was deleted in the meantime
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+		err = repository.UpdateEvent(event, primitive.NewObjectID().Hex(), "Work", "", true)
+		if err == nil {
+			mt.Error("got no error when updating a missing event")
+		}
+	})
+
+	mt.Run("DeleteEvent", func(mt *mtest.T) {
+		repository := NewNativeCalendarRepository(context.Background(), user.ID, mt.Coll, logger.Logger{})
+		event := newEvent()
+		event.CalendarEvents = PersistedEvents{{CalendarEventID: primitive.NewObjectID().Hex(), CalendarType: PersistedCalendarTypeNativeCalendar, UserID: user.ID}}
+
+		// Events that are already gone count as deleted
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+		err := repository.DeleteEvent(event)
+		if err != nil {
+			mt.Fatal(err)
+		}
+	})
+
+	mt.Run("Events of other calendars", func(mt *mtest.T) {
+		repository := NewNativeCalendarRepository(context.Background(), user.ID, mt.Coll, logger.Logger{})
+		event := newEvent()
+		event.CalendarEvents = PersistedEvents{{CalendarEventID: "google-event", CalendarType: PersistedCalendarTypeGoogleCalendar, UserID: user.ID}}
+
+		err := repository.UpdateEvent(event, primitive.NewObjectID().Hex(), "Work", "", true)
+		if err == nil {
+			mt.Error("got no error when updating an event of another calendar")
+		}
+
+		err = repository.DeleteEvent(event)
+		if err == nil {
+			mt.Error("got no error when deleting an event of another calendar")
+		}
+
+		if started := mt.GetStartedEvent(); started != nil {
+			mt.Errorf("sent %s for an event of another calendar", started.CommandName)
+		}
+	})
+
+	mt.Run("SyncEvents", func(mt *mtest.T) {
+		repository := NewNativeCalendarRepository(context.Background(), user.ID, mt.Coll, logger.Logger{})
+
+		eventChannel := make(chan *Event)
+		errorChannel := make(chan error)
+		userChannel := make(chan *users.User)
+		go repository.SyncEvents("", user, &eventChannel, &errorChannel, &userChannel)
+
+		if synced := <-userChannel; synced != user {
+			mt.Errorf("got user %v, want %v", synced, user)
+		}
+
+		if _, ok := <-eventChannel; ok {
+			mt.Error("got an event from the native calendar")
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package calendar
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// nonPublicNetworks are the ranges that loopback, link-local and unspecified checks don't cover,
+// like private networks, carrier-grade NAT and unique local addresses
+var nonPublicNetworks = parseNetworks("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "0.0.0.0/8", "fc00::/7")
+
+// publicTransport sends the requests to servers users configure themselves, like ICS feeds and CalDAV servers.
+// It only connects to public addresses, so that these urls can't be used to reach internal services.
+var publicTransport http.RoundTripper = newPublicTransport()
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks
+}
+
+// newPublicTransport creates a transport whose dialer checks every address after the host name was resolved,
+// so that host names resolving to internal addresses are rejected as well
+func newPublicTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   time.Second * 30,
+		KeepAlive: time.Second * 30,
+		Control:   rejectNonPublicAddress,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be dialed instead of the server, which would skip the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return transport
+}
+
+// rejectNonPublicAddress is called with the resolved address right before a connection is made
+func rejectNonPublicAddress(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("connecting to the non-public address %s is not allowed", host)
+	}
+
+	return nil
+}
+
+// isPublicIP tells if an ip address is reachable on the internet
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
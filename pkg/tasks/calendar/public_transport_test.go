@@ -0,0 +1,49 @@
+package calendar
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_isPublicIP(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{ip: "93.184.216.34", public: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", public: true},
+		{ip: "127.0.0.1"},
+		{ip: "::1"},
+		{ip: "::ffff:127.0.0.1"},
+		{ip: "10.1.2.3"},
+		{ip: "172.20.0.1"},
+		{ip: "192.168.178.1"},
+		{ip: "169.254.169.254"},
+		{ip: "fe80::1"},
+		{ip: "fd00::1"},
+		{ip: "0.0.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if public := isPublicIP(net.ParseIP(tt.ip)); public != tt.public {
+				t.Errorf("got public %t, want %t", public, tt.public)
+			}
+		})
+	}
+}
+
+func Test_newPublicTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		t.Error("the public transport connected to the loopback interface")
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newPublicTransport()}
+	_, err := client.Get(server.URL)
+	if err == nil {
+		t.Error("got no error for a server on the loopback interface")
+	}
+}
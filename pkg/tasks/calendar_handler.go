@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	"github.com/timeliness-app/timeliness-backend/internal/google"
+	"github.com/timeliness-app/timeliness-backend/internal/microsoft"
 	"github.com/timeliness-app/timeliness-backend/pkg/auth"
 	"github.com/timeliness-app/timeliness-backend/pkg/auth/encryption"
 	"github.com/timeliness-app/timeliness-backend/pkg/communication"
@@ -15,6 +17,7 @@ import (
 	"github.com/timeliness-app/timeliness-backend/pkg/logger"
 	"github.com/timeliness-app/timeliness-backend/pkg/tasks/calendar"
 	"github.com/timeliness-app/timeliness-backend/pkg/users"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"math"
 	"net/http"
 	"time"
@@ -31,10 +34,10 @@ type CalendarHandler struct {
 	CalendarRepositoryManager *CalendarRepositoryManager
 }
 
-// GoogleConnectionWithCalendars is the type the calendar handler works with
-type GoogleConnectionWithCalendars struct {
-	Connection users.GoogleCalendarConnection `json:"connection"`
-	Calendars  []*calendar.Calendar           `json:"calendars"`
+// ConnectionWithCalendars is the type the calendar handler works with
+type ConnectionWithCalendars struct {
+	Connection users.CalendarConnection `json:"connection"`
+	Calendars  []*calendar.Calendar     `json:"calendars"`
 }
 
 // GetCalendarsFromConnection responds with all calendars the user can register for busy time information
@@ -48,13 +51,11 @@ func (handler *CalendarHandler) GetCalendarsFromConnection(writer http.ResponseW
 		return
 	}
 
-	var googleConnections GoogleConnectionWithCalendars
+	var googleConnections ConnectionWithCalendars
 
-	// TODO: check which sources have a connection
-	for _, connection := range u.GoogleCalendarConnections {
-		if connection.ID != connectionID {
-			continue
-		}
+	providerConnections, index, err := u.FindCalendarConnections(connectionID)
+	if err == nil {
+		connection := (*providerConnections.Connections)[index]
 
 		googleRepo, err := handler.CalendarRepositoryManager.GetCalendarRepositoryForUserByConnectionID(request.Context(), u, connection.ID)
 		if err != nil {
@@ -80,7 +81,7 @@ func (handler *CalendarHandler) GetCalendarsFromConnection(writer http.ResponseW
 			googleCalendars = append(googleCalendars, c)
 		}
 
-		googleConnections = GoogleConnectionWithCalendars{Connection: connection, Calendars: googleCalendars}
+		googleConnections = ConnectionWithCalendars{Connection: connection, Calendars: googleCalendars}
 	}
 
 	handler.ResponseManager.Respond(writer, googleConnections)
@@ -105,13 +106,14 @@ func (handler *CalendarHandler) PatchCalendars(writer http.ResponseWriter, reque
 		return
 	}
 
-	connection, index, err := u.GoogleCalendarConnections.FindByConnectionID(connectionID)
+	providerConnections, index, err := u.FindCalendarConnections(connectionID)
 	if err != nil {
 		handler.ResponseManager.RespondWithError(writer, http.StatusNotFound, "Could not find connection", err, request, requestBody)
 		return
 	}
 
-	// TODO: check which sources have a connection
+	connection := (*providerConnections.Connections)[index]
+
 	googleRepo, err := handler.CalendarRepositoryManager.GetCalendarRepositoryForUserByConnectionID(request.Context(), u, connectionID)
 	if err != nil {
 		handler.ResponseManager.RespondWithError(writer, http.StatusUnauthorized, "Error while using Google Calendar connection", err, request, requestBody)
@@ -124,9 +126,9 @@ func (handler *CalendarHandler) PatchCalendars(writer http.ResponseWriter, reque
 		return
 	}
 
-	connection.CalendarsOfInterest, u = handler.matchNewGoogleCalendars(request.Context(), u, requestBody, googleCalendars, connection)
+	connection.CalendarsOfInterest, u = handler.matchNewGoogleCalendars(request.Context(), u, requestBody, googleCalendars, &connection)
 
-	u.GoogleCalendarConnections[index] = *connection
+	(*providerConnections.Connections)[index] = connection
 
 	err = handler.UserRepository.Update(request.Context(), u)
 	if err != nil {
@@ -134,7 +136,7 @@ func (handler *CalendarHandler) PatchCalendars(writer http.ResponseWriter, reque
 		return
 	}
 
-	err = handler.syncGoogleCalendars(writer, request, u)
+	err = handler.syncCalendars(writer, request, u)
 	if err != nil {
 		// We don't have to print error messages because the sub routine already took care of it
 	}
@@ -142,37 +144,20 @@ func (handler *CalendarHandler) PatchCalendars(writer http.ResponseWriter, reque
 	writer.WriteHeader(http.StatusAccepted)
 }
 
-func (handler *CalendarHandler) syncGoogleCalendars(writer http.ResponseWriter, request *http.Request, u *users.User) error {
+func (handler *CalendarHandler) syncCalendars(writer http.ResponseWriter, request *http.Request, u *users.User) error {
 	var err error
 
-	for connectionIndex, connection := range u.GoogleCalendarConnections {
-		u, err = handler.CalendarRepositoryManager.CheckIfGoogleTaskCalendarIsSet(request.Context(), u, &connection, connectionIndex)
-		if err != nil {
-			handler.Logger.Error("Could not check if Google Task Calendar is set", errors.Wrap(err, "could not check if Google Task Calendar is set"))
-			return err
-		}
-
-		calendarRepository, err := handler.CalendarRepositoryManager.GetCalendarRepositoryForUserByConnectionID(request.Context(), u, connection.ID)
-		if err != nil {
-			handler.Logger.Warning(fmt.Sprintf("Error while processing user %s for sync renewal", u.ID.Hex()), err)
-			return err
-		}
-
-		for calendarIndex, sync := range connection.CalendarsOfInterest {
-			if sync.IsNotSyncable {
-				continue
+	for _, providerConnections := range u.AllCalendarConnections() {
+		for connectionIndex := range *providerConnections.Connections {
+			u, err = handler.CalendarRepositoryManager.CheckIfTaskCalendarIsSet(request.Context(), u, providerConnections, connectionIndex)
+			if err != nil {
+				handler.Logger.Error("Could not check if Task Calendar is set", errors.Wrap(err, fmt.Sprintf("could not check if %s Task Calendar is set", providerConnections.Provider)))
+				return err
 			}
 
-			u, err = calendarRepository.WatchCalendar(sync.CalendarID, u)
+			err = handler.watchCalendarsOfConnection(writer, request, u, providerConnections, connectionIndex)
 			if err != nil {
-				if err.Error() == calendar.ErrNonSyncable.Error() {
-					u.GoogleCalendarConnections[connectionIndex].CalendarsOfInterest[calendarIndex].IsNotSyncable = true
-				} else {
-
-					_ = handler.UserRepository.Update(request.Context(), u)
-					handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, "Error while registering for calendar notifications", err, request, nil)
-					return err
-				}
+				return err
 			}
 		}
 	}
@@ -183,18 +168,75 @@ func (handler *CalendarHandler) syncGoogleCalendars(writer http.ResponseWriter,
 		return err
 	}
 
+	// A newly connected task calendar takes over the events that were stored natively until now
+	go handler.migrateNativeCalendarEvents(u.ID.Hex())
+
 	return nil
 }
 
-func (handler *CalendarHandler) matchNewGoogleCalendars(ctx context.Context, u *users.User, requestCalendars []calendar.Calendar, googleCalendars map[string]*calendar.Calendar, connection *users.GoogleCalendarConnection) ([]users.GoogleCalendarSync, *users.User) {
-	var newGoogleCalendars []users.GoogleCalendarSync
+// migrateNativeCalendarEvents moves the native task calendar events of a user into the connected task calendar
+func (handler *CalendarHandler) migrateNativeCalendarEvents(userID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*10)
+	defer cancel()
+
+	// The user could have changed in the meantime
+	user, err := handler.UserRepository.FindByID(ctx, userID)
+	if err != nil {
+		handler.Logger.Error(fmt.Sprintf("could not find user %s", userID), err)
+		return
+	}
+
+	migrated, err := handler.PlanningService.MigrateNativeCalendarEvents(ctx, user)
+	if err != nil {
+		handler.Logger.Warning(fmt.Sprintf("could not migrate native calendar events of user %s", userID), err)
+		return
+	}
+
+	if migrated > 0 {
+		handler.Logger.Info(fmt.Sprintf("migrated %d native calendar events of user %s", migrated, userID))
+	}
+}
+
+func (handler *CalendarHandler) watchCalendarsOfConnection(writer http.ResponseWriter, request *http.Request, u *users.User, providerConnections users.ProviderConnections, connectionIndex int) error {
+	connections := providerConnections.Connections
+	connection := (*connections)[connectionIndex]
+
+	calendarRepository, err := handler.CalendarRepositoryManager.GetCalendarRepositoryForUserByConnectionID(request.Context(), u, connection.ID)
+	if err != nil {
+		handler.Logger.Warning(fmt.Sprintf("Error while processing user %s for sync renewal", u.ID.Hex()), err)
+		return err
+	}
+
+	for calendarIndex, sync := range connection.CalendarsOfInterest {
+		if sync.IsNotSyncable {
+			continue
+		}
+
+		u, err = calendarRepository.WatchCalendar(sync.CalendarID, u)
+		if err != nil {
+			if err.Error() == calendar.ErrNonSyncable.Error() {
+				(*connections)[connectionIndex].CalendarsOfInterest[calendarIndex].IsNotSyncable = true
+			} else {
+
+				_ = handler.UserRepository.Update(request.Context(), u)
+				handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, "Error while registering for calendar notifications", err, request, nil)
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (handler *CalendarHandler) matchNewGoogleCalendars(ctx context.Context, u *users.User, requestCalendars []calendar.Calendar, googleCalendars map[string]*calendar.Calendar, connection *users.CalendarConnection) ([]users.CalendarSync, *users.User) {
+	var newGoogleCalendars []users.CalendarSync
 
 	for _, c := range requestCalendars {
 		if googleCalendars[c.CalendarID] == nil {
 			continue
 		}
 
-		var foundPresentCalendar *users.GoogleCalendarSync = nil
+		var foundPresentCalendar *users.CalendarSync = nil
 		for _, userCalendar := range connection.CalendarsOfInterest {
 			if userCalendar.CalendarID == c.CalendarID && c.IsActive {
 				foundPresentCalendar = &userCalendar
@@ -224,7 +266,7 @@ func (handler *CalendarHandler) matchNewGoogleCalendars(ctx context.Context, u *
 			continue
 		}
 
-		newGoogleCalendars = append(newGoogleCalendars, users.GoogleCalendarSync{CalendarID: c.CalendarID})
+		newGoogleCalendars = append(newGoogleCalendars, users.CalendarSync{CalendarID: c.CalendarID})
 	}
 
 	for _, sync := range connection.CalendarsOfInterest {
@@ -239,13 +281,10 @@ func (handler *CalendarHandler) matchNewGoogleCalendars(ctx context.Context, u *
 // GoogleCalendarSyncRenewal is hit by a scheduler to renew sync that are about to expire
 func (handler *CalendarHandler) GoogleCalendarSyncRenewal(writer http.ResponseWriter, request *http.Request) {
 	pageSize := 25
-	schedulerSecret := environment.Global.SchedulerSecret
-	if schedulerSecret == "" {
-		schedulerSecret = "local"
-	}
 
-	if request.Header.Get("scheduler-secret") != schedulerSecret {
-		handler.ResponseManager.RespondWithError(writer, http.StatusForbidden, "Invalid secret", fmt.Errorf("%s != the scheduler secret", request.Header.Get("scheduler-secret")), request, nil)
+	err := checkSchedulerSecret(request)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusForbidden, "Invalid secret", err, request, nil)
 		return
 	}
 
@@ -275,7 +314,7 @@ func (handler *CalendarHandler) GoogleCalendarSyncRenewal(writer http.ResponseWr
 		"usersProcessed": count,
 	}
 
-	handler.Logger.Info(fmt.Sprintf("Processed %d users for google sync renewal", count))
+	handler.Logger.Info(fmt.Sprintf("Processed %d users for calendar sync renewal", count))
 
 	handler.ResponseManager.Respond(writer, response)
 }
@@ -285,37 +324,62 @@ func (handler *CalendarHandler) processUserForSyncRenewal(user *users.User, time
 		return
 	}
 
-	// Google Calendar
-	for _, connection := range user.GoogleCalendarConnections {
-		if connection.Status != users.CalendarConnectionStatusActive {
-			continue
-		}
-
-		calendarRepository, err := handler.CalendarRepositoryManager.GetCalendarRepositoryForUserByConnectionID(context.Background(), user, connection.ID)
-		if err != nil {
-			handler.Logger.Error(fmt.Sprintf("Error while processing user %s for sync renewal", user.ID.Hex()), err)
-			return
-		}
+	type calendarOfConnection struct {
+		connectionID string
+		calendarID   string
+	}
+	var polls []calendarOfConnection
 
-		for _, sync := range connection.CalendarsOfInterest {
-			if !sync.Expiration.Before(time) || sync.IsNotSyncable {
+	for _, providerConnections := range user.AllCalendarConnections() {
+		for _, connection := range *providerConnections.Connections {
+			if connection.Status != users.CalendarConnectionStatusActive {
 				continue
 			}
 
-			// TODO: change when multiple repositories are allowed
-			user, err := calendarRepository.WatchCalendar(sync.CalendarID, user)
-			if err != nil {
-				handler.Logger.Warning(fmt.Sprintf("Error while trying to renew sync for user with calendar id, disabling it: %s", sync.CalendarID), err)
-				connection.Status = users.CalendarConnectionStatusExpired
+			// CalDAV servers and ICS feeds can't notify about changes, so their calendars are polled instead of renewed
+			if providerConnections.IsPolled() {
+				for _, sync := range connection.CalendarsOfInterest {
+					if !sync.IsNotSyncable {
+						polls = append(polls, calendarOfConnection{connectionID: connection.ID, calendarID: sync.CalendarID})
+					}
+				}
+				continue
 			}
 
-			err = handler.UserRepository.Update(context.Background(), user)
+			calendarRepository, err := handler.CalendarRepositoryManager.GetCalendarRepositoryForUserByConnectionID(context.Background(), user, connection.ID)
 			if err != nil {
-				handler.Logger.Error("Error while trying to update user", err)
+				handler.Logger.Error(fmt.Sprintf("Error while processing user %s for sync renewal", user.ID.Hex()), err)
 				return
 			}
+
+			for _, sync := range connection.CalendarsOfInterest {
+				if !sync.Expiration.Before(time) || sync.IsNotSyncable {
+					continue
+				}
+
+				// TODO: change when multiple repositories are allowed
+				user, err := calendarRepository.WatchCalendar(sync.CalendarID, user)
+				if err != nil {
+					handler.Logger.Warning(fmt.Sprintf("Error while trying to renew sync for user with calendar id, disabling it: %s", sync.CalendarID), err)
+					connection.Status = users.CalendarConnectionStatusExpired
+				}
+
+				err = handler.UserRepository.Update(context.Background(), user)
+				if err != nil {
+					handler.Logger.Error("Error while trying to update user", err)
+					return
+				}
+			}
 		}
 	}
+
+	// Polling refetches the user, so it has to happen after the renewals above were persisted
+	for _, poll := range polls {
+		handler.syncCalendarOfConnection(user.ID.Hex(), poll.connectionID, poll.calendarID)
+	}
+
+	// Native calendar events that couldn't be moved when the task calendar was connected are retried until none are left
+	handler.migrateNativeCalendarEvents(user.ID.Hex())
 }
 
 // InitiateGoogleCalendarAuth responds with the Google Auth URL
@@ -355,7 +419,7 @@ func (handler *CalendarHandler) InitiateGoogleCalendarAuth(writer http.ResponseW
 	}
 
 	if foundConnectionIndex == -1 {
-		u.GoogleCalendarConnections = append(u.GoogleCalendarConnections, users.GoogleCalendarConnection{
+		u.GoogleCalendarConnections = append(u.GoogleCalendarConnections, users.CalendarConnection{
 			ID:         "",
 			StateToken: stateToken,
 			Status:     users.CalendarConnectionStatusUnverified,
@@ -562,7 +626,7 @@ func (handler *CalendarHandler) GoogleCalendarAuthCallback(writer http.ResponseW
 		}
 
 		// We empty it, but leave the task calendar id in, because the user will probably want to reuse it, when he sees his mistake
-		usr.GoogleCalendarConnections[foundConnectionIndex].CalendarsOfInterest = users.GoogleCalendarSyncs{}
+		usr.GoogleCalendarConnections[foundConnectionIndex].CalendarsOfInterest = users.CalendarSyncs{}
 	}
 
 	usr.GoogleCalendarConnections[foundConnectionIndex].ID = userInfo.ID
@@ -575,8 +639,7 @@ func (handler *CalendarHandler) GoogleCalendarAuthCallback(writer http.ResponseW
 		usr.GoogleCalendarConnections[foundConnectionIndex].Status = users.CalendarConnectionStatusMissingScopes
 	}
 
-	// TODO: needs to check for other calendar sources as well
-	if len(usr.GoogleCalendarConnections) == 1 {
+	if usr.CountTaskCalendarCapableConnections() == 1 {
 		usr.GoogleCalendarConnections[foundConnectionIndex].IsTaskCalendarConnection = true
 	}
 
@@ -587,7 +650,7 @@ func (handler *CalendarHandler) GoogleCalendarAuthCallback(writer http.ResponseW
 	}
 
 	// Let's also set up the calendar repository here and sync it, so we can initialize things like the Timeliness calendar
-	err = handler.syncGoogleCalendars(writer, request, usr)
+	err = handler.syncCalendars(writer, request, usr)
 	if err != nil {
 		// We don't have to print error messages because the sub routine already took care of it
 	}
@@ -695,3 +758,599 @@ Loop:
 		}
 	}(user, calendarIndex)
 }
+
+// InitiateMicrosoftCalendarAuth responds with the Microsoft Auth URL
+func (handler *CalendarHandler) InitiateMicrosoftCalendarAuth(writer http.ResponseWriter, request *http.Request) {
+	userID := request.Context().Value(auth.KeyUserID).(string)
+	connectionID, ok := mux.Vars(request)["connectionID"]
+	if !ok {
+		connectionID = ""
+	}
+
+	u, err := handler.UserRepository.FindByID(request.Context(), userID)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Could not find user", err, request, nil)
+		return
+	}
+
+	var foundConnectionIndex = -1
+	if connectionID == "" {
+		for i, connection := range u.MicrosoftCalendarConnections {
+			if connection.Status == users.CalendarConnectionStatusUnverified {
+				foundConnectionIndex = i
+				break
+			}
+		}
+	} else {
+		_, foundConnectionIndex, err = u.MicrosoftCalendarConnections.FindByConnectionID(connectionID)
+		if err != nil {
+			handler.ResponseManager.RespondWithError(writer, http.StatusNotFound, "Could not find calendar connection", err, request, nil)
+			return
+		}
+	}
+
+	url, stateToken, err := microsoft.GetMicrosoftAuthURL()
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Could not get Microsoft config", err, request, nil)
+		return
+	}
+
+	if foundConnectionIndex == -1 {
+		u.MicrosoftCalendarConnections = append(u.MicrosoftCalendarConnections, users.CalendarConnection{
+			ID:         "",
+			StateToken: stateToken,
+			Status:     users.CalendarConnectionStatusUnverified,
+		})
+	} else {
+		u.MicrosoftCalendarConnections[foundConnectionIndex].StateToken = stateToken
+		u.MicrosoftCalendarConnections[foundConnectionIndex].Status = users.CalendarConnectionStatusUnverified
+	}
+
+	err = handler.UserRepository.Update(request.Context(), u)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Could not update user", err, request, nil)
+		return
+	}
+
+	var response = map[string]interface{}{
+		"url": url,
+	}
+
+	handler.ResponseManager.Respond(writer, response)
+}
+
+// DeleteMicrosoftConnection deletes a Microsoft connection and its subscriptions
+func (handler *CalendarHandler) DeleteMicrosoftConnection(writer http.ResponseWriter, request *http.Request) {
+	userID := request.Context().Value(auth.KeyUserID).(string)
+	connectionID := mux.Vars(request)["connectionID"]
+
+	u, err := handler.UserRepository.FindByID(request.Context(), userID)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Could not find user", err, request, nil)
+		return
+	}
+
+	connection, _, err := u.MicrosoftCalendarConnections.FindByConnectionID(connectionID)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusNotFound, fmt.Sprintf("Could not find connection id %s", connectionID), err, request, nil)
+		return
+	}
+
+	repository, err := handler.CalendarRepositoryManager.GetCalendarRepositoryForUserByConnectionID(request.Context(), u, connectionID)
+	if err == nil {
+		for _, sync := range connection.CalendarsOfInterest {
+			if sync.IsNotSyncable {
+				continue
+			}
+
+			u, err = repository.StopWatchingCalendar(sync.CalendarID, u)
+			if err != nil {
+				handler.Logger.Warning("Microsoft subscriptions could not be stopped", err)
+				continue
+			}
+		}
+	}
+
+	// Microsoft doesn't offer a way to revoke a single token, the user has to remove the app from the account
+	u.MicrosoftCalendarConnections = u.MicrosoftCalendarConnections.RemoveConnection(connectionID)
+
+	err = handler.UserRepository.Update(request.Context(), u)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Could not update user", err, request, nil)
+		return
+	}
+
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+// MicrosoftCalendarAuthCallback is the call the Microsoft identity platform will redirect to
+func (handler *CalendarHandler) MicrosoftCalendarAuthCallback(writer http.ResponseWriter, request *http.Request) {
+	microsoftError := request.URL.Query().Get("error")
+	authCode := request.URL.Query().Get("code")
+	stateToken := request.URL.Query().Get("state")
+
+	usr, err := handler.UserRepository.FindByMicrosoftStateToken(request.Context(), stateToken)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Invalid request", err, request, nil)
+		return
+	}
+
+	if microsoftError != "" {
+		handler.Logger.Warning(fmt.Sprintf("Access was denied by user %s", usr.ID.Hex()), fmt.Errorf("%s: %s", microsoftError, request.URL.Query().Get("error_description")))
+		http.Redirect(writer, request, fmt.Sprintf("%s/static/microsoft-error", environment.Global.FrontendBaseURL), http.StatusTemporaryRedirect)
+		return
+	}
+
+	foundConnectionIndex := -1
+	for i, connection := range usr.MicrosoftCalendarConnections {
+		if connection.StateToken == stateToken {
+			foundConnectionIndex = i
+			break
+		}
+	}
+
+	if foundConnectionIndex == -1 {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Invalid request", err, request, nil)
+		return
+	}
+
+	token, err := microsoft.GetMicrosoftToken(request.Context(), authCode)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Error getting token", err, request, nil)
+		return
+	}
+
+	userInfo, err := microsoft.GetUserInfo(request.Context(), token)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, "Error getting user id", err, request, nil)
+		return
+	}
+
+	for i, connection := range usr.MicrosoftCalendarConnections {
+		if connection.ID == userInfo.ID && i != foundConnectionIndex {
+			handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Account is already connected", fmt.Errorf("account %s is already connected", userInfo.ID), request, nil)
+			return
+		}
+	}
+
+	// Same edge case as with Google: the connection is updated, but with a different account
+	if usr.MicrosoftCalendarConnections[foundConnectionIndex].ID != "" && usr.MicrosoftCalendarConnections[foundConnectionIndex].ID != userInfo.ID {
+		repo, err := handler.CalendarRepositoryManager.GetCalendarRepositoryForUserByConnectionID(request.Context(), usr, usr.MicrosoftCalendarConnections[foundConnectionIndex].ID)
+		if err == nil {
+			for _, sync := range usr.MicrosoftCalendarConnections[foundConnectionIndex].CalendarsOfInterest {
+				usr, _ = repo.StopWatchingCalendar(sync.CalendarID, usr)
+			}
+		}
+
+		usr.MicrosoftCalendarConnections[foundConnectionIndex].CalendarsOfInterest = users.CalendarSyncs{}
+	}
+
+	usr.MicrosoftCalendarConnections[foundConnectionIndex].ID = userInfo.ID
+	usr.MicrosoftCalendarConnections[foundConnectionIndex].Email = userInfo.Email
+	usr.MicrosoftCalendarConnections[foundConnectionIndex].Token = *token
+	usr.MicrosoftCalendarConnections[foundConnectionIndex].StateToken = ""
+	usr.MicrosoftCalendarConnections[foundConnectionIndex].Status = users.CalendarConnectionStatusActive
+
+	if usr.CountTaskCalendarCapableConnections() == 1 {
+		usr.MicrosoftCalendarConnections[foundConnectionIndex].IsTaskCalendarConnection = true
+	}
+
+	err = handler.UserRepository.Update(request.Context(), usr)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, "Error updating user", err, request, nil)
+		return
+	}
+
+	err = handler.syncCalendars(writer, request, usr)
+	if err != nil {
+		// We don't have to print error messages because the sub routine already took care of it
+	}
+
+	http.Redirect(writer, request, fmt.Sprintf("%s/static/microsoft-connected", environment.Global.FrontendBaseURL), http.StatusFound)
+}
+
+// MicrosoftCalendarNotification receives change notifications of Microsoft Graph subscriptions
+func (handler *CalendarHandler) MicrosoftCalendarNotification(writer http.ResponseWriter, request *http.Request) {
+	// Graph validates the notification url when creating a subscription by expecting the token back
+	validationToken := request.URL.Query().Get("validationToken")
+	if validationToken != "" {
+		writer.Header().Set("Content-Type", "text/plain")
+		writer.WriteHeader(http.StatusOK)
+		_, _ = writer.Write([]byte(validationToken))
+		return
+	}
+
+	var body struct {
+		Value []struct {
+			SubscriptionID string `json:"subscriptionId"`
+			ClientState    string `json:"clientState"`
+		} `json:"value"`
+	}
+
+	err := json.NewDecoder(request.Body).Decode(&body)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Wrong format", err, request, nil)
+		return
+	}
+
+	// Graph batches notifications, so the same calendar can appear multiple times
+	syncs := make(map[string]bool)
+
+	for _, notification := range body.Value {
+		if notification.ClientState == "" || notification.SubscriptionID == "" {
+			continue
+		}
+
+		userID := encryption.Decrypt(notification.ClientState)
+
+		user, err := handler.UserRepository.FindByID(request.Context(), userID)
+		if err != nil {
+			handler.Logger.Warning(fmt.Sprintf("Could not find user for subscription %s", notification.SubscriptionID), err)
+			continue
+		}
+
+		if user.Billing.IsExpired() {
+			handler.Logger.Info(fmt.Sprintf("Calendar notification received, but user %s is expired", user.ID.Hex()))
+			continue
+		}
+
+	Loop:
+		for _, connection := range user.MicrosoftCalendarConnections {
+			if connection.Status != users.CalendarConnectionStatusActive {
+				continue
+			}
+
+			for _, sync := range connection.CalendarsOfInterest {
+				if sync.SyncResourceID != notification.SubscriptionID {
+					continue
+				}
+
+				key := fmt.Sprintf("%s-%s", userID, sync.CalendarID)
+				if !syncs[key] {
+					syncs[key] = true
+					go handler.syncCalendarOfConnection(userID, connection.ID, sync.CalendarID)
+				}
+
+				break Loop
+			}
+		}
+	}
+
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+// syncCalendarOfConnection syncs a calendar of a connection of any provider
+func (handler *CalendarHandler) syncCalendarOfConnection(userID string, connectionID string, calendarID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*6)
+	defer cancel()
+
+	lock, err := handler.Locker.Acquire(ctx, fmt.Sprintf("user-%s", userID), time.Minute*3, false, 5*time.Minute)
+	if err != nil {
+		handler.Logger.Error(fmt.Sprintf("error while acquiring lock for user %s", userID), err)
+		return
+	}
+
+	defer func(lock locking.LockInterface, ctx context.Context) {
+		err := lock.Release(ctx)
+		if err != nil {
+			handler.Logger.Error(fmt.Sprintf("error while releasing lock for user %s", userID), err)
+			return
+		}
+	}(lock, ctx)
+
+	// The user could have changed while waiting for the lock
+	user, err := handler.UserRepository.FindByID(ctx, userID)
+	if err != nil {
+		handler.Logger.Error(fmt.Sprintf("could not find user %s", userID), err)
+		return
+	}
+
+	providerConnections, connectionIndex, err := user.FindCalendarConnections(connectionID)
+	if err != nil || (*providerConnections.Connections)[connectionIndex].Status != users.CalendarConnectionStatusActive {
+		return
+	}
+
+	syncedUser, err := handler.PlanningService.SyncCalendar(ctx, user, calendarID)
+	if err != nil && providerConnections.IsPolled() && errors.Cause(err) != communication.ErrCalendarAuthInvalid {
+		// A server that can't be reached is polled again with the next renewal, only revoked access expires the connection
+		handler.Logger.Warning(fmt.Sprintf("error while polling user %s and calendar %s, retrying with the next renewal", userID, calendarID), err)
+		return
+	}
+
+	if err != nil {
+		handler.Logger.Warning(fmt.Sprintf("error while syncing user %s and calendar ID, disabling connection %s", userID, calendarID), err)
+		(*providerConnections.Connections)[connectionIndex].Status = users.CalendarConnectionStatusExpired
+	} else {
+		user = syncedUser
+	}
+
+	err = handler.UserRepository.Update(ctx, user)
+	if err != nil {
+		handler.Logger.Error(fmt.Sprintf("error updating user %s", userID), err)
+		return
+	}
+}
+
+// CalDAVCredentials are the credentials of a CalDAV account, the server url can be the root of the server
+type CalDAVCredentials struct {
+	ServerURL string `json:"serverUrl" validate:"required,url"`
+	Username  string `json:"username" validate:"required"`
+	Password  string `json:"password" validate:"required"`
+}
+
+// SetCalDAVConnection connects a CalDAV account or updates the credentials of an existing connection
+func (handler *CalendarHandler) SetCalDAVConnection(writer http.ResponseWriter, request *http.Request) {
+	userID := request.Context().Value(auth.KeyUserID).(string)
+	connectionID, ok := mux.Vars(request)["connectionID"]
+	if !ok {
+		connectionID = ""
+	}
+
+	credentials := CalDAVCredentials{}
+
+	err := json.NewDecoder(request.Body).Decode(&credentials)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Wrong format", err, request, nil)
+		return
+	}
+
+	err = validator.New().Struct(credentials)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Wrong format", err, request, nil)
+		return
+	}
+
+	homeSetURL, err := calendar.DiscoverCalDAV(request.Context(), credentials.ServerURL, credentials.Username, credentials.Password, handler.Logger)
+	if err != nil {
+		if errors.Cause(err) == communication.ErrCalendarAuthInvalid {
+			handler.ResponseManager.RespondWithError(writer, http.StatusUnauthorized, "Invalid CalDAV credentials", err, request, nil)
+			return
+		}
+
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Could not find calendars on the CalDAV server", err, request, nil)
+		return
+	}
+
+	lock, err := handler.Locker.Acquire(request.Context(), fmt.Sprintf("user-%s", userID), time.Minute*3, false, time.Minute*5)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, fmt.Sprintf("Error acquiring lock for user %s", userID), err, request, nil)
+		return
+	}
+
+	defer func() {
+		if err := lock.Release(request.Context()); err != nil {
+			handler.Logger.Error(fmt.Sprintf("error while releasing lock for user %s", userID), err)
+		}
+	}()
+
+	u, err := handler.UserRepository.FindByID(request.Context(), userID)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Could not find user", err, request, nil)
+		return
+	}
+
+	foundConnectionIndex := -1
+	if connectionID != "" {
+		_, foundConnectionIndex, err = u.CalDAVCalendarConnections.FindByConnectionID(connectionID)
+		if err != nil {
+			handler.ResponseManager.RespondWithError(writer, http.StatusNotFound, "Could not find calendar connection", err, request, nil)
+			return
+		}
+	}
+
+	for i, connection := range u.CalDAVCalendarConnections {
+		if connection.ServerURL == homeSetURL && connection.Username == credentials.Username && i != foundConnectionIndex {
+			handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Account is already connected", fmt.Errorf("account %s is already connected", credentials.Username), request, nil)
+			return
+		}
+	}
+
+	if foundConnectionIndex == -1 {
+		u.CalDAVCalendarConnections = append(u.CalDAVCalendarConnections, users.CalendarConnection{
+			ID: primitive.NewObjectID().Hex(),
+		})
+		foundConnectionIndex = len(u.CalDAVCalendarConnections) - 1
+	} else if u.CalDAVCalendarConnections[foundConnectionIndex].ServerURL != homeSetURL {
+		// The calendars of another account can't be reused
+		u.CalDAVCalendarConnections[foundConnectionIndex].CalendarsOfInterest = users.CalendarSyncs{}
+		u.CalDAVCalendarConnections[foundConnectionIndex].TaskCalendarID = ""
+	}
+
+	u.CalDAVCalendarConnections[foundConnectionIndex].Email = credentials.Username
+	u.CalDAVCalendarConnections[foundConnectionIndex].ServerURL = homeSetURL
+	u.CalDAVCalendarConnections[foundConnectionIndex].Username = credentials.Username
+	u.CalDAVCalendarConnections[foundConnectionIndex].Password = encryption.Encrypt(credentials.Password)
+	u.CalDAVCalendarConnections[foundConnectionIndex].Status = users.CalendarConnectionStatusActive
+
+	if u.CountTaskCalendarCapableConnections() == 1 {
+		u.CalDAVCalendarConnections[foundConnectionIndex].IsTaskCalendarConnection = true
+	}
+
+	err = handler.UserRepository.Update(request.Context(), u)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, "Error updating user", err, request, nil)
+		return
+	}
+
+	err = handler.syncCalendars(writer, request, u)
+	if err != nil {
+		// The sub routine already responded with the error
+		return
+	}
+
+	handler.ResponseManager.Respond(writer, u.CalDAVCalendarConnections[foundConnectionIndex])
+}
+
+// DeleteCalDAVConnection deletes a CalDAV connection
+func (handler *CalendarHandler) DeleteCalDAVConnection(writer http.ResponseWriter, request *http.Request) {
+	userID := request.Context().Value(auth.KeyUserID).(string)
+	connectionID := mux.Vars(request)["connectionID"]
+
+	u, err := handler.UserRepository.FindByID(request.Context(), userID)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Could not find user", err, request, nil)
+		return
+	}
+
+	_, _, err = u.CalDAVCalendarConnections.FindByConnectionID(connectionID)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusNotFound, fmt.Sprintf("Could not find connection id %s", connectionID), err, request, nil)
+		return
+	}
+
+	// CalDAV calendars are polled, so there is nothing to unsubscribe from
+	u.CalDAVCalendarConnections = u.CalDAVCalendarConnections.RemoveConnection(connectionID)
+
+	err = handler.UserRepository.Update(request.Context(), u)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Could not update user", err, request, nil)
+		return
+	}
+
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+// ICSFeed is the URL of a read-only ICS feed, webcal URLs are accepted as well
+type ICSFeed struct {
+	URL string `json:"url" validate:"required"`
+}
+
+// SetICSConnection adds an ICS feed as an availability source or changes the URL of an existing one
+func (handler *CalendarHandler) SetICSConnection(writer http.ResponseWriter, request *http.Request) {
+	userID := request.Context().Value(auth.KeyUserID).(string)
+	connectionID, ok := mux.Vars(request)["connectionID"]
+	if !ok {
+		connectionID = ""
+	}
+
+	feed := ICSFeed{}
+
+	err := json.NewDecoder(request.Body).Decode(&feed)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Wrong format", err, request, nil)
+		return
+	}
+
+	err = validator.New().Struct(feed)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Wrong format", err, request, nil)
+		return
+	}
+
+	feedURL, err := calendar.NormalizeICSURL(feed.URL)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Invalid feed url", err, request, nil)
+		return
+	}
+
+	// The feed is read once up front, so that broken URLs are rejected right away
+	testConnection := users.CalendarConnection{ID: primitive.NewObjectID().Hex(), ServerURL: feedURL}
+	feedRepository, err := calendar.NewICSCalendarRepository(request.Context(), primitive.NilObjectID, &testConnection, handler.Logger, nil)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Could not read the ICS feed", err, request, nil)
+		return
+	}
+
+	feedCalendars, err := feedRepository.GetAllCalendarsOfInterest()
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Could not read the ICS feed", err, request, nil)
+		return
+	}
+
+	lock, err := handler.Locker.Acquire(request.Context(), fmt.Sprintf("user-%s", userID), time.Minute*3, false, time.Minute*5)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, fmt.Sprintf("Error acquiring lock for user %s", userID), err, request, nil)
+		return
+	}
+
+	defer func() {
+		if err := lock.Release(request.Context()); err != nil {
+			handler.Logger.Error(fmt.Sprintf("error while releasing lock for user %s", userID), err)
+		}
+	}()
+
+	u, err := handler.UserRepository.FindByID(request.Context(), userID)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Could not find user", err, request, nil)
+		return
+	}
+
+	foundConnectionIndex := -1
+	if connectionID != "" {
+		_, foundConnectionIndex, err = u.ICSCalendarConnections.FindByConnectionID(connectionID)
+		if err != nil {
+			handler.ResponseManager.RespondWithError(writer, http.StatusNotFound, "Could not find calendar connection", err, request, nil)
+			return
+		}
+	}
+
+	for i, connection := range u.ICSCalendarConnections {
+		if connection.ServerURL == feedURL && i != foundConnectionIndex {
+			handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Feed is already connected", fmt.Errorf("feed %s is already connected", feedURL), request, nil)
+			return
+		}
+	}
+
+	if foundConnectionIndex == -1 {
+		u.ICSCalendarConnections = append(u.ICSCalendarConnections, users.CalendarConnection{
+			ID: primitive.NewObjectID().Hex(),
+		})
+		foundConnectionIndex = len(u.ICSCalendarConnections) - 1
+	} else if u.ICSCalendarConnections[foundConnectionIndex].ServerURL != feedURL {
+		// The checksum of the old feed says nothing about the new one
+		u.ICSCalendarConnections[foundConnectionIndex].CalendarsOfInterest = users.CalendarSyncs{}
+	}
+
+	connection := &u.ICSCalendarConnections[foundConnectionIndex]
+	connection.Email = feedCalendars[testConnection.ID].Name
+	connection.ServerURL = feedURL
+	connection.Status = users.CalendarConnectionStatusActive
+
+	// A feed is a single calendar, it is identified by the connection id
+	if !connection.CalendarsOfInterest.HasCalendarWithID(connection.ID) {
+		connection.CalendarsOfInterest = append(connection.CalendarsOfInterest, users.CalendarSync{CalendarID: connection.ID})
+	}
+
+	err = handler.UserRepository.Update(request.Context(), u)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, "Error updating user", err, request, nil)
+		return
+	}
+
+	err = handler.syncCalendars(writer, request, u)
+	if err != nil {
+		// The sub routine already responded with the error
+		return
+	}
+
+	handler.ResponseManager.Respond(writer, u.ICSCalendarConnections[foundConnectionIndex])
+}
+
+// DeleteICSConnection deletes an ICS connection
+func (handler *CalendarHandler) DeleteICSConnection(writer http.ResponseWriter, request *http.Request) {
+	userID := request.Context().Value(auth.KeyUserID).(string)
+	connectionID := mux.Vars(request)["connectionID"]
+
+	u, err := handler.UserRepository.FindByID(request.Context(), userID)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Could not find user", err, request, nil)
+		return
+	}
+
+	_, _, err = u.ICSCalendarConnections.FindByConnectionID(connectionID)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusNotFound, fmt.Sprintf("Could not find connection id %s", connectionID), err, request, nil)
+		return
+	}
+
+	// Feeds are polled, so there is nothing to unsubscribe from
+	u.ICSCalendarConnections = u.ICSCalendarConnections.RemoveConnection(connectionID)
+
+	err = handler.UserRepository.Update(request.Context(), u)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Could not update user", err, request, nil)
+		return
+	}
+
+	writer.WriteHeader(http.StatusAccepted)
+}
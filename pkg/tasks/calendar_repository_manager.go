@@ -8,18 +8,22 @@ import (
 	"github.com/timeliness-app/timeliness-backend/pkg/logger"
 	"github.com/timeliness-app/timeliness-backend/pkg/tasks/calendar"
 	"github.com/timeliness-app/timeliness-backend/pkg/users"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // CalendarRepositoryManager manages calendar repositories. It decided which user needs which repository.
 type CalendarRepositoryManager struct {
-	userRepository  users.UserRepositoryInterface
-	logger          logger.Interface
-	overriddenRepos map[string]calendar.RepositoryInterface
+	userRepository        users.UserRepositoryInterface
+	logger                logger.Interface
+	nativeEventCollection *mongo.Collection
+	overriddenRepos       map[string]calendar.RepositoryInterface
+	overriddenNativeRepos map[string]calendar.RepositoryInterface
 }
 
-// NewCalendarRepositoryManager creates a new CalendarRepositoryManager
-func NewCalendarRepositoryManager(_ int, userRepository users.UserRepositoryInterface, logger logger.Interface) (*CalendarRepositoryManager, error) {
-	manager := CalendarRepositoryManager{userRepository: userRepository, logger: logger}
+// NewCalendarRepositoryManager creates a new CalendarRepositoryManager, the events of users without a task calendar
+// connection are stored in nativeEventCollection
+func NewCalendarRepositoryManager(_ int, userRepository users.UserRepositoryInterface, logger logger.Interface, nativeEventCollection *mongo.Collection) (*CalendarRepositoryManager, error) {
+	manager := CalendarRepositoryManager{userRepository: userRepository, logger: logger, nativeEventCollection: nativeEventCollection}
 
 	return &manager, nil
 }
@@ -34,39 +38,49 @@ func (m *CalendarRepositoryManager) GetAllAvailabilityCalendarRepositoriesForUse
 	var repos []calendar.RepositoryInterface
 
 	// TODO: Make parallel
-	for i, connection := range user.GoogleCalendarConnections {
-		if connection.Status != users.CalendarConnectionStatusActive {
-			continue
-		}
-		calendarRepository, err := m.setupGoogleRepository(ctx, user, &connection, i)
-		if err != nil {
-			return nil, err
-		}
+	for _, providerConnections := range user.AllCalendarConnections() {
+		for i, connection := range *providerConnections.Connections {
+			if connection.Status != users.CalendarConnectionStatusActive {
+				continue
+			}
+			calendarRepository, err := m.setupRepository(ctx, user, providerConnections, i)
+			if err != nil {
+				return nil, err
+			}
 
-		repos = append(repos, calendarRepository)
+			repos = append(repos, calendarRepository)
+		}
 	}
 
 	return repos, nil
 }
 
-// GetTaskCalendarRepositoryForUser gets the task calendar repository for a user
+// GetTaskCalendarRepositoryForUser gets the task calendar repository for a user, users without a task calendar
+// connection get their native task calendar
 func (m *CalendarRepositoryManager) GetTaskCalendarRepositoryForUser(ctx context.Context, user *users.User) (calendar.RepositoryInterface, error) {
 	if len(m.overriddenRepos) > 0 && m.overriddenRepos[user.ID.Hex()] != nil {
 		return m.overriddenRepos[user.ID.Hex()], nil
 	}
 
-	for i, connection := range user.GoogleCalendarConnections {
-		if connection.IsTaskCalendarConnection {
-			calendarRepository, err := m.setupGoogleRepository(ctx, user, &connection, i)
-			if err != nil {
-				return nil, err
+	for _, providerConnections := range user.AllCalendarConnections() {
+		for i, connection := range *providerConnections.Connections {
+			if connection.IsTaskCalendarConnection {
+				return m.setupRepository(ctx, user, providerConnections, i)
 			}
-
-			return calendarRepository, nil
 		}
 	}
 
-	return nil, fmt.Errorf("could not find a connection that has a task calendar connection for user %s", user.ID.Hex())
+	return m.GetNativeCalendarRepositoryForUser(ctx, user), nil
+}
+
+// GetNativeCalendarRepositoryForUser gets the task calendar that is stored by Timeliness itself, it is used until the
+// user connects a task calendar
+func (m *CalendarRepositoryManager) GetNativeCalendarRepositoryForUser(ctx context.Context, user *users.User) calendar.RepositoryInterface {
+	if len(m.overriddenNativeRepos) > 0 && m.overriddenNativeRepos[user.ID.Hex()] != nil {
+		return m.overriddenNativeRepos[user.ID.Hex()]
+	}
+
+	return calendar.NewNativeCalendarRepository(ctx, user.ID, m.nativeEventCollection, m.logger)
 }
 
 // GetCalendarRepositoryForUserByCalendarID gets a specific calendar repository for a user
@@ -75,14 +89,11 @@ func (m *CalendarRepositoryManager) GetCalendarRepositoryForUserByCalendarID(ctx
 		return m.overriddenRepos[user.ID.Hex()], nil
 	}
 
-	for i, connection := range user.GoogleCalendarConnections {
-		if connection.CalendarsOfInterest.HasCalendarWithID(calendarID) {
-			calendarRepository, err := m.setupGoogleRepository(ctx, user, &connection, i)
-			if err != nil {
-				return nil, err
+	for _, providerConnections := range user.AllCalendarConnections() {
+		for i, connection := range *providerConnections.Connections {
+			if connection.CalendarsOfInterest.HasCalendarWithID(calendarID) {
+				return m.setupRepository(ctx, user, providerConnections, i)
 			}
-
-			return calendarRepository, nil
 		}
 	}
 
@@ -95,24 +106,18 @@ func (m *CalendarRepositoryManager) GetCalendarRepositoryForUserByConnectionID(c
 		return m.overriddenRepos[user.ID.Hex()], nil
 	}
 
-	for i, connection := range user.GoogleCalendarConnections {
-		if connection.ID == connectionID {
-			calendarRepository, err := m.setupGoogleRepository(ctx, user, &connection, i)
-			if err != nil {
-				return nil, err
-			}
-
-			return calendarRepository, nil
-		}
+	providerConnections, i, err := user.FindCalendarConnections(connectionID)
+	if err != nil {
+		return nil, fmt.Errorf("could not find a connection that has the given id %s for user %s", connectionID, user.ID.Hex())
 	}
 
-	return nil, fmt.Errorf("could not find a connection that has the given id %s for user %s", connectionID, user.ID.Hex())
+	return m.setupRepository(ctx, user, *providerConnections, i)
 }
 
-// CheckIfGoogleTaskCalendarIsSet checks if a task calendar is set already
-func (m *CalendarRepositoryManager) CheckIfGoogleTaskCalendarIsSet(ctx context.Context, u *users.User, connection *users.GoogleCalendarConnection, connectionIndex int) (*users.User, error) {
-	if connection.IsTaskCalendarConnection {
-		calendarRepository, err := m.setupGoogleRepository(ctx, u, connection, connectionIndex)
+// CheckIfTaskCalendarIsSet checks if a task calendar is set already
+func (m *CalendarRepositoryManager) CheckIfTaskCalendarIsSet(ctx context.Context, u *users.User, providerConnections users.ProviderConnections, connectionIndex int) (*users.User, error) {
+	if (*providerConnections.Connections)[connectionIndex].IsTaskCalendarConnection {
+		calendarRepository, err := m.setupRepository(ctx, u, providerConnections, connectionIndex)
 		if err != nil {
 			return nil, err
 		}
@@ -131,15 +136,53 @@ func (m *CalendarRepositoryManager) CheckIfGoogleTaskCalendarIsSet(ctx context.C
 	return u, nil
 }
 
+// setupRepository sets up the repository matching the provider of a connection
+func (m *CalendarRepositoryManager) setupRepository(ctx context.Context, u *users.User, providerConnections users.ProviderConnections, connectionIndex int) (calendar.RepositoryInterface, error) {
+	connection := (*providerConnections.Connections)[connectionIndex]
+
+	switch providerConnections.Provider {
+	case users.CalendarProviderGoogle:
+		calendarRepository, err := m.setupGoogleRepository(ctx, u, &connection, connectionIndex)
+		if err != nil {
+			return nil, err
+		}
+
+		return calendarRepository, nil
+	case users.CalendarProviderMicrosoft:
+		calendarRepository, err := m.setupMicrosoftRepository(ctx, u, &connection, connectionIndex)
+		if err != nil {
+			return nil, err
+		}
+
+		return calendarRepository, nil
+	case users.CalendarProviderCalDAV:
+		calendarRepository, err := m.setupCalDAVRepository(ctx, u, &connection)
+		if err != nil {
+			return nil, err
+		}
+
+		return calendarRepository, nil
+	case users.CalendarProviderICS:
+		calendarRepository, err := m.setupICSRepository(ctx, u, &connection)
+		if err != nil {
+			return nil, err
+		}
+
+		return calendarRepository, nil
+	}
+
+	return nil, fmt.Errorf("unknown calendar provider %s", providerConnections.Provider)
+}
+
 // setupGoogleRepository manages token refreshing and calendar creation
-func (m *CalendarRepositoryManager) setupGoogleRepository(ctx context.Context, u *users.User, connection *users.GoogleCalendarConnection, connectionIndex int) (*calendar.GoogleCalendarRepository, error) {
+func (m *CalendarRepositoryManager) setupGoogleRepository(ctx context.Context, u *users.User, connection *users.CalendarConnection, connectionIndex int) (*calendar.GoogleCalendarRepository, error) {
 	oldAccessToken := connection.Token.AccessToken
 
 	if connection.Status != users.CalendarConnectionStatusActive {
 		return nil, communication.ErrCalendarAuthInvalid
 	}
 
-	calendarRepository, err := calendar.NewGoogleCalendarRepository(ctx, u.ID, connection, m.logger, func(connection *users.GoogleCalendarConnection) {
+	calendarRepository, err := calendar.NewGoogleCalendarRepository(ctx, u.ID, connection, m.logger, func(connection *users.CalendarConnection) {
 		_, i, err := u.GoogleCalendarConnections.FindByConnectionID(connection.ID)
 		if err != nil {
 			m.logger.Error("Could not find connection", err)
@@ -171,3 +214,104 @@ func (m *CalendarRepositoryManager) setupGoogleRepository(ctx context.Context, u
 
 	return calendarRepository, nil
 }
+
+// setupMicrosoftRepository manages token refreshing for Microsoft connections
+func (m *CalendarRepositoryManager) setupMicrosoftRepository(ctx context.Context, u *users.User, connection *users.CalendarConnection, connectionIndex int) (*calendar.MicrosoftCalendarRepository, error) {
+	oldAccessToken := connection.Token.AccessToken
+
+	if connection.Status != users.CalendarConnectionStatusActive {
+		return nil, communication.ErrCalendarAuthInvalid
+	}
+
+	calendarRepository, err := calendar.NewMicrosoftCalendarRepository(ctx, u.ID, connection, m.logger, func(connection *users.CalendarConnection) {
+		_, i, err := u.MicrosoftCalendarConnections.FindByConnectionID(connection.ID)
+		if err != nil {
+			m.logger.Error("Could not find connection", err)
+			return
+		}
+
+		u.MicrosoftCalendarConnections[i] = *connection
+
+		err = m.userRepository.Update(ctx, u)
+		if err != nil {
+			m.logger.Error("Could not update user", errors.Wrap(err, "could not update user trying to update invalid connection"))
+			return
+		}
+
+		m.logger.Info(fmt.Sprintf("user with id %s updated microsoft connection %s because of an expired token ", u.ID.Hex(), connection.ID))
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if oldAccessToken != connection.Token.AccessToken {
+		u.MicrosoftCalendarConnections[connectionIndex] = *connection
+
+		err = m.userRepository.Update(ctx, u)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return calendarRepository, nil
+}
+
+// setupCalDAVRepository sets up a repository for a CalDAV connection, there is no token that needs refreshing
+func (m *CalendarRepositoryManager) setupCalDAVRepository(ctx context.Context, u *users.User, connection *users.CalendarConnection) (*calendar.CalDAVCalendarRepository, error) {
+	if connection.Status != users.CalendarConnectionStatusActive {
+		return nil, communication.ErrCalendarAuthInvalid
+	}
+
+	calendarRepository, err := calendar.NewCalDAVCalendarRepository(ctx, u.ID, connection, m.logger, func(connection *users.CalendarConnection) {
+		_, i, err := u.CalDAVCalendarConnections.FindByConnectionID(connection.ID)
+		if err != nil {
+			m.logger.Error("Could not find connection", err)
+			return
+		}
+
+		u.CalDAVCalendarConnections[i] = *connection
+
+		err = m.userRepository.Update(ctx, u)
+		if err != nil {
+			m.logger.Error("Could not update user", errors.Wrap(err, "could not update user trying to update invalid connection"))
+			return
+		}
+
+		m.logger.Info(fmt.Sprintf("user with id %s updated caldav connection %s because of invalid credentials", u.ID.Hex(), connection.ID))
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return calendarRepository, nil
+}
+
+// setupICSRepository sets up a repository for the feed of an ICS connection
+func (m *CalendarRepositoryManager) setupICSRepository(ctx context.Context, u *users.User, connection *users.CalendarConnection) (*calendar.ICSCalendarRepository, error) {
+	if connection.Status != users.CalendarConnectionStatusActive {
+		return nil, communication.ErrCalendarAuthInvalid
+	}
+
+	calendarRepository, err := calendar.NewICSCalendarRepository(ctx, u.ID, connection, m.logger, func(connection *users.CalendarConnection) {
+		_, i, err := u.ICSCalendarConnections.FindByConnectionID(connection.ID)
+		if err != nil {
+			m.logger.Error("Could not find connection", err)
+			return
+		}
+
+		u.ICSCalendarConnections[i] = *connection
+
+		err = m.userRepository.Update(ctx, u)
+		if err != nil {
+			m.logger.Error("Could not update user", errors.Wrap(err, "could not update user trying to update invalid connection"))
+			return
+		}
+
+		m.logger.Info(fmt.Sprintf("user with id %s updated ics connection %s because the feed is gone", u.ID.Hex(), connection.ID))
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return calendarRepository, nil
+}
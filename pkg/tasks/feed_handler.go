@@ -0,0 +1,223 @@
+package tasks
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/timeliness-app/timeliness-backend/internal/ical"
+	"github.com/timeliness-app/timeliness-backend/pkg/auth"
+	"github.com/timeliness-app/timeliness-backend/pkg/communication"
+	"github.com/timeliness-app/timeliness-backend/pkg/locking"
+	"github.com/timeliness-app/timeliness-backend/pkg/logger"
+	"github.com/timeliness-app/timeliness-backend/pkg/tasks/calendar"
+	"github.com/timeliness-app/timeliness-backend/pkg/users"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// feedHistory is how far into the past work units and due dates are still part of the feed
+const feedHistory = time.Hour * 24 * 30
+
+// feedRefreshInterval is the interval in which subscribed calendar apps are asked to refresh the feed
+const feedRefreshInterval = "PT15M"
+
+// feedUIDDomain makes the UIDs of the feed events globally unique
+const feedUIDDomain = "timeliness"
+
+// FeedHandler handles the ICS feed that users can subscribe to in any calendar app, it doesn't need a calendar
+// connection at all
+type FeedHandler struct {
+	UserRepository   users.UserRepositoryInterface
+	TaskRepository   TaskRepositoryInterface
+	Logger           logger.Interface
+	ResponseManager  *communication.ResponseManager
+	Locker           locking.LockerInterface
+	TaskTextRenderer *TaskTextRenderer
+}
+
+// Feed is the view of the subscription URL of the ICS feed of a user
+type Feed struct {
+	URL string `json:"url"`
+}
+
+// GetFeed is the route for getting the subscription URL of the ICS feed, the secret token is created on first use
+func (handler *FeedHandler) GetFeed(writer http.ResponseWriter, request *http.Request) {
+	handler.respondWithFeed(writer, request, false)
+}
+
+// RotateFeedToken is the route for replacing the secret token of the ICS feed, the old URL stops working
+func (handler *FeedHandler) RotateFeedToken(writer http.ResponseWriter, request *http.Request) {
+	handler.respondWithFeed(writer, request, true)
+}
+
+func (handler *FeedHandler) respondWithFeed(writer http.ResponseWriter, request *http.Request, rotate bool) {
+	userID := request.Context().Value(auth.KeyUserID).(string)
+
+	lock, err := handler.Locker.Acquire(request.Context(), fmt.Sprintf("user-%s", userID), time.Minute*3, false, time.Minute*5)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, fmt.Sprintf("Error acquiring lock for user %s", userID), err, request, nil)
+		return
+	}
+
+	defer func() {
+		if err := lock.Release(request.Context()); err != nil {
+			handler.Logger.Error(fmt.Sprintf("error while releasing lock for user %s", userID), err)
+		}
+	}()
+
+	user, err := handler.UserRepository.FindByID(request.Context(), userID)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusNotFound, fmt.Sprintf("Could not find user %s", userID), err, request, nil)
+		return
+	}
+
+	if user.FeedToken == "" || rotate {
+		user.FeedToken = uuid.New().String()
+
+		err = handler.UserRepository.UpdateFeedToken(request.Context(), user)
+		if err != nil {
+			handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, "Could not update feed token", err, request, nil)
+			return
+		}
+	}
+
+	handler.ResponseManager.Respond(writer, Feed{URL: feedURL(user.FeedToken)})
+}
+
+// ServeFeed is the unauthenticated route serving the ICS feed of the user the token in the path belongs to
+func (handler *FeedHandler) ServeFeed(writer http.ResponseWriter, request *http.Request) {
+	token := mux.Vars(request)["token"]
+
+	user, err := handler.UserRepository.FindByFeedToken(request.Context(), token)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusNotFound, "Could not find feed", err, request, nil)
+		return
+	}
+
+	from := time.Now().Add(-feedHistory)
+
+	tasks, err := handler.TaskRepository.FindForFeed(request.Context(), user.ID.Hex(), from)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, "Could not find tasks", err, request, nil)
+		return
+	}
+
+	var buffer bytes.Buffer
+	err = handler.renderFeed(tasks, from).Encode(&buffer)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, "Could not render feed", err, request, nil)
+		return
+	}
+
+	checksum := sha256.Sum256(buffer.Bytes())
+	etag := fmt.Sprintf(`"%s"`, hex.EncodeToString(checksum[:]))
+
+	writer.Header().Set("ETag", etag)
+	writer.Header().Set("Cache-Control", "private, no-cache")
+
+	if matchesETag(request.Header.Get("If-None-Match"), etag) {
+		writer.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writer.Header().Set("Content-Type", ical.ContentType)
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(buffer.Bytes()); err != nil {
+		handler.Logger.Error("error while writing feed", err)
+	}
+}
+
+// renderFeed renders the due dates, work units and breaks of the tasks that end after from, the output only
+// depends on the tasks so the ETag stays the same as long as nothing changed
+func (handler *FeedHandler) renderFeed(tasks []Task, from time.Time) *ical.Component {
+	cal := ical.NewCalendar(calendar.ICalProductID)
+	cal.Set("METHOD", "PUBLISH")
+	cal.SetText("X-WR-CALNAME", "Timeliness")
+	cal.Set("X-PUBLISHED-TTL", feedRefreshInterval)
+	cal.Properties = append(cal.Properties, ical.Property{
+		Name:   "REFRESH-INTERVAL",
+		Params: map[string]string{"VALUE": "DURATION"},
+		Value:  feedRefreshInterval,
+	})
+
+	for i := range tasks {
+		task := &tasks[i]
+
+		if !task.IsBacklog && !task.DueAt.Date.End.Before(from) {
+			event := newFeedEvent(task, fmt.Sprintf("%s-due", task.ID.Hex()), &task.DueAt, handler.TaskTextRenderer.RenderDueEventTitle(task))
+			cal.Add(event)
+		}
+
+		for j := range task.WorkUnits {
+			workUnit := &task.WorkUnits[j]
+
+			if !workUnit.ScheduledAt.Date.End.Before(from) {
+				event := newFeedEvent(task, workUnit.ID.Hex(), &workUnit.ScheduledAt, handler.TaskTextRenderer.RenderWorkUnitEventTitle(task, workUnit))
+				if handler.TaskTextRenderer.HasReminder(workUnit) {
+					alarm := &ical.Component{Name: "VALARM"}
+					alarm.Set("ACTION", "DISPLAY")
+					alarm.Set("TRIGGER", "PT0M")
+					alarm.SetText("DESCRIPTION", event.Text("SUMMARY"))
+					event.Add(alarm)
+				}
+				cal.Add(event)
+			}
+
+			if workUnit.Break != nil && !workUnit.Break.Date.End.Before(from) {
+				event := newFeedEvent(task, fmt.Sprintf("%s-break", workUnit.ID.Hex()), workUnit.Break, handler.TaskTextRenderer.RenderBreakEventTitle(task))
+				cal.Add(event)
+			}
+		}
+	}
+
+	return cal
+}
+
+func newFeedEvent(task *Task, id string, event *calendar.Event, title string) *ical.Component {
+	vevent := &ical.Component{Name: "VEVENT"}
+	vevent.Set("UID", fmt.Sprintf("%s@%s", id, feedUIDDomain))
+	vevent.SetDateTime("DTSTAMP", task.LastModifiedAt)
+	vevent.SetDateTime("LAST-MODIFIED", task.LastModifiedAt)
+	vevent.SetDateTime("DTSTART", event.Date.Start)
+	vevent.SetDateTime("DTEND", event.Date.End)
+	vevent.SetText("SUMMARY", title)
+
+	if task.Description != "" {
+		vevent.SetText("DESCRIPTION", task.Description)
+	}
+
+	if event.Blocking {
+		vevent.Set("TRANSP", "OPAQUE")
+	} else {
+		vevent.Set("TRANSP", "TRANSPARENT")
+	}
+
+	return vevent
+}
+
+// matchesETag checks an If-None-Match header, weak validators are compared like strong ones
+func matchesETag(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func feedURL(token string) string {
+	apiBaseURL := "http://localhost"
+	envBaseURL, ok := os.LookupEnv("BASE_URL")
+	if ok {
+		apiBaseURL = envBaseURL
+	}
+
+	return fmt.Sprintf("%s/v1/feed/%s.ics", apiBaseURL, token)
+}
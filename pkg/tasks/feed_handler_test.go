@@ -0,0 +1,193 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/timeliness-app/timeliness-backend/internal/ical"
+	"github.com/timeliness-app/timeliness-backend/pkg/auth"
+	"github.com/timeliness-app/timeliness-backend/pkg/communication"
+	"github.com/timeliness-app/timeliness-backend/pkg/date"
+	"github.com/timeliness-app/timeliness-backend/pkg/tasks/calendar"
+	"github.com/timeliness-app/timeliness-backend/pkg/users"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newFeedTestHandler() (*FeedHandler, *users.User) {
+	user := &users.User{ID: primitive.NewObjectID(), FeedToken: "5f0e2a4c-0000-4000-8000-000000000000"}
+
+	now := time.Now().Truncate(time.Minute)
+
+	taskRepository := &MockTaskRepository{Tasks: []*Task{
+		{
+			ID:             primitive.NewObjectID(),
+			UserID:         user.ID,
+			Name:           "Write thesis",
+			Description:    "Chapter 1, 2; and 3",
+			LastModifiedAt: now,
+			DueAt:          calendar.Event{Date: date.Timespan{Start: now.Add(time.Hour * 48), End: now.Add(time.Hour * 49)}},
+			WorkUnits: WorkUnits{
+				{
+					ID:          primitive.NewObjectID(),
+					ScheduledAt: calendar.Event{Date: date.Timespan{Start: now.Add(time.Hour), End: now.Add(time.Hour * 2)}, Blocking: true},
+					Break:       &calendar.Event{Date: date.Timespan{Start: now.Add(time.Hour * 2), End: now.Add(time.Hour*2 + time.Minute*15)}},
+				},
+			},
+		},
+		{
+			ID:             primitive.NewObjectID(),
+			UserID:         user.ID,
+			Name:           "Read book",
+			IsBacklog:      true,
+			LastModifiedAt: now,
+			WorkUnits: WorkUnits{
+				{
+					ID:          primitive.NewObjectID(),
+					IsDone:      true,
+					ScheduledAt: calendar.Event{Date: date.Timespan{Start: now.Add(-time.Hour * 3), End: now.Add(-time.Hour * 2)}, Blocking: true},
+				},
+			},
+		},
+		{
+			ID:             primitive.NewObjectID(),
+			UserID:         user.ID,
+			Name:           "Long gone",
+			LastModifiedAt: now,
+			DueAt:          calendar.Event{Date: date.Timespan{Start: now.Add(-feedHistory * 2), End: now.Add(-feedHistory * 2)}},
+		},
+		{
+			ID:             primitive.NewObjectID(),
+			UserID:         user.ID,
+			Name:           "Deleted",
+			Deleted:        true,
+			LastModifiedAt: now,
+			DueAt:          calendar.Event{Date: date.Timespan{Start: now, End: now}},
+		},
+	}}
+
+	handler := &FeedHandler{
+		UserRepository:   &users.MockUserRepository{Users: []*users.User{user}},
+		TaskRepository:   taskRepository,
+		Logger:           log,
+		ResponseManager:  &communication.ResponseManager{Logger: log},
+		Locker:           locker,
+		TaskTextRenderer: &TaskTextRenderer{},
+	}
+
+	return handler, user
+}
+
+func serveFeed(handler *FeedHandler, token string, ifNoneMatch string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/v1/feed/"+token+".ics", nil)
+	request = mux.SetURLVars(request, map[string]string{"token": token})
+	if ifNoneMatch != "" {
+		request.Header.Set("If-None-Match", ifNoneMatch)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeFeed(recorder, request)
+
+	return recorder
+}
+
+func TestFeedHandler_ServeFeed(t *testing.T) {
+	handler, user := newFeedTestHandler()
+
+	response := serveFeed(handler, user.FeedToken, "")
+	if response.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", response.Code, http.StatusOK)
+	}
+
+	if response.Header().Get("Content-Type") != ical.ContentType {
+		t.Errorf("got content type %s", response.Header().Get("Content-Type"))
+	}
+
+	cal, err := ical.Parse(strings.NewReader(response.Body.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summaries := map[string]string{}
+	for _, event := range cal.Events() {
+		summaries[event.Value("UID")] = event.Text("SUMMARY")
+
+		if event.Value("DTSTAMP") == "" || event.Value("DTSTART") == "" || event.Value("DTEND") == "" {
+			t.Errorf("event %s misses required properties", event.Value("UID"))
+		}
+	}
+
+	task := handler.TaskRepository.(*MockTaskRepository).Tasks[0]
+	backlogTask := handler.TaskRepository.(*MockTaskRepository).Tasks[1]
+	renderer := TaskTextRenderer{}
+
+	want := map[string]string{
+		task.ID.Hex() + "-due@timeliness":                 renderer.RenderDueEventTitle(task),
+		task.WorkUnits[0].ID.Hex() + "@timeliness":        renderer.RenderWorkUnitEventTitle(task, &task.WorkUnits[0]),
+		task.WorkUnits[0].ID.Hex() + "-break@timeliness":  renderer.RenderBreakEventTitle(task),
+		backlogTask.WorkUnits[0].ID.Hex() + "@timeliness": "⚙️✅ Read book",
+	}
+
+	if len(summaries) != len(want) {
+		t.Errorf("got %d events, want %d: %v", len(summaries), len(want), summaries)
+	}
+
+	for uid, summary := range want {
+		if summaries[uid] != summary {
+			t.Errorf("got summary %q for %s, want %q", summaries[uid], uid, summary)
+		}
+	}
+
+	etag := response.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no etag was set")
+	}
+
+	response = serveFeed(handler, user.FeedToken, `W/"other", `+etag)
+	if response.Code != http.StatusNotModified || response.Body.Len() != 0 {
+		t.Errorf("got status %d with %d bytes for a matching etag", response.Code, response.Body.Len())
+	}
+
+	task.Name = "Write paper"
+	response = serveFeed(handler, user.FeedToken, etag)
+	if response.Code != http.StatusOK || response.Header().Get("ETag") == etag {
+		t.Error("etag didn't change with the content of the feed")
+	}
+}
+
+func TestFeedHandler_RotateFeedToken(t *testing.T) {
+	handler, user := newFeedTestHandler()
+	oldToken := user.FeedToken
+
+	request := httptest.NewRequest(http.MethodPost, "/v1/feed/token", nil)
+	request = request.WithContext(context.WithValue(request.Context(), auth.KeyUserID, user.ID.Hex()))
+
+	recorder := httptest.NewRecorder()
+	handler.RotateFeedToken(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", recorder.Code, http.StatusOK)
+	}
+
+	feed := Feed{}
+	err := json.NewDecoder(recorder.Body).Decode(&feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.FeedToken == oldToken || !strings.HasSuffix(feed.URL, "/v1/feed/"+user.FeedToken+".ics") {
+		t.Errorf("token wasn't rotated, got url %s", feed.URL)
+	}
+
+	if response := serveFeed(handler, oldToken, ""); response.Code != http.StatusNotFound {
+		t.Errorf("got status %d for the old token, want %d", response.Code, http.StatusNotFound)
+	}
+
+	if response := serveFeed(handler, user.FeedToken, ""); response.Code != http.StatusOK {
+		t.Errorf("got status %d for the new token, want %d", response.Code, http.StatusOK)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/sync/errgroup"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -30,13 +31,14 @@ type PlanningService struct {
 	locker                    locking.LockerInterface
 	calendarRepositoryManager *CalendarRepositoryManager
 	taskTextRenderer          *TaskTextRenderer
+	tagRepository             TagRepositoryInterface
 }
 
 // NewPlanningController constructs a PlanningService that is specific for a user
 func NewPlanningController(userService users.UserRepositoryInterface,
 	taskRepository TaskRepositoryInterface,
 	logger logger.Interface, locker locking.LockerInterface,
-	calendarRepositoryManager *CalendarRepositoryManager) *PlanningService {
+	calendarRepositoryManager *CalendarRepositoryManager, tagRepository TagRepositoryInterface) *PlanningService {
 	controller := PlanningService{}
 
 	controller.userRepository = userService
@@ -45,6 +47,7 @@ func NewPlanningController(userService users.UserRepositoryInterface,
 	controller.locker = locker
 	controller.calendarRepositoryManager = calendarRepositoryManager
 	controller.taskTextRenderer = &TaskTextRenderer{}
+	controller.tagRepository = tagRepository
 
 	return &controller
 }
@@ -96,17 +99,92 @@ func (s *PlanningService) getAllRelevantUsersWithOwner(ctx context.Context, task
 	return relevantUsers, nil
 }
 
-func (s *PlanningService) initializeTimeWindow(task *Task, relevantUsers []*users.User) (*date.TimeWindow, *date.FreeConstraint, error) {
-	// TODO: This is random(first user) for now, this has to be changed when multi user support is implemented
-	location, err := time.LoadLocation(relevantUsers[0].Settings.Scheduling.TimeZone)
+// backlogHorizon is how far ahead the work of backlog tasks is planned
+const backlogHorizon = time.Hour * 24 * 14
+
+// getTagScheduling merges the scheduling rules of all tags of a task, it returns nil if none of them has any.
+//
+// If several tags set the same rule, the tag with the highest priority wins and on equal priority the tag that was
+// added to the task first. Every rule is resolved on its own, so a tag can set the timing preference while another one
+// sets the allowed timespans. The allowed timespans and allowed weekday timespans of a tag are only taken together.
+// If the merged minimum work unit duration is longer than the merged maximum, the maximum wins.
+func (s *PlanningService) getTagScheduling(ctx context.Context, task *Task) (*TagScheduling, error) {
+	if s.tagRepository == nil || len(task.Tags) == 0 {
+		return nil, nil
+	}
+
+	var rules []*TagScheduling
+	for _, tagID := range task.Tags {
+		tag, err := s.tagRepository.FindByID(ctx, tagID.Hex(), task.UserID.Hex(), false)
+		if err != nil {
+			// The tag could have been deleted in the meantime and doesn't have any rules anymore
+			s.logger.Warning(fmt.Sprintf("could not find tag %s of task %s", tagID.Hex(), task.ID.Hex()), errors.WithStack(err))
+			continue
+		}
+
+		if tag.Scheduling != nil {
+			rules = append(rules, tag.Scheduling)
+		}
+	}
+
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Priority > rules[j].Priority
+	})
+
+	merged := &TagScheduling{Priority: rules[0].Priority}
+	for _, rule := range rules {
+		if !merged.HasAllowedTimespans() && rule.HasAllowedTimespans() {
+			merged.AllowedTimespans = rule.AllowedTimespans
+			merged.AllowedWeekdayTimespans = rule.AllowedWeekdayTimespans
+		}
+
+		if merged.TimingPreference == "" {
+			merged.TimingPreference = rule.TimingPreference
+		}
+
+		if merged.MinWorkUnitDuration == 0 {
+			merged.MinWorkUnitDuration = rule.MinWorkUnitDuration
+		}
+
+		if merged.MaxWorkUnitDuration == 0 {
+			merged.MaxWorkUnitDuration = rule.MaxWorkUnitDuration
+		}
+	}
+
+	if merged.MaxWorkUnitDuration != 0 && merged.MinWorkUnitDuration > merged.MaxWorkUnitDuration {
+		merged.MinWorkUnitDuration = merged.MaxWorkUnitDuration
+	}
+
+	return merged, nil
+}
+
+// initializeTimeWindow builds the window and the constraint a task can be scheduled in.
+// The rules of the tags of the task replace the allowed timespans of the owner and the work unit durations of all users,
+// collaborators can still only work within their own allowed timespans.
+func (s *PlanningService) initializeTimeWindow(task *Task, relevantUsers []*users.User, tagScheduling *TagScheduling) (*date.TimeWindow, *date.FreeConstraint, error) {
+	// The window and the workload caps use the location of the owner
+	constraint, err := constraintForUser(relevantUsers[0])
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// TODO merge these? or only take owners constraints?; Also move this into its own function, so we can called it when needed
-	constraint := &date.FreeConstraint{
-		Location:         location,
-		AllowedTimeSpans: relevantUsers[0].Settings.Scheduling.AllowedTimespans,
+	if tagScheduling != nil && tagScheduling.HasAllowedTimespans() {
+		constraint.AllowedTimeSpans = tagScheduling.AllowedTimespans
+		constraint.AllowedWeekdayTimeSpans = tagScheduling.AllowedWeekdayTimespans
+	}
+
+	// Free time has to be within the allowed timespans of every collaborator in their own time zone
+	for _, user := range relevantUsers[1:] {
+		collaboratorConstraint, err := constraintForUser(user)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		constraint.Intersect = append(constraint.Intersect, collaboratorConstraint)
 	}
 
 	var spacing time.Duration
@@ -116,315 +194,516 @@ func (s *PlanningService) initializeTimeWindow(task *Task, relevantUsers []*user
 
 	nowRound := now().Add(time.Minute * 15).Round(time.Minute * 15)
 
-	if nowRound.Unix() == task.DueAt.Date.Start.Unix() || task.DueAt.Date.Start.Before(nowRound) {
+	if !task.IsBacklog && (nowRound.Unix() == task.DueAt.Date.Start.Unix() || task.DueAt.Date.Start.Before(nowRound)) {
 		nowRound = nowRound.Add(time.Minute * 5).Round(time.Minute * 5)
 	}
 
+	// No work can be scheduled before the task can be started
+	start := nowRound
+	if task.StartAfter != nil && task.StartAfter.After(start) {
+		start = *task.StartAfter
+	}
+
+	err = checkConstraintsOverlap(relevantUsers, start)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if tagScheduling != nil && tagScheduling.HasAllowedTimespans() {
+		if len(constraint.Test(date.Timespan{Start: start, End: start.AddDate(0, 0, 7)})) == 0 {
+			return nil, nil, &ConstraintConflictError{Users: relevantUsers, Reason: "the allowed timespans of the tags don't leave any time"}
+		}
+	}
+
+	minWorkUnitDuration, maxWorkUnitDuration := workUnitDurationsForTask(relevantUsers, tagScheduling)
+
+	// Backlog tasks don't have a due date, their work is planned within a horizon that rolls forward with time
+	end := task.DueAt.Date.Start
+	if task.IsBacklog {
+		end = start.Add(backlogHorizon)
+	}
+
+	// Work is chunked the way the owner wants it
 	return &date.TimeWindow{
-		Start:             nowRound.UTC(),
-		End:               task.DueAt.Date.Start.UTC(),
+		Start:             start.UTC(),
+		End:               end.UTC(),
 		BusyPadding:       spacing,
-		MaxWorkUnitLength: relevantUsers[0].Settings.Scheduling.MaxWorkUnitDuration,
+		MinWorkUnitLength: minWorkUnitDuration,
+		MaxWorkUnitLength: maxWorkUnitDuration,
+		ChunkLength:       relevantUsers[0].Settings.Scheduling.ChunkDuration,
+		BreakLength:       relevantUsers[0].Settings.Scheduling.BreakDuration,
+		Scorer:            getScorerForUser(relevantUsers[0], constraint.Location),
 	}, constraint, nil
 }
 
-// getAllRelevantUsers fetches all relevant users for a task, the first one is always the owner
-func (s *PlanningService) getAllRelevantUsers(ctx context.Context, task *Task) ([]*users.User, error) {
-	var initializeWithOwner *users.User
-
-	initializeWithOwner, err := s.userRepository.FindByID(ctx, task.UserID.Hex())
+// constraintForUser builds the constraint of the allowed timespans of a user in their time zone
+func constraintForUser(user *users.User) (*date.FreeConstraint, error) {
+	location, err := time.LoadLocation(user.Settings.Scheduling.TimeZone)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.getAllRelevantUsersWithOwner(ctx, task, initializeWithOwner)
+	return &date.FreeConstraint{
+		Location:                location,
+		AllowedTimeSpans:        user.Settings.Scheduling.AllowedTimespans,
+		AllowedWeekdayTimeSpans: user.Settings.Scheduling.AllowedWeekdayTimespans,
+	}, nil
 }
 
-func maxDuration(a, b time.Duration) time.Duration {
-	if a > b {
-		return a
+// ConstraintConflictError is returned when the scheduling settings of the users of a task don't leave any time to work
+type ConstraintConflictError struct {
+	Users  []*users.User
+	Reason string
+}
+
+func (e *ConstraintConflictError) Error() string {
+	var names []string
+	for _, user := range e.Users {
+		names = append(names, strings.TrimSpace(fmt.Sprintf("%s %s", user.Firstname, user.Lastname)))
 	}
-	return b
+
+	return fmt.Sprintf("scheduling settings of %s conflict: %s", strings.Join(names, ", "), e.Reason)
 }
 
-// ScheduleTask takes a task and schedules it according to workloadOverall by creating or removing WorkUnits
-// and pushes or removes events to and from the calendar. Also updates the task.
-func (s *PlanningService) ScheduleTask(ctx context.Context, t *Task, withLock bool) (*Task, error) {
-	if !t.ID.IsZero() && withLock == true {
-		lock, err := s.locker.Acquire(ctx, t.ID.Hex(), time.Second*30, false, 32*time.Second)
-		if err != nil {
-			return nil, err
-		}
+// checkConstraintsOverlap makes sure the allowed timespans of all users overlap within a week after start and
+// that their work unit durations are compatible
+func checkConstraintsOverlap(relevantUsers []*users.User, start time.Time) error {
+	if len(relevantUsers) < 2 {
+		return nil
+	}
 
-		defer func(lock locking.LockInterface, ctx context.Context) {
-			err := lock.Release(ctx)
-			if err != nil {
-				s.logger.Error("error releasing lock", errors.Wrap(err, "error releasing lock"))
-			}
-		}(lock, ctx)
+	minWorkUnitDuration, maxWorkUnitDuration := workUnitDurationsForUsers(relevantUsers)
+	if maxWorkUnitDuration != 0 && minWorkUnitDuration > maxWorkUnitDuration {
+		return &ConstraintConflictError{Users: relevantUsers, Reason: fmt.Sprintf("the minimum work unit duration %s is longer than the maximum work unit duration %s", minWorkUnitDuration, maxWorkUnitDuration)}
+	}
 
-		t, err = s.taskRepository.FindByID(ctx, t.ID.Hex(), t.UserID.Hex(), false)
+	week := date.Timespan{Start: start, End: start.AddDate(0, 0, 7)}
+
+	constraints := make([]*date.FreeConstraint, len(relevantUsers))
+	for i, user := range relevantUsers {
+		constraint, err := constraintForUser(user)
 		if err != nil {
-			return nil, err
+			return err
 		}
-	}
 
-	relevantUsers, err := s.getAllRelevantUsers(ctx, t)
-	if err != nil {
-		return nil, err
+		constraints[i] = constraint
 	}
 
-	windowTotal, constraint, err := s.initializeTimeWindow(t, relevantUsers)
-	if err != nil {
-		return nil, err
+	// Name the first pair of users that doesn't have any time in common
+	for i := range constraints {
+		for j := i + 1; j < len(constraints); j++ {
+			pair := *constraints[i]
+			pair.Intersect = []*date.FreeConstraint{constraints[j]}
+
+			if len(pair.Test(week)) == 0 {
+				return &ConstraintConflictError{Users: []*users.User{relevantUsers[i], relevantUsers[j]}, Reason: "their allowed timespans don't overlap"}
+			}
+		}
 	}
 
-	workloadToSchedule := t.WorkloadOverall
-	for _, unit := range t.WorkUnits {
-		workloadToSchedule -= unit.Workload
+	all := *constraints[0]
+	all.Intersect = constraints[1:]
+
+	if len(all.Test(week)) == 0 {
+		return &ConstraintConflictError{Users: relevantUsers, Reason: "their allowed timespans don't overlap"}
 	}
-	t.NotScheduled = 0
 
-	taskCalendarRepositories := make(map[string]calendar.RepositoryInterface)
-	var availabilityRepositories []calendar.RepositoryInterface
+	return nil
+}
+
+// workUnitDurationsForUsers returns the work unit durations all users agree on,
+// that is the longest minimum and the shortest maximum
+func workUnitDurationsForUsers(relevantUsers []*users.User) (time.Duration, time.Duration) {
+	var minWorkUnitDuration time.Duration
+	var maxWorkUnitDuration time.Duration
 
-	// TODO make TimeWindow thread safe and make this parallel
 	for _, user := range relevantUsers {
-		taskRepository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
-		if err != nil {
-			return nil, err
+		minWorkUnitDuration = maxDuration(minWorkUnitDuration, user.Settings.Scheduling.MinWorkUnitDuration)
+
+		userMax := user.Settings.Scheduling.MaxWorkUnitDuration
+		if userMax != 0 && (maxWorkUnitDuration == 0 || userMax < maxWorkUnitDuration) {
+			maxWorkUnitDuration = userMax
 		}
+	}
 
-		taskCalendarRepositories[user.ID.Hex()] = taskRepository
+	return minWorkUnitDuration, maxWorkUnitDuration
+}
 
-		// Repositories for availability
-		availabilityRepositoriesForUser, err := s.calendarRepositoryManager.GetAllAvailabilityCalendarRepositoriesForUser(ctx, user)
-		if err != nil {
-			return nil, err
-		}
+// workUnitDurationsForTask returns the work unit durations of the users unless the tags of the task set their own
+func workUnitDurationsForTask(relevantUsers []*users.User, tagScheduling *TagScheduling) (time.Duration, time.Duration) {
+	minWorkUnitDuration, maxWorkUnitDuration := workUnitDurationsForUsers(relevantUsers)
+	if tagScheduling == nil {
+		return minWorkUnitDuration, maxWorkUnitDuration
+	}
 
-		availabilityRepositories = append(availabilityRepositories, availabilityRepositoriesForUser...)
+	if tagScheduling.MinWorkUnitDuration != 0 {
+		minWorkUnitDuration = tagScheduling.MinWorkUnitDuration
 	}
 
-	// Lock scheduling so no other task can be scheduled in the meantime
-	lock, err := s.locker.Acquire(ctx, fmt.Sprintf("scheduling-%s", t.UserID.Hex()), time.Minute*1, false, 2*time.Second)
-	if err != nil {
-		return nil, errors.Wrap(err, fmt.Sprintf("could not acquire lock while scheduling task %s for user %s", t.ID.Hex(), t.UserID.Hex()))
+	if tagScheduling.MaxWorkUnitDuration != 0 {
+		maxWorkUnitDuration = tagScheduling.MaxWorkUnitDuration
 	}
 
-	defer func() {
-		err = lock.Release(ctx)
-		if err != nil {
-			s.logger.Error("could not release lock", errors.Wrap(err, fmt.Sprintf("could not release lock while scheduling task %s for user %s", t.ID.Hex(), t.UserID.Hex())))
-			return
-		}
-	}()
+	if maxWorkUnitDuration != 0 && minWorkUnitDuration > maxWorkUnitDuration {
+		minWorkUnitDuration = maxWorkUnitDuration
+	}
 
-	targetTime := s.getTargetTimeForUser(relevantUsers[0], windowTotal, workloadToSchedule)
-	windowTotal, err = s.computeAvailabilityForTimeWindow(ctx, relevantUsers, targetTime, workloadToSchedule, windowTotal, availabilityRepositories, constraint, t, "")
-	if err != nil {
-		return nil, err
+	return minWorkUnitDuration, maxWorkUnitDuration
+}
+
+// getScorerForUser builds the scorer of the scoring strategy a user has chosen
+func getScorerForUser(user *users.User, location *time.Location) date.Scorer {
+	switch user.Settings.Scheduling.ScoringStrategy {
+	case users.ScoringStrategyWeighted:
+		weights := user.Settings.Scheduling.ScoringWeights
+		return date.WeightedScorer{Factors: []date.WeightedFactor{
+			{Scorer: date.DistanceFactor{}, Weight: weights.Distance},
+			{Scorer: date.TimeOfDayFactor{Preferred: weights.PreferredTimeOfDay, Location: location}, Weight: weights.TimeOfDay},
+			{Scorer: date.NeighborFactor{}, Weight: weights.Neighbors},
+			{Scorer: date.FragmentationFactor{}, Weight: weights.Fragmentation},
+		}}
 	}
 
-	if workloadToSchedule > 0 {
-		workUnits := t.WorkUnits
+	return date.DefaultScorer{}
+}
 
-		foundWorkUnits := s.findWorkUnitTimes(windowTotal, workloadToSchedule, relevantUsers[0])
+// getAllRelevantUsers fetches all relevant users for a task, the first one is always the owner
+func (s *PlanningService) getAllRelevantUsers(ctx context.Context, task *Task) ([]*users.User, error) {
+	var initializeWithOwner *users.User
 
-		for _, workUnit := range foundWorkUnits {
-			workUnit.ScheduledAt.Blocking = true
+	initializeWithOwner, err := s.userRepository.FindByID(ctx, task.UserID.Hex())
+	if err != nil {
+		return nil, err
+	}
 
-			var workEvent *calendar.Event
-			for _, user := range relevantUsers {
-				workEvent, err = taskCalendarRepositories[user.ID.Hex()].NewEvent(&workUnit.ScheduledAt, t.ID.Hex(), s.taskTextRenderer.RenderWorkUnitEventTitle(t, &workUnit), "", s.taskTextRenderer.HasReminder(&workUnit))
-				if err != nil {
-					return nil, err
-				}
-			}
+	return s.getAllRelevantUsersWithOwner(ctx, task, initializeWithOwner)
+}
 
-			workUnit.ScheduledAt = *workEvent
-			workloadToSchedule -= workUnit.Workload
-			workUnits = workUnits.Add(&workUnit)
+// getBlockedUntil returns the end of the last work unit of all tasks that block the given task and are not done yet
+func (s *PlanningService) getBlockedUntil(ctx context.Context, task *Task) time.Time {
+	var blockedUntil time.Time
+
+	for _, predecessorID := range task.BlockedBy {
+		predecessor, err := s.taskRepository.FindByID(ctx, predecessorID.Hex(), task.UserID.Hex(), false)
+		if err != nil {
+			// The predecessor could have been deleted in the meantime and doesn't block anymore
+			s.logger.Warning(fmt.Sprintf("could not find task %s blocking task %s", predecessorID.Hex(), task.ID.Hex()), errors.WithStack(err))
+			continue
 		}
 
-		if workloadToSchedule > 0 {
-			t.NotScheduled += workloadToSchedule
+		if predecessor.IsDone {
+			continue
 		}
 
-		t.WorkUnits = workUnits
-		t.IsDone = false
-	} else {
-		var shouldDelete = WorkUnits{}
-		var shouldUpdate = WorkUnits{}
-		var workUnits = WorkUnits{}
-		for index := len(t.WorkUnits) - 1; index >= 0; index-- {
-			if index < 0 {
-				return nil, errors.New("workload can't be less than all not done work units combined")
+		for _, unit := range predecessor.WorkUnits {
+			if unit.ScheduledAt.Date.End.After(blockedUntil) {
+				blockedUntil = unit.ScheduledAt.Date.End
 			}
+		}
+	}
 
-			unit := t.WorkUnits[index]
-
-			if workloadToSchedule == 0 {
-				workUnits = workUnits.Add(&t.WorkUnits[index])
-				continue
-			}
+	return blockedUntil
+}
 
-			if unit.IsDone {
-				workUnits = workUnits.Add(&t.WorkUnits[index])
-				t.WorkUnits[index].Workload += workloadToSchedule
-				continue
-			}
+// applyDependenciesToTimeWindow moves the start of the window behind the last work unit of all tasks blocking the task.
+// If the blocking tasks end after the window, nothing can be scheduled anymore, so the window is emptied and the task
+// is flagged as blocked past its deadline.
+func (s *PlanningService) applyDependenciesToTimeWindow(ctx context.Context, task *Task, window *date.TimeWindow) {
+	blockedUntil := s.getBlockedUntil(ctx, task)
+	task.IsBlockedPastDeadline = blockedUntil.After(window.End)
 
-			// If we can cut off time of an existing WorkUnit we do that
-			if -workloadToSchedule < unit.Workload {
-				t.WorkUnits[index].Workload += workloadToSchedule
-				t.WorkUnits[index].ScheduledAt.Date.End = unit.ScheduledAt.Date.End.Add(workloadToSchedule)
+	if blockedUntil.After(window.Start) {
+		window.Start = blockedUntil.UTC()
+	}
 
-				shouldUpdate = append(shouldUpdate, t.WorkUnits[index])
+	if window.Start.After(window.End) {
+		window.Start = window.End
+	}
+}
 
-				workUnits = workUnits.Add(&t.WorkUnits[index])
-				workloadToSchedule = 0
-				continue
-			}
+// getDeadlineBuffer returns the deadline buffer of the task or of the owner if the task doesn't override it
+func getDeadlineBuffer(task *Task, owner *users.User) time.Duration {
+	if task.IsBacklog {
+		return 0
+	}
 
-			shouldDelete = append(shouldDelete, unit)
+	if task.DeadlineBuffer != nil {
+		return *task.DeadlineBuffer
+	}
 
-			workloadToSchedule += unit.Workload
-		}
+	return owner.Settings.Scheduling.DeadlineBuffer
+}
 
-		t.WorkUnits = workUnits
+// applyDeadlineBufferToTimeWindow moves the end of the window in front of the deadline buffer and returns the deadline
+func applyDeadlineBufferToTimeWindow(task *Task, owner *users.User, window *date.TimeWindow) time.Time {
+	deadline := window.End
 
-		err = s.taskRepository.Update(ctx, t, false)
-		if err != nil {
-			return nil, err
-		}
+	buffer := getDeadlineBuffer(task, owner)
+	if buffer <= 0 {
+		return deadline
+	}
 
-		for _, user := range relevantUsers {
-			for _, unit := range shouldDelete {
-				err = taskCalendarRepositories[user.ID.Hex()].DeleteEvent(&unit.ScheduledAt)
-				if err != nil {
-					return nil, err
-				}
-			}
-		}
+	window.End = deadline.Add(-buffer)
 
-		for _, user := range relevantUsers {
-			for _, unit := range shouldUpdate {
-				err = taskCalendarRepositories[user.ID.Hex()].UpdateEvent(&unit.ScheduledAt, t.ID.Hex(), s.taskTextRenderer.RenderWorkUnitEventTitle(t, &unit), "", s.taskTextRenderer.HasReminder(&unit))
-				if err != nil {
-					return nil, err
-				}
-			}
-		}
+	// If there is no time left in front of the buffer, all work falls back to the buffer
+	if window.End.Before(window.Start) && !deadline.Before(window.Start) {
+		window.End = window.Start
 	}
 
-	// Check if all work units are done, and mark the task as done if so
-	allDone := true
-	for _, unit := range t.WorkUnits {
-		if !unit.IsDone {
-			allDone = false
-		}
+	return deadline
+}
 
-		// Check if a user is missing a work unit event
-		if len(unit.ScheduledAt.CalendarEvents) != len(relevantUsers) {
-			for _, user := range relevantUsers {
-				if persistedEvent := unit.ScheduledAt.CalendarEvents.FindByUserID(user.ID.Hex()); persistedEvent != nil {
-					continue
-				}
-				newEvent, err := taskCalendarRepositories[user.ID.Hex()].NewEvent(&unit.ScheduledAt, t.ID.Hex(), s.taskTextRenderer.RenderWorkUnitEventTitle(t, &unit), "", s.taskTextRenderer.HasReminder(&unit))
-				if err != nil {
-					return nil, err
-				}
+// computeAvailabilityForDeadlineBuffer computes the free time between the end of the window and the deadline.
+// The planned timespans are already taken and count towards the workload caps.
+func (s *PlanningService) computeAvailabilityForDeadlineBuffer(ctx context.Context, relevantUsers []*users.User, window *date.TimeWindow, deadline time.Time, planned []date.Timespan, timeToSchedule time.Duration, repositories []calendar.RepositoryInterface, constraint *date.FreeConstraint, task *Task, ignoreWorkUnitIDs ...string) (*date.TimeWindow, error) {
+	bufferWindow := &date.TimeWindow{
+		Start:             window.End,
+		End:               deadline,
+		BusyPadding:       window.BusyPadding,
+		MinWorkUnitLength: window.MinWorkUnitLength,
+		MaxWorkUnitLength: window.MaxWorkUnitLength,
+		ChunkLength:       window.ChunkLength,
+		BreakLength:       window.BreakLength,
+		Scorer:            window.Scorer,
+		Trace:             window.Trace,
+	}
 
-				unit.ScheduledAt = *newEvent
-			}
-		}
+	if window.Start.After(bufferWindow.Start) {
+		bufferWindow.Start = window.Start
 	}
 
-	if len(t.WorkUnits) == 0 || t.NotScheduled > 0 {
-		allDone = false
+	for _, timespan := range planned {
+		bufferWindow.AddToBusy(timespan)
 	}
-	t.IsDone = allDone
 
-	// Create due date event if it doesn't exist
-	t, err = s.UpdateDueAtEvent(ctx, t, relevantUsers, taskCalendarRepositories, false, true)
+	// Work in the buffer should still be done as early as possible
+	bufferWindow, err := s.computeAvailabilityForTimeWindow(ctx, relevantUsers, bufferWindow.Start, timeToSchedule, bufferWindow, repositories, constraint, task, ignoreWorkUnitIDs...)
 	if err != nil {
 		return nil, err
 	}
 
-	if !t.ID.IsZero() {
-		err = s.taskRepository.Update(ctx, t, false)
-		if err != nil {
-			return nil, err
+	for _, workloadCap := range bufferWindow.WorkloadCaps {
+		for _, timespan := range planned {
+			workloadCap.AddScheduled(timespan)
 		}
 	}
 
-	t = s.CheckForMergingWorkUnits(ctx, t)
-
-	return t, nil
+	return bufferWindow, nil
 }
 
-// RescheduleWorkUnit takes a work unit and reschedules it to a time between now and the task due end, updates task
-func (s *PlanningService) RescheduleWorkUnit(ctx context.Context, t *Task, w *WorkUnit, shouldIgnoreWorkUnit bool, withLock bool) (*Task, error) {
-	if withLock == true {
-		lock, err := s.locker.Acquire(ctx, t.ID.Hex(), time.Second*30, false, 32*time.Second)
-		if err != nil {
-			return nil, err
-		}
+// findWorkUnitTimesWithDeadlineBuffer finds work units in the window and only falls back to the deadline buffer for
+// workload that would otherwise not be scheduled
+func (s *PlanningService) findWorkUnitTimesWithDeadlineBuffer(ctx context.Context, relevantUsers []*users.User, window *date.TimeWindow, deadline time.Time, durationToFind time.Duration, repositories []calendar.RepositoryInterface, constraint *date.FreeConstraint, task *Task, ignoreWorkUnitIDs ...string) (WorkUnits, error) {
+	workUnits := s.findWorkUnitTimes(window, durationToFind)
+	for _, unit := range workUnits {
+		durationToFind -= unit.Workload
+	}
 
-		defer func(lock locking.LockInterface, ctx context.Context) {
-			err := lock.Release(ctx)
-			if err != nil {
-				s.logger.Error("error releasing lock", errors.Wrap(err, "error releasing lock"))
-			}
-		}(lock, ctx)
+	if durationToFind <= 0 || !window.End.Before(deadline) {
+		return workUnits, nil
+	}
 
-		// Refresh task, after potential change
-		t, err = s.taskRepository.FindByID(ctx, t.ID.Hex(), t.UserID.Hex(), false)
+	bufferWindow, err := s.computeAvailabilityForDeadlineBuffer(ctx, relevantUsers, window, deadline, workUnits.Timespans(), durationToFind, repositories, constraint, task, ignoreWorkUnitIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, unit := range s.findWorkUnitTimes(bufferWindow, durationToFind) {
+		workUnits = workUnits.Add(&unit)
+	}
+
+	return workUnits, nil
+}
+
+// applyWorkloadCaps limits the work that can be scheduled per day and week in the window to the caps of every relevant
+// user, so that a task doesn't overload a collaborator either.
+func (s *PlanningService) applyWorkloadCaps(ctx context.Context, relevantUsers []*users.User, task *Task, window *date.TimeWindow, ignoredWorkUnits map[string]bool) error {
+	window.WorkloadCaps = nil
+
+	for _, user := range relevantUsers {
+		workloadCaps, err := s.loadWorkloadCaps(ctx, user, task, window, ignoredWorkUnits)
 		if err != nil {
-			return nil, err
+			return err
 		}
+
+		window.WorkloadCaps = append(window.WorkloadCaps, workloadCaps...)
 	}
 
-	index, _ := t.WorkUnits.FindByID(w.ID.Hex())
-	if index < 0 {
-		return nil, fmt.Errorf("could not find workunit %s in task %s", w.ID.Hex(), t.ID.Hex())
+	return nil
+}
+
+// loadWorkloadCaps creates the caps of a user in the time zone of the user and fills them with the work that is
+// already scheduled for the user. The caps of a tag only apply to tasks with that tag and only count the work units of
+// those tasks.
+func (s *PlanningService) loadWorkloadCaps(ctx context.Context, user *users.User, task *Task, window *date.TimeWindow, ignoredWorkUnits map[string]bool) ([]*date.WorkloadCap, error) {
+	settings := user.Settings.Scheduling
+	if settings.MaxWorkPerDay <= 0 && settings.MaxWorkPerWeek <= 0 && len(settings.TagWorkloadCaps) == 0 {
+		return nil, nil
 	}
 
-	relevantUsers, err := s.getAllRelevantUsers(ctx, t)
+	location, err := time.LoadLocation(settings.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	var workloadCaps []*date.WorkloadCap
+
+	var overallCap *date.WorkloadCap
+	if settings.MaxWorkPerDay > 0 || settings.MaxWorkPerWeek > 0 {
+		overallCap = &date.WorkloadCap{MaxPerDay: settings.MaxWorkPerDay, MaxPerWeek: settings.MaxWorkPerWeek, Location: location}
+		workloadCaps = append(workloadCaps, overallCap)
+	}
+
+	tagCaps := make(map[primitive.ObjectID]*date.WorkloadCap)
+	for _, tagWorkloadCap := range settings.TagWorkloadCaps {
+		if _, tag := Tags(task.Tags).FindByID(tagWorkloadCap.TagID); tag == nil {
+			continue
+		}
+
+		tagCap := &date.WorkloadCap{MaxPerDay: tagWorkloadCap.MaxWorkPerDay, MaxPerWeek: tagWorkloadCap.MaxWorkPerWeek, Location: location}
+		tagCaps[tagWorkloadCap.TagID] = tagCap
+		workloadCaps = append(workloadCaps, tagCap)
+	}
+
+	if len(workloadCaps) == 0 {
+		return nil, nil
+	}
+
+	// The weekly caps need the work of the whole weeks the window touches
+	scheduled, err := s.taskRepository.FindUnwoundIntersectingTimespan(ctx, user.ID.Hex(), date.Timespan{
+		Start: window.Start.AddDate(0, 0, -7),
+		End:   window.End.AddDate(0, 0, 7),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not find scheduled work for workload caps")
+	}
+
+	for _, unwound := range scheduled {
+		if ignoredWorkUnits[unwound.WorkUnit.ID.Hex()] {
+			continue
+		}
+
+		if overallCap != nil {
+			overallCap.AddScheduled(unwound.WorkUnit.ScheduledAt.Date)
+		}
+
+		for _, tagID := range unwound.Tags {
+			if tagCap, ok := tagCaps[tagID]; ok {
+				tagCap.AddScheduled(unwound.WorkUnit.ScheduledAt.Date)
+			}
+		}
+	}
+
+	return workloadCaps, nil
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// ScheduleTask takes a task and schedules it according to workloadOverall by creating or removing WorkUnits
+// and pushes or removes events to and from the calendar. Also updates the task.
+// Tasks that are blocked by the task are moved behind its last work unit afterwards.
+func (s *PlanningService) ScheduleTask(ctx context.Context, t *Task, withLock bool) (*Task, error) {
+	t, err := s.scheduleTask(ctx, t, withLock)
 	if err != nil {
 		return nil, err
 	}
 
-	windowTotal, constraint, err := s.initializeTimeWindow(t, relevantUsers)
+	s.moveDependentTasks(ctx, t)
+	s.bumpBacklogWorkUnits(ctx, t)
+
+	return t, nil
+}
+
+// findBacklogWorkUnitIDs finds the work units of backlog tasks of a user within a timespan that may still be moved
+func (s *PlanningService) findBacklogWorkUnitIDs(ctx context.Context, userID string, timespan date.Timespan) ([]string, error) {
+	unwound, err := s.taskRepository.FindUnwoundIntersectingTimespan(ctx, userID, timespan)
 	if err != nil {
 		return nil, err
 	}
 
-	taskRepositories := make(map[string]calendar.RepositoryInterface)
-	var availabilityRepositories []calendar.RepositoryInterface
+	var workUnitIDs []string
+	for _, task := range unwound {
+		if task.IsBacklog && isMovableWorkUnit(&task.WorkUnit) {
+			workUnitIDs = append(workUnitIDs, task.WorkUnit.ID.Hex())
+		}
+	}
 
-	// TODO Make parallel
-	for _, user := range relevantUsers {
-		taskRepository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
+	return workUnitIDs, nil
+}
+
+// bumpBacklogWorkUnits moves the work units of backlog tasks of the owner out of the way of the work units of a task
+// with a due date. Backlog work units that don't fit anywhere else anymore are added to their not scheduled workload.
+func (s *PlanningService) bumpBacklogWorkUnits(ctx context.Context, t *Task) {
+	if t.IsBacklog {
+		return
+	}
+
+	for _, unit := range t.WorkUnits {
+		if unit.IsDone {
+			continue
+		}
+
+		unwound, err := s.taskRepository.FindUnwoundIntersectingTimespan(ctx, t.UserID.Hex(), unit.ScheduledAt.Date)
 		if err != nil {
-			return nil, err
+			s.logger.Error(fmt.Sprintf("error while looking for backlog work units intersecting task %s", t.ID.Hex()), err)
+			return
 		}
 
-		taskRepositories[user.ID.Hex()] = taskRepository
+		for _, backlog := range unwound {
+			if !backlog.IsBacklog || backlog.ID == t.ID || !isMovableWorkUnit(&backlog.WorkUnit) {
+				continue
+			}
 
-		// Repositories for availability
-		availabilityRepositoriesForUser, err := s.calendarRepositoryManager.GetAllAvailabilityCalendarRepositoriesForUser(ctx, user)
+			backlogTask, err := s.taskRepository.FindByID(ctx, backlog.ID.Hex(), backlog.UserID.Hex(), false)
+			if err != nil {
+				s.logger.Error(fmt.Sprintf("error while finding backlog task %s", backlog.ID.Hex()), err)
+				continue
+			}
+
+			// Moving an earlier work unit of the same task can already have moved or merged this one
+			_, backlogUnit := backlogTask.WorkUnits.FindByID(backlog.WorkUnit.ID.Hex())
+			if backlogUnit == nil || !backlogUnit.ScheduledAt.Date.IntersectsWith(unit.ScheduledAt.Date) {
+				continue
+			}
+
+			_, err = s.RescheduleWorkUnit(ctx, backlogTask, backlogUnit, true, true)
+			if err != nil {
+				s.logger.Error(fmt.Sprintf("could not move work unit %s of backlog task %s", backlog.WorkUnit.ID.Hex(), backlog.ID.Hex()), err)
+			}
+		}
+	}
+}
+
+func (s *PlanningService) scheduleTask(ctx context.Context, t *Task, withLock bool) (*Task, error) {
+	if !t.ID.IsZero() && withLock == true {
+		lock, err := s.locker.Acquire(ctx, t.ID.Hex(), time.Second*30, false, 32*time.Second)
 		if err != nil {
 			return nil, err
 		}
 
-		availabilityRepositories = append(availabilityRepositories, availabilityRepositoriesForUser...)
-	}
+		defer func(lock locking.LockInterface, ctx context.Context) {
+			err := lock.Release(ctx)
+			if err != nil {
+				s.logger.Error("error releasing lock", errors.Wrap(err, "error releasing lock"))
+			}
+		}(lock, ctx)
 
-	ignoreWorkUnitID := ""
-	if shouldIgnoreWorkUnit {
-		ignoreWorkUnitID = w.ID.Hex()
+		t, err = s.taskRepository.FindByID(ctx, t.ID.Hex(), t.UserID.Hex(), false)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Lock scheduling so no other task can be scheduled in the meantime
@@ -439,458 +718,2589 @@ func (s *PlanningService) RescheduleWorkUnit(ctx context.Context, t *Task, w *Wo
 			s.logger.Error("could not release lock", errors.Wrap(err, fmt.Sprintf("could not release lock while scheduling task %s for user %s", t.ID.Hex(), t.UserID.Hex())))
 			return
 		}
-	}()
+	}()
+
+	plan, err := s.planTask(ctx, t, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	relevantUsers := plan.relevantUsers
+	workloadToSchedule := plan.workloadToSchedule
+	t.NotScheduled = 0
+
+	taskCalendarRepositories := make(map[string]calendar.RepositoryInterface)
+	for _, user := range relevantUsers {
+		taskRepository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+
+		taskCalendarRepositories[user.ID.Hex()] = taskRepository
+	}
+
+	if workloadToSchedule > 0 {
+		workUnits := t.WorkUnits
+
+		for _, workUnit := range plan.workUnits {
+			var workEvent *calendar.Event
+			for _, user := range relevantUsers {
+				workEvent, err = taskCalendarRepositories[user.ID.Hex()].NewEvent(&workUnit.ScheduledAt, t.ID.Hex(), s.taskTextRenderer.RenderWorkUnitEventTitle(t, &workUnit), "", s.taskTextRenderer.HasReminder(&workUnit))
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			workUnit.ScheduledAt = *workEvent
+
+			err = s.newBreakEvent(t, &workUnit, relevantUsers, taskCalendarRepositories)
+			if err != nil {
+				return nil, err
+			}
+
+			workloadToSchedule -= workUnit.Workload
+			workUnits = workUnits.Add(&workUnit)
+		}
+
+		if workloadToSchedule > 0 {
+			t.NotScheduled += workloadToSchedule
+		}
+
+		t.WorkUnits = workUnits
+		t.IsDone = false
+	} else {
+		var shouldDelete = WorkUnits{}
+		var shouldUpdate = WorkUnits{}
+		var workUnits = WorkUnits{}
+		var skippedPinned = false
+		for index := len(t.WorkUnits) - 1; index >= 0; index-- {
+			if index < 0 {
+				return nil, errors.New("workload can't be less than all not done work units combined")
+			}
+
+			unit := t.WorkUnits[index]
+
+			if workloadToSchedule == 0 {
+				workUnits = workUnits.Add(&t.WorkUnits[index])
+				continue
+			}
+
+			if unit.IsDone {
+				workUnits = workUnits.Add(&t.WorkUnits[index])
+				t.WorkUnits[index].Workload += workloadToSchedule
+				continue
+			}
+
+			// Pinned work units are kept as they are
+			if unit.IsPinned {
+				workUnits = workUnits.Add(&t.WorkUnits[index])
+				skippedPinned = true
+				continue
+			}
+
+			// If we can cut off time of an existing WorkUnit we do that
+			if -workloadToSchedule < unit.Workload {
+				t.WorkUnits[index].Workload += workloadToSchedule
+				t.WorkUnits[index].ScheduledAt.Date.End = unit.ScheduledAt.Date.End.Add(workloadToSchedule)
+
+				shouldUpdate = append(shouldUpdate, t.WorkUnits[index])
+
+				workUnits = workUnits.Add(&t.WorkUnits[index])
+				workloadToSchedule = 0
+				continue
+			}
+
+			shouldDelete = append(shouldDelete, unit)
+
+			workloadToSchedule += unit.Workload
+		}
+
+		if skippedPinned && workloadToSchedule < 0 {
+			return nil, errors.New("workload can't be less than all pinned work units combined")
+		}
+
+		t.WorkUnits = workUnits
+
+		// Shortened or deleted chunks don't need their breaks anymore
+		var breaks []*calendar.Event
+		for _, unit := range shouldDelete {
+			breaks = append(breaks, detachBreaks(t, &unit)...)
+		}
+		for _, unit := range shouldUpdate {
+			if unit.Break == nil {
+				continue
+			}
+
+			breaks = append(breaks, unit.Break)
+			if index, _ := t.WorkUnits.FindByID(unit.ID.Hex()); index >= 0 {
+				t.WorkUnits[index].Break = nil
+			}
+		}
+
+		err = s.taskRepository.Update(ctx, t, false)
+		if err != nil {
+			return nil, err
+		}
+
+		s.deleteBreakEvents(breaks, relevantUsers, taskCalendarRepositories)
+
+		for _, user := range relevantUsers {
+			for _, unit := range shouldDelete {
+				err = taskCalendarRepositories[user.ID.Hex()].DeleteEvent(&unit.ScheduledAt)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		for _, user := range relevantUsers {
+			for _, unit := range shouldUpdate {
+				err = taskCalendarRepositories[user.ID.Hex()].UpdateEvent(&unit.ScheduledAt, t.ID.Hex(), s.taskTextRenderer.RenderWorkUnitEventTitle(t, &unit), "", s.taskTextRenderer.HasReminder(&unit))
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	// Check if all work units are done, and mark the task as done if so
+	allDone := true
+	for _, unit := range t.WorkUnits {
+		if !unit.IsDone {
+			allDone = false
+		}
+
+		// Check if a user is missing a work unit event
+		if len(unit.ScheduledAt.CalendarEvents) != len(relevantUsers) {
+			for _, user := range relevantUsers {
+				if persistedEvent := unit.ScheduledAt.CalendarEvents.FindByUserID(user.ID.Hex()); persistedEvent != nil {
+					continue
+				}
+				newEvent, err := taskCalendarRepositories[user.ID.Hex()].NewEvent(&unit.ScheduledAt, t.ID.Hex(), s.taskTextRenderer.RenderWorkUnitEventTitle(t, &unit), "", s.taskTextRenderer.HasReminder(&unit))
+				if err != nil {
+					return nil, err
+				}
+
+				unit.ScheduledAt = *newEvent
+			}
+		}
+	}
+
+	if len(t.WorkUnits) == 0 || t.NotScheduled > 0 {
+		allDone = false
+	}
+	t.IsDone = allDone
+
+	// Create due date event if it doesn't exist
+	t, err = s.UpdateDueAtEvent(ctx, t, relevantUsers, taskCalendarRepositories, false, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if !t.ID.IsZero() {
+		err = s.taskRepository.Update(ctx, t, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	t = s.CheckForMergingWorkUnits(ctx, t)
+
+	return t, nil
+}
+
+// PreviewTask finds the work units ScheduleTask would create for a task without creating calendar events or
+// persisting anything. The proposed work units are returned in the task together with the workload that doesn't fit.
+func (s *PlanningService) PreviewTask(ctx context.Context, t *Task) (*Task, error) {
+	return s.previewTask(ctx, t, nil)
+}
+
+// previewTask finds the work units for the workload of a task that isn't covered by its work units yet,
+// the decisions are recorded in trace if it isn't nil
+func (s *PlanningService) previewTask(ctx context.Context, t *Task, trace *date.Trace, ignoreWorkUnitIDs ...string) (*Task, error) {
+	// Lock scheduling so the preview sees the same free time scheduling would
+	lock, err := s.locker.Acquire(ctx, fmt.Sprintf("scheduling-%s", t.UserID.Hex()), time.Minute*1, false, 2*time.Second)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("could not acquire lock while previewing task %s for user %s", t.ID.Hex(), t.UserID.Hex()))
+	}
+
+	defer func() {
+		err = lock.Release(ctx)
+		if err != nil {
+			s.logger.Error("could not release lock", errors.Wrap(err, fmt.Sprintf("could not release lock while previewing task %s for user %s", t.ID.Hex(), t.UserID.Hex())))
+			return
+		}
+	}()
+
+	plan, err := s.planTask(ctx, t, trace, ignoreWorkUnitIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	workloadToSchedule := plan.workloadToSchedule
+	t.NotScheduled = 0
+
+	for _, workUnit := range plan.workUnits {
+		workloadToSchedule -= workUnit.Workload
+		t.WorkUnits = t.WorkUnits.Add(&workUnit)
+	}
+
+	if workloadToSchedule > 0 {
+		t.NotScheduled = workloadToSchedule
+	}
+
+	return t, nil
+}
+
+// schedulePlan is where the workload of a task that isn't covered by its work units yet would go, nothing of it is in
+// a calendar or persisted yet
+type schedulePlan struct {
+	relevantUsers      []*users.User
+	workloadToSchedule time.Duration
+	workUnits          WorkUnits
+}
+
+// planTask finds the work units for the workload of a task that isn't covered by its work units yet, the decisions are
+// recorded in trace if it isn't nil. Work units of backlog tasks make room if the work of a task with a due date
+// doesn't fit otherwise. The caller has to hold the scheduling lock of the owner.
+func (s *PlanningService) planTask(ctx context.Context, t *Task, trace *date.Trace, ignoreWorkUnitIDs ...string) (*schedulePlan, error) {
+	relevantUsers, err := s.getAllRelevantUsers(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	t.IsDueDuringAbsence = isDueDuringAbsence(t, relevantUsers[0])
+
+	tagScheduling, err := s.getTagScheduling(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	windowTotal, constraint, err := s.initializeTimeWindow(t, relevantUsers, tagScheduling)
+	if err != nil {
+		return nil, err
+	}
+
+	windowTotal.Trace = trace
+
+	s.applyDependenciesToTimeWindow(ctx, t, windowTotal)
+	deadline := applyDeadlineBufferToTimeWindow(t, relevantUsers[0], windowTotal)
+
+	plan := &schedulePlan{relevantUsers: relevantUsers, workloadToSchedule: t.WorkloadOverall}
+	for _, unit := range t.WorkUnits {
+		plan.workloadToSchedule -= unit.Workload
+	}
+
+	if plan.workloadToSchedule <= 0 {
+		return plan, nil
+	}
+
+	// TODO make TimeWindow thread safe and make this parallel
+	var availabilityRepositories []calendar.RepositoryInterface
+	for _, user := range relevantUsers {
+		availabilityRepositoriesForUser, err := s.calendarRepositoryManager.GetAllAvailabilityCalendarRepositoriesForUser(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+
+		availabilityRepositories = append(availabilityRepositories, availabilityRepositoriesForUser...)
+	}
+
+	targetTime := s.getTargetTimeForUsers(relevantUsers, tagScheduling, windowTotal, plan.workloadToSchedule)
+	windowTotal, err = s.computeAvailabilityForTimeWindow(ctx, relevantUsers, targetTime, plan.workloadToSchedule, windowTotal, availabilityRepositories, constraint, t, ignoreWorkUnitIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Work units of backlog tasks make room if the work of a task with a due date doesn't fit otherwise,
+	// they are moved by ScheduleTask afterwards
+	if !t.IsBacklog && windowTotal.FreeDurationWithinCaps() < plan.workloadToSchedule {
+		backlogWorkUnitIDs, err := s.findBacklogWorkUnitIDs(ctx, t.UserID.Hex(), date.Timespan{Start: windowTotal.Start, End: deadline})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(backlogWorkUnitIDs) > 0 {
+			ignoreWorkUnitIDs = append(append([]string{}, ignoreWorkUnitIDs...), backlogWorkUnitIDs...)
+
+			windowTotal, _, err = s.initializeTimeWindow(t, relevantUsers, tagScheduling)
+			if err != nil {
+				return nil, err
+			}
+
+			windowTotal.Trace = trace
+
+			s.applyDependenciesToTimeWindow(ctx, t, windowTotal)
+			applyDeadlineBufferToTimeWindow(t, relevantUsers[0], windowTotal)
+
+			windowTotal, err = s.computeAvailabilityForTimeWindow(ctx, relevantUsers, targetTime, plan.workloadToSchedule, windowTotal, availabilityRepositories, constraint, t, ignoreWorkUnitIDs...)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	plan.workUnits, err = s.findWorkUnitTimesWithDeadlineBuffer(ctx, relevantUsers, windowTotal, deadline, plan.workloadToSchedule, availabilityRepositories, constraint, t, ignoreWorkUnitIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	for index := range plan.workUnits {
+		plan.workUnits[index].ScheduledAt.Blocking = true
+	}
+
+	return plan, nil
+}
+
+// feasibilityHorizon is how far after the due date a later due date is searched for
+const feasibilityHorizon = time.Hour * 24 * 28
+
+// Feasibility tells if the workload of a task fits into the free time before its due date
+type Feasibility struct {
+	Feasible    bool                    `json:"feasible"`
+	Workload    time.Duration           `json:"workload"`
+	Free        time.Duration           `json:"free"`
+	Shortfall   time.Duration           `json:"shortfall"`
+	Suggestions []FeasibilitySuggestion `json:"suggestions"`
+}
+
+// FeasibilitySuggestion is a change to the task that would make it fit, either a later due date or a reduced workload
+type FeasibilitySuggestion struct {
+	DueAt           *time.Time     `json:"dueAt,omitempty"`
+	WorkloadOverall *time.Duration `json:"workloadOverall,omitempty"`
+}
+
+// CheckFeasibility compares the workload of a task that still has to be scheduled with the free time between now
+// and its due date. Work units of the task and of backlog tasks that can still be moved count as free time.
+func (s *PlanningService) CheckFeasibility(ctx context.Context, t *Task) (*Feasibility, error) {
+	relevantUsers, err := s.getAllRelevantUsers(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	workload := t.WorkloadOverall
+	var ignoreWorkUnitIDs []string
+	for _, unit := range t.WorkUnits {
+		if isMovableWorkUnit(&unit) {
+			ignoreWorkUnitIDs = append(ignoreWorkUnitIDs, unit.ID.Hex())
+			continue
+		}
+
+		workload -= unit.Workload
+	}
+
+	// Backlog tasks take whatever time is left, so they always fit
+	feasibility := &Feasibility{Feasible: true, Workload: workload, Suggestions: []FeasibilitySuggestion{}}
+	if workload <= 0 || t.IsBacklog {
+		return feasibility, nil
+	}
+
+	// The window reaches past the due date, so that a later due date can be suggested
+	later := *t
+	later.DueAt.Date.Start = t.DueAt.Date.Start.Add(feasibilityHorizon)
+
+	tagScheduling, err := s.getTagScheduling(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	window, constraint, err := s.initializeTimeWindow(&later, relevantUsers, tagScheduling)
+	if err != nil {
+		return nil, err
+	}
+
+	// The window ends after the real deadline, so the flags of the task itself must not be computed with it
+	s.applyDependenciesToTimeWindow(ctx, &later, window)
+
+	// Work units of backlog tasks make room for tasks with a due date, so their time counts as free
+	backlogWorkUnitIDs, err := s.findBacklogWorkUnitIDs(ctx, t.UserID.Hex(), date.Timespan{Start: window.Start, End: window.End})
+	if err != nil {
+		return nil, err
+	}
+
+	ignoreWorkUnitIDs = append(ignoreWorkUnitIDs, backlogWorkUnitIDs...)
+
+	var availabilityRepositories []calendar.RepositoryInterface
+	for _, user := range relevantUsers {
+		availabilityRepositoriesForUser, err := s.calendarRepositoryManager.GetAllAvailabilityCalendarRepositoriesForUser(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+
+		availabilityRepositories = append(availabilityRepositories, availabilityRepositoriesForUser...)
+	}
+
+	window, err = s.computeAvailabilityForTimeWindow(ctx, relevantUsers, window.Start, window.Duration(), window, availabilityRepositories, constraint, t, ignoreWorkUnitIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	var free time.Duration
+	var fitsAt *time.Time
+	for _, timespan := range window.FreeWithinCaps() {
+		if timespan.Start.Before(t.DueAt.Date.Start) {
+			end := timespan.End
+			if end.After(t.DueAt.Date.Start) {
+				end = t.DueAt.Date.Start
+			}
+
+			feasibility.Free += end.Sub(timespan.Start)
+		}
+
+		if fitsAt == nil && free+timespan.Duration() >= workload {
+			end := timespan.Start.Add(workload - free)
+			fitsAt = &end
+		}
+
+		free += timespan.Duration()
+	}
+
+	if feasibility.Free >= workload {
+		return feasibility, nil
+	}
+
+	feasibility.Feasible = false
+	feasibility.Shortfall = workload - feasibility.Free
+
+	if fitsAt != nil {
+		dueAt := fitsAt.Truncate(time.Minute * 15)
+		if dueAt.Before(*fitsAt) {
+			dueAt = dueAt.Add(time.Minute * 15)
+		}
+
+		feasibility.Suggestions = append(feasibility.Suggestions, FeasibilitySuggestion{DueAt: &dueAt})
+	}
+
+	workloadOverall := (t.WorkloadOverall - feasibility.Shortfall).Truncate(time.Minute * 15)
+	if workloadOverall > 0 {
+		feasibility.Suggestions = append(feasibility.Suggestions, FeasibilitySuggestion{WorkloadOverall: &workloadOverall})
+	}
+
+	return feasibility, nil
+}
+
+// ScheduleExplanation shows where the open work units of a task would be scheduled now and why
+type ScheduleExplanation struct {
+	TaskID       primitive.ObjectID `json:"taskId"`
+	WorkUnits    WorkUnits          `json:"workUnits"`
+	NotScheduled time.Duration      `json:"notScheduled"`
+	BlockedUntil time.Time          `json:"blockedUntil"`
+	Trace        *date.Trace        `json:"trace"`
+}
+
+// ExplainSchedule replans the work units of a task that haven't started yet without persisting anything and records
+// every decision on the way, so that it can be explained why work units end up where they are or aren't scheduled.
+func (s *PlanningService) ExplainSchedule(ctx context.Context, task *Task) (*ScheduleExplanation, error) {
+	replanned := *task
+	replanned.WorkUnits = WorkUnits{}
+
+	var ignoreWorkUnitIDs []string
+	for _, unit := range task.WorkUnits {
+		if isMovableWorkUnit(&unit) {
+			ignoreWorkUnitIDs = append(ignoreWorkUnitIDs, unit.ID.Hex())
+			continue
+		}
+
+		replanned.WorkUnits = replanned.WorkUnits.Add(&unit)
+	}
+
+	trace := &date.Trace{}
+	previewed, err := s.previewTask(ctx, &replanned, trace, ignoreWorkUnitIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScheduleExplanation{
+		TaskID:       task.ID,
+		WorkUnits:    previewed.WorkUnits,
+		NotScheduled: previewed.NotScheduled,
+		BlockedUntil: s.getBlockedUntil(ctx, task),
+		Trace:        trace,
+	}, nil
+}
+
+// RescheduleWorkUnit takes a work unit and reschedules it to a time between now and the task due end, updates task
+// Tasks that are blocked by the task are moved behind its last work unit afterwards.
+func (s *PlanningService) RescheduleWorkUnit(ctx context.Context, t *Task, w *WorkUnit, shouldIgnoreWorkUnit bool, withLock bool) (*Task, error) {
+	t, err := s.rescheduleWorkUnit(ctx, t, w, shouldIgnoreWorkUnit, withLock)
+	if err != nil {
+		return nil, err
+	}
+
+	s.moveDependentTasks(ctx, t)
+
+	return t, nil
+}
+
+func (s *PlanningService) rescheduleWorkUnit(ctx context.Context, t *Task, w *WorkUnit, shouldIgnoreWorkUnit bool, withLock bool) (*Task, error) {
+	if withLock == true {
+		lock, err := s.locker.Acquire(ctx, t.ID.Hex(), time.Second*30, false, 32*time.Second)
+		if err != nil {
+			return nil, err
+		}
+
+		defer func(lock locking.LockInterface, ctx context.Context) {
+			err := lock.Release(ctx)
+			if err != nil {
+				s.logger.Error("error releasing lock", errors.Wrap(err, "error releasing lock"))
+			}
+		}(lock, ctx)
+
+		// Refresh task, after potential change
+		t, err = s.taskRepository.FindByID(ctx, t.ID.Hex(), t.UserID.Hex(), false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	index, _ := t.WorkUnits.FindByID(w.ID.Hex())
+	if index < 0 {
+		return nil, fmt.Errorf("could not find workunit %s in task %s", w.ID.Hex(), t.ID.Hex())
+	}
+
+	relevantUsers, err := s.getAllRelevantUsers(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	tagScheduling, err := s.getTagScheduling(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	windowTotal, constraint, err := s.initializeTimeWindow(t, relevantUsers, tagScheduling)
+	if err != nil {
+		return nil, err
+	}
+
+	s.applyDependenciesToTimeWindow(ctx, t, windowTotal)
+	deadline := applyDeadlineBufferToTimeWindow(t, relevantUsers[0], windowTotal)
+
+	taskRepositories := make(map[string]calendar.RepositoryInterface)
+	var availabilityRepositories []calendar.RepositoryInterface
+
+	// TODO Make parallel
+	for _, user := range relevantUsers {
+		taskRepository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+
+		taskRepositories[user.ID.Hex()] = taskRepository
+
+		// Repositories for availability
+		availabilityRepositoriesForUser, err := s.calendarRepositoryManager.GetAllAvailabilityCalendarRepositoriesForUser(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+
+		availabilityRepositories = append(availabilityRepositories, availabilityRepositoriesForUser...)
+	}
+
+	ignoreWorkUnitID := ""
+	if shouldIgnoreWorkUnit {
+		ignoreWorkUnitID = w.ID.Hex()
+	}
+
+	// Lock scheduling so no other task can be scheduled in the meantime
+	lock, err := s.locker.Acquire(ctx, fmt.Sprintf("scheduling-%s", t.UserID.Hex()), time.Minute*1, false, 2*time.Second)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("could not acquire lock while scheduling task %s for user %s", t.ID.Hex(), t.UserID.Hex()))
+	}
+
+	defer func() {
+		err = lock.Release(ctx)
+		if err != nil {
+			s.logger.Error("could not release lock", errors.Wrap(err, fmt.Sprintf("could not release lock while scheduling task %s for user %s", t.ID.Hex(), t.UserID.Hex())))
+			return
+		}
+	}()
+
+	targetTime := s.getTargetTimeForUsers(relevantUsers, tagScheduling, windowTotal, w.Workload)
+	windowTotal, err = s.computeAvailabilityForTimeWindow(ctx, relevantUsers, targetTime, w.Workload, windowTotal, availabilityRepositories, constraint, t, ignoreWorkUnitID)
+	if err != nil {
+		return nil, err
+	}
+
+	workloadToSchedule := w.Workload
+
+	foundWorkUnits, err := s.findWorkUnitTimesWithDeadlineBuffer(ctx, relevantUsers, windowTotal, deadline, workloadToSchedule, availabilityRepositories, constraint, t, ignoreWorkUnitID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The work unit leaves its place, so the breaks next to it don't separate any chunks anymore
+	breaks := detachBreaks(t, &t.WorkUnits[index])
+	s.deleteBreakEvents(breaks, relevantUsers, taskRepositories)
+
+	if len(foundWorkUnits) == 0 {
+		t.WorkUnits = t.WorkUnits.RemoveByIndex(index)
+
+		for _, user := range relevantUsers {
+			err = taskRepositories[user.ID.Hex()].DeleteEvent(&w.ScheduledAt)
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else if len(foundWorkUnits) > 0 {
+		t.WorkUnits[index].ScheduledAt.Date = foundWorkUnits[0].ScheduledAt.Date
+		t.WorkUnits[index].Workload = foundWorkUnits[0].Workload
+		t.WorkUnits[index].IsMissed = false
+		t.WorkUnits[index].Break = foundWorkUnits[0].Break
+
+		for _, user := range relevantUsers {
+			err = taskRepositories[user.ID.Hex()].UpdateEvent(&t.WorkUnits[index].ScheduledAt, t.ID.Hex(), s.taskTextRenderer.RenderWorkUnitEventTitle(t, &t.WorkUnits[index]), "", s.taskTextRenderer.HasReminder(&t.WorkUnits[index]))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err = s.newBreakEvent(t, &t.WorkUnits[index], relevantUsers, taskRepositories)
+		if err != nil {
+			return nil, err
+		}
+
+		t.WorkUnits.Sort()
+
+		foundWorkUnits = foundWorkUnits.RemoveByIndex(0)
+		workloadToSchedule -= workloadToSchedule
+	}
+
+	for _, workUnit := range foundWorkUnits {
+		workUnit.ScheduledAt.Blocking = true
+
+		var workEvent *calendar.Event
+		for _, user := range relevantUsers {
+			workEvent, err = taskRepositories[user.ID.Hex()].NewEvent(&workUnit.ScheduledAt, t.ID.Hex(), s.taskTextRenderer.RenderWorkUnitEventTitle(t, &workUnit), "", s.taskTextRenderer.HasReminder(&workUnit))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		workUnit.ScheduledAt = *workEvent
+
+		err = s.newBreakEvent(t, &workUnit, relevantUsers, taskRepositories)
+		if err != nil {
+			return nil, err
+		}
+
+		workloadToSchedule -= workloadToSchedule
+
+		t.WorkUnits = t.WorkUnits.Add(&workUnit)
+	}
+
+	if workloadToSchedule > 0 {
+		t.NotScheduled += workloadToSchedule
+	}
+
+	err = s.taskRepository.Update(ctx, t, false)
+	if err != nil {
+		return nil, err
+	}
+
+	t = s.CheckForMergingWorkUnits(ctx, t)
+
+	return t, nil
+}
+
+// SuggestTimespansForWorkUnit returns a list of timespans that can be used to reschedule a work unit
+func (s *PlanningService) SuggestTimespansForWorkUnit(ctx context.Context, t *Task, w *WorkUnit, ignoreTimespans []date.Timespan) ([][]date.Timespan, error) {
+	relevantUsers, err := s.getAllRelevantUsers(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	tagScheduling, err := s.getTagScheduling(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	windowTotal, constraint, err := s.initializeTimeWindow(t, relevantUsers, tagScheduling)
+	if err != nil {
+		return nil, err
+	}
+
+	s.applyDependenciesToTimeWindow(ctx, t, windowTotal)
+	deadline := applyDeadlineBufferToTimeWindow(t, relevantUsers[0], windowTotal)
+
+	for _, timespan := range ignoreTimespans {
+		windowTotal.AddToBusy(timespan)
+	}
+
+	taskCalendarRepositories := make(map[string]calendar.RepositoryInterface)
+	var availabilityRepositories []calendar.RepositoryInterface
+
+	// TODO make this parallel
+	for _, user := range relevantUsers {
+		taskRepository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+
+		taskCalendarRepositories[user.ID.Hex()] = taskRepository
+
+		// Repositories for availability
+		availabilityRepositoriesForUser, err := s.calendarRepositoryManager.GetAllAvailabilityCalendarRepositoriesForUser(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+
+		availabilityRepositories = append(availabilityRepositories, availabilityRepositoriesForUser...)
+	}
+
+	var iterations time.Duration = 5
+
+	targetTime := s.getTargetTimeForUsers(relevantUsers, tagScheduling, windowTotal, w.Workload)
+	windowTotal, err = s.computeAvailabilityForTimeWindow(ctx, relevantUsers, targetTime, w.Workload*iterations, windowTotal, availabilityRepositories, constraint, t, "")
+	if err != nil {
+		return nil, err
+	}
+
+	timespanGroups := s.findWorkUnitTimesForExactWorkload(windowTotal, w.Workload, int(iterations))
+
+	missingIterations := int(iterations) - len(timespanGroups)
+	if missingIterations <= 0 || !windowTotal.End.Before(deadline) {
+		return timespanGroups, nil
+	}
+
+	// Only the suggestions that couldn't be found before the deadline buffer are taken from the buffer
+	planned := append([]date.Timespan{}, ignoreTimespans...)
+	for _, timespanGroup := range timespanGroups {
+		planned = append(planned, timespanGroup...)
+	}
+
+	bufferWindow, err := s.computeAvailabilityForDeadlineBuffer(ctx, relevantUsers, windowTotal, deadline, planned, w.Workload*time.Duration(missingIterations), availabilityRepositories, constraint, t, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(timespanGroups, s.findWorkUnitTimesForExactWorkload(bufferWindow, w.Workload, missingIterations)...), nil
+}
+
+func (s *PlanningService) findWorkUnitTimes(w *date.TimeWindow, durationToFind time.Duration) WorkUnits {
+	var workUnits WorkUnits
+	if w.FreeDuration() == 0 {
+		return workUnits
+	}
+
+	if w.ChunkLength > 0 {
+		return s.findChunkedWorkUnitTimes(w, durationToFind)
+	}
+
+	minWorkUnitDuration, maxWorkUnitDuration := w.MinWorkUnitLength, w.MaxWorkUnitLength
+
+	minDuration := minWorkUnitDuration
+	if durationToFind < minWorkUnitDuration {
+		minDuration = durationToFind
+	}
+	maxDuration := maxWorkUnitDuration
+
+	for w.FreeDuration() >= 0 && durationToFind > 0 {
+		if durationToFind < maxWorkUnitDuration {
+			if durationToFind < WorkUnitDurationMin {
+				minDuration = durationToFind
+			}
+			maxDuration = durationToFind
+		}
+
+		slot := w.FindTimeSlot(&date.RuleDuration{Minimum: minDuration, Maximum: maxDuration})
+		if slot == nil {
+			break
+		}
+		durationToFind -= slot.Duration()
+		workUnits = append(workUnits, WorkUnit{ScheduledAt: calendar.Event{Date: *slot}, Workload: slot.Duration()})
+	}
+
+	return workUnits
+}
+
+// findChunkedWorkUnitTimes finds work units of a fixed length. Consecutive chunks are searched as one time slot that
+// is split into chunks, each followed by a break that belongs to the work unit before it.
+// Only the last chunk can be shorter, if there is not enough workload left.
+func (s *PlanningService) findChunkedWorkUnitTimes(w *date.TimeWindow, durationToFind time.Duration) WorkUnits {
+	var workUnits WorkUnits
+
+	maxChunks := 1
+	if w.MaxWorkUnitLength > w.ChunkLength {
+		maxChunks = int((w.MaxWorkUnitLength + w.BreakLength) / (w.ChunkLength + w.BreakLength))
+	}
+
+	for w.FreeDuration() > 0 && durationToFind > 0 {
+		chunks := int((durationToFind + w.ChunkLength - 1) / w.ChunkLength)
+		if chunks > maxChunks {
+			chunks = maxChunks
+		}
+
+		// The break after the last chunk is part of the slot, so that the next slot can't start right away
+		minimum := minDuration(durationToFind, w.ChunkLength)
+		maximum := minDuration(durationToFind, time.Duration(chunks)*w.ChunkLength) + time.Duration(chunks)*w.BreakLength
+
+		slot := w.FindTimeSlot(&date.RuleDuration{Minimum: minimum, Maximum: maximum})
+		if slot == nil {
+			break
+		}
+
+		start := slot.Start
+		for durationToFind > 0 {
+			end := start.Add(minDuration(durationToFind, w.ChunkLength))
+			if end.After(slot.End) {
+				break
+			}
+
+			unit := WorkUnit{ScheduledAt: calendar.Event{Date: date.Timespan{Start: start, End: end}}, Workload: end.Sub(start)}
+			durationToFind -= unit.Workload
+			start = end.Add(w.BreakLength)
+
+			if w.BreakLength > 0 && durationToFind > 0 && !start.After(slot.End) {
+				unit.Break = &calendar.Event{Date: date.Timespan{Start: end, End: start}}
+			}
+
+			workUnits = append(workUnits, unit)
+		}
+	}
+
+	// A break is only kept if another chunk follows right after it
+	starts := make(map[int64]bool)
+	for _, unit := range workUnits {
+		starts[unit.ScheduledAt.Date.Start.Unix()] = true
+	}
+
+	for index, unit := range workUnits {
+		if unit.Break != nil && !starts[unit.Break.Date.End.Unix()] {
+			workUnits[index].Break = nil
+		}
+	}
+
+	return workUnits
+}
+
+// newBreakEvent writes the break after a work unit as a non-blocking event to the task calendars of all users
+func (s *PlanningService) newBreakEvent(task *Task, unit *WorkUnit, relevantUsers []*users.User, taskCalendarRepositories map[string]calendar.RepositoryInterface) error {
+	if unit.Break == nil {
+		return nil
+	}
+
+	unit.Break.Blocking = false
+
+	var breakEvent *calendar.Event
+	for _, user := range relevantUsers {
+		var err error
+		breakEvent, err = taskCalendarRepositories[user.ID.Hex()].NewEvent(unit.Break, task.ID.Hex(), s.taskTextRenderer.RenderBreakEventTitle(task), "", false)
+		if err != nil {
+			return err
+		}
+	}
+
+	unit.Break = breakEvent
+	return nil
+}
+
+// deleteBreakEvents deletes the events of breaks from the task calendars of all users
+func (s *PlanningService) deleteBreakEvents(breaks []*calendar.Event, relevantUsers []*users.User, taskCalendarRepositories map[string]calendar.RepositoryInterface) {
+	for _, user := range relevantUsers {
+		for _, breakEvent := range breaks {
+			err := taskCalendarRepositories[user.ID.Hex()].DeleteEvent(breakEvent)
+			if err != nil {
+				s.logger.Warning(fmt.Sprintf("failed to delete break event for user %s", user.ID.Hex()), errors.WithStack(err))
+			}
+		}
+	}
+}
+
+// isSameBreak tells if a planned break takes the same time as an existing one, which can be kept as it is then
+func isSameBreak(existing *calendar.Event, planned *calendar.Event) bool {
+	if existing == nil || planned == nil {
+		return existing == planned
+	}
+
+	return existing.Date.Start.Equal(planned.Date.Start) && existing.Date.End.Equal(planned.Date.End)
+}
+
+// detachBreaks removes the breaks before and after a work unit that is moved or deleted, because they don't separate
+// two chunks anymore. The events of the returned breaks still have to be deleted.
+func detachBreaks(task *Task, unit *WorkUnit) []*calendar.Event {
+	var breaks []*calendar.Event
+	if unit.Break != nil {
+		breaks = append(breaks, unit.Break)
+	}
+
+	for index := range task.WorkUnits {
+		other := &task.WorkUnits[index]
+		if other.Break == nil || (other.ID != unit.ID && !other.Break.Date.End.Equal(unit.ScheduledAt.Date.Start)) {
+			continue
+		}
+
+		if other.ID != unit.ID || unit.Break == nil {
+			breaks = append(breaks, other.Break)
+		}
+		other.Break = nil
+	}
+
+	unit.Break = nil
+	return breaks
+}
+
+func (s *PlanningService) findWorkUnitTimesForExactWorkload(w *date.TimeWindow, durationToFindPerIteration time.Duration, iterations int) [][]date.Timespan {
+	var timespanGroups = make([][]date.Timespan, 0)
+
+	if w.FreeDuration() == 0 || w.FreeDuration() < durationToFindPerIteration {
+		return timespanGroups
+	}
+
+	minWorkUnitDuration, maxWorkUnitDuration := w.MinWorkUnitLength, w.MaxWorkUnitLength
+
+	for i := 0; i < iterations; i++ {
+		durationToFind := durationToFindPerIteration
+
+		minDuration := minWorkUnitDuration
+		if durationToFind < minWorkUnitDuration {
+			minDuration = durationToFind
+		}
+		maxDuration := maxWorkUnitDuration
+
+		timespanGroup := make([]date.Timespan, 0)
+
+		for w.FreeDuration() >= 0 && durationToFind > 0 {
+			if durationToFind < maxWorkUnitDuration {
+				if durationToFind < WorkUnitDurationMin {
+					minDuration = durationToFind
+				}
+				maxDuration = durationToFind
+			}
+
+			slot := w.FindTimeSlot(&date.RuleDuration{Minimum: minDuration, Maximum: maxDuration})
+			if slot == nil {
+				break
+			}
+			durationToFind -= slot.Duration()
+
+			timespanGroup = append(timespanGroup, *slot)
+		}
+
+		if durationToFind != 0 {
+			break
+		}
+
+		sort.Slice(timespanGroup, func(i, j int) bool {
+			return timespanGroup[i].Start.Before(timespanGroup[j].Start)
+		})
+
+		timespanGroups = append(timespanGroups, timespanGroup)
+	}
+
+	sort.Slice(timespanGroups, func(i, j int) bool {
+		return timespanGroups[i][0].Start.Before(timespanGroups[j][0].Start)
+	})
+
+	return timespanGroups
+}
+
+// CreateNewSpecificWorkUnits creates new work units for a specific task and times (won't save the task)
+func (s *PlanningService) CreateNewSpecificWorkUnits(ctx context.Context, task *Task, timespans []date.Timespan) (*Task, error) {
+	relevantUsers, err := s.getAllRelevantUsers(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, timespan := range timespans {
+		workUnit := WorkUnit{
+			ID:       primitive.NewObjectID(),
+			Workload: timespan.Duration(),
+			ScheduledAt: calendar.Event{
+				Blocking: true,
+				Date:     timespan,
+			},
+		}
+
+		title := s.taskTextRenderer.RenderWorkUnitEventTitle(task, &workUnit)
+
+		for _, user := range relevantUsers {
+			repository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
+			if err != nil {
+				return nil, err
+			}
+
+			event, err := repository.NewEvent(&workUnit.ScheduledAt, task.ID.Hex(), title, "", s.taskTextRenderer.HasReminder(&workUnit))
+			if err != nil {
+				return nil, err
+			}
+
+			workUnit.ScheduledAt = *event
+		}
+
+		task.WorkloadOverall += workUnit.Workload
+		task.WorkUnits = task.WorkUnits.Add(&workUnit)
+	}
+
+	return task, nil
+}
+
+// UpdateDueAtEvent updates a due at event, creates missing events and deletes event when necessary
+func (s *PlanningService) UpdateDueAtEvent(ctx context.Context, task *Task, relevantUsers []*users.User, taskCalendarRepositories map[string]calendar.RepositoryInterface, needsUpdate bool, ownerNeedsUpdate bool) (*Task, error) {
+	// Backlog tasks don't have a due date, events left from before the task was moved to the backlog are removed
+	if task.IsBacklog {
+		if task.DueAt.CalendarEvents.IsEmpty() {
+			return task, nil
+		}
+
+		for _, user := range relevantUsers {
+			if persistedEvent := task.DueAt.CalendarEvents.FindByUserID(user.ID.Hex()); persistedEvent == nil {
+				continue
+			}
+
+			err := taskCalendarRepositories[user.ID.Hex()].DeleteEvent(&task.DueAt)
+			if err != nil {
+				return nil, err
+			}
+
+			task.DueAt.CalendarEvents = task.DueAt.CalendarEvents.RemoveByUserID(user.ID.Hex())
+		}
+
+		err := s.taskRepository.Update(ctx, task, false)
+		if err != nil {
+			return nil, err
+		}
+
+		return task, nil
+	}
+
+	// Create a new event for the due at date if it doesn't exist for a user
+	if !task.IsDone && len(task.DueAt.CalendarEvents) != len(relevantUsers) {
+		task.DueAt.Blocking = false
+		task.DueAt.Date.End = task.DueAt.Date.Start.Add(time.Minute * 15)
+
+		var dueEvent *calendar.Event
+		for _, user := range relevantUsers {
+			if persistedEvent := task.DueAt.CalendarEvents.FindByUserID(user.ID.Hex()); persistedEvent != nil {
+				continue
+			}
+
+			var err error
+			dueEvent, err = taskCalendarRepositories[user.ID.Hex()].NewEvent(&task.DueAt, task.ID.Hex(), s.taskTextRenderer.RenderDueEventTitle(task), "", s.taskTextRenderer.HasReminder(task))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		task.DueAt = *dueEvent
+
+		err := s.taskRepository.Update(ctx, task, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Remove event when it's not needed anymore
+	if relevantUsers[0].Settings.Scheduling.HideDeadlineWhenDone && task.IsDone {
+		for _, user := range relevantUsers {
+			if persistedEvent := task.DueAt.CalendarEvents.FindByUserID(user.ID.Hex()); persistedEvent == nil {
+				continue
+			}
+
+			var err error
+			err = taskCalendarRepositories[user.ID.Hex()].DeleteEvent(&task.DueAt)
+			if err != nil {
+				// We ignore the error here, because we don't want to stop the update process
+			}
+
+			task.DueAt.CalendarEvents = task.DueAt.CalendarEvents.RemoveByUserID(user.ID.Hex())
+		}
+
+		err := s.taskRepository.Update(ctx, task, false)
+		if err != nil {
+			return nil, err
+		}
+
+		return task, nil
+	}
+
+	if !needsUpdate {
+		return task, nil
+	}
+
+	for _, user := range relevantUsers {
+		if user.ID.Hex() == task.UserID.Hex() && !ownerNeedsUpdate {
+			continue
+		}
+
+		repository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+
+		err = repository.UpdateEvent(&task.DueAt, task.ID.Hex(), s.taskTextRenderer.RenderDueEventTitle(task), "", s.taskTextRenderer.HasReminder(task))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := s.taskRepository.Update(ctx, task, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// UpdateWorkUnitEvent updates a work unit event
+func (s *PlanningService) UpdateWorkUnitEvent(ctx context.Context, task *Task, unit *WorkUnit) error {
+	relevantUsers, err := s.getAllRelevantUsers(ctx, task)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range relevantUsers {
+		repository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
+		if err != nil {
+			return err
+		}
+
+		err = repository.UpdateEvent(&unit.ScheduledAt, task.ID.Hex(), s.taskTextRenderer.RenderWorkUnitEventTitle(task, unit), "", s.taskTextRenderer.HasReminder(unit))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateTaskTitle updates the events of the tasks and work units
+func (s *PlanningService) UpdateTaskTitle(ctx context.Context, task *Task, updateWorkUnits bool) error {
+	relevantUsers, err := s.getAllRelevantUsers(ctx, task)
+	if err != nil {
+		return err
+	}
+
+	repositories := make(map[string]calendar.RepositoryInterface)
+
+	for _, user := range relevantUsers {
+		repository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
+		if err != nil {
+			return err
+		}
+
+		repositories[user.ID.Hex()] = repository
+	}
+
+	task, err = s.UpdateDueAtEvent(ctx, task, relevantUsers, repositories, true, true)
+	if err != nil {
+		return err
+	}
+
+	if !updateWorkUnits {
+		return nil
+	}
+
+	for _, unit := range task.WorkUnits {
+		unitTitle := s.taskTextRenderer.RenderWorkUnitEventTitle(task, &unit)
+
+		for _, user := range relevantUsers {
+			err = repositories[user.ID.Hex()].UpdateEvent(&unit.ScheduledAt, task.ID.Hex(), unitTitle, "", s.taskTextRenderer.HasReminder(&unit))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// UpdateWorkUnitTitle updates the event title of a work unit
+func (s *PlanningService) UpdateWorkUnitTitle(ctx context.Context, task *Task, unit *WorkUnit) error {
+	title := s.taskTextRenderer.RenderWorkUnitEventTitle(task, unit)
+
+	relevantUsers, err := s.getAllRelevantUsers(ctx, task)
+	if err != nil {
+		return err
+	}
+
+	repositories := make(map[string]calendar.RepositoryInterface)
+
+	for _, user := range relevantUsers {
+		repository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
+		if err != nil {
+			return err
+		}
+
+		repositories[user.ID.Hex()] = repository
+
+		err = repository.UpdateEvent(&unit.ScheduledAt, task.ID.Hex(), title, "", s.taskTextRenderer.HasReminder(unit))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteTask deletes all events that are connected to a task
+func (s *PlanningService) DeleteTask(ctx context.Context, task *Task) error {
+	err := s.taskRepository.Delete(ctx, task.ID.Hex(), task.UserID.Hex())
+	if err != nil {
+		return err
+	}
+
+	relevantUsers, err := s.getAllRelevantUsers(ctx, task)
+	if err != nil {
+		return err
+	}
+
+	repositories := make(map[string]calendar.RepositoryInterface)
+
+	// TODO make these parallel
+	for _, user := range relevantUsers {
+		repository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
+		if err != nil {
+			return err
+		}
+
+		repositories[user.ID.Hex()] = repository
+
+		for _, unit := range task.WorkUnits {
+			if unit.Break != nil {
+				err = repository.DeleteEvent(unit.Break)
+				if err != nil {
+					s.logger.Warning(fmt.Sprintf("failed to delete break event after work unit %s", unit.ID.Hex()), errors.WithStack(err))
+				}
+			}
+
+			err = repository.DeleteEvent(&unit.ScheduledAt)
+			if err != nil {
+				s.logger.Warning(fmt.Sprintf("failed to delete work unit %s event", unit.ID.Hex()), errors.WithStack(err))
+				continue
+			}
+		}
+	}
+
+	for _, user := range relevantUsers {
+		// Backlog tasks don't have a due event
+		if task.IsBacklog {
+			break
+		}
+
+		err = repositories[user.ID.Hex()].DeleteEvent(&task.DueAt)
+		if err != nil {
+			s.logger.Warning(fmt.Sprintf("failed to delete task %s event", task.ID.Hex()), errors.WithStack(err))
+		}
+	}
+
+	return nil
+}
+
+// ErrDependencyCycle is returned when a task would be blocked by itself through its dependencies
+var ErrDependencyCycle = errors.New("task dependencies contain a cycle")
+
+// ValidateDependencies checks that all tasks blocking the task exist and that the dependencies contain no cycle
+func (s *PlanningService) ValidateDependencies(ctx context.Context, task *Task) error {
+	visited := make(map[primitive.ObjectID]bool)
+	toVisit := append([]primitive.ObjectID{}, task.BlockedBy...)
+
+	for len(toVisit) > 0 {
+		predecessorID := toVisit[0]
+		toVisit = toVisit[1:]
+
+		if predecessorID == task.ID {
+			return ErrDependencyCycle
+		}
+
+		if visited[predecessorID] {
+			continue
+		}
+		visited[predecessorID] = true
+
+		predecessor, err := s.taskRepository.FindByID(ctx, predecessorID.Hex(), task.UserID.Hex(), false)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("could not find blocking task %s", predecessorID.Hex()))
+		}
+
+		toVisit = append(toVisit, predecessor.BlockedBy...)
+	}
+
+	return nil
+}
+
+// RescheduleBlockedWorkUnits reschedules all work units of a task that start before the tasks blocking it are done
+func (s *PlanningService) RescheduleBlockedWorkUnits(ctx context.Context, task *Task, withLock bool) (*Task, error) {
+	blockedUntil := s.getBlockedUntil(ctx, task)
+
+	var toReschedule []WorkUnit
+	for _, unit := range task.WorkUnits {
+		if !unit.IsDone && !unit.IsPinned && unit.ScheduledAt.Date.Start.Before(blockedUntil) {
+			toReschedule = append(toReschedule, unit)
+		}
+	}
+
+	if len(toReschedule) == 0 {
+		return task, nil
+	}
+
+	for _, unit := range toReschedule {
+		var err error
+		task, err = s.rescheduleWorkUnit(ctx, task, &unit, true, withLock)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The tasks blocked by this one only have to be moved once after all of its work units moved
+	s.moveDependentTasks(ctx, task)
+
+	return task, nil
+}
+
+// moveDependentTasks moves the work units of all tasks blocked by the given task behind its last work unit
+func (s *PlanningService) moveDependentTasks(ctx context.Context, task *Task) {
+	if task.ID.IsZero() {
+		return
+	}
+
+	dependents, err := s.taskRepository.FindBlockedBy(ctx, task.ID.Hex(), task.UserID.Hex())
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("could not find tasks blocked by task %s", task.ID.Hex()), err)
+		return
+	}
+
+	for _, dependent := range dependents {
+		dependent := dependent
+
+		_, err = s.RescheduleBlockedWorkUnits(ctx, &dependent, true)
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("could not move task %s blocked by task %s", dependent.ID.Hex(), task.ID.Hex()), err)
+			continue
+		}
+	}
+}
+
+// RebalanceResult summarizes the calendar changes made by a rebalance
+type RebalanceResult struct {
+	Tasks        int           `json:"tasks"`
+	Created      int           `json:"created"`
+	Updated      int           `json:"updated"`
+	Deleted      int           `json:"deleted"`
+	NotScheduled time.Duration `json:"notScheduled"`
+}
+
+// isMovableWorkUnit returns true if a rebalance may move the work unit
+func isMovableWorkUnit(unit *WorkUnit) bool {
+	return !unit.IsDone && !unit.IsPinned && unit.ScheduledAt.Date.Start.After(now())
+}
+
+// sortTasksForRebalance orders tasks by their due date (earliest deadline first) followed by the backlog tasks,
+// a task that blocks other tasks is always placed before them
+func sortTasksForRebalance(tasks []Task) []Task {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].IsBacklog != tasks[j].IsBacklog {
+			return !tasks[i].IsBacklog
+		}
+
+		return tasks[i].DueAt.Date.Start.Before(tasks[j].DueAt.Date.Start)
+	})
+
+	byID := make(map[primitive.ObjectID]*Task)
+	for i := range tasks {
+		byID[tasks[i].ID] = &tasks[i]
+	}
+
+	sorted := make([]Task, 0, len(tasks))
+	visited := make(map[primitive.ObjectID]bool)
+
+	var visit func(task *Task)
+	visit = func(task *Task) {
+		if visited[task.ID] {
+			return
+		}
+		visited[task.ID] = true
+
+		for _, predecessorID := range task.BlockedBy {
+			if predecessor, ok := byID[predecessorID]; ok {
+				visit(predecessor)
+			}
+		}
+
+		sorted = append(sorted, *task)
+	}
+
+	for i := range tasks {
+		visit(&tasks[i])
+	}
+
+	return sorted
+}
+
+// RebalanceTasks replans all open work units of a user that haven't started yet at once.
+// Tasks are planned earliest deadline first, so that an urgent task doesn't lose its time to tasks with more slack.
+// Done and already started work units stay where they are, and so do the work units of tasks that are currently
+// locked elsewhere. Calendar events are only changed for work units that actually move.
+func (s *PlanningService) RebalanceTasks(ctx context.Context, userID string) (*RebalanceResult, error) {
+	// Lock scheduling so no other task can be scheduled in the meantime
+	lock, err := s.locker.Acquire(ctx, fmt.Sprintf("scheduling-%s", userID), time.Minute*5, false, 2*time.Second)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("could not acquire lock while rebalancing tasks for user %s", userID))
+	}
+
+	defer func() {
+		err := lock.Release(ctx)
+		if err != nil {
+			s.logger.Error("could not release lock", errors.Wrap(err, fmt.Sprintf("could not release lock while rebalancing tasks for user %s", userID)))
+		}
+	}()
+
+	openTasks, err := s.taskRepository.FindOpenTasks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tasks that are changed elsewhere right now keep their work units
+	var tasks []Task
+	for _, task := range openTasks {
+		taskLock, err := s.locker.Acquire(ctx, task.ID.Hex(), time.Minute*5, true, 0)
+		if err != nil {
+			continue
+		}
+
+		defer func(lock locking.LockInterface, ctx context.Context) {
+			err := lock.Release(ctx)
+			if err != nil {
+				s.logger.Error("error releasing lock", errors.Wrap(err, "error releasing lock"))
+			}
+		}(taskLock, ctx)
+
+		tasks = append(tasks, task)
+	}
+
+	tasks = sortTasksForRebalance(tasks)
+
+	result := &RebalanceResult{Tasks: len(tasks)}
+
+	for i := range tasks {
+		task := &tasks[i]
+
+		// Work units of tasks that are not replanned yet don't block time, replanned tasks are already persisted
+		var movableWorkUnitIDs []string
+		for _, remaining := range tasks[i:] {
+			for _, unit := range remaining.WorkUnits {
+				if isMovableWorkUnit(&unit) {
+					movableWorkUnitIDs = append(movableWorkUnitIDs, unit.ID.Hex())
+				}
+			}
+		}
+
+		relevantUsers, err := s.getAllRelevantUsers(ctx, task)
+		if err != nil {
+			return nil, err
+		}
+
+		tagScheduling, err := s.getTagScheduling(ctx, task)
+		if err != nil {
+			return nil, err
+		}
+
+		windowTotal, constraint, err := s.initializeTimeWindow(task, relevantUsers, tagScheduling)
+		if err != nil {
+			return nil, err
+		}
+
+		// Predecessors are planned before, so their new work units are already persisted
+		s.applyDependenciesToTimeWindow(ctx, task, windowTotal)
+		deadline := applyDeadlineBufferToTimeWindow(task, relevantUsers[0], windowTotal)
+
+		workloadToSchedule := task.WorkloadOverall
+		for _, unit := range task.WorkUnits {
+			if !isMovableWorkUnit(&unit) {
+				workloadToSchedule -= unit.Workload
+			}
+		}
+
+		taskCalendarRepositories := make(map[string]calendar.RepositoryInterface)
+		var availabilityRepositories []calendar.RepositoryInterface
+		for _, user := range relevantUsers {
+			taskRepository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
+			if err != nil {
+				return nil, err
+			}
+
+			taskCalendarRepositories[user.ID.Hex()] = taskRepository
+
+			availabilityRepositoriesForUser, err := s.calendarRepositoryManager.GetAllAvailabilityCalendarRepositoriesForUser(ctx, user)
+			if err != nil {
+				return nil, err
+			}
+
+			availabilityRepositories = append(availabilityRepositories, availabilityRepositoriesForUser...)
+		}
+
+		var foundWorkUnits WorkUnits
+		if workloadToSchedule > 0 {
+			targetTime := s.getTargetTimeForUsers(relevantUsers, tagScheduling, windowTotal, workloadToSchedule)
+			windowTotal, err = s.computeAvailabilityForTimeWindow(ctx, relevantUsers, targetTime, workloadToSchedule, windowTotal, availabilityRepositories, constraint, task, movableWorkUnitIDs...)
+			if err != nil {
+				return nil, err
+			}
+
+			foundWorkUnits, err = s.findWorkUnitTimesWithDeadlineBuffer(ctx, relevantUsers, windowTotal, deadline, workloadToSchedule, availabilityRepositories, constraint, task, movableWorkUnitIDs...)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, unit := range foundWorkUnits {
+			workloadToSchedule -= unit.Workload
+		}
+
+		task.NotScheduled = 0
+		if workloadToSchedule > 0 {
+			task.NotScheduled = workloadToSchedule
+			result.NotScheduled += workloadToSchedule
+		}
+
+		err = s.applyRebalancedWorkUnits(task, relevantUsers, taskCalendarRepositories, foundWorkUnits, result)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.taskRepository.Update(ctx, task, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// applyRebalancedWorkUnits replaces the movable work units of a task with the found ones.
+// Work units that keep their time are left untouched, moved work units reuse existing events
+// and only the difference in the number of work units creates or deletes events.
+func (s *PlanningService) applyRebalancedWorkUnits(task *Task, relevantUsers []*users.User, taskCalendarRepositories map[string]calendar.RepositoryInterface, foundWorkUnits WorkUnits, result *RebalanceResult) error {
+	var workUnits WorkUnits
+	var previous WorkUnits
+	for _, unit := range task.WorkUnits {
+		if isMovableWorkUnit(&unit) {
+			previous = append(previous, unit)
+			continue
+		}
+
+		workUnits = workUnits.Add(&unit)
+	}
+
+	// Only the breaks of work units that moved or were removed are written again
+	var breaks []*calendar.Event
+
+	var moved WorkUnits
+	for _, unit := range foundWorkUnits {
+		kept := false
+		for index, previousUnit := range previous {
+			if previousUnit.ScheduledAt.Date.Start.Equal(unit.ScheduledAt.Date.Start) && previousUnit.ScheduledAt.Date.End.Equal(unit.ScheduledAt.Date.End) {
+				if !isSameBreak(previousUnit.Break, unit.Break) {
+					if previousUnit.Break != nil {
+						breaks = append(breaks, previousUnit.Break)
+					}
+					previousUnit.Break = unit.Break
+
+					err := s.newBreakEvent(task, &previousUnit, relevantUsers, taskCalendarRepositories)
+					if err != nil {
+						return err
+					}
+				}
+
+				workUnits = workUnits.Add(&previousUnit)
+				previous = previous.RemoveByIndex(index)
+				kept = true
+				break
+			}
+		}
+
+		if !kept {
+			moved = append(moved, unit)
+		}
+	}
+
+	for _, unit := range moved {
+		if len(previous) > 0 {
+			previousUnit := previous[0]
+			previous = previous[1:]
+
+			if previousUnit.Break != nil {
+				breaks = append(breaks, previousUnit.Break)
+			}
+
+			previousUnit.ScheduledAt.Date = unit.ScheduledAt.Date
+			previousUnit.Workload = unit.Workload
+			previousUnit.Break = unit.Break
+
+			for _, user := range relevantUsers {
+				err := taskCalendarRepositories[user.ID.Hex()].UpdateEvent(&previousUnit.ScheduledAt, task.ID.Hex(), s.taskTextRenderer.RenderWorkUnitEventTitle(task, &previousUnit), "", s.taskTextRenderer.HasReminder(&previousUnit))
+				if err != nil {
+					return err
+				}
+			}
+
+			err := s.newBreakEvent(task, &previousUnit, relevantUsers, taskCalendarRepositories)
+			if err != nil {
+				return err
+			}
+
+			result.Updated++
+			workUnits = workUnits.Add(&previousUnit)
+			continue
+		}
+
+		unit.ScheduledAt.Blocking = true
+
+		var workEvent *calendar.Event
+		for _, user := range relevantUsers {
+			var err error
+			workEvent, err = taskCalendarRepositories[user.ID.Hex()].NewEvent(&unit.ScheduledAt, task.ID.Hex(), s.taskTextRenderer.RenderWorkUnitEventTitle(task, &unit), "", s.taskTextRenderer.HasReminder(&unit))
+			if err != nil {
+				return err
+			}
+		}
+
+		unit.ScheduledAt = *workEvent
+
+		err := s.newBreakEvent(task, &unit, relevantUsers, taskCalendarRepositories)
+		if err != nil {
+			return err
+		}
+
+		result.Created++
+		workUnits = workUnits.Add(&unit)
+	}
+
+	for _, unit := range previous {
+		if unit.Break != nil {
+			breaks = append(breaks, unit.Break)
+		}
+
+		for _, user := range relevantUsers {
+			err := taskCalendarRepositories[user.ID.Hex()].DeleteEvent(&unit.ScheduledAt)
+			if err != nil {
+				return err
+			}
+		}
+
+		result.Deleted++
+	}
+
+	s.deleteBreakEvents(breaks, relevantUsers, taskCalendarRepositories)
+	task.WorkUnits = workUnits
+
+	return nil
+}
+
+// ScheduleBacklogTasks schedules the workload of backlog tasks that didn't fit yet, so that their work follows the
+// horizon as it rolls forward. It returns the number of tasks that were scheduled.
+func (s *PlanningService) ScheduleBacklogTasks(ctx context.Context) (int, error) {
+	lock, err := s.locker.Acquire(ctx, "schedule-backlog-tasks", time.Minute*10, true, 2*time.Second)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not acquire lock for scheduling backlog tasks")
+	}
+
+	defer func(lock locking.LockInterface, ctx context.Context) {
+		err := lock.Release(ctx)
+		if err != nil {
+			s.logger.Error("error releasing lock", errors.Wrap(err, "error releasing lock"))
+		}
+	}(lock, ctx)
+
+	tasks, err := s.taskRepository.FindUnscheduledBacklogTasks(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	scheduled := 0
+	for _, task := range tasks {
+		task := task
+
+		_, err = s.ScheduleTask(ctx, &task, true)
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("could not schedule backlog task %s", task.ID.Hex()), err)
+			continue
+		}
+
+		scheduled++
+	}
+
+	return scheduled, nil
+}
+
+// MissedWorkUnitsResult summarizes what happened with the missed work units of a sweep
+type MissedWorkUnitsResult struct {
+	Tasks       int `json:"tasks"`
+	Rescheduled int `json:"rescheduled"`
+	MarkedDone  int `json:"markedDone"`
+	Asked       int `json:"asked"`
+}
+
+// SweepMissedWorkUnits handles the work units of all users that passed without being done, depending on the
+// MissedWorkUnitAction of the owner. Only a single sweep runs at a time.
+func (s *PlanningService) SweepMissedWorkUnits(ctx context.Context) (*MissedWorkUnitsResult, error) {
+	lock, err := s.locker.Acquire(ctx, "sweep-missed-work-units", time.Minute*10, true, 2*time.Second)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not acquire lock for sweeping missed work units")
+	}
+
+	defer func(lock locking.LockInterface, ctx context.Context) {
+		err := lock.Release(ctx)
+		if err != nil {
+			s.logger.Error("error releasing lock", errors.Wrap(err, "error releasing lock"))
+		}
+	}(lock, ctx)
+
+	tasks, err := s.taskRepository.FindWithMissedWorkUnits(ctx, now())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MissedWorkUnitsResult{}
+	for _, task := range tasks {
+		task := task
+
+		err = s.handleMissedWorkUnits(ctx, &task, result)
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("could not handle missed work units of task %s", task.ID.Hex()), err)
+			continue
+		}
+
+		result.Tasks++
+	}
+
+	return result, nil
+}
+
+// handleMissedWorkUnits reschedules, marks as done or flags the missed work units of a single task
+func (s *PlanningService) handleMissedWorkUnits(ctx context.Context, task *Task, result *MissedWorkUnitsResult) error {
+	lock, err := s.locker.Acquire(ctx, task.ID.Hex(), time.Second*60, false, 62*time.Second)
+	if err != nil {
+		return err
+	}
+
+	defer func(lock locking.LockInterface, ctx context.Context) {
+		err := lock.Release(ctx)
+		if err != nil {
+			s.logger.Error("error releasing lock", errors.Wrap(err, "error releasing lock"))
+		}
+	}(lock, ctx)
+
+	// Refresh task, after potential change
+	task, err = s.taskRepository.FindByID(ctx, task.ID.Hex(), task.UserID.Hex(), false)
+	if err != nil {
+		return err
+	}
+
+	owner, err := s.userRepository.FindByID(ctx, task.UserID.Hex())
+	if err != nil {
+		return err
+	}
+
+	action := owner.Settings.Scheduling.MissedWorkUnitAction
+
+	var toReschedule []WorkUnit
+	var markedDone []int
+	for i, unit := range task.WorkUnits {
+		if unit.IsDone || unit.IsMissed || !unit.ScheduledAt.Date.End.Before(now()) {
+			continue
+		}
+
+		switch {
+		// The user chose the time of a pinned work unit, so the user decides what happens with it
+		case unit.IsPinned || (action != users.MissedWorkUnitActionReschedule && action != users.MissedWorkUnitActionMarkDone):
+			task.WorkUnits[i].IsMissed = true
+			result.Asked++
+		case action == users.MissedWorkUnitActionMarkDone:
+			task.WorkUnits[i].IsDone = true
+			task.WorkUnits[i].MarkedDoneAt = now()
+			markedDone = append(markedDone, i)
+			result.MarkedDone++
+		default:
+			toReschedule = append(toReschedule, unit)
+		}
+	}
+
+	// The task is done once the last open work unit is marked as done
+	if len(markedDone) > 0 {
+		allAreDone := true
+		for _, unit := range task.WorkUnits {
+			if !unit.IsDone {
+				allAreDone = false
+				break
+			}
+		}
+
+		task.IsDone = allAreDone
+	}
+
+	err = s.taskRepository.Update(ctx, task, false)
+	if err != nil {
+		return err
+	}
+
+	for _, index := range markedDone {
+		err = s.UpdateWorkUnitTitle(ctx, task, &task.WorkUnits[index])
+		if err != nil {
+			return err
+		}
+	}
+
+	if task.IsDone {
+		err = s.UpdateTaskTitle(ctx, task, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, unit := range toReschedule {
+		task, err = s.RescheduleWorkUnit(ctx, task, &unit, true, false)
+		if err != nil {
+			return err
+		}
+
+		result.Rescheduled++
+	}
+
+	return nil
+}
+
+// CapacityPeriod is the time of a user within a day or week. Free time is what is left for new work units
+// after busy time, already scheduled work units and the padding around them.
+type CapacityPeriod struct {
+	Start     time.Time     `json:"start"`
+	End       time.Time     `json:"end"`
+	Allowed   time.Duration `json:"allowed"`
+	Busy      time.Duration `json:"busy"`
+	Scheduled time.Duration `json:"scheduled"`
+	Free      time.Duration `json:"free"`
+}
+
+// CapacityWarning warns that more unscheduled work is due between Start and End than there is free time
+type CapacityWarning struct {
+	Start     time.Time     `json:"start"`
+	End       time.Time     `json:"end"`
+	Committed time.Duration `json:"committed"`
+	Free      time.Duration `json:"free"`
+	Message   string        `json:"message"`
+}
+
+// Capacity is the forecast of the time a user has between From and To
+type Capacity struct {
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Days     []CapacityPeriod  `json:"days"`
+	Weeks    []CapacityPeriod  `json:"weeks"`
+	Warnings []CapacityWarning `json:"warnings"`
+}
+
+// ForecastCapacity computes the allowed, busy, scheduled and free time of a user per day and week. Days and weeks
+// start at midnight and on Monday in the time zone of the user.
+func (s *PlanningService) ForecastCapacity(ctx context.Context, user *users.User, from time.Time, to time.Time) (*Capacity, error) {
+	constraint, err := constraintForUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	availabilityRepositories, err := s.calendarRepositoryManager.GetAllAvailabilityCalendarRepositoriesForUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	window := &date.TimeWindow{
+		Start:             from.UTC(),
+		End:               to.UTC(),
+		BusyPadding:       user.Settings.Scheduling.BusyTimeSpacing,
+		MaxWorkUnitLength: user.Settings.Scheduling.MaxWorkUnitDuration,
+	}
+
+	// Asking for all time of the window makes sure that the whole window is computed
+	window, err = s.computeAvailabilityForTimeWindow(ctx, []*users.User{user}, window.Start, window.Duration(), window, availabilityRepositories, constraint, &Task{})
+	if err != nil {
+		return nil, err
+	}
+
+	// The busy time of the calendars is collected separately, because the window doesn't tell work units apart
+	collector := &date.TimeWindow{Start: window.Start, End: window.End}
+	for _, repository := range availabilityRepositories {
+		err = repository.AddBusyToWindow(collector, window.Start, window.End)
+		if err != nil {
+			return nil, errors.Wrap(err, "error while adding busy time to window")
+		}
+	}
+
+	for _, absence := range user.Absences.Intersecting(date.Timespan{Start: window.Start, End: window.End}) {
+		collector.AddToBusy(absence)
+	}
+
+	workUnits, err := s.taskRepository.FindWorkUnitsIntersectingTimespan(ctx, user.ID.Hex(), date.Timespan{Start: window.Start, End: window.End})
+	if err != nil {
+		return nil, err
+	}
+
+	var scheduled []date.Timespan
+	for _, unit := range workUnits {
+		scheduled = append(scheduled, unit.ScheduledAt.Date)
+	}
+	scheduled = date.MergeTimespans(scheduled)
+
+	// Busy time that is taken by work units is counted as scheduled
+	busyOrScheduled := date.MergeTimespans(append(collector.Busy(), scheduled...))
+	free := date.MergeTimespans(window.Free())
+
+	capacityForPeriod := func(period date.Timespan) CapacityPeriod {
+		allowed := constraint.Test(period)
+		scheduledDuration := overlapDuration(allowed, scheduled)
+
+		return CapacityPeriod{
+			Start:     period.Start,
+			End:       period.End,
+			Allowed:   overlapDuration(allowed, []date.Timespan{period}),
+			Busy:      overlapDuration(allowed, busyOrScheduled) - scheduledDuration,
+			Scheduled: scheduledDuration,
+			Free:      overlapDuration(free, []date.Timespan{period}),
+		}
+	}
+
+	capacity := &Capacity{From: from, To: to, Days: []CapacityPeriod{}, Weeks: []CapacityPeriod{}, Warnings: []CapacityWarning{}}
+
+	for _, day := range splitIntoPeriods(from, to, constraint.Location, false) {
+		capacity.Days = append(capacity.Days, capacityForPeriod(day))
+	}
+
+	for _, week := range splitIntoPeriods(from, to, constraint.Location, true) {
+		capacity.Weeks = append(capacity.Weeks, capacityForPeriod(week))
+	}
+
+	tasks, err := s.taskRepository.FindOpenTasks(ctx, user.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	// The unscheduled work that is due until the end of a week has to fit into the free time until then
+	var freeUntil time.Duration
+	for _, week := range capacity.Weeks {
+		freeUntil += week.Free
+
+		var committed time.Duration
+		for _, task := range tasks {
+			if !task.DueAt.Date.Start.Before(from) && !task.DueAt.Date.Start.After(week.End) {
+				committed += task.NotScheduled
+			}
+		}
+
+		if committed > freeUntil {
+			capacity.Warnings = append(capacity.Warnings, CapacityWarning{
+				Start:     from,
+				End:       week.End,
+				Committed: committed,
+				Free:      freeUntil,
+				Message:   fmt.Sprintf("%s of unscheduled work is due until %s, but only %s of free time is left", committed, week.End.Format(time.RFC3339), freeUntil),
+			})
+		}
+	}
+
+	return capacity, nil
+}
+
+// splitIntoPeriods splits the time between from and to into days or weeks starting on Monday in location.
+// The first and last period are cut to from and to.
+func splitIntoPeriods(from time.Time, to time.Time, location *time.Location, weeks bool) []date.Timespan {
+	var periods []date.Timespan
+
+	year, month, day := from.In(location).Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, location)
+
+	if weeks {
+		start = start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+	}
+
+	for start.Before(to) {
+		end := start.AddDate(0, 0, 1)
+		if weeks {
+			end = start.AddDate(0, 0, 7)
+		}
+
+		period := date.Timespan{Start: start, End: end}
+		if period.Start.Before(from) {
+			period.Start = from
+		}
+
+		if period.End.After(to) {
+			period.End = to
+		}
 
-	targetTime := s.getTargetTimeForUser(relevantUsers[0], windowTotal, w.Workload)
-	windowTotal, err = s.computeAvailabilityForTimeWindow(ctx, relevantUsers, targetTime, w.Workload, windowTotal, availabilityRepositories, constraint, t, ignoreWorkUnitID)
-	if err != nil {
-		return nil, err
+		periods = append(periods, period)
+		start = end
 	}
 
-	workloadToSchedule := w.Workload
+	return periods
+}
 
-	foundWorkUnits := s.findWorkUnitTimes(windowTotal, workloadToSchedule, relevantUsers[0])
+// overlapDuration returns the duration both lists of timespans have in common, each list must not overlap itself
+func overlapDuration(a []date.Timespan, b []date.Timespan) time.Duration {
+	var duration time.Duration
 
-	if len(foundWorkUnits) == 0 {
-		t.WorkUnits = t.WorkUnits.RemoveByIndex(index)
+	for _, first := range a {
+		for _, second := range b {
+			start := first.Start
+			if second.Start.After(start) {
+				start = second.Start
+			}
 
-		for _, user := range relevantUsers {
-			err = taskRepositories[user.ID.Hex()].DeleteEvent(&w.ScheduledAt)
-			if err != nil {
-				return nil, err
+			end := first.End
+			if second.End.Before(end) {
+				end = second.End
 			}
-		}
-	} else if len(foundWorkUnits) > 0 {
-		t.WorkUnits[index].ScheduledAt.Date = foundWorkUnits[0].ScheduledAt.Date
-		t.WorkUnits[index].Workload = foundWorkUnits[0].Workload
 
-		for _, user := range relevantUsers {
-			err = taskRepositories[user.ID.Hex()].UpdateEvent(&t.WorkUnits[index].ScheduledAt, t.ID.Hex(), s.taskTextRenderer.RenderWorkUnitEventTitle(t, &t.WorkUnits[index]), "", s.taskTextRenderer.HasReminder(&t.WorkUnits[index]))
-			if err != nil {
-				return nil, err
+			if end.After(start) {
+				duration += end.Sub(start)
 			}
 		}
+	}
 
-		t.WorkUnits.Sort()
+	return duration
+}
 
-		foundWorkUnits = foundWorkUnits.RemoveByIndex(0)
-		workloadToSchedule -= workloadToSchedule
+// MaterializeSeries creates and schedules the tasks of a series following the given task up to the recurrence horizon
+func (s *PlanningService) MaterializeSeries(ctx context.Context, latest *Task) ([]Task, error) {
+	if latest.Recurrence == nil || latest.Recurrence.Rule == "" {
+		return nil, nil
 	}
 
-	for _, workUnit := range foundWorkUnits {
-		workUnit.ScheduledAt.Blocking = true
+	seriesID := latest.Recurrence.SeriesID.Hex()
 
-		var workEvent *calendar.Event
-		for _, user := range relevantUsers {
-			workEvent, err = taskRepositories[user.ID.Hex()].NewEvent(&workUnit.ScheduledAt, t.ID.Hex(), s.taskTextRenderer.RenderWorkUnitEventTitle(t, &workUnit), "", s.taskTextRenderer.HasReminder(&workUnit))
-			if err != nil {
-				return nil, err
-			}
-		}
+	lock, err := s.locker.Acquire(ctx, fmt.Sprintf("recurrence-%s", seriesID), time.Minute*1, true, 2*time.Second)
+	if errors.Cause(err) == locking.ErrNotObtained {
+		// The series is already materialized by someone else
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("could not lock series %s", seriesID))
+	}
 
-		workUnit.ScheduledAt = *workEvent
-		workloadToSchedule -= workloadToSchedule
+	defer func() {
+		err := lock.Release(ctx)
+		if err != nil {
+			s.logger.Error("could not release lock", errors.Wrap(err, fmt.Sprintf("could not release lock for materializing series %s", seriesID)))
+		}
+	}()
 
-		t.WorkUnits = t.WorkUnits.Add(&workUnit)
+	owner, err := s.userRepository.FindByID(ctx, latest.UserID.Hex())
+	if err != nil {
+		return nil, err
 	}
 
-	if workloadToSchedule > 0 {
-		t.NotScheduled += workloadToSchedule
+	location, err := time.LoadLocation(owner.Settings.Scheduling.TimeZone)
+	if err != nil {
+		return nil, err
 	}
 
-	err = s.taskRepository.Update(ctx, t, false)
+	// Refresh the series, tasks could have been created in the meantime
+	existingTasks, err := s.taskRepository.FindBySeriesID(ctx, seriesID, latest.UserID.Hex())
 	if err != nil {
 		return nil, err
 	}
 
-	t = s.CheckForMergingWorkUnits(ctx, t)
-
-	return t, nil
-}
+	after := latest.Recurrence.OccurrenceAt
+	for _, existing := range existingTasks {
+		if existing.Recurrence.OccurrenceAt.After(after) {
+			after = existing.Recurrence.OccurrenceAt
+		}
+	}
 
-// SuggestTimespansForWorkUnit returns a list of timespans that can be used to reschedule a work unit
-func (s *PlanningService) SuggestTimespansForWorkUnit(ctx context.Context, t *Task, w *WorkUnit, ignoreTimespans []date.Timespan) ([][]date.Timespan, error) {
-	relevantUsers, err := s.getAllRelevantUsers(ctx, t)
-	if err != nil {
-		return nil, err
+	// Occurrences in the past can't be scheduled anymore
+	if after.Before(now()) {
+		after = now()
 	}
 
-	windowTotal, constraint, err := s.initializeTimeWindow(t, relevantUsers)
+	occurrences, err := latest.Recurrence.Occurrences(after, now().Add(RecurrenceHorizon), location)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, timespan := range ignoreTimespans {
-		windowTotal.AddToBusy(timespan)
-	}
+	var created []Task
+	for _, occurrence := range occurrences {
+		if occurrence.Equal(after) {
+			continue
+		}
 
-	taskCalendarRepositories := make(map[string]calendar.RepositoryInterface)
-	var availabilityRepositories []calendar.RepositoryInterface
+		task := latest.NewOccurrence(occurrence)
 
-	// TODO make this parallel
-	for _, user := range relevantUsers {
-		taskRepository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
+		err = s.taskRepository.Add(ctx, task)
 		if err != nil {
-			return nil, err
+			return created, err
 		}
 
-		taskCalendarRepositories[user.ID.Hex()] = taskRepository
-
-		// Repositories for availability
-		availabilityRepositoriesForUser, err := s.calendarRepositoryManager.GetAllAvailabilityCalendarRepositoriesForUser(ctx, user)
+		scheduled, err := s.ScheduleTask(ctx, task, false)
 		if err != nil {
-			return nil, err
+			// The next run starts after the latest task of the series, so the task is removed to be created again
+			s.discardOccurrence(ctx, task)
+			return created, err
 		}
 
-		availabilityRepositories = append(availabilityRepositories, availabilityRepositoriesForUser...)
+		created = append(created, *scheduled)
 	}
 
-	var iterations time.Duration = 5
+	return created, nil
+}
 
-	targetTime := s.getTargetTimeForUser(relevantUsers[0], windowTotal, w.Workload)
-	windowTotal, err = s.computeAvailabilityForTimeWindow(ctx, relevantUsers, targetTime, w.Workload*iterations, windowTotal, availabilityRepositories, constraint, t, "")
-	if err != nil {
-		return nil, err
+// discardOccurrence deletes a task of a series that couldn't be scheduled together with the events created so far
+func (s *PlanningService) discardOccurrence(ctx context.Context, task *Task) {
+	stored, err := s.taskRepository.FindByID(ctx, task.ID.Hex(), task.UserID.Hex(), false)
+	if err == nil {
+		task = stored
 	}
 
-	return s.findWorkUnitTimesForExactWorkload(windowTotal, w.Workload, int(iterations), relevantUsers[0]), nil
-}
-
-func (s *PlanningService) findWorkUnitTimes(w *date.TimeWindow, durationToFind time.Duration, user *users.User) WorkUnits {
-	var workUnits WorkUnits
-	if w.FreeDuration() == 0 {
-		return workUnits
+	err = s.DeleteTask(ctx, task)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("could not delete unscheduled task %s of series %s", task.ID.Hex(), task.Recurrence.SeriesID.Hex()), err)
 	}
+}
 
-	minDuration := user.Settings.Scheduling.MinWorkUnitDuration
-	if durationToFind < user.Settings.Scheduling.MinWorkUnitDuration {
-		minDuration = durationToFind
+// MaterializeAllSeries creates the tasks of all series that are due up to the recurrence horizon
+func (s *PlanningService) MaterializeAllSeries(ctx context.Context) (int, error) {
+	latestTasks, err := s.taskRepository.FindLatestOfSeriesBefore(ctx, now().Add(RecurrenceHorizon))
+	if err != nil {
+		return 0, err
 	}
-	maxDuration := user.Settings.Scheduling.MaxWorkUnitDuration
 
-	for w.FreeDuration() >= 0 && durationToFind > 0 {
-		if durationToFind < user.Settings.Scheduling.MaxWorkUnitDuration {
-			if durationToFind < WorkUnitDurationMin {
-				minDuration = durationToFind
-			}
-			maxDuration = durationToFind
-		}
+	for _, latest := range latestTasks {
+		latest := latest
 
-		slot := w.FindTimeSlot(&date.RuleDuration{Minimum: minDuration, Maximum: maxDuration})
-		if slot == nil {
-			break
+		_, err := s.MaterializeSeries(ctx, &latest)
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("could not materialize series %s", latest.Recurrence.SeriesID.Hex()), err)
+			continue
 		}
-		durationToFind -= slot.Duration()
-		workUnits = append(workUnits, WorkUnit{ScheduledAt: calendar.Event{Date: *slot}, Workload: slot.Duration()})
 	}
 
-	return workUnits
+	return len(latestTasks), nil
 }
 
-func (s *PlanningService) findWorkUnitTimesForExactWorkload(w *date.TimeWindow, durationToFindPerIteration time.Duration, iterations int, user *users.User) [][]date.Timespan {
-	var timespanGroups = make([][]date.Timespan, 0)
+// DeleteFollowingTasks ends the series of the task before its occurrence and deletes all following tasks,
+// the task itself is only deleted if includeTask is true
+func (s *PlanningService) DeleteFollowingTasks(ctx context.Context, task *Task, recurrence *Recurrence, includeTask bool) error {
+	rule, err := recurrence.RuleEndingBefore(recurrence.OccurrenceAt)
+	if err != nil {
+		return err
+	}
 
-	if w.FreeDuration() == 0 || w.FreeDuration() < durationToFindPerIteration {
-		return timespanGroups
+	err = s.taskRepository.UpdateSeriesRule(ctx, recurrence.SeriesID.Hex(), task.UserID.Hex(), rule)
+	if err != nil {
+		return err
 	}
 
-	for i := 0; i < iterations; i++ {
-		durationToFind := durationToFindPerIteration
+	seriesTasks, err := s.taskRepository.FindBySeriesID(ctx, recurrence.SeriesID.Hex(), task.UserID.Hex())
+	if err != nil {
+		return err
+	}
 
-		minDuration := user.Settings.Scheduling.MinWorkUnitDuration
-		if durationToFind < user.Settings.Scheduling.MinWorkUnitDuration {
-			minDuration = durationToFind
-		}
-		maxDuration := user.Settings.Scheduling.MaxWorkUnitDuration
+	for _, seriesTask := range seriesTasks {
+		seriesTask := seriesTask
 
-		timespanGroup := make([]date.Timespan, 0)
+		if seriesTask.ID == task.ID || seriesTask.Recurrence.OccurrenceAt.Before(recurrence.OccurrenceAt) {
+			continue
+		}
 
-		for w.FreeDuration() >= 0 && durationToFind > 0 {
-			if durationToFind < user.Settings.Scheduling.MaxWorkUnitDuration {
-				if durationToFind < WorkUnitDurationMin {
-					minDuration = durationToFind
-				}
-				maxDuration = durationToFind
-			}
+		err = s.deleteTaskWithLock(ctx, &seriesTask)
+		if err != nil {
+			return err
+		}
+	}
 
-			slot := w.FindTimeSlot(&date.RuleDuration{Minimum: minDuration, Maximum: maxDuration})
-			if slot == nil {
-				break
-			}
-			durationToFind -= slot.Duration()
+	if !includeTask {
+		return nil
+	}
 
-			timespanGroup = append(timespanGroup, *slot)
-		}
+	return s.DeleteTask(ctx, task)
+}
 
-		if durationToFind != 0 {
-			break
-		}
+// DeleteSeries deletes all tasks of the series of the task and stops creating new ones
+func (s *PlanningService) DeleteSeries(ctx context.Context, task *Task) error {
+	seriesID := task.Recurrence.SeriesID.Hex()
 
-		sort.Slice(timespanGroup, func(i, j int) bool {
-			return timespanGroup[i].Start.Before(timespanGroup[j].Start)
-		})
+	err := s.taskRepository.UpdateSeriesRule(ctx, seriesID, task.UserID.Hex(), "")
+	if err != nil {
+		return err
+	}
 
-		timespanGroups = append(timespanGroups, timespanGroup)
+	seriesTasks, err := s.taskRepository.FindBySeriesID(ctx, seriesID, task.UserID.Hex())
+	if err != nil {
+		return err
 	}
 
-	sort.Slice(timespanGroups, func(i, j int) bool {
-		return timespanGroups[i][0].Start.Before(timespanGroups[j][0].Start)
-	})
+	for _, seriesTask := range seriesTasks {
+		seriesTask := seriesTask
 
-	return timespanGroups
+		if seriesTask.ID == task.ID {
+			continue
+		}
+
+		err = s.deleteTaskWithLock(ctx, &seriesTask)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.DeleteTask(ctx, task)
 }
 
-// CreateNewSpecificWorkUnits creates new work units for a specific task and times (won't save the task)
-func (s *PlanningService) CreateNewSpecificWorkUnits(ctx context.Context, task *Task, timespans []date.Timespan) (*Task, error) {
-	relevantUsers, err := s.getAllRelevantUsers(ctx, task)
+// SplitSeries ends the original series before the task and starts a new series with the task and its rule
+func (s *PlanningService) SplitSeries(ctx context.Context, task *Task, original *Recurrence) (*Task, error) {
+	err := s.DeleteFollowingTasks(ctx, task, original, false)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, timespan := range timespans {
-		workUnit := WorkUnit{
-			ID:       primitive.NewObjectID(),
-			Workload: timespan.Duration(),
-			ScheduledAt: calendar.Event{
-				Blocking: true,
-				Date:     timespan,
-			},
+	if task.Recurrence == nil {
+		err = s.taskRepository.Update(ctx, task, false)
+		if err != nil {
+			return nil, err
 		}
 
-		title := s.taskTextRenderer.RenderWorkUnitEventTitle(task, &workUnit)
-
-		for _, user := range relevantUsers {
-			repository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
-			if err != nil {
-				return nil, err
-			}
+		return task, nil
+	}
 
-			event, err := repository.NewEvent(&workUnit.ScheduledAt, task.ID.Hex(), title, "", s.taskTextRenderer.HasReminder(&workUnit))
-			if err != nil {
-				return nil, err
-			}
+	task.StartSeries()
 
-			workUnit.ScheduledAt = *event
-		}
+	err = s.taskRepository.Update(ctx, task, false)
+	if err != nil {
+		return nil, err
+	}
 
-		task.WorkloadOverall += workUnit.Workload
-		task.WorkUnits = task.WorkUnits.Add(&workUnit)
+	_, err = s.MaterializeSeries(ctx, task)
+	if err != nil {
+		return nil, err
 	}
 
 	return task, nil
 }
 
-// UpdateDueAtEvent updates a due at event, creates missing events and deletes event when necessary
-func (s *PlanningService) UpdateDueAtEvent(ctx context.Context, task *Task, relevantUsers []*users.User, taskCalendarRepositories map[string]calendar.RepositoryInterface, needsUpdate bool, ownerNeedsUpdate bool) (*Task, error) {
-	// Create a new event for the due at date if it doesn't exist for a user
-	if !task.IsDone && len(task.DueAt.CalendarEvents) != len(relevantUsers) {
-		task.DueAt.Blocking = false
-		task.DueAt.Date.End = task.DueAt.Date.Start.Add(time.Minute * 15)
-
-		var dueEvent *calendar.Event
-		for _, user := range relevantUsers {
-			if persistedEvent := task.DueAt.CalendarEvents.FindByUserID(user.ID.Hex()); persistedEvent != nil {
-				continue
-			}
+// UpdateFollowingTasks applies the name, description, tags and workload of the task to all following tasks of its series
+func (s *PlanningService) UpdateFollowingTasks(ctx context.Context, task *Task) error {
+	seriesTasks, err := s.taskRepository.FindBySeriesID(ctx, task.Recurrence.SeriesID.Hex(), task.UserID.Hex())
+	if err != nil {
+		return err
+	}
 
-			var err error
-			dueEvent, err = taskCalendarRepositories[user.ID.Hex()].NewEvent(&task.DueAt, task.ID.Hex(), s.taskTextRenderer.RenderDueEventTitle(task), "", s.taskTextRenderer.HasReminder(task))
-			if err != nil {
-				return nil, err
-			}
+	for _, seriesTask := range seriesTasks {
+		if seriesTask.IsDone || !seriesTask.Recurrence.OccurrenceAt.After(task.Recurrence.OccurrenceAt) {
+			continue
 		}
 
-		task.DueAt = *dueEvent
-
-		err := s.taskRepository.Update(ctx, task, false)
+		err = s.updateFollowingTask(ctx, &seriesTask, task)
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
 
-	// Remove event when it's not needed anymore
-	if relevantUsers[0].Settings.Scheduling.HideDeadlineWhenDone && task.IsDone {
-		for _, user := range relevantUsers {
-			if persistedEvent := task.DueAt.CalendarEvents.FindByUserID(user.ID.Hex()); persistedEvent == nil {
-				continue
-			}
-
-			var err error
-			err = taskCalendarRepositories[user.ID.Hex()].DeleteEvent(&task.DueAt)
-			if err != nil {
-				// We ignore the error here, because we don't want to stop the update process
-			}
+	return nil
+}
 
-			task.DueAt.CalendarEvents = task.DueAt.CalendarEvents.RemoveByUserID(user.ID.Hex())
-		}
+func (s *PlanningService) updateFollowingTask(ctx context.Context, following *Task, task *Task) error {
+	lock, err := s.locker.Acquire(ctx, following.ID.Hex(), time.Second*30, false, 32*time.Second)
+	if err != nil {
+		return err
+	}
 
-		err := s.taskRepository.Update(ctx, task, false)
+	defer func(lock locking.LockInterface, ctx context.Context) {
+		err := lock.Release(ctx)
 		if err != nil {
-			return nil, err
+			s.logger.Error("error releasing lock", errors.Wrap(err, "error releasing lock"))
 		}
+	}(lock, ctx)
 
-		return task, nil
+	// Refresh task, after potential change
+	following, err = s.taskRepository.FindByID(ctx, following.ID.Hex(), following.UserID.Hex(), false)
+	if err != nil {
+		return err
 	}
 
-	if !needsUpdate {
-		return task, nil
-	}
+	nameChanged := following.Name != task.Name
+	workloadChanged := following.WorkloadOverall != task.WorkloadOverall
 
-	for _, user := range relevantUsers {
-		if user.ID.Hex() == task.UserID.Hex() && !ownerNeedsUpdate {
-			continue
-		}
+	following.Name = task.Name
+	following.Description = task.Description
+	following.Tags = append([]primitive.ObjectID{}, task.Tags...)
+	following.WorkloadOverall = task.WorkloadOverall
+	following.DeadlineBuffer = task.DeadlineBuffer
 
-		repository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
+	if workloadChanged {
+		following, err = s.ScheduleTask(ctx, following, false)
 		if err != nil {
-			return nil, err
+			return err
 		}
+	}
 
-		err = repository.UpdateEvent(&task.DueAt, task.ID.Hex(), s.taskTextRenderer.RenderDueEventTitle(task), "", s.taskTextRenderer.HasReminder(task))
+	if nameChanged {
+		err = s.UpdateTaskTitle(ctx, following, true)
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
 
-	err := s.taskRepository.Update(ctx, task, false)
-	if err != nil {
-		return nil, err
-	}
-
-	return task, nil
+	return s.taskRepository.Update(ctx, following, false)
 }
 
-// UpdateWorkUnitEvent updates a work unit event
-func (s *PlanningService) UpdateWorkUnitEvent(ctx context.Context, task *Task, unit *WorkUnit) error {
-	relevantUsers, err := s.getAllRelevantUsers(ctx, task)
+func (s *PlanningService) deleteTaskWithLock(ctx context.Context, task *Task) error {
+	lock, err := s.locker.Acquire(ctx, task.ID.Hex(), time.Second*30, false, 32*time.Second)
 	if err != nil {
 		return err
 	}
 
-	for _, user := range relevantUsers {
-		repository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
+	defer func(lock locking.LockInterface, ctx context.Context) {
+		err := lock.Release(ctx)
 		if err != nil {
-			return err
+			s.logger.Error("error releasing lock", errors.Wrap(err, "error releasing lock"))
 		}
+	}(lock, ctx)
 
-		err = repository.UpdateEvent(&unit.ScheduledAt, task.ID.Hex(), s.taskTextRenderer.RenderWorkUnitEventTitle(task, unit), "", s.taskTextRenderer.HasReminder(unit))
+	return s.DeleteTask(ctx, task)
+}
+
+// isDueDuringAbsence returns true if the task is due while its owner is absent
+func isDueDuringAbsence(task *Task, owner *users.User) bool {
+	return !task.IsBacklog && owner.Absences.Contains(task.DueAt.Date.Start)
+}
+
+// ApplyAbsence moves all work units of a user out of an absence and flags the tasks of the user that are due during
+// any absence. The absence has to be persisted before, so that rescheduling doesn't use its time.
+// The user creates an absence explicitly, so it wins over pins: pinned work units during it are unpinned and moved
+// like all others.
+func (s *PlanningService) ApplyAbsence(ctx context.Context, user *users.User, absence *users.Absence) error {
+	absenceEvent := &calendar.Event{Date: absence.Date, Blocking: true}
+
+	intersectingTasks, err := s.taskRepository.FindIntersectingWithEvent(ctx, user.ID.Hex(), absenceEvent, primitive.NilObjectID, false)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range intersectingTasks {
+		err = s.unpinWorkUnitsIntersecting(ctx, &task, absenceEvent)
 		if err != nil {
-			return err
+			s.logger.Error(fmt.Sprintf("could not unpin the work units of task %s during an absence", task.ID.Hex()), err)
 		}
 	}
 
-	return nil
+	s.checkForIntersectingWorkUnits(ctx, user.ID.Hex(), absenceEvent, primitive.NilObjectID, primitive.NilObjectID)
+
+	return s.FlagTasksDueDuringAbsences(ctx, user)
 }
 
-// UpdateTaskTitle updates the events of the tasks and work units
-func (s *PlanningService) UpdateTaskTitle(ctx context.Context, task *Task, updateWorkUnits bool) error {
-	relevantUsers, err := s.getAllRelevantUsers(ctx, task)
+// unpinWorkUnitsIntersecting unpins the open work units of a task that intersect with an event, so they can be moved
+func (s *PlanningService) unpinWorkUnitsIntersecting(ctx context.Context, task *Task, event *calendar.Event) error {
+	lock, err := s.locker.Acquire(ctx, task.ID.Hex(), time.Second*30, false, 32*time.Second)
 	if err != nil {
 		return err
 	}
 
-	repositories := make(map[string]calendar.RepositoryInterface)
-
-	for _, user := range relevantUsers {
-		repository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
+	defer func(lock locking.LockInterface, ctx context.Context) {
+		err := lock.Release(ctx)
 		if err != nil {
-			return err
+			s.logger.Error("error releasing lock", errors.Wrap(err, "error releasing lock"))
 		}
+	}(lock, ctx)
 
-		repositories[user.ID.Hex()] = repository
-	}
-
-	task, err = s.UpdateDueAtEvent(ctx, task, relevantUsers, repositories, true, true)
+	// Refresh task, after potential change
+	task, err = s.taskRepository.FindByID(ctx, task.ID.Hex(), task.UserID.Hex(), false)
 	if err != nil {
 		return err
 	}
 
-	if !updateWorkUnits {
+	unpinned := false
+	indices, _ := task.WorkUnits.FindByEventIntersection(event, primitive.NilObjectID)
+	for _, index := range indices {
+		if task.WorkUnits[index].IsPinned {
+			task.WorkUnits[index].IsPinned = false
+			unpinned = true
+		}
+	}
+
+	if !unpinned {
 		return nil
 	}
 
-	for _, unit := range task.WorkUnits {
-		unitTitle := s.taskTextRenderer.RenderWorkUnitEventTitle(task, &unit)
+	return s.taskRepository.Update(ctx, task, false)
+}
 
-		for _, user := range relevantUsers {
-			err = repositories[user.ID.Hex()].UpdateEvent(&unit.ScheduledAt, task.ID.Hex(), unitTitle, "", s.taskTextRenderer.HasReminder(&unit))
-			if err != nil {
-				return err
-			}
+// FlagTasksDueDuringAbsences updates the absence flag of all open tasks of a user
+func (s *PlanningService) FlagTasksDueDuringAbsences(ctx context.Context, user *users.User) error {
+	openTasks, err := s.taskRepository.FindOpenTasks(ctx, user.ID.Hex())
+	if err != nil {
+		return err
+	}
+
+	for _, task := range openTasks {
+		if task.IsDueDuringAbsence == isDueDuringAbsence(&task, user) {
+			continue
+		}
+
+		err = s.flagTaskDueDuringAbsence(ctx, &task, user)
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("could not flag task %s due during an absence", task.ID.Hex()), err)
 		}
 	}
 
 	return nil
 }
 
-// UpdateWorkUnitTitle updates the event title of a work unit
-func (s *PlanningService) UpdateWorkUnitTitle(ctx context.Context, task *Task, unit *WorkUnit) error {
-	title := s.taskTextRenderer.RenderWorkUnitEventTitle(task, unit)
-
-	relevantUsers, err := s.getAllRelevantUsers(ctx, task)
+func (s *PlanningService) flagTaskDueDuringAbsence(ctx context.Context, task *Task, owner *users.User) error {
+	lock, err := s.locker.Acquire(ctx, task.ID.Hex(), time.Second*30, false, 32*time.Second)
 	if err != nil {
 		return err
 	}
 
-	repositories := make(map[string]calendar.RepositoryInterface)
-
-	for _, user := range relevantUsers {
-		repository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
+	defer func(lock locking.LockInterface, ctx context.Context) {
+		err := lock.Release(ctx)
 		if err != nil {
-			return err
+			s.logger.Error("error releasing lock", errors.Wrap(err, "error releasing lock"))
 		}
+	}(lock, ctx)
 
-		repositories[user.ID.Hex()] = repository
+	// Refresh task, after potential change
+	task, err = s.taskRepository.FindByID(ctx, task.ID.Hex(), task.UserID.Hex(), false)
+	if err != nil {
+		return err
+	}
 
-		err = repository.UpdateEvent(&unit.ScheduledAt, task.ID.Hex(), title, "", s.taskTextRenderer.HasReminder(unit))
+	task.IsDueDuringAbsence = isDueDuringAbsence(task, owner)
+
+	return s.taskRepository.Update(ctx, task, false)
+}
+
+// MigrateNativeCalendarEvents moves the events of the native task calendar of a user into the task calendar of the
+// connection the user set up since, e.g. Google Calendar. Migrated events aren't native anymore, so running it again
+// doesn't create duplicates. It returns the number of migrated events.
+func (s *PlanningService) MigrateNativeCalendarEvents(ctx context.Context, user *users.User) (int, error) {
+	taskCalendarRepository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
+	if err != nil {
+		return 0, err
+	}
+
+	// Without a task calendar connection there is nowhere to move the events to
+	if _, isNative := taskCalendarRepository.(*calendar.NativeCalendarRepository); isNative {
+		return 0, nil
+	}
+
+	nativeTasks, err := s.taskRepository.FindWithCalendarType(ctx, user.ID.Hex(), calendar.PersistedCalendarTypeNativeCalendar)
+	if err != nil {
+		return 0, err
+	}
+
+	nativeCalendarRepository := s.calendarRepositoryManager.GetNativeCalendarRepositoryForUser(ctx, user)
+
+	migrated := 0
+	for _, task := range nativeTasks {
+		count, err := s.migrateNativeTaskEvents(ctx, &task, user, nativeCalendarRepository, taskCalendarRepository)
 		if err != nil {
-			return err
+			return migrated, err
 		}
+
+		migrated += count
 	}
 
-	return nil
+	return migrated, nil
 }
 
-// DeleteTask deletes all events that are connected to a task
-func (s *PlanningService) DeleteTask(ctx context.Context, task *Task) error {
-	err := s.taskRepository.Delete(ctx, task.ID.Hex(), task.UserID.Hex())
+// migrateNativeTaskEvents moves the native events of a user for a single task. If any of them can't be created in the
+// task calendar, the ones created so far are deleted again and the task keeps all of its native events.
+func (s *PlanningService) migrateNativeTaskEvents(ctx context.Context, task *Task, user *users.User, nativeCalendarRepository calendar.RepositoryInterface, taskCalendarRepository calendar.RepositoryInterface) (int, error) {
+	lock, err := s.locker.Acquire(ctx, task.ID.Hex(), time.Second*30, false, 32*time.Second)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	relevantUsers, err := s.getAllRelevantUsers(ctx, task)
+	defer func(lock locking.LockInterface, ctx context.Context) {
+		err := lock.Release(ctx)
+		if err != nil {
+			s.logger.Error("error releasing lock", errors.Wrap(err, "error releasing lock"))
+		}
+	}(lock, ctx)
+
+	// Refresh task, after potential change
+	task, err = s.taskRepository.FindByID(ctx, task.ID.Hex(), task.UserID.Hex(), false)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	repositories := make(map[string]calendar.RepositoryInterface)
+	userID := user.ID.Hex()
 
-	// TODO make these parallel
-	for _, user := range relevantUsers {
-		repository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
+	// migration is an event that exists in the task calendar, the task is only changed once all of them do
+	type migration struct {
+		event          *calendar.Event
+		calendarEvents calendar.PersistedEvents
+		nativeEvent    calendar.PersistedEvent
+	}
+
+	var migrations []migration
+
+	migrate := func(event *calendar.Event, title string, withReminder bool) error {
+		persistedEvent := event.CalendarEvents.FindByUserID(userID)
+		if persistedEvent == nil || persistedEvent.CalendarType != calendar.PersistedCalendarTypeNativeCalendar {
+			return nil
+		}
+
+		// The events of collaborators are kept, the slice is copied because removing shifts the shared elements
+		migratedEvent := *event
+		migratedEvent.CalendarEvents = append(calendar.PersistedEvents{}, event.CalendarEvents...).RemoveByUserID(userID)
+
+		_, err := taskCalendarRepository.NewEvent(&migratedEvent, task.ID.Hex(), title, "", withReminder)
 		if err != nil {
 			return err
 		}
 
-		repositories[user.ID.Hex()] = repository
+		migrations = append(migrations, migration{event: event, calendarEvents: migratedEvent.CalendarEvents, nativeEvent: *persistedEvent})
+		return nil
+	}
 
-		for _, unit := range task.WorkUnits {
-			err = repository.DeleteEvent(&unit.ScheduledAt)
+	err = func() error {
+		err := migrate(&task.DueAt, s.taskTextRenderer.RenderDueEventTitle(task), s.taskTextRenderer.HasReminder(task))
+		if err != nil {
+			return err
+		}
+
+		for i := range task.WorkUnits {
+			unit := &task.WorkUnits[i]
+
+			err = migrate(&unit.ScheduledAt, s.taskTextRenderer.RenderWorkUnitEventTitle(task, unit), s.taskTextRenderer.HasReminder(unit))
 			if err != nil {
-				s.logger.Warning(fmt.Sprintf("failed to delete work unit %s event", unit.ID.Hex()), errors.WithStack(err))
-				continue
+				return err
+			}
+
+			if unit.Break != nil {
+				err = migrate(unit.Break, s.taskTextRenderer.RenderBreakEventTitle(task), false)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(migrations) == 0 {
+			return nil
+		}
+
+		for _, m := range migrations {
+			m.event.CalendarEvents = m.calendarEvents
+		}
+
+		return s.taskRepository.Update(ctx, task, false)
+	}()
+	if err != nil {
+		for _, m := range migrations {
+			deleteErr := taskCalendarRepository.DeleteEvent(&calendar.Event{CalendarEvents: m.calendarEvents})
+			if deleteErr != nil {
+				s.logger.Warning(fmt.Sprintf("failed to delete migrated event of task %s for user %s", task.ID.Hex(), userID), errors.WithStack(deleteErr))
 			}
 		}
+
+		return 0, err
 	}
 
-	for _, user := range relevantUsers {
-		err = repositories[user.ID.Hex()].DeleteEvent(&task.DueAt)
+	// The task doesn't point to the native events anymore, so leftovers are harmless
+	for _, m := range migrations {
+		err = nativeCalendarRepository.DeleteEvent(&calendar.Event{CalendarEvents: calendar.PersistedEvents{m.nativeEvent}})
 		if err != nil {
-			s.logger.Warning(fmt.Sprintf("failed to delete task %s event", task.ID.Hex()), errors.WithStack(err))
+			s.logger.Warning(fmt.Sprintf("failed to delete native event of task %s for user %s", task.ID.Hex(), userID), errors.WithStack(err))
 		}
 	}
 
-	return nil
+	return len(migrations), nil
 }
 
 // SyncCalendar triggers a sync on a single calendar
@@ -921,14 +3331,16 @@ func (s *PlanningService) SyncCalendar(ctx context.Context, user *users.User, ca
 		case err := <-errorChannel:
 			return nil, errors.WithStack(err)
 		case <-ctx.Done():
-			return nil, errors.Wrap(err, "context canceled")
+			return nil, errors.Wrap(ctx.Err(), "context canceled")
 		}
 	}
 }
 
 // DueDateChanged should be triggered when the due date changes. The task needs to be locked before this is called.
 func (s *PlanningService) DueDateChanged(ctx context.Context, task *Task, ownerNeedsUpdate bool) (*Task, error) {
-	task.DueAt.Date.End = task.DueAt.Date.Start.Add(15 * time.Minute)
+	if !task.IsBacklog {
+		task.DueAt.Date.End = task.DueAt.Date.Start.Add(15 * time.Minute)
+	}
 
 	relevantUsers, err := s.getAllRelevantUsers(ctx, task)
 	if err != nil {
@@ -951,10 +3363,42 @@ func (s *PlanningService) DueDateChanged(ctx context.Context, task *Task, ownerN
 		return nil, err
 	}
 
-	// In case there are work units now after the deadline
+	task.IsDueDuringAbsence = isDueDuringAbsence(task, relevantUsers[0])
+
+	if task.IsBacklog {
+		return task, nil
+	}
+
+	// In case there are work units now after the deadline, pinned work units can't stay there either
 	var toReschedule []WorkUnit
-	for _, unit := range task.WorkUnits {
+	for i, unit := range task.WorkUnits {
 		if unit.ScheduledAt.Date.End.After(task.DueAt.Date.Start) && unit.IsDone == false {
+			task.WorkUnits[i].IsPinned = false
+			unit.IsPinned = false
+			toReschedule = append(toReschedule, unit)
+		}
+	}
+
+	for _, unit := range toReschedule {
+		var err error
+		task, err = s.RescheduleWorkUnit(ctx, task, &unit, false, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return task, nil
+}
+
+// StartAfterChanged reschedules all work units that are now before the earliest start of the task
+func (s *PlanningService) StartAfterChanged(ctx context.Context, task *Task) (*Task, error) {
+	if task.StartAfter == nil {
+		return task, nil
+	}
+
+	var toReschedule []WorkUnit
+	for _, unit := range task.WorkUnits {
+		if unit.ScheduledAt.Date.Start.Before(*task.StartAfter) && unit.IsDone == false && !unit.IsPinned {
 			toReschedule = append(toReschedule, unit)
 		}
 	}
@@ -1048,6 +3492,22 @@ func (s *PlanningService) processTaskEventChange(ctx context.Context, event *cal
 		return
 	}
 
+	// Breaks between chunks only inform the user, so changes to them don't change the plan
+	if index, unit := task.WorkUnits.FindByBreakCalendarID(calendarEvent.CalendarEventID); unit != nil {
+		if event.Deleted {
+			task.WorkUnits[index].Break = nil
+		} else {
+			task.WorkUnits[index].Break.Date = event.Date
+		}
+
+		err = s.taskRepository.Update(ctx, task, false)
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("Error while updating task %s", task.ID.Hex()), err)
+		}
+
+		return
+	}
+
 	// At this point the event is not the due date event, so we check if it is a work unit event
 	index, workUnit := task.WorkUnits.FindByCalendarID(calendarEvent.CalendarEventID)
 	if workUnit == nil {
@@ -1071,18 +3531,23 @@ func (s *PlanningService) processTaskEventChange(ctx context.Context, event *cal
 		}
 
 		// Delete work unit event for all relevant users
+		var usersWithRepository []*users.User
+		calendarRepositories := make(map[string]calendar.RepositoryInterface)
 		for _, user := range relevantUsers {
-			if user.ID.Hex() == userID {
-				// We don't need to delete the already deleted event
-				continue
-			}
-
 			calendarRepository, err := s.calendarRepositoryManager.GetTaskCalendarRepositoryForUser(ctx, user)
 			if err != nil {
 				s.logger.Error(fmt.Sprintf("could not get calendar repository for user %s", user.ID.Hex()), err)
 				continue
 			}
 
+			usersWithRepository = append(usersWithRepository, user)
+			calendarRepositories[user.ID.Hex()] = calendarRepository
+
+			if user.ID.Hex() == userID {
+				// We don't need to delete the already deleted event
+				continue
+			}
+
 			err = calendarRepository.DeleteEvent(&workUnit.ScheduledAt)
 			if err != nil {
 				s.logger.Error(fmt.Sprintf("could not delete event for user %s in task %s", user.ID.Hex(), task.ID.Hex()), err)
@@ -1091,6 +3556,7 @@ func (s *PlanningService) processTaskEventChange(ctx context.Context, event *cal
 		}
 
 		// Delete the work unit
+		breaks := detachBreaks(task, &task.WorkUnits[index])
 		task.WorkUnits = task.WorkUnits.RemoveByIndex(index)
 		err = s.taskRepository.Update(ctx, task, false)
 		if err != nil {
@@ -1098,6 +3564,8 @@ func (s *PlanningService) processTaskEventChange(ctx context.Context, event *cal
 			return
 		}
 
+		s.deleteBreakEvents(breaks, usersWithRepository, calendarRepositories)
+
 		return
 	}
 
@@ -1109,7 +3577,12 @@ func (s *PlanningService) processTaskEventChange(ctx context.Context, event *cal
 		// We don't return here, because we still need to update the task
 	}
 
-	workUnitIsOutOfBounds := workUnit.ScheduledAt.Date.End.After(task.DueAt.Date.Start)
+	workUnitIsOutOfBounds := !task.IsBacklog && workUnit.ScheduledAt.Date.End.After(task.DueAt.Date.Start)
+
+	// The user chose this time, so the planner must not move it anymore unless it is after the deadline
+	if !workUnitIsOutOfBounds {
+		workUnit.IsPinned = true
+	}
 
 	workUnit.Workload = workUnit.ScheduledAt.Date.Duration()
 
@@ -1128,6 +3601,8 @@ func (s *PlanningService) processTaskEventChange(ctx context.Context, event *cal
 		return
 	}
 
+	s.moveDependentTasks(ctx, task)
+
 	_, workUnit = task.WorkUnits.FindByID(workUnit.ID.Hex())
 	if workUnit == nil || workUnit.ScheduledAt.Date != event.Date {
 		// The work unit was either merged and therefore does not exist anymore or
@@ -1165,7 +3640,13 @@ func (s *PlanningService) CheckForMergingWorkUnits(ctx context.Context, task *Ta
 	var workUnitsToRemove []*WorkUnit
 
 	for i, unit := range task.WorkUnits {
-		if (unit.ScheduledAt.Date.IntersectsWith(lastDate) || unit.ScheduledAt.Date.Start.Equal(lastDate.End)) && !unit.ScheduledAt.Date.Contains(lastDate) && !lastDate.Contains(unit.ScheduledAt.Date) {
+		// Pinned work units stay where the user put them
+		isPinned := unit.IsPinned || (i > 0 && task.WorkUnits[i-1].IsPinned)
+
+		// Work units are never merged across an intentional break
+		isAfterBreak := i > 0 && task.WorkUnits[i-1].Break != nil
+
+		if !isPinned && !isAfterBreak && (unit.ScheduledAt.Date.IntersectsWith(lastDate) || unit.ScheduledAt.Date.Start.Equal(lastDate.End)) && !unit.ScheduledAt.Date.Contains(lastDate) && !lastDate.Contains(unit.ScheduledAt.Date) {
 			if len(relevantUsers) == 0 {
 				relevantUsers, _ = s.getAllRelevantUsers(ctx, task)
 			}
@@ -1180,6 +3661,12 @@ func (s *PlanningService) CheckForMergingWorkUnits(ctx context.Context, task *Ta
 				return task
 			}
 
+			// Chunks keep their fixed size even if there is no break between them
+			if len(relevantUsers) > 0 && relevantUsers[0].Settings.Scheduling.ChunkDuration > 0 {
+				lastDate = unit.ScheduledAt.Date
+				continue
+			}
+
 			// Reduce of both work units
 			task.WorkloadOverall -= unit.Workload
 			task.WorkloadOverall -= task.WorkUnits[i-1].Workload
@@ -1273,7 +3760,16 @@ func (s *PlanningService) checkForIntersectingWorkUnits(ctx context.Context, use
 	var intersections []Intersection
 
 	for _, intersectingTask := range intersectingTasks {
-		_, workUnits := intersectingTask.WorkUnits.FindByEventIntersection(event, ignoreWorkUnitID)
+		_, intersectingWorkUnits := intersectingTask.WorkUnits.FindByEventIntersection(event, ignoreWorkUnitID)
+
+		// Pinned work units stay where they are, even if they intersect
+		var workUnits WorkUnits
+		for _, unit := range intersectingWorkUnits {
+			if !unit.IsPinned {
+				workUnits = append(workUnits, unit)
+			}
+		}
+
 		if len(workUnits) == 0 {
 			continue
 		}
@@ -1342,9 +3838,21 @@ func (s *PlanningService) lookForUnscheduledTasks(ctx context.Context, userID st
 }
 
 // computeAvailabilityForTimeWindow traverses the given time interval by two weeks and returns when it found enough free time or traversed the whole interval
-func (s *PlanningService) computeAvailabilityForTimeWindow(ctx context.Context, users []*users.User, target time.Time, timeToSchedule time.Duration, window *date.TimeWindow, repositories []calendar.RepositoryInterface, constraint *date.FreeConstraint, task *Task, ignoreWorkUnitID string) (*date.TimeWindow, error) {
+func (s *PlanningService) computeAvailabilityForTimeWindow(ctx context.Context, users []*users.User, target time.Time, timeToSchedule time.Duration, window *date.TimeWindow, repositories []calendar.RepositoryInterface, constraint *date.FreeConstraint, task *Task, ignoreWorkUnitIDs ...string) (*date.TimeWindow, error) {
+	ignoredWorkUnits := make(map[string]bool)
+	for _, ignoreWorkUnitID := range ignoreWorkUnitIDs {
+		ignoredWorkUnits[ignoreWorkUnitID] = true
+	}
+
 	window.PreferredNeighbors = task.WorkUnits.Timespans()
 
+	err := s.applyWorkloadCaps(ctx, users, task, window, ignoredWorkUnits)
+	if err != nil {
+		return nil, err
+	}
+
+	window.Trace.SetWindow(date.Timespan{Start: window.Start, End: window.End}, target, timeToSchedule)
+
 	s.generateTimespansBasedOnTargetDate(target, window, func(timespans []date.Timespan) bool {
 		if window.Start.After(window.End) {
 			s.logger.Warning(fmt.Sprintf("time window start %s after end %s", window.Start, window.End), errors.New("window start is after window end"))
@@ -1363,12 +3871,26 @@ func (s *PlanningService) computeAvailabilityForTimeWindow(ctx context.Context,
 						return err
 					}
 
+					var busyTimespans []date.Timespan
 					for _, busyWorkUnit := range busyWorkUnits {
-						if busyWorkUnit.ID.Hex() == ignoreWorkUnitID {
+						if ignoredWorkUnits[busyWorkUnit.ID.Hex()] {
 							continue
 						}
 
 						window.AddToBusy(busyWorkUnit.ScheduledAt.Date)
+						busyTimespans = append(busyTimespans, busyWorkUnit.ScheduledAt.Date)
+					}
+
+					window.Trace.AddBusy(fmt.Sprintf("work units of user %s", user.ID.Hex()), busyTimespans)
+
+					// Nobody works during their absences
+					absences := user.Absences.Intersecting(timespan)
+					if len(absences) > 0 {
+						for _, absence := range absences {
+							window.AddToBusy(absence)
+						}
+
+						window.Trace.AddBusy(fmt.Sprintf("absences of user %s", user.ID.Hex()), absences)
 					}
 
 					return nil
@@ -1380,11 +3902,28 @@ func (s *PlanningService) computeAvailabilityForTimeWindow(ctx context.Context,
 				repository := repository
 
 				wg.Go(func() error {
-					err := repository.AddBusyToWindow(window, timespan.Start, timespan.End)
+					if window.Trace == nil {
+						err := repository.AddBusyToWindow(window, timespan.Start, timespan.End)
+						if err != nil {
+							return errors.Wrap(err, "error while adding busy time to window")
+						}
+
+						return nil
+					}
+
+					// While tracing the busy time of every repository is collected separately to know its source
+					collector := &date.TimeWindow{Start: window.Start, End: window.End}
+					err := repository.AddBusyToWindow(collector, timespan.Start, timespan.End)
 					if err != nil {
 						return errors.Wrap(err, "error while adding busy time to window")
 					}
 
+					busyTimespans := collector.Busy()
+					for _, busy := range busyTimespans {
+						window.AddToBusy(busy)
+					}
+					window.Trace.AddBusy(fmt.Sprintf("calendar %T", repository), busyTimespans)
+
 					return nil
 				})
 			}
@@ -1397,7 +3936,7 @@ func (s *PlanningService) computeAvailabilityForTimeWindow(ctx context.Context,
 
 			window.ComputeFree(constraint, target, timespan)
 
-			if window.FreeDuration() >= timeToSchedule {
+			if window.FreeDurationWithinCaps() >= timeToSchedule {
 				return true
 			}
 		}
@@ -1488,10 +4027,28 @@ func (s *PlanningService) generateTimespansBasedOnTargetDate(target time.Time, w
 	}
 }
 
-func (s *PlanningService) getTargetTimeForUser(user *users.User, window *date.TimeWindow, workloadToSchedule time.Duration) time.Time {
+// getTargetTimeForUsers combines the timing preferences of all users by taking the average of their target times.
+// The timing preference of the tags of the task replaces the one of the owner.
+func (s *PlanningService) getTargetTimeForUsers(relevantUsers []*users.User, tagScheduling *TagScheduling, window *date.TimeWindow, workloadToSchedule time.Duration) time.Time {
+	ownerPreference := relevantUsers[0].Settings.Scheduling.TimingPreference
+	if tagScheduling != nil && tagScheduling.TimingPreference != "" {
+		ownerPreference = tagScheduling.TimingPreference
+	}
+
+	target := s.getTargetTimeForPreference(ownerPreference, window, workloadToSchedule)
+
+	var offsetSum time.Duration
+	for _, user := range relevantUsers[1:] {
+		offsetSum += s.getTargetTimeForPreference(user.Settings.Scheduling.TimingPreference, window, workloadToSchedule).Sub(target)
+	}
+
+	return target.Add(offsetSum / time.Duration(len(relevantUsers)))
+}
+
+func (s *PlanningService) getTargetTimeForPreference(timingPreference string, window *date.TimeWindow, workloadToSchedule time.Duration) time.Time {
 	if window.End.Before(now().Add(time.Hour * 24 * 2)) {
 		if window.End.Before(now().Add(time.Hour * 24)) {
-			switch user.Settings.Scheduling.TimingPreference {
+			switch timingPreference {
 			case users.TimingPreferenceEarly:
 			case users.TimingPreferenceVeryEarly:
 				return window.Start
@@ -1505,7 +4062,7 @@ func (s *PlanningService) getTargetTimeForUser(user *users.User, window *date.Ti
 		return window.Start.Add(time.Hour * 24)
 	}
 
-	switch user.Settings.Scheduling.TimingPreference {
+	switch timingPreference {
 	case users.TimingPreferenceVeryEarly:
 		return window.Start.Add(time.Hour * 6)
 	default:
@@ -1690,3 +1690,2334 @@ func TestPlanningService_checkForMergingWorkUnits(t *testing.T) {
 		})
 	}
 }
+
+func TestPlanningService_ScheduleTask_BlockedBy(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 1, 1, 12, 0, 0, 0, location) }
+
+	predecessorID := primitive.NewObjectID()
+	predecessor := &Task{
+		ID:              predecessorID,
+		UserID:          primaryUser.ID,
+		Name:            "Predecessor",
+		WorkloadOverall: time.Hour * 3,
+		DueAt: calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 25, 18, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 25, 18, 15, 0, 0, location),
+			},
+		},
+		WorkUnits: WorkUnits{
+			{
+				ID:       primitive.NewObjectID(),
+				Workload: time.Hour * 3,
+				ScheduledAt: calendar.Event{
+					Date: date.Timespan{
+						Start: time.Date(2021, 1, 20, 9, 0, 0, 0, location),
+						End:   time.Date(2021, 1, 20, 12, 0, 0, 0, location),
+					},
+				},
+			},
+		},
+	}
+
+	taskRepo := &MockTaskRepository{Tasks: []*Task{predecessor}}
+
+	var calendarRepositoryManager = CalendarRepositoryManager{
+		userRepository:  &userRepo,
+		logger:          log,
+		overriddenRepos: make(map[string]calendar.RepositoryInterface),
+	}
+
+	calendarRepositoryManager.overriddenRepos[primaryUser.ID.Hex()] = &calendar.MockCalendarRepository{Events: []*calendar.Event{}, User: &primaryUser}
+
+	service := PlanningService{
+		userRepository:            &userRepo,
+		taskRepository:            taskRepo,
+		calendarRepositoryManager: &calendarRepositoryManager,
+		logger:                    log,
+		locker:                    locker,
+	}
+
+	for _, timing := range users.TimingPreferences {
+		primaryUser.Settings.Scheduling.TimingPreference = timing
+
+		t.Run("Blocked task with timing "+timing, func(t *testing.T) {
+			task := Task{
+				UserID:          primaryUser.ID,
+				Name:            "Dependent",
+				WorkloadOverall: time.Hour * 4,
+				BlockedBy:       []primitive.ObjectID{predecessorID},
+				DueAt: calendar.Event{
+					Date: date.Timespan{
+						Start: time.Date(2021, 2, 1, 18, 0, 0, 0, location),
+						End:   time.Date(2021, 2, 1, 18, 15, 0, 0, location),
+					},
+				},
+			}
+
+			err := taskRepo.Add(context.TODO(), &task)
+			if err != nil {
+				t.Error(err)
+			}
+
+			scheduledTask, err := service.ScheduleTask(context.TODO(), &task, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = testScheduledTask(scheduledTask)
+			if err != nil {
+				t.Error(err)
+			}
+
+			for _, unit := range scheduledTask.WorkUnits {
+				if unit.ScheduledAt.Date.Start.Before(predecessor.WorkUnits[0].ScheduledAt.Date.End) {
+					t.Errorf("work unit %s is scheduled before the blocking task is done", unit.ScheduledAt.Date)
+				}
+			}
+		})
+	}
+
+	primaryUser.Settings.Scheduling.TimingPreference = ""
+}
+
+func TestPlanningService_moveDependentTasks(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 1, 1, 12, 0, 0, 0, location) }
+
+	predecessor := &Task{
+		ID:              primitive.NewObjectID(),
+		UserID:          primaryUser.ID,
+		Name:            "Predecessor",
+		WorkloadOverall: time.Hour * 3,
+		DueAt: calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 25, 18, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 25, 18, 15, 0, 0, location),
+			},
+		},
+		WorkUnits: WorkUnits{
+			{
+				ID:       primitive.NewObjectID(),
+				Workload: time.Hour * 3,
+				ScheduledAt: calendar.Event{
+					Date: date.Timespan{
+						Start: time.Date(2021, 1, 11, 9, 0, 0, 0, location),
+						End:   time.Date(2021, 1, 11, 12, 0, 0, 0, location),
+					},
+				},
+			},
+		},
+	}
+
+	dependentEvent := calendar.Event{
+		Date: date.Timespan{
+			Start: time.Date(2021, 1, 4, 9, 0, 0, 0, location),
+			End:   time.Date(2021, 1, 4, 11, 0, 0, 0, location),
+		},
+		CalendarEvents: calendar.PersistedEvents{
+			calendar.PersistedEvent{
+				CalendarEventID: "dependent-unit",
+				UserID:          primaryUser.ID,
+				CalendarType:    "mock_calendar",
+			},
+		},
+	}
+
+	dependent := &Task{
+		ID:              primitive.NewObjectID(),
+		UserID:          primaryUser.ID,
+		Name:            "Dependent",
+		WorkloadOverall: time.Hour * 2,
+		BlockedBy:       []primitive.ObjectID{predecessor.ID},
+		DueAt: calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 2, 1, 18, 0, 0, 0, location),
+				End:   time.Date(2021, 2, 1, 18, 15, 0, 0, location),
+			},
+		},
+		WorkUnits: WorkUnits{
+			{
+				ID:          primitive.NewObjectID(),
+				Workload:    time.Hour * 2,
+				ScheduledAt: dependentEvent,
+			},
+		},
+	}
+
+	taskRepo := &MockTaskRepository{Tasks: []*Task{predecessor, dependent}}
+
+	var calendarRepositoryManager = CalendarRepositoryManager{
+		userRepository:  &userRepo,
+		logger:          log,
+		overriddenRepos: make(map[string]calendar.RepositoryInterface),
+	}
+
+	calendarRepositoryManager.overriddenRepos[primaryUser.ID.Hex()] = &calendar.MockCalendarRepository{Events: []*calendar.Event{&dependentEvent}, User: &primaryUser}
+
+	service := PlanningService{
+		userRepository:            &userRepo,
+		taskRepository:            taskRepo,
+		calendarRepositoryManager: &calendarRepositoryManager,
+		logger:                    log,
+		locker:                    locker,
+	}
+
+	service.moveDependentTasks(context.TODO(), predecessor)
+
+	movedTask, err := taskRepo.FindByID(context.TODO(), dependent.ID.Hex(), primaryUser.ID.Hex(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(movedTask.WorkUnits) != 1 {
+		t.Fatalf("expected one work unit, got %d", len(movedTask.WorkUnits))
+	}
+
+	if movedTask.WorkUnits[0].ScheduledAt.Date.Start.Before(predecessor.WorkUnits[0].ScheduledAt.Date.End) {
+		t.Errorf("work unit %s was not moved behind the blocking task", movedTask.WorkUnits[0].ScheduledAt.Date)
+	}
+}
+
+func TestPlanningService_applyDependenciesToTimeWindow(t *testing.T) {
+	predecessorEnd := time.Date(2021, 1, 11, 12, 0, 0, 0, location)
+
+	predecessor := &Task{
+		ID:     primitive.NewObjectID(),
+		UserID: primaryUser.ID,
+		WorkUnits: WorkUnits{
+			{
+				ID:          primitive.NewObjectID(),
+				ScheduledAt: calendar.Event{Date: date.Timespan{Start: predecessorEnd.Add(-time.Hour * 3), End: predecessorEnd}},
+			},
+		},
+	}
+
+	dependent := &Task{
+		ID:        primitive.NewObjectID(),
+		UserID:    primaryUser.ID,
+		BlockedBy: []primitive.ObjectID{predecessor.ID},
+	}
+
+	service := PlanningService{
+		taskRepository: &MockTaskRepository{Tasks: []*Task{predecessor, dependent}},
+		logger:         log,
+	}
+
+	start := time.Date(2021, 1, 4, 0, 0, 0, 0, location)
+
+	window := date.TimeWindow{Start: start, End: predecessorEnd.Add(time.Hour * 24)}
+	service.applyDependenciesToTimeWindow(context.TODO(), dependent, &window)
+
+	if !window.Start.Equal(predecessorEnd) || dependent.IsBlockedPastDeadline {
+		t.Errorf("got window start %s and flag %v, want %s and false", window.Start, dependent.IsBlockedPastDeadline, predecessorEnd)
+	}
+
+	// The predecessor ends after the deadline, so no time is left
+	deadline := predecessorEnd.Add(-time.Hour * 24)
+	window = date.TimeWindow{Start: start, End: deadline}
+	service.applyDependenciesToTimeWindow(context.TODO(), dependent, &window)
+
+	if !window.Start.Equal(deadline) || !window.End.Equal(deadline) || !dependent.IsBlockedPastDeadline {
+		t.Errorf("got window %s - %s and flag %v, want an empty window at the deadline and the task flagged", window.Start, window.End, dependent.IsBlockedPastDeadline)
+	}
+}
+
+func TestPlanningService_ValidateDependencies(t *testing.T) {
+	taskA := &Task{ID: primitive.NewObjectID(), UserID: primaryUser.ID}
+	taskB := &Task{ID: primitive.NewObjectID(), UserID: primaryUser.ID, BlockedBy: []primitive.ObjectID{taskA.ID}}
+	taskC := &Task{ID: primitive.NewObjectID(), UserID: primaryUser.ID, BlockedBy: []primitive.ObjectID{taskB.ID}}
+
+	service := PlanningService{
+		taskRepository: &MockTaskRepository{Tasks: []*Task{taskA, taskB, taskC}},
+		logger:         log,
+	}
+
+	tests := []struct {
+		name    string
+		task    Task
+		wantErr bool
+	}{
+		{
+			name: "New task without dependencies",
+			task: Task{UserID: primaryUser.ID},
+		},
+		{
+			name: "New task blocked by chain",
+			task: Task{UserID: primaryUser.ID, BlockedBy: []primitive.ObjectID{taskC.ID, taskA.ID}},
+		},
+		{
+			name:    "Blocked by missing task",
+			task:    Task{UserID: primaryUser.ID, BlockedBy: []primitive.ObjectID{primitive.NewObjectID()}},
+			wantErr: true,
+		},
+		{
+			name:    "Blocked by itself",
+			task:    Task{ID: taskA.ID, UserID: primaryUser.ID, BlockedBy: []primitive.ObjectID{taskA.ID}},
+			wantErr: true,
+		},
+		{
+			name:    "Indirect cycle",
+			task:    Task{ID: taskA.ID, UserID: primaryUser.ID, BlockedBy: []primitive.ObjectID{taskC.ID}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.ValidateDependencies(context.TODO(), &tt.task)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDependencies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlanningService_MaterializeSeries(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 1, 1, 12, 0, 0, 0, location) }
+
+	taskRepo := &MockTaskRepository{Tasks: []*Task{}}
+
+	var calendarRepositoryManager = CalendarRepositoryManager{
+		userRepository:  &userRepo,
+		logger:          log,
+		overriddenRepos: make(map[string]calendar.RepositoryInterface),
+	}
+
+	calendarRepository := &calendar.MockCalendarRepository{Events: []*calendar.Event{}, User: &primaryUser}
+	calendarRepositoryManager.overriddenRepos[primaryUser.ID.Hex()] = calendarRepository
+
+	service := PlanningService{
+		userRepository:            &userRepo,
+		taskRepository:            taskRepo,
+		calendarRepositoryManager: &calendarRepositoryManager,
+		logger:                    log,
+		locker:                    locker,
+		taskTextRenderer:          &TaskTextRenderer{},
+	}
+
+	task := &Task{
+		UserID:          primaryUser.ID,
+		Name:            "Weekly report",
+		WorkloadOverall: time.Hour,
+		DueAt: calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 4, 17, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 4, 17, 15, 0, 0, location),
+			},
+		},
+		Recurrence: &Recurrence{Rule: "FREQ=WEEKLY;BYDAY=MO"},
+	}
+	task.StartSeries()
+
+	err := taskRepo.Add(context.TODO(), task)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task, err = service.ScheduleTask(context.TODO(), task, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := service.MaterializeSeries(context.TODO(), task)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Mondays from January 11th until the horizon ends on February 26th
+	if len(created) != 7 {
+		t.Fatalf("expected 7 materialized tasks, got %d", len(created))
+	}
+
+	for i, instance := range created {
+		wantDue := time.Date(2021, 1, 11+i*7, 17, 0, 0, 0, location)
+		if !instance.DueAt.Date.Start.Equal(wantDue) {
+			t.Errorf("task %d is due at %s, want %s", i, instance.DueAt.Date.Start, wantDue)
+		}
+
+		if instance.Recurrence.SeriesID != task.Recurrence.SeriesID {
+			t.Errorf("task %d is not part of the series", i)
+		}
+
+		err = testScheduledTask(&instance)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Materializing again must not create duplicates
+	created, err = service.MaterializeSeries(context.TODO(), task)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(created) != 0 {
+		t.Errorf("expected no new tasks, got %d", len(created))
+	}
+
+	err = service.DeleteSeries(context.TODO(), task)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(taskRepo.Tasks) != 0 {
+		t.Errorf("expected all tasks of the series to be deleted, %d left", len(taskRepo.Tasks))
+	}
+
+	if len(calendarRepository.Events) != 0 {
+		t.Errorf("expected all calendar events to be deleted, %d left", len(calendarRepository.Events))
+	}
+}
+
+// failingLocker is a locker that can't hand out any locks
+type failingLocker struct {
+	err error
+}
+
+func (l *failingLocker) Acquire(ctx context.Context, key string, ttl time.Duration, tryOnlyOnce bool, waitMax time.Duration) (locking.LockInterface, error) {
+	return nil, l.err
+}
+
+func TestPlanningService_MaterializeSeriesFailures(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 1, 1, 12, 0, 0, 0, location) }
+
+	taskRepo := &MockTaskRepository{Tasks: []*Task{}}
+
+	var calendarRepositoryManager = CalendarRepositoryManager{
+		userRepository:  &userRepo,
+		logger:          log,
+		overriddenRepos: make(map[string]calendar.RepositoryInterface),
+	}
+
+	calendarRepository := &calendar.MockCalendarRepository{Events: []*calendar.Event{}, User: &primaryUser}
+	calendarRepositoryManager.overriddenRepos[primaryUser.ID.Hex()] = calendarRepository
+
+	service := PlanningService{
+		userRepository:            &userRepo,
+		taskRepository:            taskRepo,
+		calendarRepositoryManager: &calendarRepositoryManager,
+		logger:                    log,
+		locker:                    locker,
+		taskTextRenderer:          &TaskTextRenderer{},
+	}
+
+	task := &Task{
+		UserID:          primaryUser.ID,
+		Name:            "Monthly report",
+		WorkloadOverall: time.Hour,
+		DueAt: calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 4, 17, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 4, 17, 15, 0, 0, location),
+			},
+		},
+		Recurrence: &Recurrence{Rule: "FREQ=WEEKLY;INTERVAL=4;BYDAY=MO"},
+	}
+	task.StartSeries()
+
+	err := taskRepo.Add(context.TODO(), task)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task, err = service.ScheduleTask(context.TODO(), task, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A series that is locked by someone else is skipped, but other lock errors are reported
+	service.locker = &failingLocker{err: locking.ErrNotObtained}
+	created, err := service.MaterializeSeries(context.TODO(), task)
+	if err != nil || len(created) != 0 {
+		t.Errorf("got %d tasks and error %v for a locked series", len(created), err)
+	}
+
+	service.locker = &failingLocker{err: errors.New("connection refused")}
+	_, err = service.MaterializeSeries(context.TODO(), task)
+	if err == nil {
+		t.Error("expected the lock error to be returned")
+	}
+
+	service.locker = locker
+
+	// The due event of the next task can't be created, so it must not be left behind unscheduled
+	calendarRepositoryManager.overriddenRepos[primaryUser.ID.Hex()] = &taskCalendarFailingOnTitle{calendarRepository, (&TaskTextRenderer{}).RenderDueEventTitle(task)}
+
+	_, err = service.MaterializeSeries(context.TODO(), task)
+	if err == nil {
+		t.Fatal("expected scheduling the next task to fail")
+	}
+
+	if len(taskRepo.Tasks) != 1 {
+		t.Fatalf("got %d tasks after failing to schedule, want only the first one", len(taskRepo.Tasks))
+	}
+
+	calendarRepositoryManager.overriddenRepos[primaryUser.ID.Hex()] = calendarRepository
+
+	created, err = service.MaterializeSeries(context.TODO(), task)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// February 1st is the next occurrence, March 1st is after the horizon
+	if len(created) != 1 || !created[0].DueAt.Date.Start.Equal(time.Date(2021, 2, 1, 17, 0, 0, 0, location)) {
+		t.Fatalf("got %d tasks when retrying, want the one due on February 1st", len(created))
+	}
+
+	err = testScheduledTask(&created[0])
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPlanningService_PreviewTask(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 1, 1, 12, 0, 0, 0, location) }
+
+	taskRepo := &MockTaskRepository{Tasks: []*Task{}}
+
+	var calendarRepositoryManager = CalendarRepositoryManager{
+		userRepository:  &userRepo,
+		logger:          log,
+		overriddenRepos: make(map[string]calendar.RepositoryInterface),
+	}
+
+	calendarRepository := &calendar.MockCalendarRepository{Events: []*calendar.Event{
+		{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 4, 9, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 4, 12, 0, 0, 0, location),
+			},
+			Blocking: true,
+		},
+	}, User: &primaryUser}
+	calendarRepositoryManager.overriddenRepos[primaryUser.ID.Hex()] = calendarRepository
+
+	service := PlanningService{
+		userRepository:            &userRepo,
+		taskRepository:            taskRepo,
+		calendarRepositoryManager: &calendarRepositoryManager,
+		logger:                    log,
+		locker:                    locker,
+	}
+
+	tests := []struct {
+		name             string
+		workload         time.Duration
+		dueAt            time.Time
+		wantNotScheduled time.Duration
+	}{
+		{
+			name:     "Enough free time",
+			workload: time.Hour * 4,
+			dueAt:    time.Date(2021, 1, 15, 18, 0, 0, 0, location),
+		},
+		{
+			// Only the afternoon of the 1st from 13:00 until 18:00 is free
+			name:             "Not enough free time",
+			workload:         time.Hour * 8,
+			dueAt:            time.Date(2021, 1, 1, 18, 0, 0, 0, location),
+			wantNotScheduled: time.Hour * 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &Task{
+				UserID:          primaryUser.ID,
+				Name:            "Preview",
+				WorkloadOverall: tt.workload,
+				DueAt: calendar.Event{
+					Date: date.Timespan{
+						Start: tt.dueAt,
+						End:   tt.dueAt.Add(15 * time.Minute),
+					},
+				},
+			}
+
+			previewedTask, err := service.PreviewTask(context.TODO(), task)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if previewedTask.NotScheduled != tt.wantNotScheduled {
+				t.Errorf("not scheduled is %s, want %s", previewedTask.NotScheduled, tt.wantNotScheduled)
+			}
+
+			var workload time.Duration
+			for _, unit := range previewedTask.WorkUnits {
+				workload += unit.Workload
+
+				if len(unit.ScheduledAt.CalendarEvents) != 0 {
+					t.Errorf("work unit %s has calendar events", unit.ScheduledAt.Date)
+				}
+			}
+
+			if workload+previewedTask.NotScheduled != tt.workload {
+				t.Errorf("previewed workload %s and not scheduled %s don't add up to %s", workload, previewedTask.NotScheduled, tt.workload)
+			}
+
+			if len(calendarRepository.Events) != 1 {
+				t.Errorf("preview created %d calendar events", len(calendarRepository.Events)-1)
+			}
+
+			if len(taskRepo.Tasks) != 0 {
+				t.Errorf("preview persisted %d tasks", len(taskRepo.Tasks))
+			}
+		})
+	}
+}
+
+// taskCalendarWithoutBusy is a task calendar whose own events don't count as busy time, like a real task calendar
+type taskCalendarWithoutBusy struct {
+	*calendar.MockCalendarRepository
+}
+
+func (r *taskCalendarWithoutBusy) AddBusyToWindow(window *date.TimeWindow, start time.Time, end time.Time) error {
+	return nil
+}
+
+func TestPlanningService_RebalanceTasks(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 1, 1, 12, 0, 0, 0, location) }
+
+	taskRepo := &MockTaskRepository{Tasks: []*Task{}}
+
+	var calendarRepositoryManager = CalendarRepositoryManager{
+		userRepository:  &userRepo,
+		logger:          log,
+		overriddenRepos: make(map[string]calendar.RepositoryInterface),
+	}
+
+	calendarRepository := &calendar.MockCalendarRepository{Events: []*calendar.Event{}, User: &primaryUser}
+	calendarRepositoryManager.overriddenRepos[primaryUser.ID.Hex()] = &taskCalendarWithoutBusy{calendarRepository}
+
+	service := PlanningService{
+		userRepository:            &userRepo,
+		taskRepository:            taskRepo,
+		calendarRepositoryManager: &calendarRepositoryManager,
+		logger:                    log,
+		locker:                    locker,
+	}
+
+	// The slack task was scheduled first and took the only free time before the deadline of the urgent task
+	slackUnit := WorkUnit{
+		ScheduledAt: calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 1, 13, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 1, 18, 0, 0, 0, location),
+			},
+			Blocking: true,
+		},
+		Workload: time.Hour * 5,
+	}
+	_, err := calendarRepository.NewEvent(&slackUnit.ScheduledAt, "", "Slack", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slackTask := &Task{
+		UserID:          primaryUser.ID,
+		Name:            "Slack",
+		WorkloadOverall: time.Hour * 5,
+		WorkUnits:       WorkUnits{slackUnit},
+		DueAt: calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 15, 18, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 15, 18, 15, 0, 0, location),
+			},
+		},
+	}
+	urgentTask := &Task{
+		UserID:          primaryUser.ID,
+		Name:            "Urgent",
+		WorkloadOverall: time.Hour * 5,
+		NotScheduled:    time.Hour * 5,
+		DueAt: calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 1, 18, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 1, 18, 15, 0, 0, location),
+			},
+		},
+	}
+
+	for _, task := range []*Task{slackTask, urgentTask} {
+		err = taskRepo.Add(context.TODO(), task)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	slackUnitID := slackTask.WorkUnits[0].ID
+
+	result, err := service.RebalanceTasks(context.TODO(), primaryUser.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The slack task is moved into the next day and split around the break at noon
+	wantResult := RebalanceResult{Tasks: 2, Created: 2, Updated: 1}
+	if !reflect.DeepEqual(*result, wantResult) {
+		t.Errorf("rebalance result is %+v, want %+v", *result, wantResult)
+	}
+
+	urgent, _ := taskRepo.FindByID(context.TODO(), urgentTask.ID.Hex(), primaryUser.ID.Hex(), false)
+	if urgent.NotScheduled != 0 || len(urgent.WorkUnits) != 1 || !urgent.WorkUnits[0].ScheduledAt.Date.Start.Equal(slackUnit.ScheduledAt.Date.Start) {
+		t.Errorf("urgent task wasn't moved into the free time before its deadline: %+v", urgent.WorkUnits)
+	}
+
+	slack, _ := taskRepo.FindByID(context.TODO(), slackTask.ID.Hex(), primaryUser.ID.Hex(), false)
+	if _, movedUnit := slack.WorkUnits.FindByID(slackUnitID.Hex()); slack.NotScheduled != 0 || movedUnit == nil {
+		t.Errorf("slack task didn't keep its moved work unit: %+v", slack.WorkUnits)
+	}
+
+	for _, unit := range slack.WorkUnits {
+		if unit.ScheduledAt.Date.IntersectsWith(urgent.WorkUnits[0].ScheduledAt.Date) {
+			t.Errorf("work units %s and %s intersect", unit.ScheduledAt.Date, urgent.WorkUnits[0].ScheduledAt.Date)
+		}
+	}
+
+	if len(calendarRepository.Events) != 3 {
+		t.Errorf("calendar has %d events, want 3", len(calendarRepository.Events))
+	}
+
+	// A second rebalance has nothing left to change
+	result, err = service.RebalanceTasks(context.TODO(), primaryUser.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantResult = RebalanceResult{Tasks: 2}
+	if !reflect.DeepEqual(*result, wantResult) {
+		t.Errorf("second rebalance result is %+v, want %+v", *result, wantResult)
+	}
+}
+
+func TestPlanningService_ScheduleTask_WorkloadCaps(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 1, 1, 12, 0, 0, 0, location) }
+
+	tag := primitive.NewObjectID()
+
+	tests := []struct {
+		name            string
+		maxWorkPerDay   time.Duration
+		tagWorkloadCaps []users.TagWorkloadCap
+		// collaboratorMaxWorkPerDay adds a collaborator with this cap to the task
+		collaboratorMaxWorkPerDay time.Duration
+		tags                      []primitive.ObjectID
+		wantNotScheduled          time.Duration
+		wantMaxPerDay             time.Duration
+	}{
+		{
+			// Without a cap the 1st and 2nd have 13 hours of free time
+			name:          "No cap",
+			wantMaxPerDay: time.Hour * 8,
+		},
+		{
+			name:             "Daily cap",
+			maxWorkPerDay:    time.Hour * 3,
+			wantNotScheduled: time.Hour * 2,
+			wantMaxPerDay:    time.Hour * 3,
+		},
+		{
+			name:             "Daily cap of the tag of the task",
+			tagWorkloadCaps:  []users.TagWorkloadCap{{TagID: tag, MaxWorkPerDay: time.Hour * 2}},
+			tags:             []primitive.ObjectID{tag},
+			wantNotScheduled: time.Hour * 4,
+			wantMaxPerDay:    time.Hour * 2,
+		},
+		{
+			name:            "Daily cap of another tag",
+			tagWorkloadCaps: []users.TagWorkloadCap{{TagID: tag, MaxWorkPerDay: time.Hour * 2}},
+			wantMaxPerDay:   time.Hour * 8,
+		},
+		{
+			name:                      "Daily cap of a collaborator",
+			collaboratorMaxWorkPerDay: time.Hour * 3,
+			wantNotScheduled:          time.Hour * 2,
+			wantMaxPerDay:             time.Hour * 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cappedUser := primaryUser
+			cappedUser.ID = primitive.NewObjectID()
+			cappedUser.Settings.Scheduling.MaxWorkPerDay = tt.maxWorkPerDay
+			cappedUser.Settings.Scheduling.TagWorkloadCaps = tt.tagWorkloadCaps
+
+			cappedUserRepo := users.MockUserRepository{Users: []*users.User{&cappedUser}}
+
+			var collaborators Collaborators
+			if tt.collaboratorMaxWorkPerDay > 0 {
+				collaborator := primaryUser
+				collaborator.ID = primitive.NewObjectID()
+				collaborator.Settings.Scheduling.MaxWorkPerDay = tt.collaboratorMaxWorkPerDay
+
+				cappedUser.Contacts = []users.Contact{{UserID: collaborator.ID}}
+				cappedUserRepo.Users = append(cappedUserRepo.Users, &collaborator)
+				collaborators = Collaborators{{UserID: collaborator.ID}}
+			}
+
+			var calendarRepositoryManager = CalendarRepositoryManager{
+				userRepository:  &cappedUserRepo,
+				logger:          log,
+				overriddenRepos: make(map[string]calendar.RepositoryInterface),
+			}
+
+			for _, user := range cappedUserRepo.Users {
+				calendarRepositoryManager.overriddenRepos[user.ID.Hex()] = &taskCalendarWithoutBusy{&calendar.MockCalendarRepository{Events: []*calendar.Event{}, User: user}}
+			}
+
+			service := PlanningService{
+				userRepository:            &cappedUserRepo,
+				taskRepository:            &MockTaskRepository{Tasks: []*Task{}},
+				calendarRepositoryManager: &calendarRepositoryManager,
+				logger:                    log,
+				locker:                    locker,
+				taskTextRenderer:          &TaskTextRenderer{},
+			}
+
+			task, err := service.ScheduleTask(context.TODO(), &Task{
+				UserID:          cappedUser.ID,
+				Name:            "Capped",
+				Tags:            tt.tags,
+				Collaborators:   collaborators,
+				WorkloadOverall: time.Hour * 8,
+				DueAt: calendar.Event{
+					Date: date.Timespan{
+						Start: time.Date(2021, 1, 2, 18, 0, 0, 0, location),
+						End:   time.Date(2021, 1, 2, 18, 15, 0, 0, location),
+					},
+				},
+			}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if task.NotScheduled != tt.wantNotScheduled {
+				t.Errorf("not scheduled is %s, want %s", task.NotScheduled, tt.wantNotScheduled)
+			}
+
+			perDay := make(map[int]time.Duration)
+			var maxPerDay time.Duration
+			for _, unit := range task.WorkUnits {
+				day := unit.ScheduledAt.Date.Start.In(location).YearDay()
+				perDay[day] += unit.Workload
+				if perDay[day] > maxPerDay {
+					maxPerDay = perDay[day]
+				}
+			}
+
+			if maxPerDay > tt.wantMaxPerDay {
+				t.Errorf("scheduled %s on a single day, want at most %s", maxPerDay, tt.wantMaxPerDay)
+			}
+		})
+	}
+}
+
+func TestPlanningService_ExplainSchedule(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 1, 1, 12, 0, 0, 0, location) }
+
+	taskRepo := &MockTaskRepository{Tasks: []*Task{}}
+
+	var calendarRepositoryManager = CalendarRepositoryManager{
+		userRepository:  &userRepo,
+		logger:          log,
+		overriddenRepos: make(map[string]calendar.RepositoryInterface),
+	}
+
+	meeting := date.Timespan{
+		Start: time.Date(2021, 1, 1, 13, 0, 0, 0, location),
+		End:   time.Date(2021, 1, 1, 17, 0, 0, 0, location),
+	}
+	calendarRepository := &calendar.MockCalendarRepository{Events: []*calendar.Event{{Date: meeting, Blocking: true}}, User: &primaryUser}
+	calendarRepositoryManager.overriddenRepos[primaryUser.ID.Hex()] = calendarRepository
+
+	service := PlanningService{
+		userRepository:            &userRepo,
+		taskRepository:            taskRepo,
+		calendarRepositoryManager: &calendarRepositoryManager,
+		logger:                    log,
+		locker:                    locker,
+	}
+
+	task := &Task{
+		UserID:          primaryUser.ID,
+		Name:            "Explained",
+		WorkloadOverall: time.Hour * 2,
+		NotScheduled:    time.Hour * 2,
+		DueAt: calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 1, 18, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 1, 18, 15, 0, 0, location),
+			},
+		},
+	}
+	err := taskRepo.Add(context.TODO(), task)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	explanation, err := service.ExplainSchedule(context.TODO(), task)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only 17:15 - 18:00 is left after the meeting and its padding
+	if explanation.NotScheduled != time.Hour+time.Minute*15 {
+		t.Errorf("not scheduled is %s, want 1h15m", explanation.NotScheduled)
+	}
+
+	trace := explanation.Trace
+	if len(trace.Slots) != len(explanation.WorkUnits) {
+		t.Errorf("trace has %d slots for %d work units", len(trace.Slots), len(explanation.WorkUnits))
+	}
+
+	if !trace.Window.End.Equal(task.DueAt.Date.Start) {
+		t.Errorf("traced window ends at %s, want %s", trace.Window.End, task.DueAt.Date.Start)
+	}
+
+	if len(trace.Busy) != 1 || len(trace.Busy[0].Timespans) != 1 || !trace.Busy[0].Timespans[0].Start.Equal(meeting.Start) {
+		t.Errorf("traced busy time is %+v, want the meeting", trace.Busy)
+	}
+
+	if len(trace.Candidates) == 0 {
+		t.Error("trace has no candidates")
+	}
+
+	hasNotAllowed := false
+	for _, rejection := range trace.Rejections {
+		if rejection.Reason == date.TraceRejectionNotAllowed && rejection.Timespan.Start.Equal(time.Date(2021, 1, 1, 12, 15, 0, 0, location)) {
+			hasNotAllowed = true
+		}
+	}
+
+	if !hasNotAllowed {
+		t.Errorf("trace doesn't reject the lunch break, rejections: %+v", trace.Rejections)
+	}
+
+	if len(calendarRepository.Events) != 1 || len(taskRepo.Tasks[0].WorkUnits) != 0 {
+		t.Error("explaining the schedule changed the calendar or the task")
+	}
+}
+
+func TestPlanningService_ScheduleTask_CollaboratorConstraints(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 1, 1, 12, 0, 0, 0, location) }
+
+	newYork, _ := time.LoadLocation("America/New_York")
+
+	tests := []struct {
+		name                string
+		timeZone            string
+		allowedTimespans    []date.Timespan
+		minWorkUnitDuration time.Duration
+		maxWorkUnitDuration time.Duration
+		wantConflict        bool
+	}{
+		{
+			// 9 to 17 in New York are 15 to 23 in Berlin, so only 15 to 18 are left
+			name:                "Collaborator in another time zone",
+			timeZone:            "America/New_York",
+			allowedTimespans:    []date.Timespan{{Start: time.Date(0, 0, 0, 9, 0, 0, 0, newYork), End: time.Date(0, 0, 0, 17, 0, 0, 0, newYork)}},
+			maxWorkUnitDuration: time.Hour * 2,
+		},
+		{
+			name:             "Allowed timespans don't overlap",
+			timeZone:         "Europe/Berlin",
+			allowedTimespans: []date.Timespan{{Start: time.Date(0, 0, 0, 19, 0, 0, 0, location), End: time.Date(0, 0, 0, 22, 0, 0, 0, location)}},
+			wantConflict:     true,
+		},
+		{
+			name:                "Minimum work unit duration above the maximum of the owner",
+			timeZone:            "Europe/Berlin",
+			minWorkUnitDuration: time.Hour * 7,
+			wantConflict:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collaborator := users.User{
+				ID:        primitive.NewObjectID(),
+				Firstname: "Jane",
+				Lastname:  "Doe",
+				Settings: users.UserSettings{
+					Scheduling: users.SchedulingSettings{
+						TimeZone:            tt.timeZone,
+						AllowedTimespans:    tt.allowedTimespans,
+						MinWorkUnitDuration: tt.minWorkUnitDuration,
+						MaxWorkUnitDuration: tt.maxWorkUnitDuration,
+					},
+				},
+			}
+
+			owner := primaryUser
+			owner.ID = primitive.NewObjectID()
+			owner.Contacts = []users.Contact{{UserID: collaborator.ID}}
+
+			collaboratorUserRepo := users.MockUserRepository{Users: []*users.User{&owner, &collaborator}}
+
+			var calendarRepositoryManager = CalendarRepositoryManager{
+				userRepository:  &collaboratorUserRepo,
+				logger:          log,
+				overriddenRepos: make(map[string]calendar.RepositoryInterface),
+			}
+
+			calendarRepositoryManager.overriddenRepos[owner.ID.Hex()] = &taskCalendarWithoutBusy{&calendar.MockCalendarRepository{Events: []*calendar.Event{}, User: &owner}}
+			calendarRepositoryManager.overriddenRepos[collaborator.ID.Hex()] = &taskCalendarWithoutBusy{&calendar.MockCalendarRepository{Events: []*calendar.Event{}, User: &collaborator}}
+
+			service := PlanningService{
+				userRepository:            &collaboratorUserRepo,
+				taskRepository:            &MockTaskRepository{Tasks: []*Task{}},
+				calendarRepositoryManager: &calendarRepositoryManager,
+				logger:                    log,
+				locker:                    locker,
+				taskTextRenderer:          &TaskTextRenderer{},
+			}
+
+			task, err := service.ScheduleTask(context.TODO(), &Task{
+				UserID:          owner.ID,
+				Name:            "Shared",
+				WorkloadOverall: time.Hour * 4,
+				Collaborators:   Collaborators{{UserID: collaborator.ID}},
+				DueAt: calendar.Event{
+					Date: date.Timespan{
+						Start: time.Date(2021, 1, 4, 18, 0, 0, 0, location),
+						End:   time.Date(2021, 1, 4, 18, 15, 0, 0, location),
+					},
+				},
+			}, false)
+
+			var conflict *ConstraintConflictError
+			if tt.wantConflict {
+				if !errors.As(err, &conflict) {
+					t.Fatalf("error is %v, want a constraint conflict", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if task.NotScheduled != 0 {
+				t.Errorf("not scheduled is %s, want 0", task.NotScheduled)
+			}
+
+			for _, unit := range task.WorkUnits {
+				start := unit.ScheduledAt.Date.Start.In(location)
+				end := unit.ScheduledAt.Date.End.In(location)
+
+				if start.Hour() < 15 || end.Hour() > 18 || (end.Hour() == 18 && end.Minute() > 0) {
+					t.Errorf("work unit %s is outside of the shared allowed timespans", unit.ScheduledAt.Date)
+				}
+
+				if unit.Workload > tt.maxWorkUnitDuration {
+					t.Errorf("work unit is %s long, want at most %s", unit.Workload, tt.maxWorkUnitDuration)
+				}
+			}
+		})
+	}
+}
+
+func TestPlanningService_ScheduleTask_DeadlineBuffer(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 1, 1, 12, 0, 0, 0, location) }
+
+	noBuffer := time.Duration(0)
+	taskBuffer := time.Hour * 48
+	dueAt := time.Date(2021, 1, 4, 18, 0, 0, 0, location)
+	bufferStart := dueAt.Add(time.Hour * -48)
+
+	tests := []struct {
+		name             string
+		userBuffer       time.Duration
+		taskBuffer       *time.Duration
+		workload         time.Duration
+		wantWorkInBuffer bool
+		wantNotScheduled time.Duration
+	}{
+		{
+			name:       "Work fits before the buffer",
+			userBuffer: time.Hour * 48,
+			workload:   time.Hour * 4,
+		},
+		{
+			name:       "Buffer of the task",
+			taskBuffer: &taskBuffer,
+			workload:   time.Hour * 12,
+		},
+		{
+			name:             "Task overrides the buffer of the user",
+			userBuffer:       time.Hour * 48,
+			taskBuffer:       &noBuffer,
+			workload:         time.Hour * 16,
+			wantWorkInBuffer: true,
+		},
+		{
+			// The 1st and 2nd only have about 13 hours of free time
+			name:             "Work falls back to the buffer",
+			userBuffer:       time.Hour * 48,
+			workload:         time.Hour * 16,
+			wantWorkInBuffer: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bufferedUser := primaryUser
+			bufferedUser.ID = primitive.NewObjectID()
+			bufferedUser.Settings.Scheduling.DeadlineBuffer = tt.userBuffer
+
+			bufferedUserRepo := users.MockUserRepository{Users: []*users.User{&bufferedUser}}
+
+			var calendarRepositoryManager = CalendarRepositoryManager{
+				userRepository:  &bufferedUserRepo,
+				logger:          log,
+				overriddenRepos: make(map[string]calendar.RepositoryInterface),
+			}
+
+			calendarRepository := &calendar.MockCalendarRepository{Events: []*calendar.Event{}, User: &bufferedUser}
+			calendarRepositoryManager.overriddenRepos[bufferedUser.ID.Hex()] = &taskCalendarWithoutBusy{calendarRepository}
+
+			service := PlanningService{
+				userRepository:            &bufferedUserRepo,
+				taskRepository:            &MockTaskRepository{Tasks: []*Task{}},
+				calendarRepositoryManager: &calendarRepositoryManager,
+				logger:                    log,
+				locker:                    locker,
+				taskTextRenderer:          &TaskTextRenderer{},
+			}
+
+			task, err := service.ScheduleTask(context.TODO(), &Task{
+				UserID:          bufferedUser.ID,
+				Name:            "Buffered",
+				WorkloadOverall: tt.workload,
+				DeadlineBuffer:  tt.taskBuffer,
+				DueAt: calendar.Event{
+					Date: date.Timespan{
+						Start: dueAt,
+						End:   dueAt.Add(time.Minute * 15),
+					},
+				},
+			}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if task.NotScheduled != tt.wantNotScheduled {
+				t.Errorf("not scheduled is %s, want %s", task.NotScheduled, tt.wantNotScheduled)
+			}
+
+			workInBuffer := false
+			for _, unit := range task.WorkUnits {
+				if unit.ScheduledAt.Date.End.After(bufferStart) {
+					workInBuffer = true
+				}
+
+				if unit.ScheduledAt.Date.End.After(dueAt) {
+					t.Errorf("work unit %s is after the deadline", unit.ScheduledAt.Date)
+				}
+			}
+
+			if workInBuffer != tt.wantWorkInBuffer {
+				t.Errorf("work in buffer is %t, want %t", workInBuffer, tt.wantWorkInBuffer)
+			}
+		})
+	}
+}
+
+func TestPlanningService_StartAfter(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 1, 1, 12, 0, 0, 0, location) }
+
+	startAfter := time.Date(2021, 1, 3, 0, 0, 0, 0, location)
+
+	var calendarRepositoryManager = CalendarRepositoryManager{
+		userRepository:  &userRepo,
+		logger:          log,
+		overriddenRepos: make(map[string]calendar.RepositoryInterface),
+	}
+
+	calendarRepository := &calendar.MockCalendarRepository{Events: []*calendar.Event{}, User: &primaryUser}
+	calendarRepositoryManager.overriddenRepos[primaryUser.ID.Hex()] = &taskCalendarWithoutBusy{calendarRepository}
+
+	taskRepo := &MockTaskRepository{Tasks: []*Task{}}
+
+	service := PlanningService{
+		userRepository:            &userRepo,
+		taskRepository:            taskRepo,
+		calendarRepositoryManager: &calendarRepositoryManager,
+		logger:                    log,
+		locker:                    locker,
+		taskTextRenderer:          &TaskTextRenderer{},
+	}
+
+	task := &Task{
+		UserID:          primaryUser.ID,
+		Name:            "Materials arrive",
+		WorkloadOverall: time.Hour * 4,
+		DueAt: calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 5, 18, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 5, 18, 15, 0, 0, location),
+			},
+		},
+	}
+
+	err := taskRepo.Add(context.TODO(), task)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task, err = service.ScheduleTask(context.TODO(), task, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scheduledBefore := false
+	for _, unit := range task.WorkUnits {
+		if unit.ScheduledAt.Date.Start.Before(startAfter) {
+			scheduledBefore = true
+		}
+	}
+
+	if !scheduledBefore {
+		t.Fatal("expected work to be scheduled before the earliest start is set")
+	}
+
+	task.StartAfter = &startAfter
+
+	task, err = service.StartAfterChanged(context.TODO(), task)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if task.NotScheduled != 0 {
+		t.Errorf("not scheduled is %s, want 0", task.NotScheduled)
+	}
+
+	var workload time.Duration
+	for _, unit := range task.WorkUnits {
+		workload += unit.Workload
+
+		if unit.ScheduledAt.Date.Start.Before(startAfter) {
+			t.Errorf("work unit %s starts before %s", unit.ScheduledAt.Date, startAfter)
+		}
+	}
+
+	if workload != task.WorkloadOverall {
+		t.Errorf("scheduled workload is %s, want %s", workload, task.WorkloadOverall)
+	}
+}
+
+func TestPlanningService_PinnedWorkUnits(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 1, 1, 12, 0, 0, 0, location) }
+
+	taskRepo := &MockTaskRepository{Tasks: []*Task{}}
+
+	var calendarRepositoryManager = CalendarRepositoryManager{
+		userRepository:  &userRepo,
+		logger:          log,
+		overriddenRepos: make(map[string]calendar.RepositoryInterface),
+	}
+
+	calendarRepository := &calendar.MockCalendarRepository{Events: []*calendar.Event{}, User: &primaryUser}
+	calendarRepositoryManager.overriddenRepos[primaryUser.ID.Hex()] = &taskCalendarWithoutBusy{calendarRepository}
+
+	service := PlanningService{
+		userRepository:            &userRepo,
+		taskRepository:            taskRepo,
+		calendarRepositoryManager: &calendarRepositoryManager,
+		logger:                    log,
+		locker:                    locker,
+		taskTextRenderer:          &TaskTextRenderer{},
+	}
+
+	// The pinned work unit takes the only free time before the deadline of the urgent task
+	pinnedUnit := WorkUnit{
+		IsPinned: true,
+		ScheduledAt: calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 1, 13, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 1, 15, 0, 0, 0, location),
+			},
+			Blocking: true,
+		},
+		Workload: time.Hour * 2,
+	}
+	followingUnit := WorkUnit{
+		ScheduledAt: calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 1, 15, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 1, 18, 0, 0, 0, location),
+			},
+			Blocking: true,
+		},
+		Workload: time.Hour * 3,
+	}
+
+	for _, unit := range []*WorkUnit{&pinnedUnit, &followingUnit} {
+		_, err := calendarRepository.NewEvent(&unit.ScheduledAt, "", "Pinned", "", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pinnedTask := &Task{
+		UserID:          primaryUser.ID,
+		Name:            "Pinned",
+		WorkloadOverall: time.Hour * 5,
+		WorkUnits:       WorkUnits{pinnedUnit, followingUnit},
+		DueAt: calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 15, 18, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 15, 18, 15, 0, 0, location),
+			},
+		},
+	}
+	urgentTask := &Task{
+		UserID:          primaryUser.ID,
+		Name:            "Urgent",
+		WorkloadOverall: time.Hour * 5,
+		NotScheduled:    time.Hour * 5,
+		DueAt: calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 1, 18, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 1, 18, 15, 0, 0, location),
+			},
+		},
+	}
+
+	for _, task := range []*Task{pinnedTask, urgentTask} {
+		err := taskRepo.Add(context.TODO(), task)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	pinnedUnitID := pinnedTask.WorkUnits[0].ID
+
+	merged := service.CheckForMergingWorkUnits(context.TODO(), pinnedTask)
+	if len(merged.WorkUnits) != 2 {
+		t.Fatalf("pinned work unit was merged: %+v", merged.WorkUnits)
+	}
+
+	_, err := service.RebalanceTasks(context.TODO(), primaryUser.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pinned, _ := taskRepo.FindByID(context.TODO(), pinnedTask.ID.Hex(), primaryUser.ID.Hex(), false)
+	_, unit := pinned.WorkUnits.FindByID(pinnedUnitID.Hex())
+	if unit == nil || unit.ScheduledAt.Date != pinnedUnit.ScheduledAt.Date {
+		t.Errorf("pinned work unit was moved: %+v", pinned.WorkUnits)
+	}
+
+	// Only the unpinned work unit could make room for the urgent task, minus the padding to the pinned work unit
+	wantNotScheduled := time.Hour*2 + time.Minute*15
+
+	urgent, _ := taskRepo.FindByID(context.TODO(), urgentTask.ID.Hex(), primaryUser.ID.Hex(), false)
+	if urgent.NotScheduled != wantNotScheduled {
+		t.Errorf("urgent task has %s not scheduled, want %s", urgent.NotScheduled, wantNotScheduled)
+	}
+
+	for _, urgentUnit := range urgent.WorkUnits {
+		if urgentUnit.ScheduledAt.Date.IntersectsWith(pinnedUnit.ScheduledAt.Date) {
+			t.Errorf("urgent work unit %s intersects with the pinned work unit", urgentUnit.ScheduledAt.Date)
+		}
+	}
+}
+
+func TestPlanningService_SweepMissedWorkUnits(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 1, 1, 12, 0, 0, 0, location) }
+
+	tests := []struct {
+		name            string
+		action          string
+		isPinned        bool
+		wantResult      MissedWorkUnitsResult
+		wantDone        bool
+		wantMissed      bool
+		wantRescheduled bool
+	}{
+		{
+			name:       "Ask by default",
+			wantResult: MissedWorkUnitsResult{Tasks: 1, Asked: 1},
+			wantMissed: true,
+		},
+		{
+			name:            "Reschedule",
+			action:          users.MissedWorkUnitActionReschedule,
+			wantResult:      MissedWorkUnitsResult{Tasks: 1, Rescheduled: 1},
+			wantRescheduled: true,
+		},
+		{
+			name:       "Mark as done",
+			action:     users.MissedWorkUnitActionMarkDone,
+			wantResult: MissedWorkUnitsResult{Tasks: 1, MarkedDone: 1},
+			wantDone:   true,
+		},
+		{
+			name:       "Pinned work units are never rescheduled",
+			action:     users.MissedWorkUnitActionReschedule,
+			isPinned:   true,
+			wantResult: MissedWorkUnitsResult{Tasks: 1, Asked: 1},
+			wantMissed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sweepingUser := primaryUser
+			sweepingUser.ID = primitive.NewObjectID()
+			sweepingUser.Settings.Scheduling.MissedWorkUnitAction = tt.action
+
+			sweepingUserRepo := users.MockUserRepository{Users: []*users.User{&sweepingUser}}
+
+			var calendarRepositoryManager = CalendarRepositoryManager{
+				userRepository:  &sweepingUserRepo,
+				logger:          log,
+				overriddenRepos: make(map[string]calendar.RepositoryInterface),
+			}
+
+			calendarRepository := &calendar.MockCalendarRepository{Events: []*calendar.Event{}, User: &sweepingUser}
+			calendarRepositoryManager.overriddenRepos[sweepingUser.ID.Hex()] = &taskCalendarWithoutBusy{calendarRepository}
+
+			taskRepo := &MockTaskRepository{Tasks: []*Task{}}
+
+			service := PlanningService{
+				userRepository:            &sweepingUserRepo,
+				taskRepository:            taskRepo,
+				calendarRepositoryManager: &calendarRepositoryManager,
+				logger:                    log,
+				locker:                    locker,
+				taskTextRenderer:          &TaskTextRenderer{},
+			}
+
+			missedUnit := WorkUnit{
+				IsPinned: tt.isPinned,
+				ScheduledAt: calendar.Event{
+					Date: date.Timespan{
+						Start: time.Date(2021, 1, 1, 9, 0, 0, 0, location),
+						End:   time.Date(2021, 1, 1, 11, 0, 0, 0, location),
+					},
+					Blocking: true,
+				},
+				Workload: time.Hour * 2,
+			}
+			_, err := calendarRepository.NewEvent(&missedUnit.ScheduledAt, "", "Missed", "", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			task := &Task{
+				UserID:          sweepingUser.ID,
+				Name:            "Missed",
+				WorkloadOverall: time.Hour * 2,
+				WorkUnits:       WorkUnits{missedUnit},
+				DueAt: calendar.Event{
+					Date: date.Timespan{
+						Start: time.Date(2021, 1, 5, 18, 0, 0, 0, location),
+						End:   time.Date(2021, 1, 5, 18, 15, 0, 0, location),
+					},
+				},
+			}
+
+			_, err = calendarRepository.NewEvent(&task.DueAt, "", "Missed", "", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = taskRepo.Add(context.TODO(), task)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := service.SweepMissedWorkUnits(context.TODO())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *result != tt.wantResult {
+				t.Errorf("sweep result is %+v, want %+v", *result, tt.wantResult)
+			}
+
+			swept, _ := taskRepo.FindByID(context.TODO(), task.ID.Hex(), sweepingUser.ID.Hex(), false)
+			if len(swept.WorkUnits) != 1 {
+				t.Fatalf("task has %d work units, want 1", len(swept.WorkUnits))
+			}
+
+			unit := swept.WorkUnits[0]
+			if unit.IsDone != tt.wantDone || swept.IsDone != tt.wantDone {
+				t.Errorf("work unit done is %t and task done is %t, want %t", unit.IsDone, swept.IsDone, tt.wantDone)
+			}
+
+			if unit.IsMissed != tt.wantMissed {
+				t.Errorf("work unit missed is %t, want %t", unit.IsMissed, tt.wantMissed)
+			}
+
+			if rescheduled := unit.ScheduledAt.Date.Start.After(now()); rescheduled != tt.wantRescheduled {
+				t.Errorf("work unit rescheduled is %t, want %t", rescheduled, tt.wantRescheduled)
+			}
+
+			// Handled work units aren't swept again
+			result, err = service.SweepMissedWorkUnits(context.TODO())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *result != (MissedWorkUnitsResult{}) {
+				t.Errorf("second sweep result is %+v, want nothing", *result)
+			}
+		})
+	}
+}
+
+func TestPlanningService_ForecastCapacity(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 1, 1, 12, 0, 0, 0, location) }
+
+	var calendarRepositoryManager = CalendarRepositoryManager{
+		userRepository:  &userRepo,
+		logger:          log,
+		overriddenRepos: make(map[string]calendar.RepositoryInterface),
+	}
+
+	calendarRepositoryManager.overriddenRepos[primaryUser.ID.Hex()] = &calendar.MockCalendarRepository{
+		Events: []*calendar.Event{
+			{
+				Date: date.Timespan{
+					Start: time.Date(2021, 1, 4, 9, 0, 0, 0, location),
+					End:   time.Date(2021, 1, 4, 10, 0, 0, 0, location),
+				},
+			},
+		},
+		User: &primaryUser,
+	}
+
+	taskRepo := &MockTaskRepository{Tasks: []*Task{
+		{
+			UserID: primaryUser.ID,
+			DueAt:  calendar.Event{Date: date.Timespan{Start: time.Date(2021, 1, 5, 18, 0, 0, 0, location)}},
+			WorkUnits: WorkUnits{
+				{
+					ID:       primitive.NewObjectID(),
+					Workload: time.Hour * 2,
+					ScheduledAt: calendar.Event{Date: date.Timespan{
+						Start: time.Date(2021, 1, 5, 9, 0, 0, 0, location),
+						End:   time.Date(2021, 1, 5, 11, 0, 0, 0, location),
+					}},
+				},
+			},
+		},
+		{
+			UserID:       primaryUser.ID,
+			DueAt:        calendar.Event{Date: date.Timespan{Start: time.Date(2021, 1, 5, 17, 0, 0, 0, location)}},
+			NotScheduled: time.Hour * 14,
+		},
+	}}
+
+	service := PlanningService{
+		userRepository:            &userRepo,
+		taskRepository:            taskRepo,
+		calendarRepositoryManager: &calendarRepositoryManager,
+		logger:                    log,
+		locker:                    locker,
+	}
+
+	from := time.Date(2021, 1, 4, 0, 0, 0, 0, location)
+	to := time.Date(2021, 1, 6, 0, 0, 0, 0, location)
+
+	capacity, err := service.ForecastCapacity(context.TODO(), &primaryUser, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedDays := []CapacityPeriod{
+		{
+			Start:   from,
+			End:     time.Date(2021, 1, 5, 0, 0, 0, 0, location),
+			Allowed: time.Hour * 8,
+			Busy:    time.Hour,
+			Free:    time.Hour*6 + time.Minute*45,
+		},
+		{
+			Start:     time.Date(2021, 1, 5, 0, 0, 0, 0, location),
+			End:       to,
+			Allowed:   time.Hour * 8,
+			Scheduled: time.Hour * 2,
+			Free:      time.Hour*5 + time.Minute*45,
+		},
+	}
+
+	if len(capacity.Days) != len(expectedDays) {
+		t.Fatalf("got %d days, want %d", len(capacity.Days), len(expectedDays))
+	}
+
+	for i, day := range capacity.Days {
+		expected := expectedDays[i]
+		if !day.Start.Equal(expected.Start) || !day.End.Equal(expected.End) || day.Allowed != expected.Allowed ||
+			day.Busy != expected.Busy || day.Scheduled != expected.Scheduled || day.Free != expected.Free {
+			t.Errorf("day %d is %+v, want %+v", i, day, expected)
+		}
+	}
+
+	if len(capacity.Weeks) != 1 {
+		t.Fatalf("got %d weeks, want 1", len(capacity.Weeks))
+	}
+
+	week := capacity.Weeks[0]
+	if week.Allowed != time.Hour*16 || week.Busy != time.Hour || week.Scheduled != time.Hour*2 || week.Free != time.Hour*12+time.Minute*30 {
+		t.Errorf("week is %+v", week)
+	}
+
+	if len(capacity.Warnings) != 1 {
+		t.Fatalf("got %d warnings, want 1", len(capacity.Warnings))
+	}
+
+	if capacity.Warnings[0].Committed != time.Hour*14 || capacity.Warnings[0].Free != week.Free {
+		t.Errorf("warning is %+v", capacity.Warnings[0])
+	}
+}
+
+func TestPlanningService_CheckFeasibility(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 1, 1, 12, 0, 0, 0, location) }
+
+	var calendarRepositoryManager = CalendarRepositoryManager{
+		userRepository:  &userRepo,
+		logger:          log,
+		overriddenRepos: make(map[string]calendar.RepositoryInterface),
+	}
+
+	calendarRepositoryManager.overriddenRepos[primaryUser.ID.Hex()] = &calendar.MockCalendarRepository{Events: []*calendar.Event{}, User: &primaryUser}
+
+	// Backlog tasks make room, so their work units don't take away free time
+	backlogTask := &Task{
+		ID:              primitive.NewObjectID(),
+		UserID:          primaryUser.ID,
+		WorkloadOverall: time.Hour * 3,
+		IsBacklog:       true,
+		WorkUnits: WorkUnits{
+			{
+				ID:       primitive.NewObjectID(),
+				Workload: time.Hour * 3,
+				ScheduledAt: calendar.Event{Date: date.Timespan{
+					Start: time.Date(2021, 1, 4, 9, 0, 0, 0, location),
+					End:   time.Date(2021, 1, 4, 12, 0, 0, 0, location),
+				}},
+			},
+		},
+	}
+
+	service := PlanningService{
+		userRepository:            &userRepo,
+		taskRepository:            &MockTaskRepository{Tasks: []*Task{backlogTask}},
+		calendarRepositoryManager: &calendarRepositoryManager,
+		logger:                    log,
+		locker:                    locker,
+	}
+
+	dueAt := time.Date(2021, 1, 4, 18, 0, 0, 0, location)
+	laterDueAt := time.Date(2021, 1, 5, 16, 0, 0, 0, location)
+	reducedWorkload := time.Hour * 29
+
+	tests := []struct {
+		name     string
+		workload time.Duration
+		want     Feasibility
+	}{
+		{
+			name:     "Fits before the due date",
+			workload: time.Hour * 10,
+			want:     Feasibility{Feasible: true, Workload: time.Hour * 10, Free: time.Hour * 29, Suggestions: []FeasibilitySuggestion{}},
+		},
+		{
+			name:     "Too much workload",
+			workload: time.Hour * 35,
+			want: Feasibility{
+				Feasible:  false,
+				Workload:  time.Hour * 35,
+				Free:      time.Hour * 29,
+				Shortfall: time.Hour * 6,
+				Suggestions: []FeasibilitySuggestion{
+					{DueAt: &laterDueAt},
+					{WorkloadOverall: &reducedWorkload},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &Task{
+				UserID:                primaryUser.ID,
+				WorkloadOverall:       tt.workload,
+				DueAt:                 calendar.Event{Date: date.Timespan{Start: dueAt, End: dueAt.Add(time.Minute * 15)}},
+				IsBlockedPastDeadline: true,
+			}
+
+			feasibility, err := service.CheckFeasibility(context.TODO(), task)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The window of the check reaches past the due date, which says nothing about the task being blocked
+			if !task.IsBlockedPastDeadline {
+				t.Error("checking the feasibility changed if the task is blocked past its deadline")
+			}
+
+			if !reflect.DeepEqual(*feasibility, tt.want) {
+				t.Errorf("feasibility is %+v, want %+v", *feasibility, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanningService_BacklogTasks(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 1, 1, 12, 0, 0, 0, location) }
+
+	var calendarRepositoryManager = CalendarRepositoryManager{
+		userRepository:  &userRepo,
+		logger:          log,
+		overriddenRepos: make(map[string]calendar.RepositoryInterface),
+	}
+
+	calendarRepository := &calendar.MockCalendarRepository{Events: []*calendar.Event{}, User: &primaryUser}
+	calendarRepositoryManager.overriddenRepos[primaryUser.ID.Hex()] = &taskCalendarWithoutBusy{calendarRepository}
+
+	taskRepo := &MockTaskRepository{Tasks: []*Task{}}
+
+	service := PlanningService{
+		userRepository:            &userRepo,
+		taskRepository:            taskRepo,
+		calendarRepositoryManager: &calendarRepositoryManager,
+		logger:                    log,
+		locker:                    locker,
+		taskTextRenderer:          &TaskTextRenderer{},
+	}
+
+	backlogTask := &Task{
+		UserID:          primaryUser.ID,
+		Name:            "Sort the photos",
+		WorkloadOverall: time.Hour * 8,
+		IsBacklog:       true,
+	}
+
+	err := taskRepo.Add(context.TODO(), backlogTask)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backlogTask, err = service.ScheduleTask(context.TODO(), backlogTask, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if backlogTask.NotScheduled != 0 {
+		t.Errorf("not scheduled of the backlog task is %s, want 0", backlogTask.NotScheduled)
+	}
+
+	if !backlogTask.DueAt.CalendarEvents.IsEmpty() {
+		t.Error("expected no due event for the backlog task")
+	}
+
+	horizon := now().Add(backlogHorizon)
+	for _, unit := range backlogTask.WorkUnits {
+		if unit.ScheduledAt.Date.End.After(horizon) {
+			t.Errorf("work unit %s of the backlog task is after the horizon %s", unit.ScheduledAt.Date, horizon)
+		}
+	}
+
+	// The backlog task took all time before the deadline
+	deadlineTask := &Task{
+		UserID:          primaryUser.ID,
+		Name:            "Hand in the report",
+		WorkloadOverall: time.Hour * 4,
+		DueAt: calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 2, 12, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 2, 12, 15, 0, 0, location),
+			},
+		},
+	}
+
+	err = taskRepo.Add(context.TODO(), deadlineTask)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The preview makes room the same way scheduling does
+	previewedTask := *deadlineTask
+	_, err = service.PreviewTask(context.TODO(), &previewedTask)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if previewedTask.NotScheduled != 0 {
+		t.Errorf("not scheduled of the previewed deadline task is %s, want 0", previewedTask.NotScheduled)
+	}
+
+	deadlineTask, err = service.ScheduleTask(context.TODO(), deadlineTask, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if deadlineTask.NotScheduled != 0 {
+		t.Errorf("not scheduled of the deadline task is %s, want 0", deadlineTask.NotScheduled)
+	}
+
+	backlogTask, err = taskRepo.FindByID(context.TODO(), backlogTask.ID.Hex(), primaryUser.ID.Hex(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workload := backlogTask.NotScheduled
+	for _, backlogUnit := range backlogTask.WorkUnits {
+		workload += backlogUnit.Workload
+
+		for _, unit := range deadlineTask.WorkUnits {
+			if backlogUnit.ScheduledAt.Date.IntersectsWith(unit.ScheduledAt.Date) {
+				t.Errorf("backlog work unit %s intersects work unit %s", backlogUnit.ScheduledAt.Date, unit.ScheduledAt.Date)
+			}
+		}
+	}
+
+	if workload != backlogTask.WorkloadOverall {
+		t.Errorf("workload of the backlog task is %s, want %s", workload, backlogTask.WorkloadOverall)
+	}
+}
+
+func TestPlanningService_TagScheduling(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 1, 4, 8, 0, 0, 0, location) }
+
+	deepWork := &Tag{
+		ID:     primitive.NewObjectID(),
+		UserID: primaryUser.ID,
+		Value:  "Deep work",
+		Scheduling: &TagScheduling{
+			AllowedTimespans: []date.Timespan{
+				{
+					Start: time.Date(0, 0, 0, 9, 0, 0, 0, location),
+					End:   time.Date(0, 0, 0, 12, 0, 0, 0, location),
+				},
+			},
+			MinWorkUnitDuration: time.Hour * 2,
+			MaxWorkUnitDuration: time.Hour * 3,
+			Priority:            1,
+		},
+	}
+
+	admin := &Tag{
+		ID:     primitive.NewObjectID(),
+		UserID: primaryUser.ID,
+		Value:  "Admin",
+		Scheduling: &TagScheduling{
+			AllowedTimespans: []date.Timespan{
+				{
+					Start: time.Date(0, 0, 0, 13, 0, 0, 0, location),
+					End:   time.Date(0, 0, 0, 18, 0, 0, 0, location),
+				},
+			},
+			TimingPreference:    users.TimingPreferenceVeryEarly,
+			MaxWorkUnitDuration: time.Hour,
+		},
+	}
+
+	tests := []struct {
+		name        string
+		tags        []primitive.ObjectID
+		startHour   int
+		endHour     int
+		minDuration time.Duration
+		maxDuration time.Duration
+	}{
+		{name: "Deep work in the morning", tags: []primitive.ObjectID{deepWork.ID}, startHour: 9, endHour: 12, minDuration: time.Hour * 2, maxDuration: time.Hour * 3},
+		{name: "Admin in the afternoon", tags: []primitive.ObjectID{admin.ID}, startHour: 13, endHour: 18, maxDuration: time.Hour},
+		{name: "Higher priority wins", tags: []primitive.ObjectID{admin.ID, deepWork.ID}, startHour: 9, endHour: 12, minDuration: time.Hour * 2, maxDuration: time.Hour * 3},
+		{name: "Without rules", tags: []primitive.ObjectID{primitive.NewObjectID()}, startHour: 9, endHour: 18, maxDuration: time.Hour * 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calendarRepositoryManager = CalendarRepositoryManager{
+				userRepository:  &userRepo,
+				logger:          log,
+				overriddenRepos: make(map[string]calendar.RepositoryInterface),
+			}
+
+			calendarRepository := &calendar.MockCalendarRepository{Events: []*calendar.Event{}, User: &primaryUser}
+			calendarRepositoryManager.overriddenRepos[primaryUser.ID.Hex()] = &taskCalendarWithoutBusy{calendarRepository}
+
+			taskRepo := &MockTaskRepository{Tasks: []*Task{}}
+
+			service := PlanningService{
+				userRepository:            &userRepo,
+				taskRepository:            taskRepo,
+				tagRepository:             &MockTagRepository{Tags: []*Tag{deepWork, admin}},
+				calendarRepositoryManager: &calendarRepositoryManager,
+				logger:                    log,
+				locker:                    locker,
+				taskTextRenderer:          &TaskTextRenderer{},
+			}
+
+			task := &Task{
+				UserID:          primaryUser.ID,
+				Name:            tt.name,
+				WorkloadOverall: time.Hour * 8,
+				Tags:            tt.tags,
+				DueAt: calendar.Event{
+					Date: date.Timespan{
+						Start: time.Date(2021, 1, 8, 18, 0, 0, 0, location),
+						End:   time.Date(2021, 1, 8, 18, 15, 0, 0, location),
+					},
+				},
+			}
+
+			err := taskRepo.Add(context.TODO(), task)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			task, err = service.ScheduleTask(context.TODO(), task, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if task.NotScheduled != 0 {
+				t.Errorf("not scheduled is %s, want 0", task.NotScheduled)
+			}
+
+			for _, unit := range task.WorkUnits {
+				start := unit.ScheduledAt.Date.Start.In(location)
+				end := unit.ScheduledAt.Date.End.In(location)
+				dayStart := time.Date(start.Year(), start.Month(), start.Day(), tt.startHour, 0, 0, 0, location)
+				dayEnd := time.Date(start.Year(), start.Month(), start.Day(), tt.endHour, 0, 0, 0, location)
+
+				if start.Before(dayStart) || end.After(dayEnd) {
+					t.Errorf("work unit %s is outside of %d to %d", unit.ScheduledAt.Date, tt.startHour, tt.endHour)
+				}
+
+				if unit.Workload < tt.minDuration || unit.Workload > tt.maxDuration {
+					t.Errorf("work unit %s takes %s, want between %s and %s", unit.ScheduledAt.Date, unit.Workload, tt.minDuration, tt.maxDuration)
+				}
+			}
+		})
+	}
+}
+
+func TestPlanningService_ChunkedWorkUnits(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 1, 4, 8, 0, 0, 0, location) }
+
+	chunkingUser := primaryUser
+	chunkingUser.ID = primitive.NewObjectID()
+	chunkingUser.Settings.Scheduling.ChunkDuration = time.Minute * 25
+	chunkingUser.Settings.Scheduling.BreakDuration = time.Minute * 5
+	chunkingUser.Settings.Scheduling.MaxWorkUnitDuration = time.Hour
+
+	chunkingUserRepo := users.MockUserRepository{Users: []*users.User{&chunkingUser}}
+
+	var calendarRepositoryManager = CalendarRepositoryManager{
+		userRepository:  &chunkingUserRepo,
+		logger:          log,
+		overriddenRepos: make(map[string]calendar.RepositoryInterface),
+	}
+
+	calendarRepository := &calendar.MockCalendarRepository{Events: []*calendar.Event{}, User: &chunkingUser}
+	calendarRepositoryManager.overriddenRepos[chunkingUser.ID.Hex()] = &taskCalendarWithoutBusy{calendarRepository}
+
+	taskRepo := &MockTaskRepository{Tasks: []*Task{}}
+
+	service := PlanningService{
+		userRepository:            &chunkingUserRepo,
+		taskRepository:            taskRepo,
+		calendarRepositoryManager: &calendarRepositoryManager,
+		logger:                    log,
+		locker:                    locker,
+		taskTextRenderer:          &TaskTextRenderer{},
+	}
+
+	task := &Task{
+		UserID:          chunkingUser.ID,
+		Name:            "Write report",
+		WorkloadOverall: time.Hour * 2,
+		DueAt: calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 8, 18, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 8, 18, 15, 0, 0, location),
+			},
+		},
+	}
+
+	err := taskRepo.Add(context.TODO(), task)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task, err = service.ScheduleTask(context.TODO(), task, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if task.NotScheduled != 0 {
+		t.Errorf("not scheduled is %s, want 0", task.NotScheduled)
+	}
+
+	if len(task.WorkUnits) != 5 {
+		t.Fatalf("got %d work units, want 5", len(task.WorkUnits))
+	}
+
+	breaks := 0
+	for index, unit := range task.WorkUnits {
+		if unit.Workload > time.Minute*25 {
+			t.Errorf("work unit %s takes %s, want at most 25m", unit.ScheduledAt.Date, unit.Workload)
+		}
+
+		if unit.Break == nil {
+			continue
+		}
+		breaks++
+
+		if index+1 >= len(task.WorkUnits) {
+			t.Fatalf("last work unit %s has a break", unit.ScheduledAt.Date)
+		}
+
+		next := task.WorkUnits[index+1]
+		if !unit.Break.Date.Start.Equal(unit.ScheduledAt.Date.End) || !unit.Break.Date.End.Equal(next.ScheduledAt.Date.Start) {
+			t.Errorf("break %s doesn't separate %s and %s", unit.Break.Date, unit.ScheduledAt.Date, next.ScheduledAt.Date)
+		}
+
+		if unit.Break.Date.Duration() != time.Minute*5 {
+			t.Errorf("break %s takes %s, want 5m", unit.Break.Date, unit.Break.Date.Duration())
+		}
+
+		if unit.Break.Blocking {
+			t.Errorf("break %s is blocking", unit.Break.Date)
+		}
+
+		if len(unit.Break.CalendarEvents) != 1 {
+			t.Errorf("break %s has %d calendar events, want 1", unit.Break.Date, len(unit.Break.CalendarEvents))
+		}
+	}
+
+	if breaks == 0 {
+		t.Fatal("expected breaks between consecutive chunks")
+	}
+
+	merged := service.CheckForMergingWorkUnits(context.TODO(), task)
+	if len(merged.WorkUnits) != 5 {
+		t.Errorf("got %d work units after merging, want 5", len(merged.WorkUnits))
+	}
+
+	// Rebalancing a plan that doesn't change keeps the break events as they are
+	events := make(map[*calendar.Event]bool)
+	for _, event := range calendarRepository.Events {
+		events[event] = true
+	}
+
+	_, err = service.RebalanceTasks(context.TODO(), chunkingUser.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task, err = taskRepo.FindByID(context.TODO(), task.ID.Hex(), chunkingUser.ID.Hex(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, event := range calendarRepository.Events {
+		if !events[event] {
+			t.Errorf("event %s was written again by the rebalance", event.Date)
+		}
+	}
+
+	if len(calendarRepository.Events) != len(events) {
+		t.Errorf("got %d events after rebalancing, want %d", len(calendarRepository.Events), len(events))
+	}
+
+	err = service.DeleteTask(context.TODO(), task)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(calendarRepository.Events) != 0 {
+		t.Errorf("got %d events after deleting the task, want 0", len(calendarRepository.Events))
+	}
+}
+
+func TestPlanningService_Absences(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 1, 4, 8, 0, 0, 0, location) }
+
+	absentUser := primaryUser
+	absentUser.ID = primitive.NewObjectID()
+	absentUser.Absences = nil
+
+	absentUserRepo := users.MockUserRepository{Users: []*users.User{&absentUser}}
+
+	var calendarRepositoryManager = CalendarRepositoryManager{
+		userRepository:  &absentUserRepo,
+		logger:          log,
+		overriddenRepos: make(map[string]calendar.RepositoryInterface),
+	}
+
+	calendarRepository := &calendar.MockCalendarRepository{Events: []*calendar.Event{}, User: &absentUser}
+	calendarRepositoryManager.overriddenRepos[absentUser.ID.Hex()] = &taskCalendarWithoutBusy{calendarRepository}
+
+	taskRepo := &MockTaskRepository{Tasks: []*Task{}}
+
+	service := PlanningService{
+		userRepository:            &absentUserRepo,
+		taskRepository:            taskRepo,
+		calendarRepositoryManager: &calendarRepositoryManager,
+		logger:                    log,
+		locker:                    locker,
+		taskTextRenderer:          &TaskTextRenderer{},
+	}
+
+	task := &Task{
+		UserID:          absentUser.ID,
+		Name:            "Prepare presentation",
+		WorkloadOverall: time.Hour * 4,
+		DueAt: calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 11, 18, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 11, 18, 15, 0, 0, location),
+			},
+		},
+	}
+
+	dueDuringAbsence := &Task{
+		UserID:          absentUser.ID,
+		Name:            "Hand in report",
+		WorkloadOverall: time.Hour,
+		DueAt: calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 5, 12, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 5, 12, 15, 0, 0, location),
+			},
+		},
+	}
+
+	for _, tsk := range []*Task{task, dueDuringAbsence} {
+		err := taskRepo.Add(context.TODO(), tsk)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	task, err := service.ScheduleTask(context.TODO(), task, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	absence := users.Absence{
+		ID:   primitive.NewObjectID(),
+		Name: "Vacation",
+		Date: date.Timespan{
+			Start: time.Date(2021, 1, 4, 0, 0, 0, 0, location),
+			End:   time.Date(2021, 1, 7, 0, 0, 0, 0, location),
+		},
+	}
+
+	intersecting := 0
+	var pinnedID primitive.ObjectID
+	for index, unit := range task.WorkUnits {
+		if unit.ScheduledAt.Date.IntersectsWith(absence.Date) {
+			intersecting++
+
+			// The absence wins over pins
+			if pinnedID.IsZero() {
+				task.WorkUnits[index].IsPinned = true
+				pinnedID = unit.ID
+			}
+		}
+	}
+
+	if intersecting == 0 {
+		t.Fatal("expected work units during the absence before applying it")
+	}
+
+	err = taskRepo.Update(context.TODO(), task, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	absentUser.Absences = users.Absences{absence}
+
+	err = service.ApplyAbsence(context.TODO(), &absentUser, &absence)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task, err = taskRepo.FindByID(context.TODO(), task.ID.Hex(), absentUser.ID.Hex(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if task.NotScheduled != 0 {
+		t.Errorf("not scheduled is %s after applying the absence, want 0", task.NotScheduled)
+	}
+
+	for _, unit := range task.WorkUnits {
+		if unit.ScheduledAt.Date.IntersectsWith(absence.Date) {
+			t.Errorf("work unit %s is scheduled during the absence %s", unit.ScheduledAt.Date, absence.Date)
+		}
+
+		if unit.ID == pinnedID && unit.IsPinned {
+			t.Errorf("pinned work unit %s moved out of the absence is still pinned", unit.ScheduledAt.Date)
+		}
+	}
+
+	if task.IsDueDuringAbsence {
+		t.Error("task due after the absence is flagged")
+	}
+
+	dueDuringAbsence, err = taskRepo.FindByID(context.TODO(), dueDuringAbsence.ID.Hex(), absentUser.ID.Hex(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !dueDuringAbsence.IsDueDuringAbsence {
+		t.Error("task due during the absence isn't flagged")
+	}
+
+	absentUser.Absences = absentUser.Absences.RemoveByID(absence.ID.Hex())
+
+	err = service.FlagTasksDueDuringAbsences(context.TODO(), &absentUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dueDuringAbsence, err = taskRepo.FindByID(context.TODO(), dueDuringAbsence.ID.Hex(), absentUser.ID.Hex(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dueDuringAbsence.IsDueDuringAbsence {
+		t.Error("task is still flagged after removing the absence")
+	}
+}
+
+// taskCalendarFailingOnTitle is a task calendar that refuses to create events with a specific title
+type taskCalendarFailingOnTitle struct {
+	*calendar.MockCalendarRepository
+	failingTitle string
+}
+
+func (r *taskCalendarFailingOnTitle) NewEvent(event *calendar.Event, taskID string, title string, description string, withReminder bool) (*calendar.Event, error) {
+	if title == r.failingTitle {
+		return nil, errors.New("calendar is not available")
+	}
+
+	return r.MockCalendarRepository.NewEvent(event, taskID, title, description, withReminder)
+}
+
+func TestPlanningService_MigrateNativeCalendarEvents(t *testing.T) {
+	migratingUser := primaryUser
+	migratingUser.ID = primitive.NewObjectID()
+
+	collaborator := secondaryUser
+
+	nativeEvent := func(id string, start time.Time, blocking bool) calendar.Event {
+		return calendar.Event{
+			Date:     date.Timespan{Start: start, End: start.Add(time.Hour)},
+			Blocking: blocking,
+			CalendarEvents: calendar.PersistedEvents{
+				{CalendarEventID: id, CalendarType: calendar.PersistedCalendarTypeNativeCalendar, UserID: migratingUser.ID},
+				{CalendarEventID: id + "-collaborator", CalendarType: calendar.PersistedCalendarTypeGoogleCalendar, UserID: collaborator.ID},
+			},
+		}
+	}
+
+	day := time.Date(2021, 1, 4, 0, 0, 0, 0, location)
+	breakEvent := nativeEvent("break", day.Add(time.Hour*11), false)
+
+	task := &Task{
+		ID:     primitive.NewObjectID(),
+		UserID: migratingUser.ID,
+		Name:   "Write thesis",
+		DueAt:  nativeEvent("due", day.Add(time.Hour*48), false),
+		WorkUnits: WorkUnits{
+			{
+				ID:          primitive.NewObjectID(),
+				ScheduledAt: nativeEvent("unit", day.Add(time.Hour*9), true),
+				Break:       &breakEvent,
+			},
+		},
+	}
+
+	nativeCalendar := &calendar.MockCalendarRepository{User: &migratingUser}
+	for _, event := range []calendar.Event{task.DueAt, task.WorkUnits[0].ScheduledAt, breakEvent} {
+		event := event
+		nativeCalendar.Events = append(nativeCalendar.Events, &event)
+	}
+
+	taskCalendar := &calendar.MockCalendarRepository{Events: []*calendar.Event{}, User: &migratingUser}
+
+	var calendarRepositoryManager = CalendarRepositoryManager{
+		userRepository:        &users.MockUserRepository{Users: []*users.User{&migratingUser}},
+		logger:                log,
+		overriddenRepos:       make(map[string]calendar.RepositoryInterface),
+		overriddenNativeRepos: make(map[string]calendar.RepositoryInterface),
+	}
+
+	// The break is migrated last, so the events of the due date and the work unit were created already
+	calendarRepositoryManager.overriddenRepos[migratingUser.ID.Hex()] = &taskCalendarFailingOnTitle{taskCalendar, (&TaskTextRenderer{}).RenderBreakEventTitle(task)}
+	calendarRepositoryManager.overriddenNativeRepos[migratingUser.ID.Hex()] = nativeCalendar
+
+	taskRepo := &MockTaskRepository{Tasks: []*Task{task}}
+
+	service := PlanningService{
+		taskRepository:            taskRepo,
+		calendarRepositoryManager: &calendarRepositoryManager,
+		logger:                    log,
+		locker:                    locker,
+		taskTextRenderer:          &TaskTextRenderer{},
+	}
+
+	// A failing task calendar must not leave events behind that would be created a second time by the next try
+	migrated, err := service.MigrateNativeCalendarEvents(context.TODO(), &migratingUser)
+	if err == nil {
+		t.Fatal("expected the migration to fail")
+	}
+
+	if migrated != 0 || len(taskCalendar.Events) != 0 || len(nativeCalendar.Events) != 3 {
+		t.Fatalf("got %d migrated events, %d events in the task calendar and %d native events after a failed migration", migrated, len(taskCalendar.Events), len(nativeCalendar.Events))
+	}
+
+	if taskRepo.Tasks[0].DueAt.CalendarEvents.FindByUserID(migratingUser.ID.Hex()).CalendarType != calendar.PersistedCalendarTypeNativeCalendar {
+		t.Fatal("task lost its native events after a failed migration")
+	}
+
+	calendarRepositoryManager.overriddenRepos[migratingUser.ID.Hex()] = taskCalendar
+
+	for i := 0; i < 2; i++ {
+		migrated, err = service.MigrateNativeCalendarEvents(context.TODO(), &migratingUser)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Running it again finds nothing to migrate
+		if want := []int{3, 0}[i]; migrated != want {
+			t.Errorf("got %d migrated events in run %d, want %d", migrated, i+1, want)
+		}
+	}
+
+	if len(taskCalendar.Events) != 3 || len(nativeCalendar.Events) != 0 {
+		t.Fatalf("got %d events in the task calendar and %d native events, want 3 and 0", len(taskCalendar.Events), len(nativeCalendar.Events))
+	}
+
+	migratedTask := taskRepo.Tasks[0]
+	for _, event := range []*calendar.Event{&migratedTask.DueAt, &migratedTask.WorkUnits[0].ScheduledAt, migratedTask.WorkUnits[0].Break} {
+		if len(event.CalendarEvents) != 2 {
+			t.Errorf("got persisted events %v, want one for each user", event.CalendarEvents)
+		}
+
+		if persistedEvent := event.CalendarEvents.FindByUserID(migratingUser.ID.Hex()); persistedEvent == nil || persistedEvent.CalendarType != "mock-calendar" {
+			t.Errorf("got persisted event %v, want one in the task calendar", persistedEvent)
+		}
+
+		if event.CalendarEvents.FindByUserID(collaborator.ID.Hex()) == nil {
+			t.Error("persisted event of the collaborator is gone")
+		}
+	}
+
+	if !migratedTask.WorkUnits[0].ScheduledAt.Blocking || migratedTask.WorkUnits[0].Break.Blocking {
+		t.Error("migrated events changed whether they are blocking")
+	}
+}
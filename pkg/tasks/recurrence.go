@@ -0,0 +1,118 @@
+package tasks
+
+import (
+	"github.com/pkg/errors"
+	"github.com/teambition/rrule-go"
+	"github.com/timeliness-app/timeliness-backend/pkg/date"
+	"github.com/timeliness-app/timeliness-backend/pkg/tasks/calendar"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+	"time"
+)
+
+// RecurrenceHorizon is how far into the future the tasks of a series are created ahead of time
+const RecurrenceHorizon = time.Hour * 24 * 7 * 8
+
+// RecurrenceScopeThis applies a change only to a single task of a series
+const RecurrenceScopeThis = "this"
+
+// RecurrenceScopeFollowing applies a change to a task and all following tasks of its series
+const RecurrenceScopeFollowing = "following"
+
+// RecurrenceScopeSeries applies a change to all tasks of a series
+const RecurrenceScopeSeries = "series"
+
+// Recurrence connects the tasks of a recurring series
+type Recurrence struct {
+	// Rule is an RFC 5545 RRULE like FREQ=WEEKLY;BYDAY=MO, its DTSTART is the Start of the series
+	Rule         string             `json:"rule" bson:"rule"`
+	SeriesID     primitive.ObjectID `json:"seriesId" bson:"seriesId"`
+	Start        time.Time          `json:"start" bson:"start"`
+	OccurrenceAt time.Time          `json:"occurrenceAt" bson:"occurrenceAt"`
+}
+
+func (r *Recurrence) parseRule() (*rrule.ROption, error) {
+	option, err := rrule.StrToROption(strings.TrimPrefix(strings.TrimSpace(r.Rule), "RRULE:"))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid recurrence rule")
+	}
+
+	return option, nil
+}
+
+// Validate checks that the rule is a valid RRULE
+func (r *Recurrence) Validate() error {
+	if r.Rule == "" {
+		return errors.New("recurrence rule can't be empty")
+	}
+
+	_, err := r.parseRule()
+	return err
+}
+
+// Occurrences returns the due dates of the series after a date up to and including another date,
+// the start is interpreted in location so that the clock of the due date stays the same across DST changes
+func (r *Recurrence) Occurrences(after time.Time, until time.Time, location *time.Location) ([]time.Time, error) {
+	option, err := r.parseRule()
+	if err != nil {
+		return nil, err
+	}
+
+	option.Dtstart = r.Start.In(location)
+
+	rule, err := rrule.NewRRule(*option)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid recurrence rule")
+	}
+
+	return rule.Between(after, until, true), nil
+}
+
+// RuleEndingBefore returns the rule limited to the occurrences before the given date
+func (r *Recurrence) RuleEndingBefore(end time.Time) (string, error) {
+	option, err := r.parseRule()
+	if err != nil {
+		return "", err
+	}
+
+	until := end.Add(-time.Second).UTC()
+	if !option.Until.IsZero() && option.Until.Before(until) {
+		until = option.Until
+	}
+
+	// COUNT and UNTIL must not occur together
+	option.Count = 0
+	option.Until = until
+
+	return option.RRuleString(), nil
+}
+
+// StartSeries makes the task the first task of a new series with its current rule
+func (t *Task) StartSeries() {
+	t.Recurrence.SeriesID = primitive.NewObjectID()
+	t.Recurrence.Start = t.DueAt.Date.Start
+	t.Recurrence.OccurrenceAt = t.DueAt.Date.Start
+}
+
+// NewOccurrence creates an unsaved task of the same series that is due at the given date
+func (t *Task) NewOccurrence(dueAt time.Time) *Task {
+	recurrence := *t.Recurrence
+	recurrence.OccurrenceAt = dueAt
+
+	return &Task{
+		UserID:          t.UserID,
+		Name:            t.Name,
+		Description:     t.Description,
+		Tags:            append([]primitive.ObjectID{}, t.Tags...),
+		Collaborators:   append(Collaborators{}, t.Collaborators...),
+		WorkloadOverall: t.WorkloadOverall,
+		DeadlineBuffer:  t.DeadlineBuffer,
+		DueAt: calendar.Event{
+			Date: date.Timespan{
+				Start: dueAt,
+				End:   dueAt.Add(15 * time.Minute),
+			},
+		},
+		Recurrence: &recurrence,
+	}
+}
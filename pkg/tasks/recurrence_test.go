@@ -0,0 +1,94 @@
+package tasks
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecurrence_Occurrences(t *testing.T) {
+	tests := []struct {
+		name       string
+		recurrence Recurrence
+		after      time.Time
+		until      time.Time
+		want       []time.Time
+	}{
+		{
+			name: "Weekly keeps the clock across DST",
+			recurrence: Recurrence{
+				Rule:  "FREQ=WEEKLY",
+				Start: time.Date(2021, 3, 22, 9, 0, 0, 0, location).UTC(),
+			},
+			after: time.Date(2021, 3, 23, 0, 0, 0, 0, location),
+			until: time.Date(2021, 4, 6, 0, 0, 0, 0, location),
+			want: []time.Time{
+				time.Date(2021, 3, 29, 9, 0, 0, 0, location),
+				time.Date(2021, 4, 5, 9, 0, 0, 0, location),
+			},
+		},
+		{
+			name: "RRULE prefix and count",
+			recurrence: Recurrence{
+				Rule:  "RRULE:FREQ=MONTHLY;BYMONTHDAY=1;COUNT=2",
+				Start: time.Date(2021, 1, 1, 12, 0, 0, 0, location),
+			},
+			after: time.Date(2021, 1, 1, 12, 0, 0, 0, location),
+			until: time.Date(2021, 6, 1, 0, 0, 0, 0, location),
+			want: []time.Time{
+				time.Date(2021, 1, 1, 12, 0, 0, 0, location),
+				time.Date(2021, 2, 1, 12, 0, 0, 0, location),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.recurrence.Occurrences(tt.after, tt.until, location)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Occurrences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecurrence_RuleEndingBefore(t *testing.T) {
+	recurrence := Recurrence{
+		Rule:  "FREQ=DAILY;COUNT=10",
+		Start: time.Date(2021, 1, 1, 12, 0, 0, 0, location),
+	}
+
+	rule, err := recurrence.RuleEndingBefore(time.Date(2021, 1, 4, 12, 0, 0, 0, location))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ended := Recurrence{Rule: rule, Start: recurrence.Start}
+
+	got, err := ended.Occurrences(recurrence.Start, time.Date(2021, 2, 1, 0, 0, 0, 0, location), location)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 3 {
+		t.Errorf("expected 3 occurrences before the end, got %v for rule %s", got, rule)
+	}
+}
+
+func TestRecurrence_Validate(t *testing.T) {
+	for _, rule := range []string{"", "FREQ=SOMETIMES", "BYDAY=MO"} {
+		recurrence := Recurrence{Rule: rule}
+		if recurrence.Validate() == nil {
+			t.Errorf("rule %q should be invalid", rule)
+		}
+	}
+
+	recurrence := Recurrence{Rule: "FREQ=WEEKLY;BYDAY=MO,FR"}
+	if err := recurrence.Validate(); err != nil {
+		t.Errorf("rule should be valid: %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/timeliness-app/timeliness-backend/pkg/auth"
 	"github.com/timeliness-app/timeliness-backend/pkg/communication"
+	"github.com/timeliness-app/timeliness-backend/pkg/date"
 	"github.com/timeliness-app/timeliness-backend/pkg/logger"
 	"github.com/timeliness-app/timeliness-backend/pkg/users"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -52,6 +53,12 @@ func (handler *TagHandler) TagAdd(writer http.ResponseWriter, request *http.Requ
 		}
 	}
 
+	err = validateTagScheduling(tag.Scheduling)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, err.Error(), err, request, tag)
+		return
+	}
+
 	existingTag, err := handler.TagRepository.FindByValue(request.Context(), tag.Value, userID.Hex(), false)
 	if err == nil && existingTag != nil {
 		handler.ResponseManager.RespondWithError(writer, http.StatusConflict, "This tag already exists", fmt.Errorf("tag already exists"), request, tag)
@@ -67,6 +74,54 @@ func (handler *TagHandler) TagAdd(writer http.ResponseWriter, request *http.Requ
 	handler.ResponseManager.Respond(writer, &tag)
 }
 
+// validateTagScheduling checks the scheduling rules of a tag with the same limits as the settings of a user
+// and normalizes its allowed timespans the same way
+func validateTagScheduling(scheduling *TagScheduling) error {
+	if scheduling == nil {
+		return nil
+	}
+
+	if scheduling.TimingPreference != "" {
+		valid := false
+		for _, timingPreference := range users.TimingPreferences {
+			if scheduling.TimingPreference == timingPreference {
+				valid = true
+				break
+			}
+		}
+
+		if !valid {
+			return fmt.Errorf("timing preference is invalid")
+		}
+	}
+
+	if scheduling.MinWorkUnitDuration != 0 && (scheduling.MinWorkUnitDuration < time.Minute*5 || scheduling.MinWorkUnitDuration > time.Hour*8) {
+		return fmt.Errorf("minimum work unit duration is invalid")
+	}
+
+	if scheduling.MaxWorkUnitDuration != 0 && (scheduling.MaxWorkUnitDuration < time.Hour || scheduling.MaxWorkUnitDuration > time.Hour*8) {
+		return fmt.Errorf("maximum work unit duration is invalid")
+	}
+
+	if scheduling.MaxWorkUnitDuration != 0 && scheduling.MinWorkUnitDuration > scheduling.MaxWorkUnitDuration {
+		return fmt.Errorf("minimum work unit duration can't be longer than the maximum")
+	}
+
+	allowedTimespans, err := date.NormalizeAllowedTimespans(scheduling.AllowedTimespans)
+	if err != nil {
+		return err
+	}
+	scheduling.AllowedTimespans = allowedTimespans
+
+	allowedWeekdayTimespans, err := date.NormalizeAllowedWeekdayTimespans(scheduling.AllowedWeekdayTimespans)
+	if err != nil {
+		return err
+	}
+	scheduling.AllowedWeekdayTimespans = allowedWeekdayTimespans
+
+	return nil
+}
+
 // TagUpdate is the route for updating a Tag
 func (handler *TagHandler) TagUpdate(writer http.ResponseWriter, request *http.Request) {
 	userID := request.Context().Value(auth.KeyUserID).(string)
@@ -86,6 +141,12 @@ func (handler *TagHandler) TagUpdate(writer http.ResponseWriter, request *http.R
 		return
 	}
 
+	err = validateTagScheduling(tagUpdate.Scheduling)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, err.Error(), err, request, tagUpdate)
+		return
+	}
+
 	err = handler.TagRepository.Update(request.Context(), (*Tag)(tagUpdate))
 	if err != nil {
 		handler.ResponseManager.RespondWithError(writer, http.StatusNotFound, "Couldn't update tag", err, request, tagUpdate)
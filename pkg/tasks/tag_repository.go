@@ -3,6 +3,7 @@ package tasks
 import (
 	"context"
 	"github.com/pkg/errors"
+	"github.com/timeliness-app/timeliness-backend/pkg/date"
 	"github.com/timeliness-app/timeliness-backend/pkg/logger"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -17,6 +18,7 @@ type Tag struct {
 	UserID         primitive.ObjectID `json:"-" bson:"userId" validate:"required"`
 	Value          string             `json:"value" bson:"value" validate:"required"`
 	Color          string             `json:"color" bson:"color" validate:"required"`
+	Scheduling     *TagScheduling     `json:"scheduling,omitempty" bson:"scheduling"`
 	CreatedAt      time.Time          `json:"createdAt" bson:"createdAt"`
 	LastModifiedAt time.Time          `json:"lastModifiedAt" bson:"lastModifiedAt"`
 	Deleted        bool               `json:"deleted" bson:"deleted"`
@@ -28,11 +30,40 @@ type TagUpdate struct {
 	UserID         primitive.ObjectID `json:"-" bson:"userId" validate:"required"`
 	Value          string             `json:"value" bson:"value" validate:"required"`
 	Color          string             `json:"color" bson:"color" validate:"required"`
+	Scheduling     *TagScheduling     `json:"scheduling,omitempty" bson:"scheduling"`
 	CreatedAt      time.Time          `json:"-" bson:"createdAt"`
 	LastModifiedAt time.Time          `json:"-" bson:"lastModifiedAt"`
 	Deleted        bool               `json:"deleted" bson:"deleted"`
 }
 
+// TagScheduling are scheduling rules for the tasks of a tag, unset fields fall back to the settings of the user
+type TagScheduling struct {
+	AllowedTimespans []date.Timespan `json:"allowedTimespans,omitempty" bson:"allowedTimespans,omitempty"`
+	// AllowedWeekdayTimespans override AllowedTimespans for single weekdays
+	AllowedWeekdayTimespans []date.WeekdayTimespans `json:"allowedWeekdayTimespans,omitempty" bson:"allowedWeekdayTimespans,omitempty"`
+	TimingPreference        string                  `json:"timingPreference,omitempty" bson:"timingPreference,omitempty"`
+	MinWorkUnitDuration     time.Duration           `json:"minWorkUnitDuration,omitempty" bson:"minWorkUnitDuration,omitempty"`
+	MaxWorkUnitDuration     time.Duration           `json:"maxWorkUnitDuration,omitempty" bson:"maxWorkUnitDuration,omitempty"`
+	// Priority decides which tag wins if the rules of several tags of a task conflict, higher wins
+	Priority int `json:"priority" bson:"priority"`
+}
+
+// HasAllowedTimespans returns true if the tag restricts when its tasks can be worked on
+func (s *TagScheduling) HasAllowedTimespans() bool {
+	return len(s.AllowedTimespans) > 0 || len(s.AllowedWeekdayTimespans) > 0
+}
+
+// TagRepositoryInterface is used to find tags, e.g. for their scheduling rules
+type TagRepositoryInterface interface {
+	Add(ctx context.Context, tag *Tag) error
+	Update(ctx context.Context, tag *Tag) error
+	FindByID(ctx context.Context, tagID string, userID string, isDeleted bool) (*Tag, error)
+	FindByValue(ctx context.Context, value string, userID string, isDeleted bool) (*Tag, error)
+	FindAll(ctx context.Context, userID string, page int, pageSize int, filters []Filter, includeDeleted bool) ([]Tag, int, error)
+	Delete(ctx context.Context, tagID string, userID string) error
+	DeleteFinally(ctx context.Context, tagID string, userID string) error
+}
+
 // TagRepository manages the tags of tasks
 type TagRepository struct {
 	DB     *mongo.Collection
@@ -0,0 +1,105 @@
+package tasks
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// MockTagRepository is a tag repository for testing
+type MockTagRepository struct {
+	Tags []*Tag
+}
+
+// Add adds a tag
+func (m *MockTagRepository) Add(_ context.Context, tag *Tag) error {
+	tag.CreatedAt = time.Now()
+	tag.LastModifiedAt = time.Now()
+	tag.ID = primitive.NewObjectID()
+
+	m.Tags = append(m.Tags, tag)
+	return nil
+}
+
+// Update updates a tag
+func (m *MockTagRepository) Update(_ context.Context, tag *Tag) error {
+	for index, t := range m.Tags {
+		if t.ID == tag.ID && t.UserID == tag.UserID {
+			tag.LastModifiedAt = time.Now()
+			m.Tags[index] = tag
+			return nil
+		}
+	}
+
+	return errors.New("updated count != 1")
+}
+
+// FindByID finds a specific tag by ID
+func (m *MockTagRepository) FindByID(_ context.Context, tagID string, userID string, isDeleted bool) (*Tag, error) {
+	for _, tag := range m.Tags {
+		if tag.ID.Hex() == tagID && tag.UserID.Hex() == userID && tag.Deleted == isDeleted {
+			return tag, nil
+		}
+	}
+
+	return nil, errors.New("tag not found")
+}
+
+// FindByValue finds a specific tag by value
+func (m *MockTagRepository) FindByValue(_ context.Context, value string, userID string, isDeleted bool) (*Tag, error) {
+	for _, tag := range m.Tags {
+		if tag.Value == value && tag.UserID.Hex() == userID && tag.Deleted == isDeleted {
+			return tag, nil
+		}
+	}
+
+	return nil, errors.New("tag not found")
+}
+
+// FindAll finds all tags paginated, filters are ignored
+func (m *MockTagRepository) FindAll(_ context.Context, userID string, page int, pageSize int, _ []Filter, includeDeleted bool) ([]Tag, int, error) {
+	var tags []Tag
+	for _, tag := range m.Tags {
+		if tag.UserID.Hex() == userID && (includeDeleted || !tag.Deleted) {
+			tags = append(tags, *tag)
+		}
+	}
+
+	count := len(tags)
+	offset := page * pageSize
+	if offset > count {
+		offset = count
+	}
+	end := offset + pageSize
+	if end > count {
+		end = count
+	}
+
+	return tags[offset:end], count, nil
+}
+
+// Delete marks a tag as deleted
+func (m *MockTagRepository) Delete(_ context.Context, tagID string, userID string) error {
+	for _, tag := range m.Tags {
+		if tag.ID.Hex() == tagID && tag.UserID.Hex() == userID {
+			tag.Deleted = true
+			tag.LastModifiedAt = time.Now()
+			return nil
+		}
+	}
+
+	return errors.New("tag not found")
+}
+
+// DeleteFinally removes a tag
+func (m *MockTagRepository) DeleteFinally(_ context.Context, tagID string, userID string) error {
+	for index, tag := range m.Tags {
+		if tag.ID.Hex() == tagID && tag.UserID.Hex() == userID {
+			m.Tags = append(m.Tags[:index], m.Tags[index+1:]...)
+			return nil
+		}
+	}
+
+	return errors.New("tag not found")
+}
@@ -45,27 +45,70 @@ type Task struct {
 	IsDone         bool                 `json:"isDone" bson:"isDone"`
 	Tags           []primitive.ObjectID `json:"tags" bson:"tags"`
 	Collaborators  Collaborators        `json:"collaborators" bson:"collaborators"`
+	BlockedBy      []primitive.ObjectID `json:"blockedBy" bson:"blockedBy"`
+	Recurrence     *Recurrence          `json:"recurrence" bson:"recurrence"`
 
 	WorkloadOverall time.Duration  `json:"workloadOverall" bson:"workloadOverall"`
 	NotScheduled    time.Duration  `json:"notScheduled" bson:"notScheduled"`
-	DueAt           calendar.Event `json:"dueAt" bson:"dueAt" validate:"required"`
+	DueAt           calendar.Event `json:"dueAt" bson:"dueAt"`
 	WorkUnits       WorkUnits      `json:"workUnits" bson:"workUnits"`
+
+	// IsBacklog marks a task without a due date, its work only fills free time that is left
+	IsBacklog bool `json:"isBacklog" bson:"isBacklog"`
+
+	// DeadlineBuffer overrides the deadline buffer of the scheduling settings of the owner
+	DeadlineBuffer *time.Duration `json:"deadlineBuffer" bson:"deadlineBuffer"`
+
+	// StartAfter is the earliest time work on the task can be scheduled at
+	StartAfter *time.Time `json:"startAfter" bson:"startAfter"`
+
+	// IsDueDuringAbsence flags a task whose due date falls into an absence of the owner
+	IsDueDuringAbsence bool `json:"isDueDuringAbsence" bson:"isDueDuringAbsence"`
+
+	// IsBlockedPastDeadline flags a task that can't be worked on before its deadline, because the tasks blocking it end
+	// after it
+	IsBlockedPastDeadline bool `json:"isBlockedPastDeadline" bson:"isBlockedPastDeadline"`
 }
 
 // Validate validates the task and checks the bounds of the fields
 func (t *Task) Validate() error {
-	if t.DueAt.Date.Start.Before(time.Now()) {
-		return errors.New("due date can't be in the past")
-	}
+	if t.IsBacklog {
+		if !t.DueAt.Date.Start.IsZero() {
+			return errors.New("backlog tasks can't have a due date")
+		}
+
+		if t.Recurrence != nil {
+			return errors.New("backlog tasks can't recur")
+		}
+	} else {
+		if t.DueAt.Date.Start.Before(time.Now()) {
+			return errors.New("due date can't be in the past")
+		}
 
-	if t.DueAt.Date.Start.After(time.Now().AddDate(2, 0, 0)) {
-		return errors.New("due date can't be more than two years in the future")
+		if t.DueAt.Date.Start.After(time.Now().AddDate(2, 0, 0)) {
+			return errors.New("due date can't be more than two years in the future")
+		}
 	}
 
 	if t.WorkloadOverall > time.Hour*24 {
 		return errors.New("workload can't be more than 24 hours")
 	}
 
+	if t.StartAfter != nil && !t.IsBacklog && !t.StartAfter.Before(t.DueAt.Date.Start) {
+		return errors.New("start after has to be before the due date")
+	}
+
+	if t.DeadlineBuffer != nil && (*t.DeadlineBuffer < 0 || *t.DeadlineBuffer > time.Hour*24*14) {
+		return errors.New("deadline buffer has to be between zero and 14 days")
+	}
+
+	if t.Recurrence != nil {
+		err := t.Recurrence.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -103,11 +146,14 @@ type TaskAgenda struct {
 	IsDone         bool                 `json:"isDone" bson:"isDone"`
 	Tags           []primitive.ObjectID `json:"tags" bson:"tags"`
 	Collaborators  Collaborators        `json:"collaborators" bson:"collaborators"`
+	BlockedBy      []primitive.ObjectID `json:"blockedBy" bson:"blockedBy"`
+	Recurrence     *Recurrence          `json:"recurrence" bson:"recurrence"`
 
 	WorkloadOverall time.Duration  `json:"workloadOverall" bson:"workloadOverall"`
 	NotScheduled    time.Duration  `json:"notScheduled" bson:"notScheduled"`
-	DueAt           calendar.Event `json:"dueAt" bson:"dueAt" validate:"required"`
+	DueAt           calendar.Event `json:"dueAt" bson:"dueAt"`
 	WorkUnits       WorkUnits      `json:"workUnits" bson:"workUnits"`
+	IsBacklog       bool           `json:"isBacklog" bson:"isBacklog"`
 
 	Date          calendar.AgendaEvent `json:"date" bson:"date"`
 	WorkUnitIndex int                  `json:"workUnitIndex" bson:"workUnitIndex"`
@@ -125,14 +171,17 @@ type TaskUnwound struct {
 	IsDone         bool                 `json:"isDone" bson:"isDone"`
 	Tags           []primitive.ObjectID `json:"tags" bson:"tags"`
 	Collaborators  Collaborators        `json:"collaborators" bson:"collaborators"`
+	BlockedBy      []primitive.ObjectID `json:"blockedBy" bson:"blockedBy"`
+	Recurrence     *Recurrence          `json:"recurrence" bson:"recurrence"`
 
 	WorkloadOverall time.Duration  `json:"workloadOverall" bson:"workloadOverall"`
 	NotScheduled    time.Duration  `json:"notScheduled" bson:"notScheduled"`
-	DueAt           calendar.Event `json:"dueAt" bson:"dueAt" validate:"required"`
+	DueAt           calendar.Event `json:"dueAt" bson:"dueAt"`
 	WorkUnit        WorkUnit       `json:"workUnit" bson:"workUnit"`
 	WorkUnits       WorkUnits      `json:"workUnits" bson:"workUnits"`
 	WorkUnitsIndex  int            `json:"workUnitsIndex" bson:"workUnitsIndex"`
 	WorkUnitsCount  int            `json:"workUnitsCount" bson:"workUnitsCount"`
+	IsBacklog       bool           `json:"isBacklog" bson:"isBacklog"`
 }
 
 // TaskUpdate is the view of a task for an update
@@ -147,11 +196,29 @@ type TaskUpdate struct {
 	IsDone         bool                 `json:"isDone" bson:"isDone"`
 	Tags           []primitive.ObjectID `json:"tags" bson:"tags"`
 	Collaborators  Collaborators        `json:"-" bson:"collaborators"`
+	BlockedBy      []primitive.ObjectID `json:"blockedBy" bson:"blockedBy"`
+	Recurrence     *Recurrence          `json:"recurrence" bson:"recurrence"`
 
 	WorkloadOverall time.Duration  `json:"workloadOverall" bson:"workloadOverall"`
 	NotScheduled    time.Duration  `json:"-" bson:"notScheduled"`
-	DueAt           calendar.Event `json:"dueAt" bson:"dueAt" validate:"required"`
+	DueAt           calendar.Event `json:"dueAt" bson:"dueAt"`
 	WorkUnits       WorkUnits      `json:"-" bson:"workUnits"`
+
+	// IsBacklog marks a task without a due date, its work only fills free time that is left
+	IsBacklog bool `json:"isBacklog" bson:"isBacklog"`
+
+	// DeadlineBuffer overrides the deadline buffer of the scheduling settings of the owner
+	DeadlineBuffer *time.Duration `json:"deadlineBuffer" bson:"deadlineBuffer"`
+
+	// StartAfter is the earliest time work on the task can be scheduled at
+	StartAfter *time.Time `json:"startAfter" bson:"startAfter"`
+
+	// IsDueDuringAbsence flags a task whose due date falls into an absence of the owner
+	IsDueDuringAbsence bool `json:"-" bson:"isDueDuringAbsence"`
+
+	// IsBlockedPastDeadline flags a task that can't be worked on before its deadline, because the tasks blocking it end
+	// after it
+	IsBlockedPastDeadline bool `json:"-" bson:"isBlockedPastDeadline"`
 }
 
 // Collaborator is a contact that is part of a task
@@ -223,6 +290,23 @@ func (w WorkUnits) FindByCalendarID(calendarID string) (int, *WorkUnit) {
 	return -1, nil
 }
 
+// FindByBreakCalendarID finds the work unit whose break has the given calendar event ID
+func (w WorkUnits) FindByBreakCalendarID(calendarID string) (int, *WorkUnit) {
+	for i, unit := range w {
+		if unit.Break == nil {
+			continue
+		}
+
+		for _, cEvent := range unit.Break.CalendarEvents {
+			if cEvent.CalendarEventID == calendarID {
+				return i, &unit
+			}
+		}
+	}
+
+	return -1, nil
+}
+
 // FindByID finds a single work unit by its ID
 func (w WorkUnits) FindByID(ID string) (int, *WorkUnit) {
 	for i, unit := range w {
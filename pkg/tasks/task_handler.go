@@ -2,6 +2,7 @@ package tasks
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"github.com/timeliness-app/timeliness-backend/pkg/auth"
 	"github.com/timeliness-app/timeliness-backend/pkg/communication"
 	"github.com/timeliness-app/timeliness-backend/pkg/date"
+	"github.com/timeliness-app/timeliness-backend/pkg/environment"
 	"github.com/timeliness-app/timeliness-backend/pkg/locking"
 	"github.com/timeliness-app/timeliness-backend/pkg/logger"
 	"github.com/timeliness-app/timeliness-backend/pkg/tasks/calendar"
@@ -39,7 +41,13 @@ type Handler struct {
 func (handler *Handler) TaskAdd(writer http.ResponseWriter, request *http.Request) {
 	parsedTask := TaskUpdate{}
 
-	err := json.NewDecoder(request.Body).Decode(&parsedTask)
+	force, err := parseForce(request)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Bad value for force", err, request, nil)
+		return
+	}
+
+	err = json.NewDecoder(request.Body).Decode(&parsedTask)
 	if err != nil {
 		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Wrong format", err, request, parsedTask)
 		return
@@ -70,6 +78,20 @@ func (handler *Handler) TaskAdd(writer http.ResponseWriter, request *http.Reques
 		return
 	}
 
+	err = handler.PlanningService.ValidateDependencies(request.Context(), &task)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "task dependencies invalid", err, request, parsedTask)
+		return
+	}
+
+	if !force && !handler.checkFeasibility(writer, request, &task, parsedTask) {
+		return
+	}
+
+	if task.Recurrence != nil {
+		task.StartSeries()
+	}
+
 	err = handler.TaskRepository.Add(request.Context(), &task)
 	if err != nil {
 		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, "Persisting task in database did not work", err, request, parsedTask)
@@ -84,17 +106,161 @@ func (handler *Handler) TaskAdd(writer http.ResponseWriter, request *http.Reques
 			return
 		}
 
+		var conflict *ConstraintConflictError
+		if errors.As(err, &conflict) {
+			handler.ResponseManager.RespondWithError(writer, http.StatusConflict, conflict.Error(), err, request, parsedTask)
+			return
+		}
+
 		handler.ResponseManager.RespondWithErrorAndErrorType(writer, http.StatusInternalServerError, "Error while creating calendar events", err, request, communication.Calendar, parsedTask)
 		return
 	}
 
+	if scheduledTask.Recurrence != nil {
+		// Missing tasks of the series are created again by the next materialization
+		_, err = handler.PlanningService.MaterializeSeries(request.Context(), scheduledTask)
+		if err != nil {
+			handler.Logger.Error(fmt.Sprintf("Error while materializing series of task %s", scheduledTask.ID.Hex()), err)
+		}
+	}
+
 	handler.ResponseManager.Respond(writer, &scheduledTask)
 }
 
-// TaskUpdate is the route for updating a Task
+// parseForce reads the force query parameter, which skips the feasibility check
+func parseForce(request *http.Request) (bool, error) {
+	queryForce := request.URL.Query().Get("force")
+	if queryForce == "" {
+		return false, nil
+	}
+
+	return strconv.ParseBool(queryForce)
+}
+
+// checkSchedulerSecret checks that a request to an endpoint of the scheduler carries the scheduler secret,
+// the received value is never part of the error since it could be close to the real secret
+func checkSchedulerSecret(request *http.Request) error {
+	schedulerSecret := environment.Global.SchedulerSecret
+	if schedulerSecret == "" {
+		schedulerSecret = "local"
+	}
+
+	if subtle.ConstantTimeCompare([]byte(request.Header.Get("scheduler-secret")), []byte(schedulerSecret)) != 1 {
+		return errors.New("the scheduler secret doesn't match")
+	}
+
+	return nil
+}
+
+// checkFeasibility responds with a conflict and the shortfall together with suggestions if the workload of the task
+// doesn't fit before its due date, it returns false if a response was written
+func (handler *Handler) checkFeasibility(writer http.ResponseWriter, request *http.Request, task *Task, body interface{}) bool {
+	feasibility, err := handler.PlanningService.CheckFeasibility(request.Context(), task)
+	if err != nil {
+		var conflict *ConstraintConflictError
+		if errors.As(err, &conflict) {
+			handler.ResponseManager.RespondWithError(writer, http.StatusConflict, conflict.Error(), err, request, body)
+			return false
+		}
+
+		handler.ResponseManager.RespondWithErrorAndErrorType(writer, http.StatusInternalServerError, "Error while checking the feasibility of the task", err, request, communication.Calendar, body)
+		return false
+	}
+
+	if feasibility.Feasible {
+		return true
+	}
+
+	message := fmt.Sprintf("The workload doesn't fit before the due date, %s of free time are missing", feasibility.Shortfall)
+	handler.Logger.Info(fmt.Sprintf("Rejected infeasible task of user %s: %s", task.UserID.Hex(), message))
+
+	var response = map[string]interface{}{
+		"status": http.StatusConflict,
+		"error": map[string]interface{}{
+			"type":    communication.Infeasible,
+			"message": message,
+		},
+		"feasibility": feasibility,
+	}
+
+	handler.ResponseManager.RespondWithStatus(writer, response, http.StatusConflict)
+	return false
+}
+
+// TaskPreview is the route for previewing where the work units of a new task would be scheduled, nothing is persisted
+func (handler *Handler) TaskPreview(writer http.ResponseWriter, request *http.Request) {
+	parsedTask := TaskUpdate{}
+
+	err := json.NewDecoder(request.Body).Decode(&parsedTask)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Wrong format", err, request, parsedTask)
+		return
+	}
+
+	task := Task(parsedTask)
+
+	userID, err := primitive.ObjectIDFromHex(request.Context().Value(auth.KeyUserID).(string))
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, "UserID malformed", err, request, parsedTask)
+		return
+	}
+
+	task.UserID = userID
+
+	v := validator.New()
+	err = v.Struct(task)
+	if err != nil {
+		for _, e := range err.(validator.ValidationErrors) {
+			handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, e.Error(), e, request, parsedTask)
+			return
+		}
+	}
+
+	err = task.Validate()
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "task invalid", err, request, parsedTask)
+		return
+	}
+
+	err = handler.PlanningService.ValidateDependencies(request.Context(), &task)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "task dependencies invalid", err, request, parsedTask)
+		return
+	}
+
+	previewedTask, err := handler.PlanningService.PreviewTask(request.Context(), &task)
+	if err != nil {
+		var conflict *ConstraintConflictError
+		if errors.As(err, &conflict) {
+			handler.ResponseManager.RespondWithError(writer, http.StatusConflict, conflict.Error(), err, request, parsedTask)
+			return
+		}
+
+		handler.ResponseManager.RespondWithErrorAndErrorType(writer, http.StatusInternalServerError, "Error while previewing the task", err, request, communication.Calendar, parsedTask)
+		return
+	}
+
+	handler.ResponseManager.Respond(writer, previewedTask)
+}
+
+// TasksRebalance is the route for replanning all open work units of the user at once
+func (handler *Handler) TasksRebalance(writer http.ResponseWriter, request *http.Request) {
+	userID := request.Context().Value(auth.KeyUserID).(string)
+
+	result, err := handler.PlanningService.RebalanceTasks(request.Context(), userID)
+	if err != nil {
+		handler.ResponseManager.RespondWithErrorAndErrorType(writer, http.StatusInternalServerError, "Error while rebalancing tasks", err, request, communication.Calendar, nil)
+		return
+	}
+
+	handler.ResponseManager.Respond(writer, result)
+}
+
+// TaskUpdate is the route for updating a Task, for recurring tasks the recurrenceScope decides if following tasks change too
 func (handler *Handler) TaskUpdate(writer http.ResponseWriter, request *http.Request) {
 	userID := request.Context().Value(auth.KeyUserID).(string)
 	taskID := mux.Vars(request)["taskID"]
+	recurrenceScope := request.URL.Query().Get("recurrenceScope")
 
 	isValid := primitive.IsValidObjectID(taskID)
 	if !isValid {
@@ -102,6 +268,21 @@ func (handler *Handler) TaskUpdate(writer http.ResponseWriter, request *http.Req
 		return
 	}
 
+	if recurrenceScope == "" {
+		recurrenceScope = RecurrenceScopeThis
+	}
+
+	force, err := parseForce(request)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Bad value for force", err, request, nil)
+		return
+	}
+
+	if recurrenceScope != RecurrenceScopeThis && recurrenceScope != RecurrenceScopeFollowing {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Invalid recurrenceScope", errors.Errorf("invalid recurrence scope %s", recurrenceScope), request, nil)
+		return
+	}
+
 	lock, err := handler.Locker.Acquire(request.Context(), taskID, time.Second*10, false, 10*time.Second)
 	if err != nil {
 		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, fmt.Sprintf("Could not acquire lock for %s", taskID), err, request, nil)
@@ -122,6 +303,19 @@ func (handler *Handler) TaskUpdate(writer http.ResponseWriter, request *http.Req
 	}
 	parsedTask := (TaskUpdate)(*original)
 
+	// Decode into a copy of the recurrence, so that the original stays untouched
+	originalRecurrence := original.Recurrence
+	if originalRecurrence != nil {
+		recurrence := *originalRecurrence
+		parsedTask.Recurrence = &recurrence
+	}
+
+	// The same goes for the earliest start
+	if original.StartAfter != nil {
+		startAfter := *original.StartAfter
+		parsedTask.StartAfter = &startAfter
+	}
+
 	err = json.NewDecoder(request.Body).Decode(&parsedTask)
 	if err != nil {
 		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Wrong format", err, request, parsedTask)
@@ -130,16 +324,62 @@ func (handler *Handler) TaskUpdate(writer http.ResponseWriter, request *http.Req
 
 	task := (*Task)(&parsedTask)
 
+	// A task that is moved to the backlog loses its due date
+	if task.IsBacklog && !original.IsBacklog {
+		task.DueAt.Date = date.Timespan{}
+	}
+
 	err = task.Validate()
 	if err != nil {
 		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "task invalid", err, request, parsedTask)
 		return
 	}
 
+	seriesNeedsSplit := false
+	if originalRecurrence != nil {
+		// Only the rule of a recurrence can be changed directly
+		if task.Recurrence != nil {
+			task.Recurrence.SeriesID = originalRecurrence.SeriesID
+			task.Recurrence.Start = originalRecurrence.Start
+			task.Recurrence.OccurrenceAt = originalRecurrence.OccurrenceAt
+		}
+
+		ruleChanged := task.Recurrence == nil || task.Recurrence.Rule != originalRecurrence.Rule
+		if ruleChanged && recurrenceScope != RecurrenceScopeFollowing {
+			handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "The recurrence can only be changed for this and following tasks", errors.New("recurrence changed for a single task"), request, parsedTask)
+			return
+		}
+
+		seriesNeedsSplit = recurrenceScope == RecurrenceScopeFollowing && (ruleChanged || original.DueAt.Date.Start != task.DueAt.Date.Start)
+	} else if task.Recurrence != nil {
+		task.StartSeries()
+	}
+
+	err = handler.PlanningService.ValidateDependencies(request.Context(), task)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "task dependencies invalid", err, request, parsedTask)
+		return
+	}
+
+	startAfterChanged := (original.StartAfter == nil) != (task.StartAfter == nil) ||
+		(task.StartAfter != nil && !task.StartAfter.Equal(*original.StartAfter))
+
+	// Only changes that affect how much time the task gets are checked
+	feasibilityChanged := original.WorkloadOverall != task.WorkloadOverall || original.DueAt.Date != task.DueAt.Date || startAfterChanged
+	if !force && feasibilityChanged && !handler.checkFeasibility(writer, request, task, parsedTask) {
+		return
+	}
+
 	// If the tasks' workload was changed or if we have unscheduled time we want to schedule the task
 	if original.WorkloadOverall != task.WorkloadOverall || task.NotScheduled > 0 {
 		task, err = handler.PlanningService.ScheduleTask(request.Context(), task, false)
 		if err != nil {
+			var conflict *ConstraintConflictError
+			if errors.As(err, &conflict) {
+				handler.ResponseManager.RespondWithError(writer, http.StatusConflict, conflict.Error(), err, request, parsedTask)
+				return
+			}
+
 			handler.ResponseManager.RespondWithErrorAndErrorType(writer, http.StatusInternalServerError, fmt.Sprintf("Error scheduling task %s", taskID), err, request, communication.Calendar, parsedTask)
 			return
 		}
@@ -153,6 +393,23 @@ func (handler *Handler) TaskUpdate(writer http.ResponseWriter, request *http.Req
 		}
 	}
 
+	if startAfterChanged {
+		task, err = handler.PlanningService.StartAfterChanged(request.Context(), task)
+		if err != nil {
+			handler.ResponseManager.RespondWithErrorAndErrorType(writer, http.StatusInternalServerError, fmt.Sprintf("Error updating earliest start for task %s", taskID), err, request, communication.Calendar, parsedTask)
+			return
+		}
+	}
+
+	// Work units could now be scheduled before a task that blocks this task
+	if len(task.BlockedBy) > 0 {
+		task, err = handler.PlanningService.RescheduleBlockedWorkUnits(request.Context(), task, false)
+		if err != nil {
+			handler.ResponseManager.RespondWithErrorAndErrorType(writer, http.StatusInternalServerError, fmt.Sprintf("Error rescheduling blocked work units for task %s", taskID), err, request, communication.Calendar, parsedTask)
+			return
+		}
+	}
+
 	if original.IsDone != task.IsDone {
 		if task.IsDone {
 			// TODO task was switched to done so we should remove all workunits left and remove the time from workload
@@ -173,6 +430,27 @@ func (handler *Handler) TaskUpdate(writer http.ResponseWriter, request *http.Req
 		return
 	}
 
+	switch {
+	case seriesNeedsSplit:
+		task, err = handler.PlanningService.SplitSeries(request.Context(), task, originalRecurrence)
+		if err != nil {
+			handler.ResponseManager.RespondWithErrorAndErrorType(writer, http.StatusInternalServerError, "Error updating following tasks", err, request, communication.Calendar, parsedTask)
+			return
+		}
+	case originalRecurrence != nil && recurrenceScope == RecurrenceScopeFollowing:
+		err = handler.PlanningService.UpdateFollowingTasks(request.Context(), task)
+		if err != nil {
+			handler.ResponseManager.RespondWithErrorAndErrorType(writer, http.StatusInternalServerError, "Error updating following tasks", err, request, communication.Calendar, parsedTask)
+			return
+		}
+	case originalRecurrence == nil && task.Recurrence != nil:
+		_, err = handler.PlanningService.MaterializeSeries(request.Context(), task)
+		if err != nil {
+			handler.ResponseManager.RespondWithErrorAndErrorType(writer, http.StatusInternalServerError, "Error creating following tasks", err, request, communication.Calendar, parsedTask)
+			return
+		}
+	}
+
 	handler.ResponseManager.Respond(writer, task)
 }
 
@@ -215,7 +493,23 @@ func (handler *Handler) WorkUnitUpdate(writer http.ResponseWriter, request *http
 
 	workUnit := task.WorkUnits[index]
 	original := workUnit
-	err = json.NewDecoder(request.Body).Decode(&workUnit)
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Wrong format", err, request, nil)
+		return
+	}
+
+	err = json.Unmarshal(body, &workUnit)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Wrong format", err, request, workUnit)
+		return
+	}
+
+	// Tells apart an explicit isPinned of false from one that was left out
+	var pinning struct {
+		IsPinned *bool `json:"isPinned"`
+	}
+	err = json.Unmarshal(body, &pinning)
 	if err != nil {
 		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Wrong format", err, request, workUnit)
 		return
@@ -236,6 +530,12 @@ func (handler *Handler) WorkUnitUpdate(writer http.ResponseWriter, request *http
 			return
 		}
 
+		// A work unit the user moved stays where it is, unless the user unpinned it at the same time
+		if pinning.IsPinned == nil {
+			workUnit.IsPinned = true
+		}
+		workUnit.IsMissed = false
+
 		err = handler.PlanningService.UpdateWorkUnitEvent(request.Context(), task, &workUnit)
 		if err != nil {
 			handler.ResponseManager.RespondWithErrorAndErrorType(writer, http.StatusInternalServerError, "Error updating the task", err, request, communication.Calendar, workUnit)
@@ -331,6 +631,7 @@ func (handler *Handler) MarkWorkUnitAsDone(writer http.ResponseWriter, request *
 	}
 
 	workUnit.IsDone = requestBody.IsDone
+	workUnit.IsMissed = false
 
 	if workUnit.IsDone && requestBody.TimeLeft > 0 {
 		workUnit.ScheduledAt.Date.End = workUnit.ScheduledAt.Date.End.Add(requestBody.TimeLeft * -1)
@@ -388,10 +689,75 @@ func (handler *Handler) MarkWorkUnitAsDone(writer http.ResponseWriter, request *
 	handler.ResponseManager.Respond(writer, task)
 }
 
-// TaskDelete deletes a task
+// MaterializeRecurringTasks creates the tasks of all recurring series up to the recurrence horizon, called by a scheduler
+func (handler *Handler) MaterializeRecurringTasks(writer http.ResponseWriter, request *http.Request) {
+	err := checkSchedulerSecret(request)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusForbidden, "Invalid secret", err, request, nil)
+		return
+	}
+
+	go func() {
+		count, err := handler.PlanningService.MaterializeAllSeries(context.Background())
+		if err != nil {
+			handler.Logger.Error("Error while materializing recurring tasks", err)
+			return
+		}
+
+		handler.Logger.Info(fmt.Sprintf("Materialized %d recurring series", count))
+	}()
+
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+// SweepMissedWorkUnits handles the work units of all users that passed without being done, called by a scheduler
+func (handler *Handler) SweepMissedWorkUnits(writer http.ResponseWriter, request *http.Request) {
+	err := checkSchedulerSecret(request)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusForbidden, "Invalid secret", err, request, nil)
+		return
+	}
+
+	go func() {
+		result, err := handler.PlanningService.SweepMissedWorkUnits(context.Background())
+		if err != nil {
+			handler.Logger.Error("Error while sweeping missed work units", err)
+			return
+		}
+
+		handler.Logger.Info(fmt.Sprintf("Swept missed work units of %d tasks: %d rescheduled, %d marked as done, %d asked",
+			result.Tasks, result.Rescheduled, result.MarkedDone, result.Asked))
+	}()
+
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+// ScheduleBacklogTasks schedules the work of backlog tasks that didn't fit so far, called by a scheduler
+func (handler *Handler) ScheduleBacklogTasks(writer http.ResponseWriter, request *http.Request) {
+	err := checkSchedulerSecret(request)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusForbidden, "Invalid secret", err, request, nil)
+		return
+	}
+
+	go func() {
+		scheduled, err := handler.PlanningService.ScheduleBacklogTasks(context.Background())
+		if err != nil {
+			handler.Logger.Error("Error while scheduling backlog tasks", err)
+			return
+		}
+
+		handler.Logger.Info(fmt.Sprintf("Scheduled %d backlog tasks", scheduled))
+	}()
+
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+// TaskDelete deletes a task, for recurring tasks the recurrenceScope decides if following tasks or the series are deleted
 func (handler *Handler) TaskDelete(writer http.ResponseWriter, request *http.Request) {
 	userID := request.Context().Value(auth.KeyUserID).(string)
 	taskID := mux.Vars(request)["taskID"]
+	recurrenceScope := request.URL.Query().Get("recurrenceScope")
 
 	isValid := primitive.IsValidObjectID(taskID)
 	if !isValid {
@@ -399,6 +765,11 @@ func (handler *Handler) TaskDelete(writer http.ResponseWriter, request *http.Req
 		return
 	}
 
+	if recurrenceScope != "" && recurrenceScope != RecurrenceScopeThis && recurrenceScope != RecurrenceScopeFollowing && recurrenceScope != RecurrenceScopeSeries {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Invalid recurrenceScope", errors.Errorf("invalid recurrence scope %s", recurrenceScope), request, nil)
+		return
+	}
+
 	lock, err := handler.Locker.Acquire(request.Context(), taskID, time.Second*10, false, 2*time.Second)
 	if err != nil {
 		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, fmt.Sprintf("Could not acquire lock for %s", taskID), err, request, nil)
@@ -418,7 +789,15 @@ func (handler *Handler) TaskDelete(writer http.ResponseWriter, request *http.Req
 		return
 	}
 
-	err = handler.PlanningService.DeleteTask(request.Context(), task)
+	switch {
+	case task.Recurrence != nil && recurrenceScope == RecurrenceScopeSeries:
+		err = handler.PlanningService.DeleteSeries(request.Context(), task)
+	case task.Recurrence != nil && recurrenceScope == RecurrenceScopeFollowing:
+		err = handler.PlanningService.DeleteFollowingTasks(request.Context(), task, task.Recurrence, true)
+	default:
+		err = handler.PlanningService.DeleteTask(request.Context(), task)
+	}
+
 	if err != nil {
 		handler.ResponseManager.RespondWithErrorAndErrorType(writer, http.StatusInternalServerError, "Could not delete task events", err, request, communication.Calendar, nil)
 		return
@@ -565,6 +944,26 @@ func (handler *Handler) TaskGet(writer http.ResponseWriter, request *http.Reques
 	handler.ResponseManager.Respond(writer, task)
 }
 
+// TaskScheduleExplain is the route for explaining where the work units of a task would be scheduled and why
+func (handler *Handler) TaskScheduleExplain(writer http.ResponseWriter, request *http.Request) {
+	userID := request.Context().Value(auth.KeyUserID).(string)
+	taskID := mux.Vars(request)["taskID"]
+
+	task, err := handler.TaskRepository.FindByID(request.Context(), taskID, userID, false)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusNotFound, "Could not find task", err, request, nil)
+		return
+	}
+
+	explanation, err := handler.PlanningService.ExplainSchedule(request.Context(), task)
+	if err != nil {
+		handler.ResponseManager.RespondWithErrorAndErrorType(writer, http.StatusInternalServerError, "Error while explaining the schedule", err, request, communication.Calendar, nil)
+		return
+	}
+
+	handler.ResponseManager.Respond(writer, explanation)
+}
+
 // GetAllTasksByWorkUnits is the route for getting all tasks, but by TaskUnwound
 func (handler *Handler) GetAllTasksByWorkUnits(writer http.ResponseWriter, request *http.Request) {
 	userID := request.Context().Value(auth.KeyUserID).(string)
@@ -739,6 +1138,50 @@ func (handler *Handler) GetWorkUnitsBetween(writer http.ResponseWriter, request
 	handler.ResponseManager.Respond(writer, response)
 }
 
+// Capacity is the route for the forecast of the free time of the user between from and to
+func (handler *Handler) Capacity(writer http.ResponseWriter, request *http.Request) {
+	userID := request.Context().Value(auth.KeyUserID).(string)
+
+	queryFrom := request.URL.Query().Get("from")
+	queryTo := request.URL.Query().Get("to")
+
+	from, err := time.Parse(time.RFC3339, queryFrom)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Wrong date format in query string from", err, request, nil)
+		return
+	}
+
+	to, err := time.Parse(time.RFC3339, queryTo)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "Wrong date format in query string to", err, request, nil)
+		return
+	}
+
+	if !to.After(from) {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "To has to be after from", errors.New("to is not after from"), request, nil)
+		return
+	}
+
+	if to.Sub(from) > time.Hour*24*92 {
+		handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, "The range can't be longer than 92 days", errors.New("range too long"), request, nil)
+		return
+	}
+
+	u, err := handler.UserRepository.FindByID(request.Context(), userID)
+	if err != nil {
+		handler.ResponseManager.RespondWithError(writer, http.StatusInternalServerError, "Could not find user", err, request, nil)
+		return
+	}
+
+	capacity, err := handler.PlanningService.ForecastCapacity(request.Context(), u, from, to)
+	if err != nil {
+		handler.ResponseManager.RespondWithErrorAndErrorType(writer, http.StatusInternalServerError, "Error while forecasting the capacity", err, request, communication.Calendar, nil)
+		return
+	}
+
+	handler.ResponseManager.Respond(writer, capacity)
+}
+
 // GetTasksByAgenda is the route for the agenda view
 func (handler *Handler) GetTasksByAgenda(writer http.ResponseWriter, request *http.Request) {
 	userID := request.Context().Value(auth.KeyUserID).(string)
@@ -0,0 +1,170 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/timeliness-app/timeliness-backend/pkg/auth"
+	"github.com/timeliness-app/timeliness-backend/pkg/communication"
+	"github.com/timeliness-app/timeliness-backend/pkg/date"
+	"github.com/timeliness-app/timeliness-backend/pkg/tasks/calendar"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_WorkUnitUpdate(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 1, 1, 12, 0, 0, 0, location) }
+
+	taskRepo := &MockTaskRepository{Tasks: []*Task{}}
+
+	var calendarRepositoryManager = CalendarRepositoryManager{
+		userRepository:  &userRepo,
+		logger:          log,
+		overriddenRepos: make(map[string]calendar.RepositoryInterface),
+	}
+
+	calendarRepository := &calendar.MockCalendarRepository{Events: []*calendar.Event{}, User: &primaryUser}
+	calendarRepositoryManager.overriddenRepos[primaryUser.ID.Hex()] = calendarRepository
+
+	handler := &Handler{
+		TaskRepository:  taskRepo,
+		UserRepository:  &userRepo,
+		Logger:          log,
+		Locker:          locker,
+		ResponseManager: &communication.ResponseManager{Logger: log},
+		PlanningService: &PlanningService{
+			userRepository:            &userRepo,
+			taskRepository:            taskRepo,
+			calendarRepositoryManager: &calendarRepositoryManager,
+			logger:                    log,
+			locker:                    locker,
+			taskTextRenderer:          &TaskTextRenderer{},
+		},
+	}
+
+	unit := WorkUnit{
+		ID: primitive.NewObjectID(),
+		ScheduledAt: calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 2, 9, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 2, 11, 0, 0, 0, location),
+			},
+			Blocking: true,
+		},
+		Workload: time.Hour * 2,
+	}
+	_, err := calendarRepository.NewEvent(&unit.ScheduledAt, "", "Work", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task := &Task{
+		ID:              primitive.NewObjectID(),
+		UserID:          primaryUser.ID,
+		Name:            "Pinning",
+		WorkloadOverall: time.Hour * 2,
+		DueAt: calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 10, 12, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 10, 12, 0, 0, 0, location),
+			},
+		},
+		WorkUnits: WorkUnits{unit},
+	}
+	err = taskRepo.Add(context.Background(), task)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updateWorkUnit := func(body map[string]interface{}) *WorkUnit {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		request := httptest.NewRequest(http.MethodPatch, "/v1/tasks/"+task.ID.Hex()+"/workunits/"+unit.ID.Hex(), bytes.NewReader(encoded))
+		request = mux.SetURLVars(request, map[string]string{"taskID": task.ID.Hex(), "workUnitID": unit.ID.Hex()})
+		request = request.WithContext(context.WithValue(request.Context(), auth.KeyUserID, primaryUser.ID.Hex()))
+
+		recorder := httptest.NewRecorder()
+		handler.WorkUnitUpdate(recorder, request)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("got status %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+		}
+
+		persisted, err := taskRepo.FindByID(context.Background(), task.ID.Hex(), primaryUser.ID.Hex(), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, workUnit := persisted.WorkUnits.FindByID(unit.ID.Hex())
+		return workUnit
+	}
+
+	scheduledAt := func(hour int) calendar.Event {
+		return calendar.Event{
+			Date: date.Timespan{
+				Start: time.Date(2021, 1, 2, hour, 0, 0, 0, location),
+				End:   time.Date(2021, 1, 2, hour+2, 0, 0, 0, location),
+			},
+		}
+	}
+
+	workUnit := updateWorkUnit(map[string]interface{}{"isPinned": true})
+	if !workUnit.IsPinned {
+		t.Error("work unit isn't pinned after pinning it")
+	}
+
+	workUnit = updateWorkUnit(map[string]interface{}{"isPinned": false})
+	if workUnit.IsPinned {
+		t.Error("work unit is still pinned after unpinning it")
+	}
+
+	workUnit = updateWorkUnit(map[string]interface{}{"scheduledAt": scheduledAt(13)})
+	if !workUnit.IsPinned {
+		t.Error("work unit isn't pinned after moving it")
+	}
+
+	if !workUnit.ScheduledAt.Date.Start.Equal(time.Date(2021, 1, 2, 13, 0, 0, 0, location)) {
+		t.Errorf("got work unit at %s, want it at 13:00", workUnit.ScheduledAt.Date.Start)
+	}
+
+	workUnit = updateWorkUnit(map[string]interface{}{"scheduledAt": scheduledAt(15), "isPinned": false})
+	if workUnit.IsPinned {
+		t.Error("work unit is pinned after moving and unpinning it")
+	}
+
+	if !workUnit.ScheduledAt.Date.Start.Equal(time.Date(2021, 1, 2, 15, 0, 0, 0, location)) {
+		t.Errorf("got work unit at %s, want it at 15:00", workUnit.ScheduledAt.Date.Start)
+	}
+}
+
+func TestCheckSchedulerSecret(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/v1/tasks/recurring/materialize", nil)
+
+	err := checkSchedulerSecret(request)
+	if err == nil {
+		t.Error("got no error for a request without the scheduler secret")
+	}
+
+	request.Header.Set("scheduler-secret", "almost-local")
+	err = checkSchedulerSecret(request)
+	if err == nil {
+		t.Fatal("got no error for a wrong scheduler secret")
+	}
+
+	if strings.Contains(err.Error(), "almost-local") {
+		t.Errorf("error %q contains the received secret", err)
+	}
+
+	request.Header.Set("scheduler-secret", "local")
+	err = checkSchedulerSecret(request)
+	if err != nil {
+		t.Errorf("got error %v for the scheduler secret", err)
+	}
+}
@@ -24,7 +24,17 @@ type TaskRepositoryInterface interface {
 	FindByCalendarEventID(ctx context.Context, calendarEventID string, userID string, isDeleted bool) (*Task, error)
 	FindIntersectingWithEvent(ctx context.Context, userID string, event *calendar.Event, ignoreWorkUnitID primitive.ObjectID, isDeleted bool) ([]Task, error)
 	FindWorkUnitsIntersectingTimespan(ctx context.Context, userID string, timespan date.Timespan) ([]WorkUnit, error)
+	FindUnwoundIntersectingTimespan(ctx context.Context, userID string, timespan date.Timespan) ([]TaskUnwound, error)
 	FindUnscheduledTasks(ctx context.Context, userID string, page int, pageSize int) ([]Task, int, error)
+	FindBlockedBy(ctx context.Context, taskID string, userID string) ([]Task, error)
+	FindOpenTasks(ctx context.Context, userID string) ([]Task, error)
+	FindForFeed(ctx context.Context, userID string, from time.Time) ([]Task, error)
+	FindWithCalendarType(ctx context.Context, userID string, calendarType calendar.Type) ([]Task, error)
+	FindWithMissedWorkUnits(ctx context.Context, before time.Time) ([]Task, error)
+	FindUnscheduledBacklogTasks(ctx context.Context) ([]Task, error)
+	FindBySeriesID(ctx context.Context, seriesID string, userID string) ([]Task, error)
+	FindLatestOfSeriesBefore(ctx context.Context, until time.Time) ([]Task, error)
+	UpdateSeriesRule(ctx context.Context, seriesID string, userID string, rule string) error
 	CountTasksBetween(ctx context.Context, userID string, from time.Time, to time.Time, isDone bool) (int64, error)
 	CountWorkUnitsBetween(ctx context.Context, userID string, from time.Time, to time.Time, isDone bool) (int64, error)
 	Delete(ctx context.Context, taskID string, userID string) error
@@ -148,7 +158,8 @@ func (s *MongoDBTaskRepository) FindAll(ctx context.Context, userID string, page
 	offset := page * pageSize
 
 	findOptions := options.Find()
-	findOptions.SetSort(bson.M{"dueAt.date.start": 1})
+	// Backlog tasks don't have a due date and come last
+	findOptions.SetSort(bson.D{{Key: "isBacklog", Value: 1}, {Key: "dueAt.date.start", Value: 1}})
 	findOptions.SetSkip(int64(offset))
 	findOptions.SetLimit(int64(pageSize))
 
@@ -174,6 +185,7 @@ func (s *MongoDBTaskRepository) FindAll(ctx context.Context, userID string, page
 		filter = append(filter, bson.E{Key: "$or", Value: bson.A{
 			bson.M{"isDone": false},
 			bson.M{"isDone": true, "dueAt.date.start": bson.M{"$gte": isDoneAndDueAt}},
+			bson.M{"isDone": true, "isBacklog": true, "lastModifiedAt": bson.M{"$gte": isDoneAndDueAt}},
 		}})
 	}
 
@@ -338,6 +350,63 @@ func (s *MongoDBTaskRepository) FindWorkUnitsIntersectingTimespan(ctx context.Co
 	return results, nil
 }
 
+// FindUnwoundIntersectingTimespan finds all work units intersecting a timespan together with their task
+func (s *MongoDBTaskRepository) FindUnwoundIntersectingTimespan(ctx context.Context, userID string, timespan date.Timespan) ([]TaskUnwound, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	queryFilters := bson.D{
+		{
+			Key: "$or", Value: bson.A{
+				bson.D{
+					{Key: "userId", Value: userObjectID},
+				},
+				bson.D{
+					{Key: "collaborators.userId", Value: userObjectID},
+				},
+			},
+		},
+		{Key: "deleted", Value: false},
+		{
+			Key: "workUnits", Value: bson.D{
+				{
+					Key: "$elemMatch", Value: bson.M{
+						"scheduledAt.date.start": bson.M{"$lte": timespan.End},
+						"scheduledAt.date.end":   bson.M{"$gte": timespan.Start},
+					},
+				},
+			},
+		},
+	}
+
+	matchStage := bson.D{{Key: "$match", Value: queryFilters}}
+	addFieldsStage := bson.D{{Key: "$addFields", Value: bson.M{"workUnit": "$workUnits"}}}
+	unwindStage := bson.D{{Key: "$unwind", Value: bson.M{"path": "$workUnit", "includeArrayIndex": "workUnitsIndex"}}}
+	matchStage2 := bson.D{
+		{
+			Key: "$match", Value: bson.M{
+				"workUnit.scheduledAt.date.start": bson.M{"$lte": timespan.End},
+				"workUnit.scheduledAt.date.end":   bson.M{"$gte": timespan.Start},
+			},
+		},
+	}
+
+	cursor, err := s.DB.Aggregate(ctx, mongo.Pipeline{matchStage, addFieldsStage, unwindStage, matchStage2})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []TaskUnwound
+	err = cursor.All(ctx, &results)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
 // FindUnscheduledTasks finds tasks where task.NotScheduled != 0, paginated
 func (s *MongoDBTaskRepository) FindUnscheduledTasks(ctx context.Context, userID string, page int, pageSize int) ([]Task, int, error) {
 	var t []Task
@@ -422,9 +491,10 @@ func (s *MongoDBTaskRepository) FindAllByDate(ctx context.Context, userID string
 	queryAgendaFilters := buildConcatFilterQuery(bson.D{}, filters)
 
 	matchStage := bson.D{{Key: "$match", Value: queryFilters}}
+	// Backlog tasks only show up with their work units
 	addFieldsStage := bson.D{{
 		Key: "$addFields", Value: bson.M{
-			"dueAtDate":     bson.A{"$dueAt"},
+			"dueAtDate":     bson.M{"$cond": bson.A{"$isBacklog", bson.A{}, bson.A{"$dueAt"}}},
 			"workUnitDates": "$workUnits.scheduledAt",
 		},
 	}}
@@ -456,7 +526,7 @@ func (s *MongoDBTaskRepository) FindAllByDate(ctx context.Context, userID string
 		Key: "$set", Value: bson.M{
 			"workUnitIndex": bson.M{"$subtract": bson.A{
 				"$workUnitIndex",
-				1,
+				bson.M{"$size": "$dueAtDate"},
 			}},
 		},
 	}}
@@ -541,7 +611,7 @@ func (s *MongoDBTaskRepository) FindByID(ctx context.Context, taskID string, use
 	return &t, nil
 }
 
-// FindByCalendarEventID finds a specific task by a calendar event ID in workUnits or dueAt
+// FindByCalendarEventID finds a specific task by a calendar event ID in workUnits, their breaks or dueAt
 func (s *MongoDBTaskRepository) FindByCalendarEventID(ctx context.Context, calendarEventID string, userID string, isDeleted bool) (*Task, error) {
 	t := Task{}
 
@@ -564,6 +634,7 @@ func (s *MongoDBTaskRepository) FindByCalendarEventID(ctx context.Context, calen
 		{Key: "deleted", Value: isDeleted},
 		{Key: "$or", Value: bson.A{
 			bson.M{"workUnits.scheduledAt.calendarEvents.calendarEventID": calendarEventID},
+			bson.M{"workUnits.break.calendarEvents.calendarEventID": calendarEventID},
 			bson.M{"dueAt.calendarEvents.calendarEventID": calendarEventID},
 		}},
 	})
@@ -580,6 +651,318 @@ func (s *MongoDBTaskRepository) FindByCalendarEventID(ctx context.Context, calen
 	return &t, nil
 }
 
+// FindBlockedBy finds all tasks that are not done and blocked by the given task
+func (s *MongoDBTaskRepository) FindBlockedBy(ctx context.Context, taskID string, userID string) ([]Task, error) {
+	var t []Task
+
+	taskObjectID, err := primitive.ObjectIDFromHex(taskID)
+	if err != nil {
+		return nil, err
+	}
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.D{
+		{
+			Key: "$or", Value: bson.A{
+				bson.D{
+					{Key: "userId", Value: userObjectID},
+				},
+				bson.D{
+					{Key: "collaborators.userId", Value: userObjectID},
+				},
+			},
+		},
+		{Key: "blockedBy", Value: taskObjectID},
+		{Key: "deleted", Value: false},
+		{Key: "isDone", Value: false},
+	}
+
+	cursor, err := s.DB.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cursor.All(ctx, &t)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// FindOpenTasks finds all tasks owned by a user that are not done sorted by their due date
+func (s *MongoDBTaskRepository) FindOpenTasks(ctx context.Context, userID string) ([]Task, error) {
+	var t []Task
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.D{
+		{Key: "userId", Value: userObjectID},
+		{Key: "deleted", Value: false},
+		{Key: "isDone", Value: false},
+	}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "dueAt.date.start", Value: 1}})
+
+	cursor, err := s.DB.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cursor.All(ctx, &t)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// FindForFeed finds all tasks a user owns or collaborates on that are due or have work units ending after from
+func (s *MongoDBTaskRepository) FindForFeed(ctx context.Context, userID string, from time.Time) ([]Task, error) {
+	var t []Task
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.D{
+		{Key: "deleted", Value: false},
+		{Key: "$and", Value: bson.A{
+			bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "userId", Value: userObjectID}},
+				bson.D{{Key: "collaborators.userId", Value: userObjectID}},
+			}}},
+			bson.D{{Key: "$or", Value: bson.A{
+				bson.D{
+					{Key: "isBacklog", Value: false},
+					{Key: "dueAt.date.end", Value: bson.D{{Key: "$gte", Value: from}}},
+				},
+				bson.D{{Key: "workUnits.scheduledAt.date.end", Value: bson.D{{Key: "$gte", Value: from}}}},
+			}}},
+		}},
+	}
+
+	findOptions := options.Find()
+	// the ID breaks ties so the feed is rendered identically as long as nothing changed
+	findOptions.SetSort(bson.D{{Key: "dueAt.date.start", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := s.DB.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cursor.All(ctx, &t)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// FindWithCalendarType finds all tasks that have an event persisted in a calendar of the given type for a user
+func (s *MongoDBTaskRepository) FindWithCalendarType(ctx context.Context, userID string, calendarType calendar.Type) ([]Task, error) {
+	var t []Task
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	persistedEvent := bson.D{{Key: "$elemMatch", Value: bson.D{
+		{Key: "userID", Value: userObjectID},
+		{Key: "calendarType", Value: calendarType},
+	}}}
+
+	filter := bson.D{
+		{Key: "deleted", Value: false},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "dueAt.calendarEvents", Value: persistedEvent}},
+			bson.D{{Key: "workUnits.scheduledAt.calendarEvents", Value: persistedEvent}},
+			bson.D{{Key: "workUnits.break.calendarEvents", Value: persistedEvent}},
+		}},
+	}
+
+	cursor, err := s.DB.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cursor.All(ctx, &t)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// FindWithMissedWorkUnits finds the tasks of all users with work units that ended before the given time without
+// being done and that weren't marked as missed yet
+func (s *MongoDBTaskRepository) FindWithMissedWorkUnits(ctx context.Context, before time.Time) ([]Task, error) {
+	var t []Task
+
+	filter := bson.D{
+		{Key: "deleted", Value: false},
+		{Key: "isDone", Value: false},
+		{
+			Key: "workUnits", Value: bson.D{
+				{
+					Key: "$elemMatch", Value: bson.M{
+						"isDone":               false,
+						"isMissed":             bson.M{"$ne": true},
+						"scheduledAt.date.end": bson.M{"$lt": before},
+					},
+				},
+			},
+		},
+	}
+
+	cursor, err := s.DB.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cursor.All(ctx, &t)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// FindUnscheduledBacklogTasks finds the backlog tasks of all users that have workload that isn't scheduled yet
+func (s *MongoDBTaskRepository) FindUnscheduledBacklogTasks(ctx context.Context) ([]Task, error) {
+	var t []Task
+
+	filter := bson.D{
+		{Key: "deleted", Value: false},
+		{Key: "isDone", Value: false},
+		{Key: "isBacklog", Value: true},
+		{Key: "notScheduled", Value: bson.M{"$gt": 0}},
+	}
+
+	cursor, err := s.DB.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cursor.All(ctx, &t)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// FindBySeriesID finds all tasks of a recurring series sorted by their occurrence
+func (s *MongoDBTaskRepository) FindBySeriesID(ctx context.Context, seriesID string, userID string) ([]Task, error) {
+	var t []Task
+
+	seriesObjectID, err := primitive.ObjectIDFromHex(seriesID)
+	if err != nil {
+		return nil, err
+	}
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.D{
+		{
+			Key: "$or", Value: bson.A{
+				bson.D{
+					{Key: "userId", Value: userObjectID},
+				},
+				bson.D{
+					{Key: "collaborators.userId", Value: userObjectID},
+				},
+			},
+		},
+		{Key: "recurrence.seriesId", Value: seriesObjectID},
+		{Key: "deleted", Value: false},
+	}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "recurrence.occurrenceAt", Value: 1}})
+
+	cursor, err := s.DB.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cursor.All(ctx, &t)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// FindLatestOfSeriesBefore finds the latest task of every recurring series if it occurs before until.
+// Deleted tasks are included, so that a deleted occurrence isn't created again.
+func (s *MongoDBTaskRepository) FindLatestOfSeriesBefore(ctx context.Context, until time.Time) ([]Task, error) {
+	var t []Task
+
+	matchStage := bson.D{{Key: "$match", Value: bson.M{"recurrence.rule": bson.M{"$exists": true, "$ne": ""}}}}
+	sortStage := bson.D{{Key: "$sort", Value: bson.D{{Key: "recurrence.occurrenceAt", Value: -1}}}}
+	groupStage := bson.D{{Key: "$group", Value: bson.M{"_id": "$recurrence.seriesId", "latest": bson.M{"$first": "$$ROOT"}}}}
+	replaceRootStage := bson.D{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$latest"}}}
+	matchStage2 := bson.D{{Key: "$match", Value: bson.M{"recurrence.occurrenceAt": bson.M{"$lt": until}}}}
+
+	cursor, err := s.DB.Aggregate(ctx, mongo.Pipeline{matchStage, sortStage, groupStage, replaceRootStage, matchStage2})
+	if err != nil {
+		return nil, err
+	}
+
+	err = cursor.All(ctx, &t)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// UpdateSeriesRule sets the rule for all tasks of a series including deleted ones
+func (s *MongoDBTaskRepository) UpdateSeriesRule(ctx context.Context, seriesID string, userID string, rule string) error {
+	seriesObjectID, err := primitive.ObjectIDFromHex(seriesID)
+	if err != nil {
+		return err
+	}
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.DB.UpdateMany(ctx,
+		bson.D{
+			{
+				Key: "$or", Value: bson.A{
+					bson.D{
+						{Key: "userId", Value: userObjectID},
+					},
+					bson.D{
+						{Key: "collaborators.userId", Value: userObjectID},
+					},
+				},
+			},
+			{Key: "recurrence.seriesId", Value: seriesObjectID},
+		}, bson.M{
+			"$set": bson.M{
+				"recurrence.rule": rule,
+				"lastModifiedAt":  time.Now(),
+			},
+		})
+
+	return err
+}
+
 // FindIntersectingWithEvent finds tasks whose WorkUnits are scheduled so that they intersect with a given Event
 // The ignoreWorkUnitByID Parameter is optional, so it can be empty
 func (s *MongoDBTaskRepository) FindIntersectingWithEvent(ctx context.Context, userID string, event *calendar.Event, ignoreWorkUnitID primitive.ObjectID, isDeleted bool) ([]Task, error) {
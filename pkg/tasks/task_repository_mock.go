@@ -6,6 +6,7 @@ import (
 	"github.com/timeliness-app/timeliness-backend/pkg/date"
 	"github.com/timeliness-app/timeliness-backend/pkg/tasks/calendar"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
 	"time"
 )
 
@@ -34,6 +35,12 @@ func (m *MockTaskRepository) Add(_ context.Context, task *Task) error {
 func (m *MockTaskRepository) Update(_ context.Context, task *Task, deleted bool) error {
 	taskObjectID := task.ID
 	userObjectID := task.UserID
+	for index, unit := range task.WorkUnits {
+		if unit.ID.IsZero() {
+			task.WorkUnits[index].ID = primitive.NewObjectID()
+		}
+	}
+
 	for i, t := range m.Tasks {
 		if t.ID == taskObjectID && t.UserID == userObjectID {
 			m.Tasks[i] = (*Task)(task)
@@ -99,7 +106,8 @@ func (m *MockTaskRepository) FindByCalendarEventID(ctx context.Context, calendar
 	for _, t := range m.Tasks {
 		calendarEventDue := t.DueAt.CalendarEvents.FindByCalendarID(calendarEventID)
 		_, workUnit := t.WorkUnits.FindByCalendarID(calendarEventID)
-		if (calendarEventDue != nil && calendarEventDue.CalendarEventID == calendarEventID || workUnit != nil) &&
+		_, workUnitWithBreak := t.WorkUnits.FindByBreakCalendarID(calendarEventID)
+		if (calendarEventDue != nil && calendarEventDue.CalendarEventID == calendarEventID || workUnit != nil || workUnitWithBreak != nil) &&
 			(t.UserID == userObjectID || t.Collaborators.IncludesUser(userID)) {
 			return (*Task)(t), nil
 		}
@@ -134,9 +142,224 @@ func (m *MockTaskRepository) FindUnscheduledTasks(ctx context.Context, userID st
 	return nil, 0, nil
 }
 
-// FindIntersectingWithEvent is not implemented yet
-func (m *MockTaskRepository) FindIntersectingWithEvent(ctx context.Context, userID string, event *calendar.Event, ignoreTaskID primitive.ObjectID, isDeleted bool) ([]Task, error) {
-	return []Task{}, nil
+// FindBlockedBy finds all tasks that are not done and blocked by the given task
+func (m *MockTaskRepository) FindBlockedBy(ctx context.Context, taskID string, userID string) ([]Task, error) {
+	taskObjectID, _ := primitive.ObjectIDFromHex(taskID)
+	userObjectID, _ := primitive.ObjectIDFromHex(userID)
+
+	var tasks []Task
+	for _, t := range m.Tasks {
+		if t.IsDone || (t.UserID != userObjectID && !t.Collaborators.IncludesUser(userID)) {
+			continue
+		}
+
+		for _, predecessorID := range t.BlockedBy {
+			if predecessorID == taskObjectID {
+				tasks = append(tasks, *t)
+				break
+			}
+		}
+	}
+
+	return tasks, nil
+}
+
+// FindOpenTasks finds all tasks owned by a user that are not done sorted by their due date
+func (m *MockTaskRepository) FindOpenTasks(ctx context.Context, userID string) ([]Task, error) {
+	userObjectID, _ := primitive.ObjectIDFromHex(userID)
+
+	var tasks []Task
+	for _, t := range m.Tasks {
+		if t.Deleted || t.IsDone || t.UserID != userObjectID {
+			continue
+		}
+
+		tasks = append(tasks, *t)
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].DueAt.Date.Start.Before(tasks[j].DueAt.Date.Start)
+	})
+
+	return tasks, nil
+}
+
+// FindWithCalendarType finds all tasks that have an event persisted in a calendar of the given type for a user
+func (m *MockTaskRepository) FindWithCalendarType(ctx context.Context, userID string, calendarType calendar.Type) ([]Task, error) {
+	hasCalendarType := func(event *calendar.Event) bool {
+		persistedEvent := event.CalendarEvents.FindByUserID(userID)
+		return persistedEvent != nil && persistedEvent.CalendarType == calendarType
+	}
+
+	var tasks []Task
+	for _, t := range m.Tasks {
+		if t.Deleted {
+			continue
+		}
+
+		found := hasCalendarType(&t.DueAt)
+		for _, unit := range t.WorkUnits {
+			if hasCalendarType(&unit.ScheduledAt) || (unit.Break != nil && hasCalendarType(unit.Break)) {
+				found = true
+			}
+		}
+
+		if found {
+			tasks = append(tasks, *t)
+		}
+	}
+
+	return tasks, nil
+}
+
+// FindForFeed finds all tasks a user owns or collaborates on that are due or have work units ending after from
+func (m *MockTaskRepository) FindForFeed(ctx context.Context, userID string, from time.Time) ([]Task, error) {
+	userObjectID, _ := primitive.ObjectIDFromHex(userID)
+
+	var tasks []Task
+	for _, t := range m.Tasks {
+		if t.Deleted || (t.UserID != userObjectID && !t.Collaborators.IncludesUser(userID)) {
+			continue
+		}
+
+		relevant := !t.IsBacklog && !t.DueAt.Date.End.Before(from)
+		for _, unit := range t.WorkUnits {
+			if !unit.ScheduledAt.Date.End.Before(from) {
+				relevant = true
+			}
+		}
+
+		if relevant {
+			tasks = append(tasks, *t)
+		}
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].DueAt.Date.Start.Before(tasks[j].DueAt.Date.Start)
+	})
+
+	return tasks, nil
+}
+
+// FindWithMissedWorkUnits finds the tasks of all users with work units that ended before the given time without
+// being done and that weren't marked as missed yet
+func (m *MockTaskRepository) FindWithMissedWorkUnits(ctx context.Context, before time.Time) ([]Task, error) {
+	var tasks []Task
+	for _, t := range m.Tasks {
+		if t.Deleted || t.IsDone {
+			continue
+		}
+
+		for _, unit := range t.WorkUnits {
+			if !unit.IsDone && !unit.IsMissed && unit.ScheduledAt.Date.End.Before(before) {
+				tasks = append(tasks, *t)
+				break
+			}
+		}
+	}
+
+	return tasks, nil
+}
+
+// FindUnscheduledBacklogTasks finds the backlog tasks of all users that have workload that isn't scheduled yet
+func (m *MockTaskRepository) FindUnscheduledBacklogTasks(ctx context.Context) ([]Task, error) {
+	var tasks []Task
+	for _, t := range m.Tasks {
+		if t.Deleted || t.IsDone || !t.IsBacklog || t.NotScheduled <= 0 {
+			continue
+		}
+
+		tasks = append(tasks, *t)
+	}
+
+	return tasks, nil
+}
+
+// FindBySeriesID finds all tasks of a recurring series sorted by their occurrence
+func (m *MockTaskRepository) FindBySeriesID(ctx context.Context, seriesID string, userID string) ([]Task, error) {
+	seriesObjectID, _ := primitive.ObjectIDFromHex(seriesID)
+	userObjectID, _ := primitive.ObjectIDFromHex(userID)
+
+	var tasks []Task
+	for _, t := range m.Tasks {
+		if t.Deleted || t.Recurrence == nil || t.Recurrence.SeriesID != seriesObjectID {
+			continue
+		}
+
+		if t.UserID == userObjectID || t.Collaborators.IncludesUser(userID) {
+			tasks = append(tasks, *t)
+		}
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].Recurrence.OccurrenceAt.Before(tasks[j].Recurrence.OccurrenceAt)
+	})
+
+	return tasks, nil
+}
+
+// FindLatestOfSeriesBefore finds the latest task of every recurring series if it occurs before until
+func (m *MockTaskRepository) FindLatestOfSeriesBefore(ctx context.Context, until time.Time) ([]Task, error) {
+	latest := make(map[primitive.ObjectID]*Task)
+	var seriesIDs []primitive.ObjectID
+
+	for _, t := range m.Tasks {
+		if t.Recurrence == nil || t.Recurrence.Rule == "" {
+			continue
+		}
+
+		current, ok := latest[t.Recurrence.SeriesID]
+		if !ok {
+			seriesIDs = append(seriesIDs, t.Recurrence.SeriesID)
+		}
+
+		if !ok || t.Recurrence.OccurrenceAt.After(current.Recurrence.OccurrenceAt) {
+			latest[t.Recurrence.SeriesID] = t
+		}
+	}
+
+	var tasks []Task
+	for _, seriesID := range seriesIDs {
+		if latest[seriesID].Recurrence.OccurrenceAt.Before(until) {
+			tasks = append(tasks, *latest[seriesID])
+		}
+	}
+
+	return tasks, nil
+}
+
+// UpdateSeriesRule sets the rule for all tasks of a series including deleted ones
+func (m *MockTaskRepository) UpdateSeriesRule(ctx context.Context, seriesID string, userID string, rule string) error {
+	seriesObjectID, _ := primitive.ObjectIDFromHex(seriesID)
+
+	for _, t := range m.Tasks {
+		if t.Recurrence != nil && t.Recurrence.SeriesID == seriesObjectID {
+			t.Recurrence.Rule = rule
+		}
+	}
+
+	return nil
+}
+
+// FindIntersectingWithEvent finds tasks whose WorkUnits are scheduled so that they intersect with a given Event
+func (m *MockTaskRepository) FindIntersectingWithEvent(ctx context.Context, userID string, event *calendar.Event, ignoreWorkUnitID primitive.ObjectID, isDeleted bool) ([]Task, error) {
+	userObjectID, _ := primitive.ObjectIDFromHex(userID)
+
+	tasks := []Task{}
+	for _, t := range m.Tasks {
+		if t.Deleted != isDeleted || (t.UserID != userObjectID && !t.Collaborators.IncludesUser(userID)) {
+			continue
+		}
+
+		indices, _ := t.WorkUnits.FindByEventIntersection(event, ignoreWorkUnitID)
+		if len(indices) == 0 {
+			continue
+		}
+
+		tasks = append(tasks, *t)
+	}
+
+	return tasks, nil
 }
 
 // FindWorkUnitsIntersectingTimespan finds all work units intersecting a timespan
@@ -155,6 +378,35 @@ func (m *MockTaskRepository) FindWorkUnitsIntersectingTimespan(ctx context.Conte
 	return workUnits, nil
 }
 
+// FindUnwoundIntersectingTimespan finds all work units intersecting a timespan together with their task
+func (m *MockTaskRepository) FindUnwoundIntersectingTimespan(ctx context.Context, userID string, timespan date.Timespan) ([]TaskUnwound, error) {
+	var unwound []TaskUnwound
+
+	for _, task := range m.Tasks {
+		if task.Deleted {
+			continue
+		}
+
+		for index, unit := range task.WorkUnits {
+			if unit.ScheduledAt.Date.IntersectsWith(timespan) {
+				unwound = append(unwound, TaskUnwound{
+					ID:              task.ID,
+					UserID:          task.UserID,
+					Name:            task.Name,
+					Tags:            task.Tags,
+					WorkloadOverall: task.WorkloadOverall,
+					DueAt:           task.DueAt,
+					WorkUnit:        unit,
+					WorkUnitsIndex:  index,
+					IsBacklog:       task.IsBacklog,
+				})
+			}
+		}
+	}
+
+	return unwound, nil
+}
+
 // DeleteFinally is not implemented yet
 func (m *MockTaskRepository) DeleteFinally(ctx context.Context, taskID string, userID string) error {
 	panic("implement me")
@@ -27,6 +27,11 @@ func (t *TaskTextRenderer) RenderWorkUnitEventTitle(task *Task, workUnit *WorkUn
 	return fmt.Sprintf("%s %s", icon, task.Name)
 }
 
+// RenderBreakEventTitle renders a title of a break between two work units
+func (t *TaskTextRenderer) RenderBreakEventTitle(task *Task) string {
+	return fmt.Sprintf("☕ Break from %s", task.Name)
+}
+
 // HasReminder returns true if the Task or WorkUnit implementing Done is done
 func (t *TaskTextRenderer) HasReminder(element Done) bool {
 	return !element.CheckDone()
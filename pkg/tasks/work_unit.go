@@ -13,8 +13,17 @@ type WorkUnit struct {
 	IsDone       bool               `json:"isDone" bson:"isDone"`
 	MarkedDoneAt time.Time          `json:"markedDoneAt" bson:"markedDoneAt"`
 
+	// IsPinned work units are never moved, merged or cut by the planner, only by the user
+	IsPinned bool `json:"isPinned" bson:"isPinned"`
+
+	// IsMissed work units passed without being done and wait for the user to decide what happens with them
+	IsMissed bool `json:"isMissed" bson:"isMissed"`
+
 	ScheduledAt calendar.Event `json:"scheduledAt" bson:"scheduledAt"`
 	Workload    time.Duration  `json:"workload" bson:"workload"`
+
+	// Break is the intentional break after the work unit when work is split into chunks, it doesn't block any time
+	Break *calendar.Event `json:"break,omitempty" bson:"break,omitempty"`
 }
 
 // CheckDone checks if the task is done
@@ -24,6 +24,18 @@ const CalendarConnectionStatusUnverified = "unverified"
 // CalendarConnectionStatusMissingScopes marks that a calendar connection is missing scopes
 const CalendarConnectionStatusMissingScopes = "missing_scopes"
 
+// CalendarProviderGoogle is the provider of connections in User.GoogleCalendarConnections
+const CalendarProviderGoogle = "google"
+
+// CalendarProviderMicrosoft is the provider of connections in User.MicrosoftCalendarConnections
+const CalendarProviderMicrosoft = "microsoft"
+
+// CalendarProviderCalDAV is the provider of connections in User.CalDAVCalendarConnections
+const CalendarProviderCalDAV = "caldav"
+
+// CalendarProviderICS is the provider of connections in User.ICSCalendarConnections
+const CalendarProviderICS = "ics"
+
 // User represents the user
 type User struct {
 	ID             primitive.ObjectID `json:"id" bson:"_id"`
@@ -39,10 +51,74 @@ type User struct {
 	Contacts       []Contact          `json:"contacts" bson:"contacts"`
 	Billing        Billing            `json:"billing" bson:"billing"`
 
-	GoogleCalendarConnections GoogleCalendarConnections `json:"googleCalendarConnections" bson:"googleCalendarConnections"`
-	Settings                  UserSettings              `json:"settings" bson:"settings"`
-	EmailVerified             bool                      `json:"emailVerified" bson:"emailVerified"`
-	EmailVerificationToken    string                    `json:"-" bson:"emailVerificationToken"`
+	GoogleCalendarConnections    CalendarConnections `json:"googleCalendarConnections" bson:"googleCalendarConnections"`
+	MicrosoftCalendarConnections CalendarConnections `json:"microsoftCalendarConnections" bson:"microsoftCalendarConnections"`
+	CalDAVCalendarConnections    CalendarConnections `json:"caldavCalendarConnections" bson:"caldavCalendarConnections"`
+	ICSCalendarConnections       CalendarConnections `json:"icsCalendarConnections" bson:"icsCalendarConnections"`
+	Settings                     UserSettings        `json:"settings" bson:"settings"`
+	Absences                     Absences            `json:"absences" bson:"absences"`
+	EmailVerified                bool                `json:"emailVerified" bson:"emailVerified"`
+	EmailVerificationToken       string              `json:"-" bson:"emailVerificationToken"`
+
+	// FeedToken is the secret part of the URL of the ICS feed of the user
+	FeedToken string `json:"-" bson:"feedToken,omitempty"`
+}
+
+// Absence is a period in which the user doesn't work at all, e.g. a vacation
+type Absence struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id"`
+	Name           string             `json:"name" bson:"name"`
+	Date           date.Timespan      `json:"date" bson:"date" validate:"required"`
+	CreatedAt      time.Time          `json:"createdAt" bson:"createdAt"`
+	LastModifiedAt time.Time          `json:"lastModifiedAt" bson:"lastModifiedAt"`
+}
+
+// Absences is a slice of Absence
+type Absences []Absence
+
+// FindByID finds an absence by its ID
+func (a Absences) FindByID(ID string) (*Absence, int, error) {
+	for i, absence := range a {
+		if absence.ID.Hex() == ID {
+			return &absence, i, nil
+		}
+	}
+
+	return nil, 0, fmt.Errorf("could not find absence with id %s", ID)
+}
+
+// RemoveByID removes an absence by its ID
+func (a Absences) RemoveByID(ID string) Absences {
+	for i, absence := range a {
+		if absence.ID.Hex() == ID {
+			return append(a[:i], a[i+1:]...)
+		}
+	}
+
+	return a
+}
+
+// Intersecting returns the timespans of all absences intersecting the timespan
+func (a Absences) Intersecting(timespan date.Timespan) []date.Timespan {
+	var timespans []date.Timespan
+	for _, absence := range a {
+		if absence.Date.IntersectsWith(timespan) {
+			timespans = append(timespans, absence.Date)
+		}
+	}
+
+	return timespans
+}
+
+// Contains returns true if the time is within any absence
+func (a Absences) Contains(t time.Time) bool {
+	for _, absence := range a {
+		if !t.Before(absence.Date.Start) && t.Before(absence.Date.End) {
+			return true
+		}
+	}
+
+	return false
 }
 
 // UserLogin is the view for users logger in
@@ -68,11 +144,11 @@ type DeviceToken struct {
 	LastRegistered time.Time
 }
 
-// GoogleCalendarConnections is a slice of GoogleCalendarConnection
-type GoogleCalendarConnections []GoogleCalendarConnection
+// CalendarConnections is a slice of CalendarConnection
+type CalendarConnections []CalendarConnection
 
 // FindByConnectionID finds a connection by it ID
-func (g GoogleCalendarConnections) FindByConnectionID(connectionID string) (*GoogleCalendarConnection, int, error) {
+func (g CalendarConnections) FindByConnectionID(connectionID string) (*CalendarConnection, int, error) {
 	for i, connection := range g {
 		if connectionID == connection.ID {
 			return &connection, i, nil
@@ -83,7 +159,7 @@ func (g GoogleCalendarConnections) FindByConnectionID(connectionID string) (*Goo
 }
 
 // GetTaskCalendarConnection finds a connection if its a task calendar connection
-func (g GoogleCalendarConnections) GetTaskCalendarConnection() (*GoogleCalendarConnection, int, error) {
+func (g CalendarConnections) GetTaskCalendarConnection() (*CalendarConnection, int, error) {
 	for i, connection := range g {
 		if connection.IsTaskCalendarConnection && connection.TaskCalendarID != "" {
 			return &connection, i, nil
@@ -94,7 +170,7 @@ func (g GoogleCalendarConnections) GetTaskCalendarConnection() (*GoogleCalendarC
 }
 
 // RemoveConnection removes a connection
-func (g GoogleCalendarConnections) RemoveConnection(connectionID string) GoogleCalendarConnections {
+func (g CalendarConnections) RemoveConnection(connectionID string) CalendarConnections {
 	for i, connection := range g {
 		if connectionID == connection.ID {
 			return append(g[:i], g[i+1:]...)
@@ -104,23 +180,80 @@ func (g GoogleCalendarConnections) RemoveConnection(connectionID string) GoogleC
 	return g
 }
 
-// GoogleCalendarConnection stores everything related to Google Calendar
-type GoogleCalendarConnection struct {
-	ID                       string              `json:"id" bson:"_id"`
-	Email                    string              `json:"email" bson:"email"`
-	IsTaskCalendarConnection bool                `json:"isTaskCalendarConnection" bson:"isTaskCalendarConnection"`
-	Status                   string              `json:"status" bson:"status"`
-	Token                    oauth2.Token        `json:"-" bson:"token,omitempty"`
-	StateToken               string              `json:"-" bson:"stateToken,omitempty"`
-	TaskCalendarID           string              `json:"-" bson:"taskCalendarID,omitempty"`
-	CalendarsOfInterest      GoogleCalendarSyncs `json:"-" bson:"calendarsOfInterest,omitempty"`
+// CalendarConnection stores everything related to a connected calendar account of any provider
+type CalendarConnection struct {
+	ID                       string        `json:"id" bson:"_id"`
+	Email                    string        `json:"email" bson:"email"`
+	IsTaskCalendarConnection bool          `json:"isTaskCalendarConnection" bson:"isTaskCalendarConnection"`
+	Status                   string        `json:"status" bson:"status"`
+	Token                    oauth2.Token  `json:"-" bson:"token,omitempty"`
+	StateToken               string        `json:"-" bson:"stateToken,omitempty"`
+	TaskCalendarID           string        `json:"-" bson:"taskCalendarID,omitempty"`
+	CalendarsOfInterest      CalendarSyncs `json:"-" bson:"calendarsOfInterest,omitempty"`
+
+	// CalDAV connections authenticate with basic auth instead of a token, the password is stored encrypted.
+	// ICS connections only use the ServerURL, it is the URL of the feed.
+	ServerURL string `json:"serverUrl,omitempty" bson:"serverUrl,omitempty"`
+	Username  string `json:"username,omitempty" bson:"username,omitempty"`
+	Password  string `json:"-" bson:"password,omitempty"`
+}
+
+// ProviderConnections are the connections of a single calendar provider
+type ProviderConnections struct {
+	Provider    string
+	Connections *CalendarConnections
+
+	// IsAvailabilityOnly providers only contribute busy times and can't hold the task calendar
+	IsAvailabilityOnly bool
 }
 
-// GoogleCalendarSyncs is a slice of GoogleCalendarSync
-type GoogleCalendarSyncs []GoogleCalendarSync
+// IsPolled tells if the calendars of the provider are polled, because it can't notify about changes
+func (p *ProviderConnections) IsPolled() bool {
+	return p.Provider == CalendarProviderCalDAV || p.Provider == CalendarProviderICS
+}
+
+// AllCalendarConnections returns the connections of all calendar providers of a user
+func (u *User) AllCalendarConnections() []ProviderConnections {
+	return []ProviderConnections{
+		{Provider: CalendarProviderGoogle, Connections: &u.GoogleCalendarConnections},
+		{Provider: CalendarProviderMicrosoft, Connections: &u.MicrosoftCalendarConnections},
+		{Provider: CalendarProviderCalDAV, Connections: &u.CalDAVCalendarConnections},
+		{Provider: CalendarProviderICS, Connections: &u.ICSCalendarConnections, IsAvailabilityOnly: true},
+	}
+}
+
+// FindCalendarConnections finds the connections of the provider that contain the connection and the index of it
+func (u *User) FindCalendarConnections(connectionID string) (*ProviderConnections, int, error) {
+	for _, providerConnections := range u.AllCalendarConnections() {
+		_, index, err := providerConnections.Connections.FindByConnectionID(connectionID)
+		if err == nil {
+			return &providerConnections, index, nil
+		}
+	}
+
+	return nil, 0, fmt.Errorf("could not find connection with id %s", connectionID)
+}
+
+// CountTaskCalendarCapableConnections counts the connections of all calendar providers of a user that can hold the
+// task calendar
+func (u *User) CountTaskCalendarCapableConnections() int {
+	count := 0
+	for _, providerConnections := range u.AllCalendarConnections() {
+		if providerConnections.IsAvailabilityOnly {
+			continue
+		}
+
+		count += len(*providerConnections.Connections)
+	}
+
+	return count
+}
+
+// CalendarSyncs is a slice of CalendarSync
+type CalendarSyncs []CalendarSync
 
 // HasCalendarWithID checks if a calendar exists in a sync
-func (s GoogleCalendarSyncs) HasCalendarWithID(ID string) bool {
+func (s CalendarSyncs) HasCalendarWithID(ID string) bool {
 	for _, sync := range s {
 		if sync.CalendarID == ID {
 			return true
@@ -131,7 +264,7 @@ func (s GoogleCalendarSyncs) HasCalendarWithID(ID string) bool {
 }
 
 // RemoveCalendar removes a calendar sync
-func (s GoogleCalendarSyncs) RemoveCalendar(ID string) GoogleCalendarSyncs {
+func (s CalendarSyncs) RemoveCalendar(ID string) CalendarSyncs {
 	for i, sync := range s {
 		if sync.CalendarID == ID {
 			return append(s[:i], s[i+1:]...)
@@ -141,8 +274,8 @@ func (s GoogleCalendarSyncs) RemoveCalendar(ID string) GoogleCalendarSyncs {
 	return s
 }
 
-// GoogleCalendarSync holds information about a calendar that will be used to determine busy times
-type GoogleCalendarSync struct {
+// CalendarSync holds information about a calendar that will be used to determine busy times
+type CalendarSync struct {
 	CalendarID     string    `json:"-" bson:"calendarId,omitempty"`
 	SyncResourceID string    `json:"-" bson:"syncResourceId,omitempty"`
 	ChannelID      string    `json:"-" bson:"channelId,omitempty"`
@@ -171,6 +304,48 @@ var TimingPreferences = []string{
 	TimingPreferenceVeryLate,
 }
 
+// ScoringStrategyDefault prefers free time close to the target time and next to existing work units
+const ScoringStrategyDefault = "default"
+
+// ScoringStrategyWeighted combines the factors of ScoringWeights
+const ScoringStrategyWeighted = "weighted"
+
+// ScoringStrategies represent all possible scoring strategies
+var ScoringStrategies = []string{
+	ScoringStrategyDefault,
+	ScoringStrategyWeighted,
+}
+
+// MissedWorkUnitActionAsk flags missed work units, so that the user can decide what happens with them
+const MissedWorkUnitActionAsk = "ask"
+
+// MissedWorkUnitActionReschedule reschedules missed work units into the remaining time until the deadline
+const MissedWorkUnitActionReschedule = "reschedule"
+
+// MissedWorkUnitActionMarkDone marks missed work units as done
+const MissedWorkUnitActionMarkDone = "markDone"
+
+// MissedWorkUnitActions represent all possible actions for missed work units
+var MissedWorkUnitActions = []string{
+	MissedWorkUnitActionAsk,
+	MissedWorkUnitActionReschedule,
+	MissedWorkUnitActionMarkDone,
+}
+
+// ScoringWeights tune the weighted scoring strategy. Every factor is measured in hours, so a weight says how many
+// hours of distance to the target time one hour of the factor is worth. A weight of zero turns the factor off.
+type ScoringWeights struct {
+	// Distance weighs the distance to the target time
+	Distance float64 `json:"distance" bson:"distance"`
+	// TimeOfDay weighs the distance to the clock of PreferredTimeOfDay
+	TimeOfDay          float64   `json:"timeOfDay" bson:"timeOfDay"`
+	PreferredTimeOfDay time.Time `json:"preferredTimeOfDay" bson:"preferredTimeOfDay"`
+	// Neighbors is the penalty for free time that isn't next to another work unit of the task
+	Neighbors float64 `json:"neighbors" bson:"neighbors"`
+	// Fragmentation weighs the free time that stays left over when a work unit is taken from a free timespan
+	Fragmentation float64 `json:"fragmentation" bson:"fragmentation"`
+}
+
 // UserSettings hold different settings roughly separated by topics
 type UserSettings struct {
 	OnboardingCompleted bool               `json:"onboardingCompleted" bson:"onboardingCompleted"`
@@ -186,6 +361,37 @@ type SchedulingSettings struct {
 	MinWorkUnitDuration  time.Duration   `json:"minWorkUnitDuration" bson:"minWorkUnitDuration"`
 	MaxWorkUnitDuration  time.Duration   `json:"maxWorkUnitDuration" bson:"maxWorkUnitDuration"`
 	HideDeadlineWhenDone bool            `json:"hideDeadlineWhenDone" bson:"hideDeadlineWhenDone"`
+
+	// AllowedWeekdayTimespans override AllowedTimespans for single weekdays
+	AllowedWeekdayTimespans []date.WeekdayTimespans `json:"allowedWeekdayTimespans" bson:"allowedWeekdayTimespans"`
+
+	// MaxWorkPerDay and MaxWorkPerWeek limit the work scheduled for all tasks, zero means no limit
+	MaxWorkPerDay   time.Duration    `json:"maxWorkPerDay" bson:"maxWorkPerDay"`
+	MaxWorkPerWeek  time.Duration    `json:"maxWorkPerWeek" bson:"maxWorkPerWeek"`
+	TagWorkloadCaps []TagWorkloadCap `json:"tagWorkloadCaps" bson:"tagWorkloadCaps"`
+
+	// ScoringStrategy decides which free time is used first, empty means ScoringStrategyDefault
+	ScoringStrategy string         `json:"scoringStrategy" bson:"scoringStrategy"`
+	ScoringWeights  ScoringWeights `json:"scoringWeights" bson:"scoringWeights"`
+
+	// DeadlineBuffer is the time before the due date in which work is only scheduled if it doesn't fit anywhere else
+	DeadlineBuffer time.Duration `json:"deadlineBuffer" bson:"deadlineBuffer"`
+
+	// MissedWorkUnitAction decides what happens with work units that passed without being done,
+	// empty means MissedWorkUnitActionAsk
+	MissedWorkUnitAction string `json:"missedWorkUnitAction" bson:"missedWorkUnitAction"`
+
+	// ChunkDuration splits work into work units of this fixed size, zero means work units are only limited by
+	// MinWorkUnitDuration and MaxWorkUnitDuration. Consecutive chunks are separated by a break of BreakDuration.
+	ChunkDuration time.Duration `json:"chunkDuration" bson:"chunkDuration"`
+	BreakDuration time.Duration `json:"breakDuration" bson:"breakDuration"`
+}
+
+// TagWorkloadCap limits the work scheduled for tasks with a tag, zero means no limit
+type TagWorkloadCap struct {
+	TagID          primitive.ObjectID `json:"tagId" bson:"tagId"`
+	MaxWorkPerDay  time.Duration      `json:"maxWorkPerDay" bson:"maxWorkPerDay"`
+	MaxWorkPerWeek time.Duration      `json:"maxWorkPerWeek" bson:"maxWorkPerWeek"`
 }
 
 // AppScopeFree is the free scope
@@ -19,6 +19,7 @@ import (
 	"github.com/timeliness-app/timeliness-backend/pkg/environment"
 	"github.com/timeliness-app/timeliness-backend/pkg/locking"
 	"github.com/timeliness-app/timeliness-backend/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
 	"io/ioutil"
 	"net/http"
@@ -354,7 +355,7 @@ func (handler *Handler) UserLoginWithGoogle(writer http.ResponseWriter, request
 			handler.Logger.Error(fmt.Sprintf("Could not add user %s to app users list", user.ID.Hex()), err)
 		}
 
-		user.GoogleCalendarConnections = append(user.GoogleCalendarConnections, GoogleCalendarConnection{
+		user.GoogleCalendarConnections = append(user.GoogleCalendarConnections, CalendarConnection{
 			ID:                       userInfo.ID,
 			Email:                    userInfo.Email,
 			IsTaskCalendarConnection: true,
@@ -365,7 +366,7 @@ func (handler *Handler) UserLoginWithGoogle(writer http.ResponseWriter, request
 		_, _, err = user.GoogleCalendarConnections.FindByConnectionID(userInfo.ID)
 		if err != nil {
 			// If they don't, add it
-			user.GoogleCalendarConnections = append(user.GoogleCalendarConnections, GoogleCalendarConnection{
+			user.GoogleCalendarConnections = append(user.GoogleCalendarConnections, CalendarConnection{
 				ID:                       userInfo.ID,
 				Email:                    userInfo.Email,
 				IsTaskCalendarConnection: true,
@@ -470,14 +471,23 @@ func (handler *Handler) UserSettingsPatch(writer http.ResponseWriter, request *h
 	}
 
 	if !reflect.DeepEqual(userSettings.Scheduling.AllowedTimespans, originalSettings.Scheduling.AllowedTimespans) {
-		for _, timespan := range userSettings.Scheduling.AllowedTimespans {
-			if !timespan.IsStartBeforeEnd() || timespan.Duration() == 0 {
-				handler.ResponseManager.RespondWithError(writer, 400, fmt.Sprintf("Allowed Timespan %s is invalid", timespan), nil, request, userSettings)
-				return
-			}
+		allowedTimespans, err := date.NormalizeAllowedTimespans(userSettings.Scheduling.AllowedTimespans)
+		if err != nil {
+			handler.ResponseManager.RespondWithError(writer, 400, err.Error(), err, request, userSettings)
+			return
 		}
 
-		userSettings.Scheduling.AllowedTimespans = date.MergeTimespans(userSettings.Scheduling.AllowedTimespans)
+		userSettings.Scheduling.AllowedTimespans = allowedTimespans
+	}
+
+	if !reflect.DeepEqual(userSettings.Scheduling.AllowedWeekdayTimespans, originalSettings.Scheduling.AllowedWeekdayTimespans) {
+		allowedWeekdayTimespans, err := date.NormalizeAllowedWeekdayTimespans(userSettings.Scheduling.AllowedWeekdayTimespans)
+		if err != nil {
+			handler.ResponseManager.RespondWithError(writer, 400, err.Error(), err, request, userSettings)
+			return
+		}
+
+		userSettings.Scheduling.AllowedWeekdayTimespans = allowedWeekdayTimespans
 	}
 
 	if userSettings.Scheduling.BusyTimeSpacing != originalSettings.Scheduling.BusyTimeSpacing {
@@ -511,6 +521,84 @@ func (handler *Handler) UserSettingsPatch(writer http.ResponseWriter, request *h
 		}
 	}
 
+	if userSettings.Scheduling.ChunkDuration != originalSettings.Scheduling.ChunkDuration {
+		if userSettings.Scheduling.ChunkDuration != 0 && (userSettings.Scheduling.ChunkDuration < time.Minute*10 || userSettings.Scheduling.ChunkDuration > time.Hour*2) {
+			handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, fmt.Sprintf("ChunkDuration is invalid"), nil, request, userSettings)
+			return
+		}
+	}
+
+	if userSettings.Scheduling.BreakDuration != originalSettings.Scheduling.BreakDuration {
+		if userSettings.Scheduling.BreakDuration < 0 || userSettings.Scheduling.BreakDuration > time.Hour {
+			handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, fmt.Sprintf("BreakDuration is invalid"), nil, request, userSettings)
+			return
+		}
+	}
+
+	if userSettings.Scheduling.MaxWorkPerDay != originalSettings.Scheduling.MaxWorkPerDay {
+		if userSettings.Scheduling.MaxWorkPerDay < 0 || userSettings.Scheduling.MaxWorkPerDay > time.Hour*24 {
+			handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, fmt.Sprintf("MaxWorkPerDay is invalid"), nil, request, userSettings)
+			return
+		}
+	}
+
+	if userSettings.Scheduling.MaxWorkPerWeek != originalSettings.Scheduling.MaxWorkPerWeek {
+		if userSettings.Scheduling.MaxWorkPerWeek < 0 || userSettings.Scheduling.MaxWorkPerWeek > time.Hour*24*7 {
+			handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, fmt.Sprintf("MaxWorkPerWeek is invalid"), nil, request, userSettings)
+			return
+		}
+	}
+
+	if userSettings.Scheduling.DeadlineBuffer != originalSettings.Scheduling.DeadlineBuffer {
+		if userSettings.Scheduling.DeadlineBuffer < 0 || userSettings.Scheduling.DeadlineBuffer > time.Hour*24*14 {
+			handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, fmt.Sprintf("DeadlineBuffer is invalid"), nil, request, userSettings)
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(userSettings.Scheduling.TagWorkloadCaps, originalSettings.Scheduling.TagWorkloadCaps) {
+		seenTags := make(map[primitive.ObjectID]bool)
+
+		for _, tagWorkloadCap := range userSettings.Scheduling.TagWorkloadCaps {
+			if tagWorkloadCap.TagID.IsZero() || seenTags[tagWorkloadCap.TagID] {
+				handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, fmt.Sprintf("Tag %s of workload cap is invalid", tagWorkloadCap.TagID.Hex()), nil, request, userSettings)
+				return
+			}
+			seenTags[tagWorkloadCap.TagID] = true
+
+			if tagWorkloadCap.MaxWorkPerDay < 0 || tagWorkloadCap.MaxWorkPerDay > time.Hour*24 ||
+				tagWorkloadCap.MaxWorkPerWeek < 0 || tagWorkloadCap.MaxWorkPerWeek > time.Hour*24*7 {
+				handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, fmt.Sprintf("Workload cap of tag %s is invalid", tagWorkloadCap.TagID.Hex()), nil, request, userSettings)
+				return
+			}
+		}
+	}
+
+	if userSettings.Scheduling.ScoringStrategy != originalSettings.Scheduling.ScoringStrategy {
+		if userSettings.Scheduling.ScoringStrategy != ScoringStrategyDefault &&
+			userSettings.Scheduling.ScoringStrategy != ScoringStrategyWeighted {
+			handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, fmt.Sprintf("ScoringStrategy is invalid"), nil, request, userSettings)
+			return
+		}
+	}
+
+	if userSettings.Scheduling.MissedWorkUnitAction != originalSettings.Scheduling.MissedWorkUnitAction {
+		if userSettings.Scheduling.MissedWorkUnitAction != MissedWorkUnitActionAsk &&
+			userSettings.Scheduling.MissedWorkUnitAction != MissedWorkUnitActionReschedule &&
+			userSettings.Scheduling.MissedWorkUnitAction != MissedWorkUnitActionMarkDone {
+			handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, fmt.Sprintf("MissedWorkUnitAction is invalid"), nil, request, userSettings)
+			return
+		}
+	}
+
+	if userSettings.Scheduling.ScoringWeights != originalSettings.Scheduling.ScoringWeights {
+		weights := userSettings.Scheduling.ScoringWeights
+		if weights.Distance < 0 || weights.TimeOfDay < 0 || weights.Neighbors < 0 || weights.Fragmentation < 0 {
+			handler.ResponseManager.RespondWithError(writer, http.StatusBadRequest, fmt.Sprintf("ScoringWeights are invalid"), nil, request, userSettings)
+			return
+		}
+	}
+
 	v := validator.New()
 	err = v.Struct(userSettings)
 	if err != nil {
@@ -17,12 +17,16 @@ type UserRepositoryInterface interface {
 	FindByID(ctx context.Context, id string) (*User, error)
 	FindByEmail(ctx context.Context, email string) (*User, error)
 	FindByGoogleStateToken(ctx context.Context, stateToken string) (*User, error)
+	FindByMicrosoftStateToken(ctx context.Context, stateToken string) (*User, error)
 	FindByBillingCustomerID(ctx context.Context, customerID string) (*User, error)
 	FindByVerificationToken(ctx context.Context, token string) (*User, error)
 	FindBySyncExpiration(ctx context.Context, greaterThan time.Time, page int, pageSize int) ([]*User, int, error)
 	FindByIdentityProvider(ctx context.Context, email string, ID string) (*User, error)
+	FindByFeedToken(ctx context.Context, token string) (*User, error)
 	Update(ctx context.Context, user *User) error
 	UpdateSettings(ctx context.Context, user *User) error
+	UpdateAbsences(ctx context.Context, user *User) error
+	UpdateFeedToken(ctx context.Context, user *User) error
 	Remove(ctx context.Context, id string) error
 }
 
@@ -93,6 +97,38 @@ func (s *UserRepository) FindByGoogleStateToken(ctx context.Context, stateToken
 	return &u, nil
 }
 
+// FindByMicrosoftStateToken finds a user by its Microsoft state Token
+func (s *UserRepository) FindByMicrosoftStateToken(ctx context.Context, stateToken string) (*User, error) {
+	var u = User{}
+
+	result := s.DB.FindOne(ctx, bson.M{"microsoftCalendarConnections.stateToken": stateToken})
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	err := result.Decode(&u)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// FindByFeedToken finds a user by the secret token of its ICS feed
+func (s *UserRepository) FindByFeedToken(ctx context.Context, token string) (*User, error) {
+	var u = User{}
+
+	result := s.DB.FindOne(ctx, bson.M{"feedToken": token})
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	err := result.Decode(&u)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
 // FindByBillingCustomerID finds a user by its Billing Customer ID
 func (s *UserRepository) FindByBillingCustomerID(ctx context.Context, customerID string) (*User, error) {
 	var u = User{}
@@ -145,7 +181,8 @@ func (s *UserRepository) FindByIdentityProvider(ctx context.Context, email strin
 	return &u, nil
 }
 
-// FindBySyncExpiration finds user documents where at least one sync is ready for renewal
+// FindBySyncExpiration finds user documents where at least one sync is ready for renewal, CalDAV and ICS calendars are
+// always ready because they are polled
 func (s *UserRepository) FindBySyncExpiration(ctx context.Context, greaterThan time.Time, page int, pageSize int) ([]*User, int, error) {
 	var users []*User
 	offset := page * pageSize
@@ -157,12 +194,48 @@ func (s *UserRepository) FindBySyncExpiration(ctx context.Context, greaterThan t
 
 	queryFilter := bson.D{
 		{
-			Key:   "googleCalendarConnections.calendarsOfInterest.expiration",
-			Value: bson.M{"$lte": greaterThan},
-		},
-		{
-			Key:   "googleCalendarConnections.status",
-			Value: bson.M{"$eq": CalendarConnectionStatusActive},
+			Key: "$or", Value: bson.A{
+				bson.D{
+					{
+						Key:   "googleCalendarConnections.calendarsOfInterest.expiration",
+						Value: bson.M{"$lte": greaterThan},
+					},
+					{
+						Key:   "googleCalendarConnections.status",
+						Value: bson.M{"$eq": CalendarConnectionStatusActive},
+					},
+				},
+				bson.D{
+					{
+						Key:   "microsoftCalendarConnections.calendarsOfInterest.expiration",
+						Value: bson.M{"$lte": greaterThan},
+					},
+					{
+						Key:   "microsoftCalendarConnections.status",
+						Value: bson.M{"$eq": CalendarConnectionStatusActive},
+					},
+				},
+				bson.D{
+					{
+						Key:   "caldavCalendarConnections.calendarsOfInterest.expiration",
+						Value: bson.M{"$lte": greaterThan},
+					},
+					{
+						Key:   "caldavCalendarConnections.status",
+						Value: bson.M{"$eq": CalendarConnectionStatusActive},
+					},
+				},
+				bson.D{
+					{
+						Key:   "icsCalendarConnections.calendarsOfInterest.expiration",
+						Value: bson.M{"$lte": greaterThan},
+					},
+					{
+						Key:   "icsCalendarConnections.status",
+						Value: bson.M{"$eq": CalendarConnectionStatusActive},
+					},
+				},
+			},
 		},
 	}
 
@@ -215,6 +288,38 @@ func (s *UserRepository) UpdateSettings(ctx context.Context, user *User) error {
 	return nil
 }
 
+// UpdateAbsences updates the absences of a user
+func (s *UserRepository) UpdateAbsences(ctx context.Context, user *User) error {
+	user.LastModifiedAt = time.Now()
+
+	result, err := s.DB.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"absences": user.Absences, "lastModifiedAt": user.LastModifiedAt}})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount != 1 {
+		return errors.New("updated count != 1")
+	}
+
+	return nil
+}
+
+// UpdateFeedToken updates only the ICS feed token of a user
+func (s *UserRepository) UpdateFeedToken(ctx context.Context, user *User) error {
+	user.LastModifiedAt = time.Now()
+
+	result, err := s.DB.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"feedToken": user.FeedToken, "lastModifiedAt": user.LastModifiedAt}})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount != 1 {
+		return errors.New("updated count != 1")
+	}
+
+	return nil
+}
+
 // Remove Deletes a user
 func (s *UserRepository) Remove(ctx context.Context, id string) error {
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -63,6 +63,19 @@ func (r *MockUserRepository) FindByGoogleStateToken(ctx context.Context, stateTo
 	return nil, errors.New("user not found")
 }
 
+// FindByMicrosoftStateToken finds a user by a MicrosoftStateToken
+func (r *MockUserRepository) FindByMicrosoftStateToken(ctx context.Context, stateToken string) (*User, error) {
+	for _, user := range r.Users {
+		for _, connection := range user.MicrosoftCalendarConnections {
+			if connection.StateToken == stateToken {
+				return user, nil
+			}
+		}
+	}
+
+	return nil, errors.New("user not found")
+}
+
 // FindByBillingCustomerID finds a user by a billing customer ID
 func (r *MockUserRepository) FindByBillingCustomerID(ctx context.Context, customerID string) (*User, error) {
 	for _, user := range r.Users {
@@ -85,6 +98,17 @@ func (r *MockUserRepository) FindByVerificationToken(ctx context.Context, token
 	return nil, errors.New("user not found")
 }
 
+// FindByFeedToken finds a user by the token of its ICS feed
+func (r *MockUserRepository) FindByFeedToken(ctx context.Context, token string) (*User, error) {
+	for _, user := range r.Users {
+		if user.FeedToken != "" && user.FeedToken == token {
+			return user, nil
+		}
+	}
+
+	return nil, errors.New("user not found")
+}
+
 // FindBySyncExpiration is not implemented yet
 func (r *MockUserRepository) FindBySyncExpiration(ctx context.Context, greaterThan time.Time, page int, pageSize int) ([]*User, int, error) {
 	panic("implement me")
@@ -102,6 +126,30 @@ func (r *MockUserRepository) Update(ctx context.Context, user *User) error {
 	return errors.New("user not found")
 }
 
+// UpdateAbsences updates the absences of a user
+func (r *MockUserRepository) UpdateAbsences(ctx context.Context, user *User) error {
+	for i, u := range r.Users {
+		if u.ID == user.ID {
+			r.Users[i].Absences = user.Absences
+			return nil
+		}
+	}
+
+	return errors.New("user not found")
+}
+
+// UpdateFeedToken updates the ICS feed token of a user
+func (r *MockUserRepository) UpdateFeedToken(ctx context.Context, user *User) error {
+	for i, u := range r.Users {
+		if u.ID == user.ID {
+			r.Users[i].FeedToken = user.FeedToken
+			return nil
+		}
+	}
+
+	return errors.New("user not found")
+}
+
 // UpdateSettings updates a users settings
 func (r *MockUserRepository) UpdateSettings(ctx context.Context, user *User) error {
 	for i, user := range r.Users {